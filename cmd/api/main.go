@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"petrochemical-data-platform/internal/config"
+	"petrochemical-data-platform/internal/handler"
+	"petrochemical-data-platform/internal/migrate"
+	"petrochemical-data-platform/internal/repository"
+	"petrochemical-data-platform/internal/service"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func main() {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Fatal("Failed to load config", zap.Error(err))
+	}
+
+	// Subcommand: api migrate [up|down|status] ...
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:], logger); err != nil {
+			logger.Fatal("Migration failed", zap.Error(err))
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pgRepo, err := repository.NewPostgresRepository(cfg.Database.PostgreSQL.DSN(), logger)
+	if err != nil {
+		logger.Fatal("Failed to connect to PostgreSQL", zap.Error(err))
+	}
+	defer pgRepo.Close()
+
+	chRepo, err := newClickHouseRepository(cfg.Database.ClickHouse, logger)
+	if err != nil {
+		logger.Fatal("Failed to connect to ClickHouse", zap.Error(err))
+	}
+	defer chRepo.Close()
+
+	redisRepo, err := repository.NewRedisRepository(
+		cfg.Redis.Host+":"+cfg.Redis.Port, cfg.Redis.Password, cfg.Redis.DB, logger)
+	if err != nil {
+		logger.Fatal("Failed to connect to Redis", zap.Error(err))
+	}
+	defer redisRepo.Close()
+
+	retentionSvc := service.NewRetentionService(pgRepo, chRepo,
+		migrate.NewClickHouseDriver(chRepo.Conn()), logger)
+
+	if cfg.Database.MigrateOnStart {
+		if err := migrateUp(ctx, pgRepo, chRepo, logger); err != nil {
+			logger.Fatal("Failed to apply migrations", zap.Error(err))
+		}
+		if err := retentionSvc.SyncTTL(ctx); err != nil {
+			logger.Fatal("Failed to apply retention policies", zap.Error(err))
+		}
+	}
+
+	indexSvc, err := service.NewIndexService(chRepo, cfg.Indices, logger)
+	if err != nil {
+		logger.Fatal("Invalid price index configuration", zap.Error(err))
+	}
+
+	completenessSvc, err := service.NewCompletenessService(chRepo, pgRepo, cfg.Completeness, logger)
+	if err != nil {
+		logger.Fatal("Invalid completeness configuration", zap.Error(err))
+	}
+
+	writeMode := service.WriteRevision
+	if cfg.Telemetry.WriteMode != "" {
+		if writeMode, err = service.ParseWriteMode(cfg.Telemetry.WriteMode); err != nil {
+			logger.Fatal("Invalid telemetry configuration", zap.Error(err))
+		}
+	}
+	detector, err := service.NewAnomalyDetector(cfg.Anomaly)
+	if err != nil {
+		logger.Fatal("Invalid anomaly configuration", zap.Error(err))
+	}
+	ingestionSvc, err := service.NewIngestionService(chRepo, pgRepo, redisRepo,
+		detector, writeMode, cfg.Ingest, logger)
+	if err != nil {
+		logger.Fatal("Invalid ingest configuration", zap.Error(err))
+	}
+
+	feedSvc, err := service.NewFeedService(ingestionSvc, pgRepo, cfg.Feeds, logger)
+	if err != nil {
+		logger.Fatal("Invalid feeds configuration", zap.Error(err))
+	}
+
+	modbusSvc, err := service.NewModbusService(ingestionSvc, cfg.Modbus, logger)
+	if err != nil {
+		logger.Fatal("Invalid modbus configuration", zap.Error(err))
+	}
+
+	opcuaSvc, err := service.NewOPCUAService(ingestionSvc, cfg.OPCUA, logger)
+	if err != nil {
+		logger.Fatal("Invalid OPC UA configuration", zap.Error(err))
+	}
+
+	mqttIngestSvc, err := service.NewMQTTIngestService(ingestionSvc, cfg.MQTT, cfg.Ingest.MQTT, logger)
+	if err != nil {
+		logger.Fatal("Invalid ingest configuration", zap.Error(err))
+	}
+
+	mqttControlSvc, err := service.NewMQTTControlService(cfg.MQTT, cfg.Control.MQTT, logger)
+	if err != nil {
+		logger.Fatal("Invalid control configuration", zap.Error(err))
+	}
+
+	h := handler.NewHandler(
+		service.NewAssetService(pgRepo, chRepo, redisRepo, logger),
+		service.NewTelemetryService(chRepo, pgRepo, cfg.Telemetry, logger),
+		service.NewControlService(logger, modbusSvc, mqttControlSvc),
+		service.NewForecastService(chRepo, logger),
+		service.NewAnalyticsService(chRepo, logger),
+		indexSvc,
+		retentionSvc,
+		completenessSvc,
+		ingestionSvc,
+		opcuaSvc,
+		logger,
+	)
+
+	go ingestionSvc.Run(ctx)
+	go indexSvc.Run(ctx)
+	go completenessSvc.Run(ctx)
+	go feedSvc.Run(ctx)
+	go modbusSvc.Run(ctx)
+	go opcuaSvc.Run(ctx)
+	go mqttIngestSvc.Run(ctx)
+	go mqttControlSvc.Run(ctx)
+
+	r := gin.Default()
+	r.Use(cors.Default())
+	handler.SetupRoutes(r, h)
+
+	srv := &http.Server{
+		Addr:    ":" + cfg.Server.Port,
+		Handler: r,
+	}
+
+	go func() {
+		logger.Info("API server starting", zap.String("addr", srv.Addr))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("API server failed", zap.Error(err))
+		}
+	}()
+
+	<-ctx.Done()
+	logger.Info("Shutting down API server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Graceful shutdown failed", zap.Error(err))
+	}
+}
+
+func newClickHouseRepository(c config.ClickHouseConfig, logger *zap.Logger) (*repository.ClickHouseRepository, error) {
+	return repository.NewClickHouseRepository(c.Host+":"+c.Port, c.User, c.Password, logger)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"petrochemical-data-platform/internal/config"
+	"petrochemical-data-platform/internal/migrate"
+	"petrochemical-data-platform/internal/repository"
+	"petrochemical-data-platform/internal/service"
+
+	"go.uber.org/zap"
+)
+
+const migrateUsage = `usage: api migrate [-db all|postgres|clickhouse] <command>
+
+commands:
+  up [version]   apply pending migrations (up to version, if given)
+  down [steps]   revert the last applied migrations (default 1)
+  status         print the state of every migration`
+
+// runMigrate implements the migrate subcommand
+func runMigrate(cfg *config.Config, args []string, logger *zap.Logger) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	db := fs.String("db", "all", "database to migrate: all, postgres or clickhouse")
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, migrateUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing migrate command")
+	}
+
+	ctx := context.Background()
+	var migrators []*migrate.Migrator
+	var pgRepo *repository.PostgresRepository
+	var chRepo *repository.ClickHouseRepository
+	var err error
+
+	if *db == "all" || *db == "postgres" {
+		if pgRepo, err = repository.NewPostgresRepository(cfg.Database.PostgreSQL.DSN(), logger); err != nil {
+			return err
+		}
+		defer pgRepo.Close()
+
+		m, err := newPostgresMigrator(pgRepo, logger)
+		if err != nil {
+			return err
+		}
+		migrators = append(migrators, m)
+	}
+
+	if *db == "all" || *db == "clickhouse" {
+		if chRepo, err = newClickHouseRepository(cfg.Database.ClickHouse, logger); err != nil {
+			return err
+		}
+		defer chRepo.Close()
+
+		m, err := newClickHouseMigrator(chRepo, logger)
+		if err != nil {
+			return err
+		}
+		migrators = append(migrators, m)
+	}
+
+	if len(migrators) == 0 {
+		return fmt.Errorf("unknown database %q", *db)
+	}
+
+	command, arg := fs.Arg(0), fs.Arg(1)
+	for _, m := range migrators {
+		switch command {
+		case "up":
+			var target int64
+			if arg != "" {
+				v, err := strconv.ParseInt(arg, 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid version %q", arg)
+				}
+				target = v
+			}
+			if err := m.Up(ctx, target); err != nil {
+				return err
+			}
+		case "down":
+			steps := 1
+			if arg != "" {
+				n, err := strconv.Atoi(arg)
+				if err != nil || n < 1 {
+					return fmt.Errorf("invalid steps %q", arg)
+				}
+				steps = n
+			}
+			if err := m.Down(ctx, steps); err != nil {
+				return err
+			}
+		case "status":
+			statuses, err := m.Status(ctx)
+			if err != nil {
+				return err
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(statuses); err != nil {
+				return err
+			}
+		default:
+			fs.Usage()
+			return fmt.Errorf("unknown migrate command %q", command)
+		}
+	}
+
+	// Migrations that rebuild a table drop its TTL, so the retention policies stored in
+	// PostgreSQL are reapplied after every ClickHouse upgrade
+	if command == "up" && chRepo != nil {
+		if pgRepo == nil {
+			if pgRepo, err = repository.NewPostgresRepository(cfg.Database.PostgreSQL.DSN(), logger); err != nil {
+				return fmt.Errorf("failed to read retention policies: %w", err)
+			}
+			defer pgRepo.Close()
+		}
+		if err := syncRetention(ctx, pgRepo, chRepo, logger); err != nil {
+			return fmt.Errorf("failed to apply retention policies: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// syncRetention applies the stored retention policies as ClickHouse TTLs
+func syncRetention(ctx context.Context, pgRepo *repository.PostgresRepository, chRepo *repository.ClickHouseRepository, logger *zap.Logger) error {
+	retention := service.NewRetentionService(pgRepo, chRepo, migrate.NewClickHouseDriver(chRepo.Conn()), logger)
+	return retention.SyncTTL(ctx)
+}
+
+// migrateUp applies all pending migrations to both databases at API startup
+func migrateUp(ctx context.Context, pgRepo *repository.PostgresRepository, chRepo *repository.ClickHouseRepository, logger *zap.Logger) error {
+	pg, err := newPostgresMigrator(pgRepo, logger)
+	if err != nil {
+		return err
+	}
+	if err := pg.Up(ctx, 0); err != nil {
+		return err
+	}
+
+	ch, err := newClickHouseMigrator(chRepo, logger)
+	if err != nil {
+		return err
+	}
+	return ch.Up(ctx, 0)
+}
+
+func newPostgresMigrator(repo *repository.PostgresRepository, logger *zap.Logger) (*migrate.Migrator, error) {
+	migrations, err := migrate.Load(migrate.PostgresDir)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewMigrator(migrate.NewPostgresDriver(repo.Pool()), migrations, migrationOwner(), logger), nil
+}
+
+func newClickHouseMigrator(repo *repository.ClickHouseRepository, logger *zap.Logger) (*migrate.Migrator, error) {
+	migrations, err := migrate.Load(migrate.ClickHouseDir)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewMigrator(migrate.NewClickHouseDriver(repo.Conn()), migrations, migrationOwner(), logger), nil
+}
+
+// migrationOwner identifies this process in the migration lock tables
+func migrationOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}
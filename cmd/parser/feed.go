@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"petrochemical-data-platform/internal/config"
+	"petrochemical-data-platform/internal/pkg/parser"
+	"petrochemical-data-platform/internal/service"
+)
+
+const feedUsage = `usage: parser feed [-source name] <file>
+
+Parses a price feed report with the source from the feeds section of the config
+and prints the quotes and the points it would write. Nothing is written.
+
+flags:`
+
+// runFeed implements the feed subcommand: a dry run of a feed source against a file
+func runFeed(args []string) error {
+	fs := flag.NewFlagSet("feed", flag.ContinueOnError)
+	sourceName := fs.String("source", "", "feed source name (default: the source whose pattern matches the file name)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, feedUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("missing file")
+	}
+	file := fs.Arg(0)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	sources, err := service.NewFeedSources(cfg.Feeds)
+	if err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(sources, func(s *parser.FeedSource) bool {
+		if *sourceName != "" {
+			return s.Name == *sourceName
+		}
+		return s.Match(file)
+	})
+	if i < 0 {
+		return fmt.Errorf("no feed source for %s", file)
+	}
+	src := sources[i]
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	report, err := src.Adapter.Parse(bytes.NewReader(data), file)
+	if err != nil {
+		return err
+	}
+	points, unmapped := src.Mapper.Map(report, src.Name)
+
+	printFeed(os.Stdout, src.Name, report, points, unmapped)
+	return nil
+}
+
+func printFeed(w io.Writer, source string, report *parser.FeedReport, points []parser.DataPoint, unmapped []string) {
+	fmt.Fprintf(w, "Source: %s\nDate:   %s\nQuotes: %d\n\n", source, report.Date.Format(time.DateOnly), len(report.Quotes))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LINE\tINSTRUMENT\tNAME\tVALUES")
+	for _, q := range report.Quotes {
+		values := make([]string, 0, len(q.Values))
+		for _, field := range slices.Sorted(maps.Keys(q.Values)) {
+			values = append(values, fmt.Sprintf("%s=%g", field, q.Values[field]))
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", q.Line, q.Instrument, q.Name, strings.Join(values, " "))
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMPANY\tSERIES\tVALUE\tUNIT\tINSTRUMENTS")
+	for _, p := range points {
+		fmt.Fprintf(tw, "%s\t%s\t%g\t%s\t%s\n", p.CompanyID, p.ProductName, p.Value, p.Unit, p.Labels["instrument"])
+	}
+	tw.Flush()
+
+	if len(unmapped) > 0 {
+		fmt.Fprintf(w, "\nUnmapped instruments: %s\n", strings.Join(unmapped, ", "))
+	}
+}
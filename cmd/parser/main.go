@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"petrochemical-data-platform/internal/config"
+	"petrochemical-data-platform/internal/pkg/importer"
+	"petrochemical-data-platform/internal/pkg/parser"
+	"petrochemical-data-platform/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+const usage = `usage: parser -profile <profile.yaml> [flags] <file.csv|file.xlsx>
+       parser feed [-source name] <file>
+
+Imports historical market data (monthly prices and volumes from industry reports)
+into ClickHouse using a column mapping profile. See configs/import for examples.
+
+flags:`
+
+// maxReportIssues ограничивает число ошибок, выводимых в отчете
+const maxReportIssues = 100
+
+func main() {
+	// Subcommand: parser feed ...
+	if len(os.Args) > 1 && os.Args[1] == "feed" {
+		if err := runFeed(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "feed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	profilePath := flag.String("profile", "", "column mapping profile (YAML)")
+	sheet := flag.String("sheet", "", "XLSX sheet name (overrides the profile)")
+	dryRun := flag.Bool("dry-run", false, "validate the file and print a report without writing data")
+	resume := flag.Bool("resume", false, "continue an interrupted import from the state file")
+	statePath := flag.String("state", "", "state file for resumable imports (default <file>.import-state.json)")
+	batchSize := flag.Int("batch", 5000, "points per ClickHouse batch")
+	maxErrors := flag.Int("max-errors", 0, "import valid rows if the file has at most this many invalid rows")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *profilePath == "" || flag.NArg() != 1 || *batchSize <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	file := flag.Arg(0)
+	if *statePath == "" {
+		*statePath = file + ".import-state.json"
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	profile, err := importer.LoadProfile(*profilePath)
+	if err != nil {
+		logger.Fatal("Failed to load profile", zap.Error(err))
+	}
+	if *sheet != "" {
+		profile.Sheet = *sheet
+	}
+
+	rows, report, err := validate(file, profile)
+	if err != nil {
+		logger.Fatal("Failed to read file", zap.String("file", file), zap.Error(err))
+	}
+	report.Print(os.Stdout, maxReportIssues)
+
+	if *dryRun {
+		if len(report.Issues) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	if invalid := invalidRows(report.Issues); invalid > *maxErrors {
+		logger.Fatal("File has invalid rows, nothing was imported; fix the file or raise -max-errors to skip them",
+			zap.Int("invalid_rows", invalid), zap.Int("max_errors", *maxErrors))
+	}
+
+	state, err := prepareState(file, *statePath, profile.Name, *resume)
+	if err != nil {
+		logger.Fatal("Failed to prepare import state", zap.Error(err))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Fatal("Failed to load config", zap.Error(err))
+	}
+	c := cfg.Database.ClickHouse
+	chRepo, err := repository.NewClickHouseRepository(c.Host+":"+c.Port, c.User, c.Password, logger)
+	if err != nil {
+		logger.Fatal("Failed to connect to ClickHouse", zap.Error(err))
+	}
+	defer chRepo.Close()
+
+	// Блокировки рядов в PostgreSQL согласуют запись и пересчет агрегатов с API и другими загрузками
+	pgRepo, err := repository.NewPostgresRepository(cfg.Database.PostgreSQL.DSN(), logger)
+	if err != nil {
+		logger.Fatal("Failed to connect to PostgreSQL", zap.Error(err))
+	}
+	defer pgRepo.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := load(ctx, chRepo, pgRepo, rows, report, state, *statePath, *batchSize, logger); err != nil {
+		logger.Fatal("Import failed; rerun with -resume to continue",
+			zap.Int("last_row", state.LastRow), zap.String("state", *statePath), zap.Error(err))
+	}
+
+	logger.Info("Import completed",
+		zap.String("file", file),
+		zap.Int("points", state.Points),
+		zap.Int("skipped_rows", invalidRows(report.Issues)))
+}
+
+// convertedRow — точки одной строки файла
+type convertedRow struct {
+	number int
+	points []parser.DataPoint
+}
+
+// validate читает и преобразует весь файл до записи, чтобы ошибки были видны сразу,
+// а не после загрузки половины данных
+func validate(file string, profile *importer.Profile) ([]convertedRow, *importer.Report, error) {
+	sheet, err := importer.ReadFile(file, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	im, err := importer.New(profile, sheet)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := im.NewReport(file)
+	var rows []convertedRow
+	for _, row := range im.Rows() {
+		points, issues := im.Convert(row)
+		report.Add(points, issues)
+		// Строка с ошибками пропускается целиком, чтобы не загрузить ее частично
+		if len(points) > 0 && len(issues) == 0 {
+			rows = append(rows, convertedRow{number: row.Number, points: points})
+		}
+	}
+	return rows, report, nil
+}
+
+func invalidRows(issues []importer.Issue) int {
+	rows := make(map[int]struct{})
+	for _, issue := range issues {
+		rows[issue.Row] = struct{}{}
+	}
+	return len(rows)
+}
+
+func prepareState(file, path, profile string, resume bool) (*importState, error) {
+	checksum, err := fileChecksum(file)
+	if err != nil {
+		return nil, err
+	}
+	fresh := &importState{File: file, SHA256: checksum, Profile: profile}
+	if !resume {
+		return fresh, nil
+	}
+
+	state, err := loadState(path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case state == nil:
+		return fresh, nil
+	case state.SHA256 != checksum:
+		return nil, fmt.Errorf("file %s has changed since the interrupted import; run without -resume to import it again", file)
+	case state.Profile != profile:
+		return nil, fmt.Errorf("interrupted import used profile %q, not %q", state.Profile, profile)
+	case state.Completed:
+		return nil, fmt.Errorf("file %s has already been imported", file)
+	}
+	return state, nil
+}
+
+// load пишет точки пакетами и сохраняет прогресс после каждого пакета. Повторная запись
+// строк после сбоя безопасна: ReplacingMergeTree оставит одну версию точки.
+func load(ctx context.Context, chRepo *repository.ClickHouseRepository, pgRepo *repository.PostgresRepository, rows []convertedRow,
+	report *importer.Report, state *importState, statePath string, batchSize int, logger *zap.Logger) error {
+	batch := make([]repository.TelemetryData, 0, batchSize)
+	lastRow := state.LastRow
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		// Запись не должна попасть между удалением и вставкой бакетов при пересчете агрегатов
+		unlock, err := pgRepo.LockSeries(ctx, repository.SeriesOf(batch))
+		if err != nil {
+			return err
+		}
+		err = chRepo.SaveTelemetryBatch(ctx, batch)
+		unlock()
+		if err != nil {
+			return err
+		}
+		state.Points += len(batch)
+		state.LastRow = lastRow
+		if err := state.save(statePath); err != nil {
+			return err
+		}
+		logger.Info("Batch written", zap.Int("last_row", state.LastRow), zap.Int("points", state.Points))
+		batch = batch[:0]
+		return nil
+	}
+
+	for _, row := range rows {
+		if row.number <= state.LastRow {
+			continue
+		}
+		for _, p := range row.points {
+			batch = append(batch, repository.TelemetryData{
+				CompanyID:   p.CompanyID,
+				ProductName: p.ProductName,
+				Value:       p.Value,
+				Unit:        p.Unit,
+				Timestamp:   p.Timestamp,
+				Quality:     p.Quality,
+				Tags:        p.Tags,
+				Labels:      p.Labels,
+			})
+		}
+		lastRow = row.number
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	// Агрегаты считаются материализованными представлениями при вставке, поэтому
+	// повторный импорт тех же точек удвоил бы их: пересчитываем агрегаты по загруженным рядам
+	if err := rebuildRollups(ctx, chRepo, pgRepo, report); err != nil {
+		return fmt.Errorf("failed to rebuild rollups: %w", err)
+	}
+
+	state.Completed = true
+	return state.save(statePath)
+}
+
+func rebuildRollups(ctx context.Context, chRepo *repository.ClickHouseRepository, pgRepo *repository.PostgresRepository, report *importer.Report) error {
+	series := report.Series()
+	if len(series) == 0 {
+		return nil
+	}
+
+	var filter repository.TelemetryFilter
+	start, end := series[0].First, series[0].Last
+	for _, s := range series {
+		if !slices.Contains(filter.CompanyIDs, s.CompanyID) {
+			filter.CompanyIDs = append(filter.CompanyIDs, s.CompanyID)
+		}
+		if !slices.Contains(filter.Products, s.ProductName) {
+			filter.Products = append(filter.Products, s.ProductName)
+		}
+		start = minTime(start, s.First)
+		end = maxTime(end, s.Last)
+	}
+
+	unlock, err := pgRepo.LockTelemetry(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return chRepo.BackfillRollups(ctx, filter, start, end)
+}
+
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
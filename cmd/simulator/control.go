@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/pkg/simulator"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.uber.org/zap"
+)
+
+// commandListener принимает команды управления установками сценария из топиков команд
+// и публикует подтверждения после задержки установки (транспорт MQTT сервиса управления)
+type commandListener struct {
+	gen      *simulator.Generator
+	ackTopic string
+	logger   *zap.Logger
+
+	client mqtt.Client
+	wg     sync.WaitGroup
+}
+
+// listenCommands подписывается на топики команд всех установок. Команды для оборудования,
+// которого нет в сценарии, пропускаются: их может обслуживать другой экземпляр.
+func listenCommands(ctx context.Context, gen *simulator.Generator, broker, commandTopic, ackTopic string, logger *zap.Logger) (*commandListener, error) {
+	l := &commandListener{gen: gen, ackTopic: ackTopic, logger: logger}
+	topic := strings.ReplaceAll(commandTopic, "{equipment_id}", "+")
+
+	client, err := connectMQTT(broker, "equipment", func(c mqtt.Client) {
+		handler := func(_ mqtt.Client, msg mqtt.Message) { l.onCommand(ctx, msg) }
+		if token := c.Subscribe(topic, 1, handler); token.Wait() && token.Error() != nil {
+			logger.Error("Failed to subscribe to control commands", zap.String("topic", topic), zap.Error(token.Error()))
+			return
+		}
+		logger.Info("Listening for control commands", zap.String("topic", topic), zap.Int("equipment", gen.EquipmentCount()))
+	})
+	if err != nil {
+		return nil, err
+	}
+	l.client = client
+	return l, nil
+}
+
+func (l *commandListener) onCommand(ctx context.Context, msg mqtt.Message) {
+	var cmd domain.ControlCommand
+	if err := json.Unmarshal(msg.Payload(), &cmd); err != nil {
+		l.logger.Warn("Invalid control command", zap.String("topic", msg.Topic()), zap.Error(err))
+		return
+	}
+	e, ok := l.gen.Equipment(cmd.EquipmentID)
+	if !ok {
+		return
+	}
+
+	// Обработчик сообщений paho не должен блокироваться: задержка выдерживается отдельно
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.AckDelay(cmd.ID)):
+		}
+
+		ack := e.Execute(cmd, time.Now())
+		l.logger.Info("Control command handled",
+			zap.String("command_id", cmd.ID),
+			zap.String("equipment_id", cmd.EquipmentID),
+			zap.String("command", cmd.Command),
+			zap.String("status", ack.Status),
+			zap.String("reason", ack.Reason),
+			zap.String("state", ack.State))
+		l.publish(ack)
+	}()
+}
+
+func (l *commandListener) publish(ack domain.ControlAck) {
+	payload, err := json.Marshal(ack)
+	if err != nil {
+		l.logger.Error("Failed to encode control ack", zap.Error(err))
+		return
+	}
+	topic := strings.ReplaceAll(l.ackTopic, "{equipment_id}", ack.EquipmentID)
+	token := l.client.Publish(topic, 1, false, payload)
+	if !token.WaitTimeout(mqttTimeout) || token.Error() != nil {
+		l.logger.Error("Failed to publish control ack", zap.String("topic", topic), zap.Error(token.Error()))
+	}
+}
+
+// Close дожидается обработки принятых команд и отключается от брокера
+func (l *commandListener) Close() {
+	l.wg.Wait()
+	l.client.Disconnect(250)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"petrochemical-data-platform/internal/pkg/parser"
+	"petrochemical-data-platform/internal/pkg/simulator"
+
+	"go.uber.org/zap"
+)
+
+const usage = `usage: simulator [flags]
+       simulator replay -start <RFC3339> -end <RFC3339> [flags]
+
+Generates telemetry from a YAML scenario (baseline, growth or decline trend, seasonality
+and noise per series, plus scheduled outages, spikes, stuck values and bad quality) and
+sends it to stdout, the ingest API, MQTT or ClickHouse. The same scenario and seed always
+produce the same data. With a start and an end the period is generated as fast as
+possible; otherwise the simulator runs in real time (catching up from a past start), and
+scenario equipment follows start, stop and set_setpoint control commands received over
+MQTT. See configs/scenarios for examples. The replay subcommand plays stored telemetry
+back (simulator replay -h).
+
+flags:`
+
+// closeTimeout ограничивает завершение выхода (в т.ч. пересчет агрегатов) после остановки
+const closeTimeout = 10 * time.Minute
+
+func main() {
+	// Subcommand: simulator replay ...
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := runReplay(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	scenarioPath := flag.String("scenario", env("SIMULATOR_SCENARIO", "configs/scenarios/default.yaml"), "scenario file (YAML)")
+	outputKind := flag.String("output", env("SIMULATOR_OUTPUT", outputStdout), "output: stdout, http, mqtt or clickhouse")
+	url := flag.String("url", env("SIMULATOR_INGEST_URL", "http://localhost:8080/api/v1/telemetry/ingest"), "ingest API URL for -output http")
+	broker := flag.String("broker", env("MQTT_BROKER", "tcp://localhost:1883"), "MQTT broker for -output mqtt")
+	topic := flag.String("topic", env("SIMULATOR_TOPIC", "petrochem/telemetry/{company_id}"), "MQTT topic template for -output mqtt")
+	commandTopic := flag.String("command-topic", env("SIMULATOR_COMMAND_TOPIC", "petrochem/control/{equipment_id}/command"), "MQTT topic template of control commands for scenario equipment")
+	ackTopic := flag.String("ack-topic", env("SIMULATOR_ACK_TOPIC", "petrochem/control/{equipment_id}/ack"), "MQTT topic template of control command acks")
+	seed := flag.Int64("seed", 0, "random seed (overrides the scenario)")
+	start := flag.String("start", "", "scenario start, RFC3339 (overrides the scenario)")
+	end := flag.String("end", "", "scenario end, RFC3339 (overrides the scenario)")
+	speed := flag.Float64("speed", 0, "model time speed-up in real-time mode; above 1 timestamps run ahead of the clock (overrides the scenario)")
+	batchSize := flag.Int("batch", 5000, "points per batch")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 0 || *batchSize <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+
+	sc, err := simulator.LoadScenario(*scenarioPath)
+	if err != nil {
+		logger.Fatal("Failed to load scenario", zap.String("scenario", *scenarioPath), zap.Error(err))
+	}
+
+	var overrideErr error
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "seed":
+			sc.Seed = *seed
+		case "speed":
+			sc.Speed = *speed
+		case "start":
+			sc.Start, err = time.Parse(time.RFC3339, *start)
+			overrideErr = errors.Join(overrideErr, err)
+		case "end":
+			sc.End, err = time.Parse(time.RFC3339, *end)
+			overrideErr = errors.Join(overrideErr, err)
+		}
+	})
+	if overrideErr != nil {
+		logger.Fatal("Invalid flags", zap.Error(overrideErr))
+	}
+
+	gen, err := simulator.NewGenerator(sc)
+	if err != nil {
+		logger.Fatal("Invalid scenario", zap.String("scenario", *scenarioPath), zap.Error(err))
+	}
+
+	out, err := newOutput(*outputKind, outputOptions{URL: *url, Broker: *broker, Topic: *topic, Scenario: sc.Name}, logger)
+	if err != nil {
+		logger.Fatal("Failed to open output", zap.String("output", *outputKind), zap.Error(err))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("Simulator started",
+		zap.String("scenario", sc.Name),
+		zap.Int64("seed", sc.Seed),
+		zap.Int("series", gen.SeriesCount()),
+		zap.Duration("interval", sc.Interval),
+		zap.Time("start", gen.Start()),
+		zap.String("output", *outputKind))
+
+	w := &batchWriter{out: out, size: *batchSize, logger: logger}
+	if sc.End.IsZero() {
+		// Установки принимают команды только в реальном времени
+		if gen.EquipmentCount() > 0 {
+			listener, err := listenCommands(ctx, gen, *broker, *commandTopic, *ackTopic, logger)
+			if err != nil {
+				logger.Fatal("Failed to listen for control commands", zap.Error(err))
+			}
+			defer listener.Close()
+		}
+		err = runRealtime(ctx, gen, w)
+	} else {
+		err = runHistory(ctx, gen, w)
+	}
+
+	closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), closeTimeout)
+	defer cancel()
+	// При остановке сигналом накопленные точки все же передаются
+	if errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	if err == nil {
+		err = w.flush(closeCtx)
+	}
+	err = errors.Join(err, out.Close(closeCtx))
+
+	if err != nil {
+		logger.Fatal("Simulation failed", zap.Int("points", w.total), zap.Error(err))
+	}
+	logger.Info("Simulator stopped", zap.Int("points", w.total), zap.Time("until", w.until))
+}
+
+// env возвращает значение переменной окружения или значение по умолчанию
+func env(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// runHistory генерирует период [start, end) с максимальной скоростью
+func runHistory(ctx context.Context, gen *simulator.Generator, w *batchWriter) error {
+	sc := gen.Scenario()
+	for t := gen.Start(); t.Before(sc.End); t = t.Add(sc.Interval) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := w.add(ctx, t, gen.Points(t)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runRealtime передает точки по мере наступления их момента. Прошедший период от начала
+// сценария догоняется пакетами; при speed > 1 модельное время идет быстрее настенного
+// и уходит вперед текущего времени.
+func runRealtime(ctx context.Context, gen *simulator.Generator, w *batchWriter) error {
+	sc := gen.Scenario()
+	wallStart, modelStart := time.Now(), gen.Start()
+	next := modelStart
+
+	for {
+		now := time.Now()
+		accelerated := modelStart.Add(time.Duration(float64(now.Sub(wallStart)) * sc.Speed))
+		model := maxTime(now, accelerated)
+
+		for ; !next.After(model); next = next.Add(sc.Interval) {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := w.add(ctx, next, gen.Points(next)); err != nil {
+				return err
+			}
+		}
+		if err := w.flush(ctx); err != nil {
+			return err
+		}
+
+		// Следующий момент наступает по настенным или по ускоренным модельным часам
+		wait := min(next.Sub(now), time.Duration(float64(next.Sub(accelerated))/sc.Speed))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// batchWriter накапливает точки и передает их в выход пакетами
+type batchWriter struct {
+	out    output
+	size   int
+	logger *zap.Logger
+
+	batch []parser.DataPoint
+	total int
+	until time.Time // Последний переданный момент модельного времени
+}
+
+func (w *batchWriter) add(ctx context.Context, t time.Time, points []parser.DataPoint) error {
+	w.batch = append(w.batch, points...)
+	w.until = t
+	if len(w.batch) >= w.size {
+		return w.flush(ctx)
+	}
+	return nil
+}
+
+func (w *batchWriter) flush(ctx context.Context) error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	if err := w.out.Write(ctx, w.batch); err != nil {
+		return fmt.Errorf("failed to write batch: %w", err)
+	}
+	w.total += len(w.batch)
+	w.logger.Info("Batch written", zap.Int("points", w.total), zap.Time("until", w.until))
+	w.batch = w.batch[:0]
+	return nil
+}
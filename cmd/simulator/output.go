@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"petrochemical-data-platform/internal/config"
+	"petrochemical-data-platform/internal/pkg/parser"
+	"petrochemical-data-platform/internal/repository"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.uber.org/zap"
+)
+
+// Выходы симулятора
+const (
+	outputStdout     = "stdout"
+	outputHTTP       = "http"
+	outputMQTT       = "mqtt"
+	outputClickHouse = "clickhouse"
+)
+
+// output принимает пакеты точек симулятора
+type output interface {
+	Write(ctx context.Context, points []parser.DataPoint) error
+	Close(ctx context.Context) error
+}
+
+// outputOptions — параметры выходов из флагов
+type outputOptions struct {
+	URL      string
+	Broker   string
+	Topic    string
+	Scenario string
+}
+
+func newOutput(kind string, opts outputOptions, logger *zap.Logger) (output, error) {
+	switch kind {
+	case outputStdout:
+		return &stdoutOutput{w: bufio.NewWriter(os.Stdout)}, nil
+	case outputHTTP:
+		return &httpOutput{url: opts.URL, scenario: opts.Scenario, client: &http.Client{Timeout: 30 * time.Second}, logger: logger}, nil
+	case outputMQTT:
+		return newMQTTOutput(opts.Broker, opts.Topic)
+	case outputClickHouse:
+		return newClickHouseOutput(logger)
+	}
+	return nil, fmt.Errorf("unknown output %q: use stdout, http, mqtt or clickhouse", kind)
+}
+
+// stdoutOutput выводит точки в NDJSON
+type stdoutOutput struct {
+	w *bufio.Writer
+}
+
+func (o *stdoutOutput) Write(_ context.Context, points []parser.DataPoint) error {
+	enc := json.NewEncoder(o.w)
+	for i := range points {
+		if err := enc.Encode(&points[i]); err != nil {
+			return err
+		}
+	}
+	return o.w.Flush()
+}
+
+func (o *stdoutOutput) Close(context.Context) error {
+	return o.w.Flush()
+}
+
+// httpMaxAttempts — число попыток отправки пакета в API
+const httpMaxAttempts = 5
+
+// httpOutput отправляет пакеты в POST /api/v1/telemetry/ingest. Ключ идемпотентности
+// строится из содержимого пакета, поэтому повтор после обрыва связи не дублирует данные.
+type httpOutput struct {
+	url      string
+	scenario string
+	client   *http.Client
+	logger   *zap.Logger
+}
+
+func (o *httpOutput) Write(ctx context.Context, points []parser.DataPoint) error {
+	body, err := json.Marshal(points)
+	if err != nil {
+		return fmt.Errorf("failed to encode batch: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	key := o.scenario + "-" + hex.EncodeToString(sum[:16])
+
+	delay := time.Second
+	for attempt := 1; ; attempt++ {
+		retry, err := o.post(ctx, body, key)
+		if err == nil || !retry || attempt == httpMaxAttempts {
+			return err
+		}
+		o.logger.Warn("Failed to send batch, retrying", zap.Int("attempt", attempt), zap.Duration("delay", delay), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post отправляет пакет и сообщает, имеет ли смысл повтор
+func (o *httpOutput) post(ctx context.Context, body []byte, key string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	switch {
+	case resp.StatusCode == http.StatusConflict, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, fmt.Errorf("ingest returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	case resp.StatusCode != http.StatusOK:
+		return false, fmt.Errorf("ingest returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	var result struct {
+		Written  int `json:"written"`
+		Revised  int `json:"revised"`
+		Rejected []struct {
+			Index  int    `json:"index"`
+			Reason string `json:"reason"`
+		} `json:"rejected"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return false, fmt.Errorf("failed to decode ingest response: %w", err)
+	}
+	if n := len(result.Rejected); n > 0 {
+		o.logger.Warn("Points rejected by ingest",
+			zap.Int("rejected", n),
+			zap.Int("first_index", result.Rejected[0].Index),
+			zap.String("first_reason", result.Rejected[0].Reason))
+	}
+	return false, nil
+}
+
+func (o *httpOutput) Close(context.Context) error {
+	o.client.CloseIdleConnections()
+	return nil
+}
+
+// mqttOutput публикует пакеты компаний JSON-массивами в топики по шаблону с {company_id}
+type mqttOutput struct {
+	client mqtt.Client
+	topic  string
+}
+
+// mqttTimeout ограничивает ожидание подключения и подтверждения публикации
+const mqttTimeout = 30 * time.Second
+
+func newMQTTOutput(broker, topic string) (*mqttOutput, error) {
+	client, err := connectMQTT(broker, "output", nil)
+	if err != nil {
+		return nil, err
+	}
+	return &mqttOutput{client: client, topic: topic}, nil
+}
+
+// connectMQTT подключается к брокеру; onConnect вызывается после каждого (пере)подключения.
+// Учетные данные берутся из MQTT_USERNAME и MQTT_PASSWORD.
+func connectMQTT(broker, role string, onConnect mqtt.OnConnectHandler) (mqtt.Client, error) {
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(fmt.Sprintf("petrochem_simulator_%s_%d", role, os.Getpid())).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOnConnectHandler(onConnect)
+	if u := os.Getenv("MQTT_USERNAME"); u != "" {
+		opts.SetUsername(u).SetPassword(os.Getenv("MQTT_PASSWORD"))
+	}
+
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(mqttTimeout) {
+		return nil, fmt.Errorf("failed to connect to MQTT broker %s: timeout", broker)
+	}
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("failed to connect to MQTT broker %s: %w", broker, err)
+	}
+	return client, nil
+}
+
+func (o *mqttOutput) Write(_ context.Context, points []parser.DataPoint) error {
+	byCompany := make(map[string][]parser.DataPoint)
+	var companies []string
+	for _, p := range points {
+		if _, ok := byCompany[p.CompanyID]; !ok {
+			companies = append(companies, p.CompanyID)
+		}
+		byCompany[p.CompanyID] = append(byCompany[p.CompanyID], p)
+	}
+
+	for _, company := range companies {
+		payload, err := json.Marshal(byCompany[company])
+		if err != nil {
+			return fmt.Errorf("failed to encode batch: %w", err)
+		}
+		topic := strings.ReplaceAll(o.topic, "{company_id}", company)
+		token := o.client.Publish(topic, 1, false, payload)
+		if !token.WaitTimeout(mqttTimeout) {
+			return fmt.Errorf("failed to publish to %s: timeout", topic)
+		}
+		if err := token.Error(); err != nil {
+			return fmt.Errorf("failed to publish to %s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+func (o *mqttOutput) Close(context.Context) error {
+	o.client.Disconnect(250)
+	return nil
+}
+
+// clickHouseOutput пишет точки напрямую в ClickHouse и при закрытии пересчитывает агрегаты
+// записанных рядов: повторный прогон сценария за тот же период иначе удвоил бы их
+type clickHouseOutput struct {
+	repo   *repository.ClickHouseRepository
+	locks  *repository.PostgresRepository // Блокировки рядов, общие с API и cmd/parser
+	filter repository.TelemetryFilter
+	first  time.Time
+	last   time.Time
+}
+
+func newClickHouseOutput(logger *zap.Logger) (*clickHouseOutput, error) {
+	repo, err := openClickHouse(logger)
+	if err != nil {
+		return nil, err
+	}
+	locks, err := openPostgres(logger)
+	if err != nil {
+		repo.Close()
+		return nil, err
+	}
+	return &clickHouseOutput{repo: repo, locks: locks}, nil
+}
+
+// openClickHouse подключается к ClickHouse по конфигурации платформы
+func openClickHouse(logger *zap.Logger) (*repository.ClickHouseRepository, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	c := cfg.Database.ClickHouse
+	repo, err := repository.NewClickHouseRepository(c.Host+":"+c.Port, c.User, c.Password, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ClickHouse: %w", err)
+	}
+	return repo, nil
+}
+
+// openPostgres подключается к PostgreSQL по конфигурации платформы
+func openPostgres(logger *zap.Logger) (*repository.PostgresRepository, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	repo, err := repository.NewPostgresRepository(cfg.Database.PostgreSQL.DSN(), logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	return repo, nil
+}
+
+func (o *clickHouseOutput) Write(ctx context.Context, points []parser.DataPoint) error {
+	batch := make([]repository.TelemetryData, len(points))
+	for i, p := range points {
+		batch[i] = repository.TelemetryData{
+			CompanyID:   p.CompanyID,
+			ProductName: p.ProductName,
+			Value:       p.Value,
+			Unit:        p.Unit,
+			Timestamp:   p.Timestamp,
+			Quality:     p.Quality,
+			Tags:        p.Tags,
+			Labels:      p.Labels,
+		}
+
+		if !slices.Contains(o.filter.CompanyIDs, p.CompanyID) {
+			o.filter.CompanyIDs = append(o.filter.CompanyIDs, p.CompanyID)
+		}
+		if !slices.Contains(o.filter.Products, p.ProductName) {
+			o.filter.Products = append(o.filter.Products, p.ProductName)
+		}
+		if o.first.IsZero() || p.Timestamp.Before(o.first) {
+			o.first = p.Timestamp
+		}
+		if p.Timestamp.After(o.last) {
+			o.last = p.Timestamp
+		}
+	}
+
+	// Запись не должна попасть между удалением и вставкой бакетов при пересчете агрегатов
+	unlock, err := o.locks.LockSeries(ctx, repository.SeriesOf(batch))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return o.repo.SaveTelemetryBatch(ctx, batch)
+}
+
+func (o *clickHouseOutput) Close(ctx context.Context) error {
+	defer o.repo.Close()
+	defer o.locks.Close()
+	if o.first.IsZero() {
+		return nil
+	}
+
+	unlock, err := o.locks.LockTelemetry(ctx, o.filter)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := o.repo.BackfillRollups(ctx, o.filter, o.first, o.last); err != nil {
+		return fmt.Errorf("failed to rebuild rollups: %w", err)
+	}
+	return nil
+}
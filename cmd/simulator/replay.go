@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/pkg/parser"
+	"petrochemical-data-platform/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+const replayUsage = `usage: simulator replay -start <RFC3339> -end <RFC3339> [flags]
+
+Replays stored ClickHouse telemetry for a time window through the ingest API, MQTT,
+stdout or ClickHouse, with timestamps shifted to the present. -speed 1 keeps the original
+pacing, -speed N plays N times faster and -speed 0 sends as fast as possible.
+
+flags:`
+
+// replayChunk — отрезок исходного времени, читаемый из ClickHouse за один запрос: чтение
+// не держит запрос открытым на все время воспроизведения, а в памяти не больше отрезка
+const replayChunk = time.Hour
+
+// Привязка сдвинутых меток времени
+const (
+	alignStart = "start" // Начало окна — момент запуска
+	alignEnd   = "end"   // Конец окна — момент запуска: все метки в прошлом
+)
+
+// runReplay implements the replay subcommand
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	startFlag := fs.String("start", "", "window start, RFC3339")
+	endFlag := fs.String("end", "", "window end, RFC3339")
+	companies := fs.String("company", "", "comma-separated company IDs (default: all)")
+	products := fs.String("product", "", "comma-separated product names (default: all)")
+	speed := fs.Float64("speed", 1, "replay speed: 1 — original pacing, N — N times faster, 0 — as fast as possible")
+	align := fs.String("align", alignStart, "rebase timestamps so the window start (start) or end (end) is now")
+	outputKind := fs.String("output", env("SIMULATOR_OUTPUT", outputStdout), "output: stdout, http, mqtt or clickhouse")
+	url := fs.String("url", env("SIMULATOR_INGEST_URL", "http://localhost:8080/api/v1/telemetry/ingest"), "ingest API URL for -output http")
+	broker := fs.String("broker", env("MQTT_BROKER", "tcp://localhost:1883"), "MQTT broker for -output mqtt")
+	topic := fs.String("topic", env("SIMULATOR_TOPIC", "petrochem/telemetry/{company_id}"), "MQTT topic template for -output mqtt")
+	batchSize := fs.Int("batch", 5000, "points per batch")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, replayUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || *batchSize <= 0 || *speed < 0 {
+		fs.Usage()
+		return errors.New("invalid arguments")
+	}
+
+	start, err := time.Parse(time.RFC3339, *startFlag)
+	if err != nil {
+		return fmt.Errorf("invalid -start: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339, *endFlag)
+	if err != nil {
+		return fmt.Errorf("invalid -end: %w", err)
+	}
+	if !end.After(start) {
+		return errors.New("-end must be after -start")
+	}
+	if *align != alignStart && *align != alignEnd {
+		return fmt.Errorf("invalid -align %q: use start or end", *align)
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+	defer logger.Sync()
+
+	chRepo, err := openClickHouse(logger)
+	if err != nil {
+		return err
+	}
+	defer chRepo.Close()
+
+	out, err := newOutput(*outputKind, outputOptions{URL: *url, Broker: *broker, Topic: *topic, Scenario: "replay"}, logger)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	r := &replayer{
+		stream: chRepo.StreamTelemetryRange,
+		filter: repository.TelemetryFilter{CompanyIDs: splitList(*companies), Products: splitList(*products)},
+		start:  start,
+		end:    end,
+		speed:  *speed,
+		w:      &batchWriter{out: out, size: *batchSize, logger: logger},
+		label:  start.UTC().Format(time.RFC3339) + "/" + end.UTC().Format(time.RFC3339),
+	}
+	// Сдвиг кратен секунде, чтобы сдвинутые метки сохранили исходное выравнивание
+	r.wallStart = time.Now()
+	r.offset = r.wallStart.Truncate(time.Second).Sub(start)
+	if *align == alignEnd {
+		r.offset = r.wallStart.Truncate(time.Second).Sub(end)
+	}
+
+	logger.Info("Replay started",
+		zap.Time("start", start),
+		zap.Time("end", end),
+		zap.Float64("speed", *speed),
+		zap.Duration("offset", r.offset),
+		zap.String("output", *outputKind))
+
+	err = r.run(ctx)
+
+	closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), closeTimeout)
+	defer cancel()
+	if errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	if err == nil {
+		err = r.w.flush(closeCtx)
+	}
+	err = errors.Join(err, out.Close(closeCtx))
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Replay stopped", zap.Int("points", r.w.total), zap.Time("until", r.w.until))
+	return nil
+}
+
+// replayer читает окно телеметрии отрезками и передает точки со сдвинутыми метками,
+// выдерживая исходные интервалы между ними с учетом скорости
+type replayer struct {
+	stream func(ctx context.Context, filter repository.TelemetryFilter, from, to time.Time, fn func(domain.TelemetryData) error) error // Полуинтервал [from, to)
+	filter repository.TelemetryFilter
+	start  time.Time
+	end    time.Time
+	speed  float64
+	w      *batchWriter
+	label  string
+
+	wallStart time.Time
+	offset    time.Duration // Сдвиг меток: сдвинутое время = исходное + offset
+}
+
+func (r *replayer) run(ctx context.Context) error {
+	// Отрезки — полуинтервалы [from, to); окно включает end, метки хранятся с точностью до мс
+	stop := r.end.Add(time.Millisecond)
+	for from := r.start; from.Before(stop); from = from.Add(replayChunk) {
+		to := from.Add(replayChunk)
+		if to.After(stop) {
+			to = stop
+		}
+
+		var chunk []domain.TelemetryData
+		err := r.stream(ctx, r.filter, from, to, func(d domain.TelemetryData) error {
+			chunk = append(chunk, d)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < len(chunk); {
+			// Точки одного момента передаются вместе
+			t := chunk[i].Timestamp
+			j := i
+			for j < len(chunk) && chunk[j].Timestamp.Equal(t) {
+				j++
+			}
+			if err := r.wait(ctx, t); err != nil {
+				return err
+			}
+			if err := r.w.add(ctx, t.Add(r.offset), r.points(chunk[i:j])); err != nil {
+				return err
+			}
+			i = j
+		}
+	}
+	return nil
+}
+
+// wait передает накопленный пакет и ждет момента передачи точки с исходным временем t
+func (r *replayer) wait(ctx context.Context, t time.Time) error {
+	if r.speed == 0 {
+		return nil
+	}
+	due := r.wallStart.Add(time.Duration(float64(t.Sub(r.start)) / r.speed))
+	if !time.Now().Before(due) {
+		return nil
+	}
+
+	if err := r.w.flush(ctx); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(due)):
+		return nil
+	}
+}
+
+func (r *replayer) points(data []domain.TelemetryData) []parser.DataPoint {
+	points := make([]parser.DataPoint, len(data))
+	for i, d := range data {
+		labels := make(map[string]string, len(d.Labels)+1)
+		for k, v := range d.Labels {
+			labels[k] = v
+		}
+		labels["replay"] = r.label
+
+		points[i] = parser.DataPoint{
+			CompanyID:   d.CompanyID,
+			ProductName: d.ProductName,
+			Value:       d.Value,
+			Unit:        d.Unit,
+			Timestamp:   d.Timestamp.Add(r.offset),
+			Quality:     d.Quality,
+			Tags:        d.Tags,
+			Labels:      labels,
+		}
+	}
+	return points
+}
+
+// splitList разбирает список через запятую; пустая строка — пустой список
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
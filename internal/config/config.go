@@ -1,14 +1,26 @@
 package config
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	MQTT     MQTTConfig     `mapstructure:"mqtt"`
+	Server       ServerConfig       `mapstructure:"server"`
+	Database     DatabaseConfig     `mapstructure:"database"`
+	Redis        RedisConfig        `mapstructure:"redis"`
+	MQTT         MQTTConfig         `mapstructure:"mqtt"`
+	Telemetry    TelemetryConfig    `mapstructure:"telemetry"`
+	Ingest       IngestConfig       `mapstructure:"ingest"`
+	Anomaly      AnomalyConfig      `mapstructure:"anomaly"`
+	Indices      IndicesConfig      `mapstructure:"indices"`
+	Completeness CompletenessConfig `mapstructure:"completeness"`
+	Feeds        FeedsConfig        `mapstructure:"feeds"`
+	Modbus       ModbusConfig       `mapstructure:"modbus"`
+	OPCUA        OPCUAConfig        `mapstructure:"opcua"`
+	Control      ControlConfig      `mapstructure:"control"`
 }
 
 type ServerConfig struct {
@@ -16,8 +28,9 @@ type ServerConfig struct {
 }
 
 type DatabaseConfig struct {
-	PostgreSQL PostgreSQLConfig `mapstructure:"postgresql"`
-	ClickHouse ClickHouseConfig `mapstructure:"clickhouse"`
+	PostgreSQL     PostgreSQLConfig `mapstructure:"postgresql"`
+	ClickHouse     ClickHouseConfig `mapstructure:"clickhouse"`
+	MigrateOnStart bool             `mapstructure:"migrate_on_start"` // Применять миграции при запуске API
 }
 
 type PostgreSQLConfig struct {
@@ -29,12 +42,17 @@ type PostgreSQLConfig struct {
 	SSLMode  string `mapstructure:"sslmode"`
 }
 
+// DSN возвращает строку подключения к PostgreSQL
+func (c PostgreSQLConfig) DSN() string {
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
+		c.User, c.Password, c.Host, c.Port, c.DBName, c.SSLMode)
+}
+
 type ClickHouseConfig struct {
 	Host     string `mapstructure:"host"`
 	Port     string `mapstructure:"port"`
 	User     string `mapstructure:"user"`
 	Password string `mapstructure:"password"`
-	Database string `mapstructure:"database"`
 }
 
 type RedisConfig struct {
@@ -51,6 +69,232 @@ type MQTTConfig struct {
 	Password string `mapstructure:"password"`
 }
 
+// TelemetryConfig задает ограничения запросов сырых данных и режим записи повторных точек
+type TelemetryConfig struct {
+	DefaultPageSize int    `mapstructure:"default_page_size"`
+	MaxPageSize     int    `mapstructure:"max_page_size"`
+	WriteMode       string `mapstructure:"write_mode"` // reject, overwrite или revision
+}
+
+// IngestConfig задает ограничения приема пачек телеметрии по HTTP и MQTT
+type IngestConfig struct {
+	MaxBodyBytes   int64            `mapstructure:"max_body_bytes"`  // Предел размера тела (после распаковки gzip)
+	MaxRecords     int              `mapstructure:"max_records"`     // Предел числа записей в пачке
+	MaxFutureSkew  time.Duration    `mapstructure:"max_future_skew"` // Насколько время точки может опережать часы сервера
+	IdempotencyTTL time.Duration    `mapstructure:"idempotency_ttl"` // Срок хранения результата по ключу идемпотентности
+	Influx         []MetricMapping  `mapstructure:"influx"`          // Сопоставление протокола InfluxDB
+	Prometheus     []MetricMapping  `mapstructure:"prometheus"`      // Сопоставление Prometheus remote_write
+	MQTT           IngestMQTTConfig `mapstructure:"mqtt"`            // Прием пачек из брокера MQTT
+}
+
+// IngestMQTTConfig задает прием телеметрии из брокера MQTT (секция mqtt): сообщения —
+// JSON-массивы точек в формате POST /api/v1/telemetry/ingest
+type IngestMQTTConfig struct {
+	Topics []string `mapstructure:"topics"` // Фильтры топиков ("petrochem/telemetry/+"); пусто — прием отключен
+}
+
+// MetricMapping сопоставляет измерение (метрику), теги и поля с продуктом компании.
+// Match и Field — регулярные выражения; остальные значения — шаблоны с подстановками
+// {measurement}, {field} и {tag:имя}. Применяется первое подходящее правило.
+type MetricMapping struct {
+	Match       string            `mapstructure:"match"`
+	Field       string            `mapstructure:"field"`
+	CompanyID   string            `mapstructure:"company_id"`
+	ProductName string            `mapstructure:"product_name"`
+	Unit        string            `mapstructure:"unit"`
+	Tags        []string          `mapstructure:"tags"`
+	Labels      map[string]string `mapstructure:"labels"`
+}
+
+// AnomalyConfig задает чувствительность детектора аномалий по умолчанию и для отдельных продуктов
+type AnomalyConfig struct {
+	Enabled  bool                          `mapstructure:"enabled"`
+	Default  AnomalySensitivity            `mapstructure:"default"`
+	Products map[string]AnomalySensitivity `mapstructure:"products"` // Ключ — название продукта
+}
+
+type AnomalySensitivity struct {
+	Threshold   float64  `mapstructure:"threshold"`
+	Window      int      `mapstructure:"window"`
+	MinPoints   int      `mapstructure:"min_points"`
+	Methods     []string `mapstructure:"methods"`      // zscore, mad, seasonal
+	Season      string   `mapstructure:"season"`       // hour_of_day, day_of_week, month_of_year
+	RaiseAlerts *bool    `mapstructure:"raise_alerts"` // Не задано — как в default
+}
+
+// IndicesConfig задает ценовые индексы и расписание их пересчета
+type IndicesConfig struct {
+	Schedule    time.Duration     `mapstructure:"schedule"` // Период пересчета, например 1h
+	Interval    string            `mapstructure:"interval"` // Шаг индекса: day, week, month
+	Definitions []IndexDefinition `mapstructure:"definitions"`
+}
+
+type IndexDefinition struct {
+	ID         string           `mapstructure:"id"`
+	Name       string           `mapstructure:"name"`
+	Sector     string           `mapstructure:"sector"`
+	Method     string           `mapstructure:"method"`    // fixed, chain
+	BaseDate   string           `mapstructure:"base_date"` // YYYY-MM-DD
+	BaseValue  float64          `mapstructure:"base_value"`
+	Start      string           `mapstructure:"start"` // Начало расчета, YYYY-MM-DD
+	Components []IndexComponent `mapstructure:"components"`
+}
+
+type IndexComponent struct {
+	CompanyID string  `mapstructure:"company_id"`
+	Product   string  `mapstructure:"product"`
+	Weight    float64 `mapstructure:"weight"`
+}
+
+// CompletenessConfig задает ожидаемую частоту поступления данных и проверку пропусков
+type CompletenessConfig struct {
+	DefaultFrequency string             `mapstructure:"default_frequency"` // hour, day, week, month
+	Grace            time.Duration      `mapstructure:"grace"`             // Допустимая задержка данных после окончания интервала
+	Lookback         time.Duration      `mapstructure:"lookback"`          // Период проверки для оповещений об отсутствии данных
+	CheckInterval    time.Duration      `mapstructure:"check_interval"`    // Период проверки; 0 отключает оповещения
+	Rules            []CompletenessRule `mapstructure:"rules"`
+}
+
+// CompletenessRule задает частоту для компании и/или продукта; пустое поле подходит для любого значения
+type CompletenessRule struct {
+	CompanyID string        `mapstructure:"company_id"`
+	Product   string        `mapstructure:"product"`
+	Frequency string        `mapstructure:"frequency"`
+	Grace     time.Duration `mapstructure:"grace"` // 0 — значение по умолчанию
+}
+
+// FeedsConfig задает прием файлов отчетов бирж и ценовых агентств из каталога
+type FeedsConfig struct {
+	Directory    string             `mapstructure:"directory"`     // Каталог входящих файлов; пусто отключает прием
+	PollInterval time.Duration      `mapstructure:"poll_interval"` // Период просмотра каталога
+	SettleTime   time.Duration      `mapstructure:"settle_time"`   // Файл обрабатывается, если не менялся это время (дозапись)
+	WriteMode    string             `mapstructure:"write_mode"`    // Режим записи исправленных отчетов; пусто — из telemetry
+	Sources      []FeedSourceConfig `mapstructure:"sources"`
+}
+
+// FeedSourceConfig описывает источник: шаблон имени файла, формат и расположение данных
+type FeedSourceConfig struct {
+	Name           string              `mapstructure:"name"`
+	Pattern        string              `mapstructure:"pattern"` // Шаблон имени файла, например spimex_fuels_*.csv
+	Format         string              `mapstructure:"format"`  // csv, xml, html
+	Encoding       string              `mapstructure:"encoding"`
+	Delimiter      string              `mapstructure:"delimiter"`
+	Decimal        string              `mapstructure:"decimal"`
+	Record         string              `mapstructure:"record"` // XML: элемент записи
+	Table          int                 `mapstructure:"table"`  // HTML: номер таблицы с единицы
+	Instrument     string              `mapstructure:"instrument"`
+	InstrumentName string              `mapstructure:"instrument_name"`
+	Fields         map[string]string   `mapstructure:"fields"` // Поле значения (price, volume) -> столбец файла
+	Date           string              `mapstructure:"date"`
+	DatePattern    string              `mapstructure:"date_pattern"`
+	DateFormat     string              `mapstructure:"date_format"`
+	Timezone       string              `mapstructure:"timezone"`
+	Instruments    []InstrumentMapping `mapstructure:"instruments"`
+}
+
+// InstrumentMapping сопоставляет инструмент (код или префикс кода) с продуктом каталога
+type InstrumentMapping struct {
+	Code        string `mapstructure:"code"`
+	Prefix      string `mapstructure:"prefix"`
+	Field       string `mapstructure:"field"` // По умолчанию price
+	CompanyID   string `mapstructure:"company_id"`
+	ProductName string `mapstructure:"product_name"`
+	SeriesName  string `mapstructure:"series_name"` // Ряд для записи, если отличается от продукта (объемы)
+	Unit        string `mapstructure:"unit"`
+	Aggregate   string `mapstructure:"aggregate"` // avg, sum, min, max
+}
+
+// ModbusConfig задает опрос оборудования по Modbus TCP
+type ModbusConfig struct {
+	Devices []ModbusDeviceConfig `mapstructure:"devices"`
+}
+
+// ModbusDeviceConfig описывает устройство: адрес, карту регистров и команды управления
+type ModbusDeviceConfig struct {
+	EquipmentID  string                 `mapstructure:"equipment_id"` // Идентификатор оборудования (ControlCommand.EquipmentID)
+	Address      string                 `mapstructure:"address"`      // host:port, обычно порт 502
+	UnitID       uint8                  `mapstructure:"unit_id"`
+	Timeout      time.Duration          `mapstructure:"timeout"`
+	PollInterval time.Duration          `mapstructure:"poll_interval"`
+	CompanyID    string                 `mapstructure:"company_id"` // Компания точек телеметрии
+	ByteOrder    string                 `mapstructure:"byte_order"` // Порядок байтов по умолчанию: ABCD, CDAB, BADC, DCBA
+	Registers    []ModbusRegisterConfig `mapstructure:"registers"`
+	Commands     []ModbusCommandConfig  `mapstructure:"commands"`
+}
+
+// ModbusRegisterConfig описывает значение в регистрах. Значение = сырое * scale + offset.
+// Регистры с product_name опрашиваются и записываются в телеметрию; остальные
+// используются только командами управления.
+type ModbusRegisterConfig struct {
+	Name        string            `mapstructure:"name"`
+	Type        string            `mapstructure:"type"` // holding, input, coil, discrete
+	Address     uint16            `mapstructure:"address"`
+	DataType    string            `mapstructure:"data_type"` // uint16, int16, uint32, int32, float32, uint64, int64, float64
+	ByteOrder   string            `mapstructure:"byte_order"`
+	Scale       float64           `mapstructure:"scale"` // 0 — 1
+	Offset      float64           `mapstructure:"offset"`
+	CompanyID   string            `mapstructure:"company_id"` // Пусто — компания устройства
+	ProductName string            `mapstructure:"product_name"`
+	Unit        string            `mapstructure:"unit"`
+	Tags        []string          `mapstructure:"tags"`
+	Labels      map[string]string `mapstructure:"labels"`
+}
+
+// ModbusCommandConfig связывает команду управления с записью регистра
+type ModbusCommandConfig struct {
+	Command   string   `mapstructure:"command"`   // ControlCommand.Command
+	Register  string   `mapstructure:"register"`  // Имя регистра карты (holding или coil)
+	Parameter string   `mapstructure:"parameter"` // Параметр команды со значением; по умолчанию value
+	Value     *float64 `mapstructure:"value"`     // Постоянное значение (пуск/останов) вместо параметра
+	Min       *float64 `mapstructure:"min"`       // Допустимый диапазон уставки
+	Max       *float64 `mapstructure:"max"`
+}
+
+// OPCUAConfig задает подключения к серверам OPC UA
+type OPCUAConfig struct {
+	Connections []OPCUAConnectionConfig `mapstructure:"connections"`
+}
+
+// OPCUAConnectionConfig описывает сервер OPC UA и подписку на его узлы
+type OPCUAConnectionConfig struct {
+	Name                 string            `mapstructure:"name"`
+	Endpoint             string            `mapstructure:"endpoint"` // opc.tcp://host:4840 или sim://<имя> для стенда
+	Username             string            `mapstructure:"username"`
+	Password             string            `mapstructure:"password"`
+	Timeout              time.Duration     `mapstructure:"timeout"`
+	PublishInterval      time.Duration     `mapstructure:"publish_interval"`       // Период публикации подписки
+	ReconnectInterval    time.Duration     `mapstructure:"reconnect_interval"`     // Первая пауза перед переподключением
+	MaxReconnectInterval time.Duration     `mapstructure:"max_reconnect_interval"` // Предел удвоения паузы
+	CompanyID            string            `mapstructure:"company_id"`
+	Nodes                []OPCUANodeConfig `mapstructure:"nodes"`
+}
+
+// OPCUANodeConfig связывает узел с рядом телеметрии. Значение = исходное * scale + offset.
+type OPCUANodeConfig struct {
+	NodeID      string            `mapstructure:"node_id"`
+	CompanyID   string            `mapstructure:"company_id"` // Пусто — компания подключения
+	ProductName string            `mapstructure:"product_name"`
+	Unit        string            `mapstructure:"unit"`
+	Scale       float64           `mapstructure:"scale"` // 0 — 1
+	Offset      float64           `mapstructure:"offset"`
+	Tags        []string          `mapstructure:"tags"`
+	Labels      map[string]string `mapstructure:"labels"`
+}
+
+// ControlConfig задает транспорты команд управления, помимо полевых протоколов
+type ControlConfig struct {
+	MQTT ControlMQTTConfig `mapstructure:"mqtt"`
+}
+
+// ControlMQTTConfig задает доставку команд через брокер MQTT (секция mqtt): команда
+// публикуется в топик оборудования, результат ожидается в топике подтверждений
+type ControlMQTTConfig struct {
+	Equipment    []string      `mapstructure:"equipment"`     // Шаблоны equipment_id (path.Match: "TOB-*")
+	CommandTopic string        `mapstructure:"command_topic"` // Шаблон с {equipment_id}
+	AckTopic     string        `mapstructure:"ack_topic"`     // Шаблон с {equipment_id}
+	Timeout      time.Duration `mapstructure:"timeout"`       // Ожидание подтверждения
+}
+
 func Load() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"petrochemical-data-platform/internal/pkg/quality"
+)
 
 // Product представляет продукт компании (бывший "датчик")
 type Product struct {
@@ -16,13 +20,95 @@ type Product struct {
 
 // TelemetryData представляет данные о продажах/производстве продуктов
 type TelemetryData struct {
-	CompanyID   string    `json:"company_id"`   // ID компании
-	ProductName string    `json:"product_name"` // Название продукта
-	Value       float64   `json:"value"`        // Объём производства
-	Unit        string    `json:"unit"`         // Единица измерения
+	CompanyID    string            `json:"company_id"`   // ID компании
+	ProductName  string            `json:"product_name"` // Название продукта
+	Value        float64           `json:"value"`        // Объём производства
+	Unit         string            `json:"unit"`         // Единица измерения
+	Timestamp    time.Time         `json:"timestamp"`
+	Quality      uint16            `json:"quality"`                // Код качества OPC DA, 192 — good (см. pkg/quality)
+	QualityInfo  *quality.Info     `json:"quality_info,omitempty"` // Разобранный код качества (для сырых данных)
+	Tags         []string          `json:"tags,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`        // Метки ключ/значение (установка, регион, канал сбыта)
+	AnomalyScore float64           `json:"anomaly_score,omitempty"` // Оценка детектора аномалий
+}
+
+// TagCount представляет значение тега или метки с числом строк, где оно встречается
+type TagCount struct {
+	Value string `json:"value"`
+	Count uint64 `json:"count"`
+}
+
+// LabelVocabulary представляет ключ метки и встречающиеся значения
+type LabelVocabulary struct {
+	Key    string     `json:"key"`
+	Count  uint64     `json:"count"`
+	Values []TagCount `json:"values"`
+}
+
+// TagVocabulary представляет словарь тегов и меток телеметрии за период
+type TagVocabulary struct {
+	Tags   []TagCount        `json:"tags"`
+	Labels []LabelVocabulary `json:"labels"`
+}
+
+// DataGap представляет непрерывный пропуск данных ряда
+type DataGap struct {
+	Start   time.Time `json:"start"` // Начало первого пропущенного интервала
+	End     time.Time `json:"end"`   // Начало первого интервала после пропуска
+	Missing int       `json:"missing"`
+}
+
+// SeriesCompleteness представляет полноту данных ряда за период
+type SeriesCompleteness struct {
+	CompanyID   string     `json:"company_id"`
+	ProductName string     `json:"product_name"`
+	Frequency   string     `json:"frequency"` // Ожидаемая частота: hour, day, week, month
+	Expected    int        `json:"expected"`  // Число интервалов, по которым данные уже должны были поступить
+	Present     int        `json:"present"`
+	Coverage    float64    `json:"coverage"` // Процент интервалов с данными
+	LastSeen    *time.Time `json:"last_seen,omitempty"`
+	Gaps        []DataGap  `json:"gaps"`
+}
+
+// CompletenessReport представляет отчет о полноте данных
+type CompletenessReport struct {
+	Start    time.Time            `json:"start"`
+	End      time.Time            `json:"end"`
+	Expected int                  `json:"expected"`
+	Present  int                  `json:"present"`
+	Coverage float64              `json:"coverage"`
+	Series   []SeriesCompleteness `json:"series"`
+}
+
+// AggregatedPoint представляет среднее значение продукта за интервал.
+// Value равен nil для пропуска, который не заполнен.
+type AggregatedPoint struct {
+	CompanyID   string    `json:"company_id"`
+	ProductName string    `json:"product_name"`
+	Unit        string    `json:"unit"`
 	Timestamp   time.Time `json:"timestamp"`
-	Quality     uint16    `json:"quality"` // 0-bad, 1-good
-	Tags        []string  `json:"tags,omitempty"`
+	Value       *float64  `json:"value"`
+	Synthetic   bool      `json:"synthetic,omitempty"` // Значение получено заполнением пропуска, а не измерено
+}
+
+// TelemetryRevision представляет замену ранее записанного значения точки
+type TelemetryRevision struct {
+	CompanyID   string    `json:"company_id"`
+	ProductName string    `json:"product_name"`
+	Timestamp   time.Time `json:"timestamp"` // Момент, к которому относится значение
+	OldValue    float64   `json:"old_value"`
+	NewValue    float64   `json:"new_value"`
+	OldQuality  uint16    `json:"old_quality"`
+	NewQuality  uint16    `json:"new_quality"`
+	RevisedAt   time.Time `json:"revised_at"`
+	Source      string    `json:"source,omitempty"` // Источник правки (например, http, mqtt, parser)
+}
+
+// TelemetryPage представляет страницу сырых данных с курсором продолжения
+type TelemetryPage struct {
+	Data          []TelemetryData `json:"data"`
+	NextCursor    string          `json:"next_cursor,omitempty"` // Пусто на последней странице
+	TotalEstimate uint64          `json:"total_estimate"`        // Общее число точек за период (без повторов ключа)
 }
 
 // ControlCommand представляет команду управления оборудованием
@@ -36,17 +122,79 @@ type ControlCommand struct {
 	ExecutedAt  *time.Time             `json:"executed_at,omitempty"`
 }
 
+// Результаты выполнения команды в подтверждении оборудования
+const (
+	AckExecuted = "executed" // Команда выполнена
+	AckRejected = "rejected" // Команда отклонена оборудованием
+	AckFailed   = "failed"   // Оборудование не смогло выполнить команду
+)
+
+// Причины отклонения и отказа в подтверждении оборудования
+const (
+	AckReasonUnsupportedCommand = "unsupported_command" // Команда не поддерживается
+	AckReasonInvalidParameters  = "invalid_parameters"  // Параметры отсутствуют или вне допустимого диапазона
+	AckReasonInvalidState       = "invalid_state"       // Команда недопустима в текущем состоянии
+	AckReasonDeviceFailure      = "device_failure"      // Отказ оборудования при выполнении
+)
+
+// ControlAck представляет подтверждение команды управления от оборудования
+type ControlAck struct {
+	CommandID   string    `json:"command_id"`
+	EquipmentID string    `json:"equipment_id"`
+	Status      string    `json:"status"` // executed, rejected, failed
+	Reason      string    `json:"reason,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	State       string    `json:"state,omitempty"` // Состояние оборудования после команды
+	ExecutedAt  time.Time `json:"executed_at"`
+}
+
+// Уровни иерархии активов: компания → площадка → завод → установка → оборудование
+const (
+	AssetLevelCompany   = "company"
+	AssetLevelSite      = "site"
+	AssetLevelPlant     = "plant"
+	AssetLevelUnit      = "unit"
+	AssetLevelEquipment = "equipment"
+)
+
 // Asset представляет промышленное оборудование или компанию
 type Asset struct {
-	ID             string                 `json:"id"`
-	Name           string                 `json:"name"`
-	Type           string                 `json:"type"`     // company, refinery, plant
-	Location       string                 `json:"location"` // Регион
-	Status         string                 `json:"status"`
-	Specifications map[string]interface{} `json:"specifications,omitempty"`
-	Products       []string               `json:"products,omitempty"` // Список продуктов компании
-	CreatedAt      time.Time              `json:"created_at"`
-	UpdatedAt      time.Time              `json:"updated_at"`
+	ID                 string                 `json:"id"`
+	Name               string                 `json:"name"`
+	Type               string                 `json:"type"`                           // company, refinery, polymer_plant, reactor
+	Level              string                 `json:"level"`                          // Уровень иерархии (AssetLevel*)
+	ParentID           string                 `json:"parent_id,omitempty"`            // Пусто у компаний
+	TelemetryCompanyID string                 `json:"telemetry_company_id,omitempty"` // company_id рядов телеметрии актива
+	Location           string                 `json:"location"`                       // Регион
+	Status             string                 `json:"status"`
+	Specifications     map[string]interface{} `json:"specifications,omitempty"`
+	Products           []string               `json:"products,omitempty"` // Список продуктов компании
+	CreatedAt          time.Time              `json:"created_at"`
+	UpdatedAt          time.Time              `json:"updated_at"`
+}
+
+// AssetNode — актив с дочерними активами для древовидного представления
+type AssetNode struct {
+	Asset
+	Children []*AssetNode `json:"children"`
+}
+
+// AssetRollup — телеметрия поддерева активов, просуммированная по интервалам: суммарный
+// средний выпуск всех рядов поддерева отдельно для каждой единицы измерения
+type AssetRollup struct {
+	AssetID     string         `json:"asset_id"`
+	Interval    string         `json:"interval"`
+	ProductType string         `json:"product_type,omitempty"`
+	Products    []string       `json:"products,omitempty"`
+	Companies   []string       `json:"companies"` // company_id телеметрии, вошедшие в сумму
+	Series      []RollupSeries `json:"series"`
+}
+
+// RollupSeries — суммарный ряд актива (или дочернего актива при разбивке) в одной единице
+type RollupSeries struct {
+	AssetID string        `json:"asset_id"`
+	Unit    string        `json:"unit"`
+	Points  []SeriesPoint `json:"points"`
 }
 
 // Alert представляет системные оповещения и уведомления
@@ -61,3 +209,107 @@ type Alert struct {
 	Acked     bool       `json:"acked"`
 	AckedAt   *time.Time `json:"acked_at,omitempty"`
 }
+
+// SeriesPoint представляет точку временного ряда
+type SeriesPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// DerivedSeries представляет производный ряд (YoY, MoM, скользящие средние и т.д.)
+type DerivedSeries struct {
+	CompanyID   string        `json:"company_id"`
+	ProductName string        `json:"product_name"`
+	Transform   string        `json:"transform"` // yoy, mom_pct, ema:12 ...
+	Points      []SeriesPoint `json:"points"`
+}
+
+// ForecastPoint представляет прогнозное значение с доверительным интервалом
+type ForecastPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+	Lower     float64   `json:"lower"`
+	Upper     float64   `json:"upper"`
+}
+
+// BacktestMetrics содержит метрики точности прогноза на исторических данных
+type BacktestMetrics struct {
+	MAPE    float64 `json:"mape"` // %
+	RMSE    float64 `json:"rmse"`
+	Holdout int     `json:"holdout"` // Количество отложенных месяцев
+}
+
+// Forecast представляет прогноз производства/цены продукта
+type Forecast struct {
+	CompanyID   string           `json:"company_id"`
+	ProductName string           `json:"product_name"`
+	Method      string           `json:"method"` // holt-winters, stl
+	Period      int              `json:"period"`
+	Confidence  float64          `json:"confidence"`
+	History     []SeriesPoint    `json:"history"`
+	Points      []ForecastPoint  `json:"points"`
+	Backtest    *BacktestMetrics `json:"backtest,omitempty"`
+}
+
+// LagCorrelation представляет корреляцию рядов при сдвиге второго ряда на Lag интервалов
+type LagCorrelation struct {
+	Lag         int      `json:"lag"`
+	Correlation *float64 `json:"correlation"` // nil, если недостаточно совпадающих точек
+	N           int      `json:"n"`
+}
+
+// CorrelationPair содержит попарную статистику двух рядов
+type CorrelationPair struct {
+	A        string           `json:"a"`
+	B        string           `json:"b"`
+	Pearson  *float64         `json:"pearson"`
+	Spearman *float64         `json:"spearman"`
+	N        int              `json:"n"`
+	Lags     []LagCorrelation `json:"lags,omitempty"`
+	BestLag  *int             `json:"best_lag,omitempty"` // Сдвиг с максимальной по модулю корреляцией
+}
+
+// CorrelationReport представляет результат корреляционного анализа нескольких рядов
+type CorrelationReport struct {
+	Interval string            `json:"interval"`
+	Series   []string          `json:"series"` // Ключи рядов в формате company_id:product_name
+	Pearson  [][]*float64      `json:"pearson"`
+	Spearman [][]*float64      `json:"spearman"`
+	Pairs    []CorrelationPair `json:"pairs"`
+}
+
+// PriceIndex описывает ценовой индекс и его корзину
+type PriceIndex struct {
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	Sector     string           `json:"sector,omitempty"` // polymers, fuels, fertilizers
+	Method     string           `json:"method"`           // fixed, chain
+	BaseDate   time.Time        `json:"base_date"`
+	BaseValue  float64          `json:"base_value"`
+	Components []IndexComponent `json:"components"`
+}
+
+// IndexComponent представляет позицию корзины индекса
+type IndexComponent struct {
+	CompanyID   string  `json:"company_id"`
+	ProductName string  `json:"product_name"`
+	Weight      float64 `json:"weight"`
+}
+
+// RetentionPolicy задает срок хранения класса данных (raw, hourly, daily, monthly)
+type RetentionPolicy struct {
+	DataClass  string    `json:"data_class"`
+	CompanyID  string    `json:"company_id,omitempty"` // Пусто — политика по умолчанию
+	RetainDays int       `json:"retain_days"`          // 0 — хранить бессрочно
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// RetentionReport показывает, сколько данных удалит политика
+type RetentionReport struct {
+	DataClass      string     `json:"data_class"`
+	CompanyID      string     `json:"company_id,omitempty"`
+	RetainDays     int        `json:"retain_days"`
+	Cutoff         *time.Time `json:"cutoff,omitempty"` // Данные старше будут удалены
+	Rows           uint64     `json:"rows"`
+	EstimatedBytes uint64     `json:"estimated_bytes"` // Оценка по среднему сжатому размеру строки
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AdminAuth protects administrative endpoints with the X-Admin-Password header,
+// checked against ADMIN_EXPORT_PASSWORD
+func (h *Handler) AdminAuth(c *gin.Context) {
+	adminPassword := os.Getenv("ADMIN_EXPORT_PASSWORD")
+	if adminPassword == "" {
+		h.logger.Error("ADMIN_EXPORT_PASSWORD environment variable not set")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Server configuration error"})
+		return
+	}
+
+	password := c.GetHeader("X-Admin-Password")
+	if subtle.ConstantTimeCompare([]byte(password), []byte(adminPassword)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin password"})
+		return
+	}
+
+	c.Next()
+}
+
+// BackfillRollups handles POST /api/v1/admin/rollups/backfill
+func (h *Handler) BackfillRollups(c *gin.Context) {
+	var req struct {
+		Start time.Time `json:"start" binding:"required"`
+		End   time.Time `json:"end" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start and end (RFC3339) are required"})
+		return
+	}
+	if !req.End.After(req.Start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end must be after start"})
+		return
+	}
+
+	if err := h.telemetryService.BackfillRollups(c.Request.Context(), req.Start, req.End); err != nil {
+		h.logger.Error("Failed to backfill rollups", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to backfill rollups"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Rollups backfilled",
+		"start":   req.Start,
+		"end":     req.End,
+	})
+}
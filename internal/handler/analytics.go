@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"petrochemical-data-platform/internal/repository"
+	"petrochemical-data-platform/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	maxCorrelationSeries = 10
+	maxCorrelationLag    = 36
+)
+
+// GetCorrelation handles GET /api/v1/analytics/correlation
+// Series are passed as ?series=SIBUR_TOBOLSK:Полипропилен&series=ROSNEFT:Автобензины (or comma-separated).
+func (h *Handler) GetCorrelation(c *gin.Context) {
+	keys, ok := parseSeriesKeys(c)
+	if !ok {
+		return
+	}
+	if len(keys) < 2 || len(keys) > maxCorrelationSeries {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "between 2 and 10 series are required"})
+		return
+	}
+
+	interval, err := repository.ParseInterval(c.DefaultQuery("interval", string(repository.IntervalMonth)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	maxLag, err := strconv.Atoi(c.DefaultQuery("max_lag", "6"))
+	if err != nil || maxLag < 0 || maxLag > maxCorrelationLag {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_lag must be between 0 and 36"})
+		return
+	}
+
+	start, end, ok := parseTimeRange(c, 5*365*24*time.Hour)
+	if !ok {
+		return
+	}
+
+	report, err := h.analyticsService.Correlate(c.Request.Context(), service.CorrelationRequest{
+		Series:   keys,
+		Start:    start,
+		End:      end,
+		Interval: interval,
+		MaxLag:   maxLag,
+	})
+	if err != nil {
+		h.logger.Error("Failed to compute correlation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute correlation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseSeriesKeys reads company_id:product_name pairs from the series query parameter.
+// Writes a 400 response on error.
+func parseSeriesKeys(c *gin.Context) ([]service.SeriesKey, bool) {
+	var keys []service.SeriesKey
+	for _, param := range c.QueryArray("series") {
+		for _, raw := range strings.Split(param, ",") {
+			raw = strings.TrimSpace(raw)
+			if raw == "" {
+				continue
+			}
+			companyID, product, found := strings.Cut(raw, ":")
+			if !found || companyID == "" || product == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "series must be in company_id:product_name format"})
+				return nil, false
+			}
+			keys = append(keys, service.SeriesKey{CompanyID: companyID, ProductName: product})
+		}
+	}
+	return keys, true
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/repository"
+	"petrochemical-data-platform/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetAssetTree handles GET /api/v1/assets/tree
+func (h *Handler) GetAssetTree(c *gin.Context) {
+	// Whole forest by default, a single subtree with ?root=SIBUR
+	h.writeAssetTree(c, c.Query("root"))
+}
+
+// GetAssetSubtree handles GET /api/v1/assets/{id}/tree
+func (h *Handler) GetAssetSubtree(c *gin.Context) {
+	h.writeAssetTree(c, c.Param("id"))
+}
+
+func (h *Handler) writeAssetTree(c *gin.Context, rootID string) {
+	tree, err := h.assetService.GetTree(c.Request.Context(), rootID)
+	if errors.Is(err, service.ErrAssetNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get asset tree", zap.Error(err), zap.String("asset_id", rootID))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve asset tree"})
+		return
+	}
+
+	if rootID != "" {
+		c.JSON(http.StatusOK, tree[0])
+		return
+	}
+	c.JSON(http.StatusOK, tree)
+}
+
+// PostAsset handles POST /api/v1/assets
+func (h *Handler) PostAsset(c *gin.Context) {
+	var asset domain.Asset
+	if err := c.ShouldBindJSON(&asset); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Creates the asset or updates the one with the same id
+	err := h.assetService.CreateAsset(c.Request.Context(), asset)
+	if errors.Is(err, service.ErrInvalidAsset) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to save asset", zap.Error(err), zap.String("asset_id", asset.ID))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save asset"})
+		return
+	}
+
+	saved, err := h.assetService.GetAsset(c.Request.Context(), asset.ID)
+	if err != nil {
+		h.logger.Error("Failed to get saved asset", zap.Error(err), zap.String("asset_id", asset.ID))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve asset"})
+		return
+	}
+	c.JSON(http.StatusCreated, saved)
+}
+
+// GetAsset handles GET /api/v1/assets/{id}
+func (h *Handler) GetAsset(c *gin.Context) {
+	id := c.Param("id")
+
+	asset, err := h.assetService.GetAsset(c.Request.Context(), id)
+	if errors.Is(err, service.ErrAssetNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get asset", zap.Error(err), zap.String("asset_id", id))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve asset"})
+		return
+	}
+
+	c.JSON(http.StatusOK, asset)
+}
+
+// GetAssetAncestors handles GET /api/v1/assets/{id}/ancestors
+func (h *Handler) GetAssetAncestors(c *gin.Context) {
+	id := c.Param("id")
+
+	assets, err := h.assetService.GetAncestors(c.Request.Context(), id)
+	if errors.Is(err, service.ErrAssetNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get asset ancestors", zap.Error(err), zap.String("asset_id", id))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve asset ancestors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"asset_id": id, "ancestors": assets})
+}
+
+// GetAssetDescendants handles GET /api/v1/assets/{id}/descendants
+func (h *Handler) GetAssetDescendants(c *gin.Context) {
+	id := c.Param("id")
+
+	assets, err := h.assetService.GetDescendants(c.Request.Context(), id)
+	if errors.Is(err, service.ErrAssetNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get asset descendants", zap.Error(err), zap.String("asset_id", id))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve asset descendants"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"asset_id": id, "descendants": assets})
+}
+
+// GetAssetTelemetry handles GET /api/v1/assets/{id}/telemetry
+func (h *Handler) GetAssetTelemetry(c *gin.Context) {
+	id := c.Param("id")
+
+	start, end, ok := parseTimeRange(c, 30*24*time.Hour)
+	if !ok {
+		return
+	}
+
+	interval, err := repository.ParseInterval(c.DefaultQuery("interval", string(repository.IntervalDay)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Total output of a product type, e.g. ?product_type=polymer&group_by=child
+	opts := service.RollupOptions{ProductType: strings.TrimSpace(c.Query("product_type"))}
+	for _, product := range c.QueryArray("product") {
+		if product = strings.TrimSpace(product); product != "" {
+			opts.Products = append(opts.Products, product)
+		}
+	}
+	switch c.DefaultQuery("group_by", "total") {
+	case "total":
+	case "child":
+		opts.ByChild = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be total or child"})
+		return
+	}
+
+	rollup, err := h.assetService.RollupTelemetry(c.Request.Context(), id, start, end, interval, opts)
+	if errors.Is(err, service.ErrAssetNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to roll up asset telemetry", zap.Error(err), zap.String("asset_id", id))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve asset telemetry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rollup)
+}
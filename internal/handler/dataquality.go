@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"petrochemical-data-platform/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// defaultCompletenessSpan is the period checked when start is omitted
+const defaultCompletenessSpan = 365 * 24 * time.Hour
+
+// GetCompleteness handles GET /api/v1/data-quality/completeness
+func (h *Handler) GetCompleteness(c *gin.Context) {
+	start, end, ok := parseTimeRange(c, defaultCompletenessSpan)
+	if !ok {
+		return
+	}
+	if !start.Before(end) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start must be before end"})
+		return
+	}
+
+	var filter repository.TelemetryFilter
+	for _, id := range c.QueryArray("company_id") {
+		if id = strings.TrimSpace(id); id != "" {
+			filter.CompanyIDs = append(filter.CompanyIDs, id)
+		}
+	}
+	for _, product := range c.QueryArray("product") {
+		if product = strings.TrimSpace(product); product != "" {
+			filter.Products = append(filter.Products, product)
+		}
+	}
+
+	report, err := h.completenessService.Report(c.Request.Context(), filter, start, end)
+	if err != nil {
+		h.logger.Error("Failed to build completeness report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build completeness report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"petrochemical-data-platform/internal/pkg/forecast"
+	"petrochemical-data-platform/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	maxForecastHorizon = 120
+	maxForecastPeriod  = 24 // Два года помесячных данных
+)
+
+// GetForecast handles GET /api/v1/forecast
+func (h *Handler) GetForecast(c *gin.Context) {
+	req := service.ForecastRequest{
+		CompanyID:   c.Query("company_id"),
+		ProductName: c.Query("product"),
+		Method:      forecast.Method(c.DefaultQuery("method", string(forecast.MethodHoltWinters))),
+	}
+
+	if req.CompanyID == "" || req.ProductName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "company_id and product are required"})
+		return
+	}
+
+	var ok bool
+	if req.Start, req.End, ok = parseTimeRange(c, 5*365*24*time.Hour); !ok {
+		return
+	}
+
+	var err error
+
+	if req.Horizon, err = strconv.Atoi(c.DefaultQuery("horizon", "12")); err != nil || req.Horizon < 1 || req.Horizon > maxForecastHorizon {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "horizon must be between 1 and 120"})
+		return
+	}
+	if req.Period, err = strconv.Atoi(c.DefaultQuery("period", "12")); err != nil || req.Period < 0 || req.Period > maxForecastPeriod {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be between 0 and 24"})
+		return
+	}
+	// Period 1 is not a season; STL needs a seasonal period, Holt-Winters treats 0 as none
+	if req.Period == 1 || (req.Method == forecast.MethodSTL && req.Period < 2) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be 0 (no seasonality) or at least 2; stl requires at least 2"})
+		return
+	}
+	if req.Confidence, err = strconv.ParseFloat(c.DefaultQuery("confidence", "0.95"), 64); err != nil || req.Confidence <= 0 || req.Confidence >= 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "confidence must be between 0 and 1"})
+		return
+	}
+	if req.Holdout, err = strconv.Atoi(c.DefaultQuery("holdout", strconv.Itoa(req.Period))); err != nil || req.Holdout < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid holdout"})
+		return
+	}
+
+	if req.Method != forecast.MethodHoltWinters && req.Method != forecast.MethodSTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "method must be holt-winters or stl"})
+		return
+	}
+
+	result, err := h.forecastService.Forecast(c.Request.Context(), req)
+	if errors.Is(err, forecast.ErrNotEnoughData) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to build forecast", zap.Error(err),
+			zap.String("company_id", req.CompanyID), zap.String("product", req.ProductName))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build forecast"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
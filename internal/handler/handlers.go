@@ -2,12 +2,20 @@ package handler
 
 import (
 	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/pkg/gapfill"
+	"petrochemical-data-platform/internal/pkg/transform"
+	"petrochemical-data-platform/internal/repository"
+	"petrochemical-data-platform/internal/service"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -56,17 +64,197 @@ func (h *Handler) GetTelemetry(c *gin.Context) {
 		end = time.Now()
 	}
 
-	data, err := h.telemetryService.GetTelemetry(c.Request.Context(), companyID, start, end)
+	// Derived series, e.g. ?transform=yoy,ema:12
+	specs, err := transform.Parse(c.Query("transform"))
 	if err != nil {
-		h.logger.Error("Failed to get telemetry", zap.Error(err), zap.String("company_id", companyID))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve telemetry data"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"company_id": companyID,
-		"data":       data,
+	// Companies, products, quality and tags, e.g. /telemetry/SIBUR,LUKOIL?product=Полипропилен&quality=good&tag=export
+	filter, err := parseTelemetryFilter(c, companyID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.RawOnly() && len(specs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quality, tag and label filters cannot be combined with transform"})
+		return
+	}
+
+	if c.Query("fill") != "" && c.Query("interval") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "fill requires interval"})
+		return
+	}
+
+	// NDJSON streaming of every raw point in the range, e.g. ?format=ndjson
+	if c.Query("format") == "ndjson" || c.GetHeader("Accept") == "application/x-ndjson" {
+		h.streamTelemetry(c, filter, start, end)
+		return
+	}
+
+	response := gin.H{"company_id": companyID}
+
+	// Bucketed data from the rollup tables, e.g. ?interval=month
+	if intervalStr := c.Query("interval"); intervalStr != "" {
+		interval, err := repository.ParseInterval(intervalStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Gap filling on a common grid, e.g. ?fill=linear
+		fill, err := gapfill.ParseMode(c.Query("fill"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		data, err := h.telemetryService.GetAggregatedTelemetry(c.Request.Context(), filter, start, end, interval, fill)
+		if errors.Is(err, service.ErrFillGridTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to get telemetry", zap.Error(err), zap.String("company_id", companyID))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve telemetry data"})
+			return
+		}
+		response["data"] = data
+	} else {
+		// Raw points, paginated with ?limit=N&cursor=<next_cursor>
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+		if err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+
+		page, err := h.telemetryService.GetTelemetry(c.Request.Context(), filter, start, end, c.Query("cursor"), limit)
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to get telemetry", zap.Error(err), zap.String("company_id", companyID))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve telemetry data"})
+			return
+		}
+		response["data"] = page.Data
+		response["next_cursor"] = page.NextCursor
+		response["total_estimate"] = page.TotalEstimate
+	}
+
+	if len(specs) > 0 {
+		derived, err := h.telemetryService.GetDerivedSeries(c.Request.Context(), filter, start, end, specs)
+		if err != nil {
+			h.logger.Error("Failed to compute derived series", zap.Error(err), zap.String("company_id", companyID))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute derived series"})
+			return
+		}
+		response["derived"] = derived
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// streamTelemetry writes raw telemetry as newline-delimited JSON, flushing as rows arrive
+func (h *Handler) streamTelemetry(c *gin.Context, filter repository.TelemetryFilter, start, end time.Time) {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+	rows := 0
+	err := h.telemetryService.StreamTelemetry(c.Request.Context(), filter, start, end, func(data domain.TelemetryData) error {
+		if err := enc.Encode(data); err != nil {
+			return err
+		}
+		if rows++; rows%1000 == 0 {
+			c.Writer.Flush()
+		}
+		return nil
 	})
+	if err != nil {
+		// Headers are already sent, so the client sees a truncated stream
+		h.logger.Error("Telemetry stream aborted", zap.Error(err),
+			zap.Strings("company_ids", filter.CompanyIDs), zap.Int("rows", rows))
+		return
+	}
+	c.Writer.Flush()
+}
+
+// defaultRevisionSpan is the period of point timestamps searched for revisions when start is omitted
+const defaultRevisionSpan = 30 * 24 * time.Hour
+
+// GetTelemetryRevisions handles GET /api/v1/telemetry/{company_id}/revisions
+func (h *Handler) GetTelemetryRevisions(c *gin.Context) {
+	companyID := c.Param("company_id")
+
+	start, end, ok := parseTimeRange(c, defaultRevisionSpan)
+	if !ok {
+		return
+	}
+
+	// Only companies and products apply to revisions
+	filter, err := parseTelemetryFilter(c, companyID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	revisions, err := h.telemetryService.GetRevisions(c.Request.Context(), filter, start, end)
+	if err != nil {
+		h.logger.Error("Failed to get telemetry revisions", zap.Error(err), zap.String("company_id", companyID))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve telemetry revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": revisions})
+}
+
+// parseTelemetryFilter builds the telemetry filter from the path and query.
+// The path segment may list several companies separated by commas; product,
+// tag and label (key:value) may be repeated.
+func parseTelemetryFilter(c *gin.Context, companyParam string) (repository.TelemetryFilter, error) {
+	var filter repository.TelemetryFilter
+
+	for _, id := range strings.Split(companyParam, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			filter.CompanyIDs = append(filter.CompanyIDs, id)
+		}
+	}
+	if len(filter.CompanyIDs) == 0 {
+		return filter, errors.New("company_id is required")
+	}
+
+	for _, product := range c.QueryArray("product") {
+		if product = strings.TrimSpace(product); product != "" {
+			filter.Products = append(filter.Products, product)
+		}
+	}
+	for _, tag := range c.QueryArray("tag") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			filter.Tags = append(filter.Tags, tag)
+		}
+	}
+
+	for _, label := range c.QueryArray("label") {
+		key, value, ok := strings.Cut(label, ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			return filter, fmt.Errorf("invalid label %q: use key:value", label)
+		}
+		if filter.Labels == nil {
+			filter.Labels = make(map[string]string)
+		}
+		filter.Labels[strings.TrimSpace(key)] = value
+	}
+
+	quality, err := repository.ParseQualityFilter(c.Query("quality"))
+	if err != nil {
+		return filter, err
+	}
+	filter.Quality = quality
+
+	return filter, nil
 }
 
 // PostControl handles POST /api/v1/control
@@ -81,15 +269,39 @@ func (h *Handler) PostControl(c *gin.Context) {
 	cmd.Status = "pending"
 	cmd.CreatedAt = time.Now()
 
-	if err := h.controlService.SendControlCommand(c.Request.Context(), cmd); err != nil {
-		h.logger.Error("Failed to send control command", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send control command"})
+	executed, err := h.controlService.SendControlCommand(c.Request.Context(), cmd)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownEquipment):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrUnsupportedCommand), errors.Is(err, service.ErrInvalidCommand):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to send control command", zap.Error(err))
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send control command", "command_id": cmd.ID})
+		}
+		return
+	}
+
+	if !executed {
+		// No transport serves the equipment: accepted, as before field protocols existed
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":    "Control command sent",
+			"command_id": cmd.ID,
+			"status":     cmd.Status,
+		})
 		return
 	}
 
-	c.JSON(http.StatusAccepted, gin.H{
-		"message":    "Control command sent",
-		"command_id": cmd.ID,
+	executedAt := time.Now()
+	cmd.Status = "executed"
+	cmd.ExecutedAt = &executedAt
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Control command executed",
+		"command_id":  cmd.ID,
+		"status":      cmd.Status,
+		"executed_at": cmd.ExecutedAt,
 	})
 }
 
@@ -126,3 +338,27 @@ func (h *Handler) VerifyExportPassword(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "error": "Invalid password"})
 	}
 }
+
+// parseTimeRange reads the start/end query parameters (RFC3339). When start is
+// omitted it defaults to end minus defaultSpan. Writes a 400 response on error.
+func parseTimeRange(c *gin.Context, defaultSpan time.Duration) (time.Time, time.Time, bool) {
+	end := time.Now()
+	if endStr := c.Query("end"); endStr != "" {
+		var err error
+		if end, err = time.Parse(time.RFC3339, endStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end time format"})
+			return time.Time{}, time.Time{}, false
+		}
+	}
+
+	start := end.Add(-defaultSpan)
+	if startStr := c.Query("start"); startStr != "" {
+		var err error
+		if start, err = time.Parse(time.RFC3339, startStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start time format"})
+			return time.Time{}, time.Time{}, false
+		}
+	}
+
+	return start, end, true
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"petrochemical-data-platform/internal/pkg/index"
+	"petrochemical-data-platform/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetIndices handles GET /api/v1/analytics/indices
+func (h *Handler) GetIndices(c *gin.Context) {
+	c.JSON(http.StatusOK, h.indexService.Indices())
+}
+
+// GetIndexSeries handles GET /api/v1/analytics/indices/{id}
+func (h *Handler) GetIndexSeries(c *gin.Context) {
+	id := c.Param("id")
+
+	definition, err := h.indexService.Index(id)
+	if errors.Is(err, service.ErrIndexNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Index not found"})
+		return
+	}
+
+	start, end, ok := parseTimeRange(c, 10*365*24*time.Hour)
+	if !ok {
+		return
+	}
+
+	// Optional rebasing, e.g. ?rebase=2022-01-01&rebase_value=100
+	var rebaseAt time.Time
+	if rebaseStr := c.Query("rebase"); rebaseStr != "" {
+		if rebaseAt, err = time.Parse(time.DateOnly, rebaseStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rebase date format, expected YYYY-MM-DD"})
+			return
+		}
+	}
+	rebaseValue, err := strconv.ParseFloat(c.DefaultQuery("rebase_value", "100"), 64)
+	if err != nil || rebaseValue <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rebase_value must be positive"})
+		return
+	}
+
+	points, err := h.indexService.GetSeries(c.Request.Context(), id, start, end, rebaseAt, rebaseValue)
+	if errors.Is(err, index.ErrNoRebaseValue) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get index series", zap.Error(err), zap.String("index_id", id))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve index series"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"index":  definition,
+		"points": points,
+	})
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"petrochemical-data-platform/internal/pkg/parser"
+	"petrochemical-data-platform/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PostTelemetryIngest handles POST /api/v1/telemetry/ingest
+// The body is a JSON array, NDJSON or CSV (by ?format= or Content-Type), optionally
+// gzip-compressed (Content-Encoding: gzip). ?mode= overrides the configured write mode.
+// A repeated request with the same Idempotency-Key header returns the stored result.
+func (h *Handler) PostTelemetryIngest(c *gin.Context) {
+	format, ok := parser.FormatFromContentType(c.ContentType())
+	if f := c.Query("format"); f != "" {
+		var err error
+		if format, err = parser.ParseFormat(f); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Use Content-Type application/json, application/x-ndjson or text/csv, or ?format="})
+		return
+	}
+
+	var opts service.IngestOptions
+	if m := c.Query("mode"); m != "" {
+		mode, err := service.ParseWriteMode(m)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		opts.Mode = mode
+	}
+	opts.Source = "http"
+
+	encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+	if encoding != "" && encoding != "identity" && encoding != "gzip" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Only gzip Content-Encoding is supported"})
+		return
+	}
+
+	payload := service.Payload{
+		Body:           c.Request.Body,
+		Format:         format,
+		Encoding:       encoding,
+		IdempotencyKey: strings.TrimSpace(c.GetHeader("Idempotency-Key")),
+	}
+
+	result, err := h.ingestionService.IngestPayload(c.Request.Context(), payload, opts)
+	if !h.ingestOK(c, err, format) {
+		return
+	}
+
+	if result.Replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// PostInfluxWrite handles POST /api/v1/ingest/influx/write and /api/v1/ingest/influx/api/v2/write
+// so InfluxDB v1 and v2 clients (e.g. Telegraf) can point at /api/v1/ingest/influx.
+// Measurements, tags and fields are mapped to products by the ingest.influx rules.
+func (h *Handler) PostInfluxWrite(c *gin.Context) {
+	precision, err := parser.ParsePrecision(c.Query("precision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+	if encoding != "" && encoding != "identity" && encoding != "gzip" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Only gzip Content-Encoding is supported"})
+		return
+	}
+
+	payload := service.Payload{
+		Body:      c.Request.Body,
+		Format:    parser.FormatInflux,
+		Encoding:  encoding,
+		Precision: precision,
+	}
+
+	result, err := h.ingestionService.IngestPayload(c.Request.Context(), payload, service.IngestOptions{Source: "influx"})
+	if !h.ingestOK(c, err, payload.Format) {
+		return
+	}
+	writeResponse(c, result)
+}
+
+// PostPrometheusWrite handles POST /api/v1/ingest/prometheus/write (remote_write 1.0,
+// snappy-compressed protobuf). Metric names and labels are mapped to products by the
+// ingest.prometheus rules; unmapped series are skipped.
+func (h *Handler) PostPrometheusWrite(c *gin.Context) {
+	encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+	if encoding == "" {
+		encoding = "snappy"
+	}
+	if encoding != "snappy" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "remote_write requires snappy Content-Encoding"})
+		return
+	}
+
+	payload := service.Payload{
+		Body:     c.Request.Body,
+		Format:   parser.FormatRemoteWrite,
+		Encoding: encoding,
+	}
+
+	result, err := h.ingestionService.IngestPayload(c.Request.Context(), payload, service.IngestOptions{Source: "prometheus"})
+	if !h.ingestOK(c, err, payload.Format) {
+		return
+	}
+	writeResponse(c, result)
+}
+
+// writeResponse answers agents the way InfluxDB and Prometheus do: 204 when every point
+// was accepted, 400 (not retried by agents) when some points were rejected.
+func writeResponse(c *gin.Context, result *service.IngestResult) {
+	if len(result.Rejected) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	first := result.Rejected[0]
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error": fmt.Sprintf("partial write: %d of %d points rejected, first at record %d: %s",
+			len(result.Rejected), result.Received, first.Index, first.Reason),
+		"rejected": result.Rejected,
+	})
+}
+
+// ingestOK writes the error response for a failed ingestion and reports whether err was nil
+func (h *Handler) ingestOK(c *gin.Context, err error, format parser.Format) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, service.ErrPayloadTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidPayload):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrIngestInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Failed to ingest telemetry", zap.Error(err), zap.String("format", string(format)))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ingest telemetry"})
+	}
+	return false
+}
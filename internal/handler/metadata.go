@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// defaultVocabularySpan is the period scanned for tags when start is omitted
+const defaultVocabularySpan = 30 * 24 * time.Hour
+
+// GetTagVocabulary handles GET /api/v1/metadata/tags
+func (h *Handler) GetTagVocabulary(c *gin.Context) {
+	start, end, ok := parseTimeRange(c, defaultVocabularySpan)
+	if !ok {
+		return
+	}
+
+	var companyIDs []string
+	for _, id := range c.QueryArray("company_id") {
+		if id = strings.TrimSpace(id); id != "" {
+			companyIDs = append(companyIDs, id)
+		}
+	}
+
+	vocabulary, err := h.telemetryService.GetTagVocabulary(c.Request.Context(), companyIDs, start, end)
+	if err != nil {
+		h.logger.Error("Failed to get tag vocabulary", zap.Error(err), zap.Strings("company_ids", companyIDs))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tag vocabulary"})
+		return
+	}
+
+	c.JSON(http.StatusOK, vocabulary)
+}
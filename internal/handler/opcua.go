@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"petrochemical-data-platform/internal/pkg/opcua"
+	"petrochemical-data-platform/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// BrowseOPCUA handles GET /api/v1/admin/opcua/{connection}/browse?node_id=...
+// Lists the child nodes of node_id (default: the Objects folder) to help build node maps.
+func (h *Handler) BrowseOPCUA(c *gin.Context) {
+	refs, err := h.opcuaService.Browse(c.Request.Context(), c.Param("connection"), c.Query("node_id"))
+	switch {
+	case errors.Is(err, service.ErrInvalidNodeID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrOPCUAConnectionNotFound), errors.Is(err, opcua.StatusBadNodeIDUnknown):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, service.ErrOPCUANotConnected):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		h.logger.Error("Failed to browse OPC UA node", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to browse OPC UA node"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"references": refs})
+}
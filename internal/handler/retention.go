@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetRetentionPolicies handles GET /api/v1/admin/retention
+func (h *Handler) GetRetentionPolicies(c *gin.Context) {
+	policies, err := h.retentionService.GetPolicies(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to get retention policies", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve retention policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// GetRetentionReport handles GET /api/v1/admin/retention/report
+func (h *Handler) GetRetentionReport(c *gin.Context) {
+	reports, err := h.retentionService.Report(c.Request.Context(), c.Query("data_class"))
+	if errors.Is(err, service.ErrUnknownDataClass) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to build retention report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build retention report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+// PutRetentionPolicy handles PUT /api/v1/admin/retention/{data_class}
+// With ?dry_run=true the policy is not saved and the deletion report is returned.
+func (h *Handler) PutRetentionPolicy(c *gin.Context) {
+	var req struct {
+		CompanyID  string `json:"company_id"`
+		RetainDays *int   `json:"retain_days" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "retain_days is required"})
+		return
+	}
+
+	policy := domain.RetentionPolicy{
+		DataClass:  c.Param("data_class"),
+		CompanyID:  req.CompanyID,
+		RetainDays: *req.RetainDays,
+	}
+	dryRun := c.Query("dry_run") == "true"
+
+	reports, err := h.retentionService.SetPolicy(c.Request.Context(), policy, dryRun)
+	if errors.Is(err, service.ErrUnknownDataClass) || errors.Is(err, service.ErrInvalidPolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to set retention policy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set retention policy"})
+		return
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{"dry_run": true, "report": reports})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Retention policy updated"})
+}
+
+// DeleteRetentionPolicy handles DELETE /api/v1/admin/retention/{data_class}?company_id=...
+func (h *Handler) DeleteRetentionPolicy(c *gin.Context) {
+	err := h.retentionService.DeletePolicy(c.Request.Context(), c.Param("data_class"), c.Query("company_id"))
+	switch {
+	case errors.Is(err, service.ErrUnknownDataClass), errors.Is(err, service.ErrInvalidPolicy):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPolicyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Retention policy not found"})
+	case err != nil:
+		h.logger.Error("Failed to delete retention policy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete retention policy"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Retention policy deleted"})
+	}
+}
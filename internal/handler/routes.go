@@ -8,18 +8,32 @@ import (
 )
 
 type Handler struct {
-	assetService     *service.AssetService
-	telemetryService *service.TelemetryService
-	controlService   *service.ControlService
-	logger           *zap.Logger
+	assetService        *service.AssetService
+	telemetryService    *service.TelemetryService
+	controlService      *service.ControlService
+	forecastService     *service.ForecastService
+	analyticsService    *service.AnalyticsService
+	indexService        *service.IndexService
+	retentionService    *service.RetentionService
+	completenessService *service.CompletenessService
+	ingestionService    *service.IngestionService
+	opcuaService        *service.OPCUAService
+	logger              *zap.Logger
 }
 
-func NewHandler(assetSvc *service.AssetService, telemetrySvc *service.TelemetryService, controlSvc *service.ControlService, logger *zap.Logger) *Handler {
+func NewHandler(assetSvc *service.AssetService, telemetrySvc *service.TelemetryService, controlSvc *service.ControlService, forecastSvc *service.ForecastService, analyticsSvc *service.AnalyticsService, indexSvc *service.IndexService, retentionSvc *service.RetentionService, completenessSvc *service.CompletenessService, ingestionSvc *service.IngestionService, opcuaSvc *service.OPCUAService, logger *zap.Logger) *Handler {
 	return &Handler{
-		assetService:     assetSvc,
-		telemetryService: telemetrySvc,
-		controlService:   controlSvc,
-		logger:           logger,
+		assetService:        assetSvc,
+		telemetryService:    telemetrySvc,
+		controlService:      controlSvc,
+		forecastService:     forecastSvc,
+		analyticsService:    analyticsSvc,
+		indexService:        indexSvc,
+		retentionService:    retentionSvc,
+		completenessService: completenessSvc,
+		ingestionService:    ingestionSvc,
+		opcuaService:        opcuaSvc,
+		logger:              logger,
 	}
 }
 
@@ -27,11 +41,39 @@ func SetupRoutes(r *gin.Engine, handler *Handler) {
 	api := r.Group("/api/v1")
 	{
 		api.GET("/assets", handler.GetAssets)
+		api.POST("/assets", handler.PostAsset)
+		api.GET("/assets/tree", handler.GetAssetTree)
+		api.GET("/assets/:id", handler.GetAsset)
+		api.GET("/assets/:id/ancestors", handler.GetAssetAncestors)
+		api.GET("/assets/:id/descendants", handler.GetAssetDescendants)
+		api.GET("/assets/:id/tree", handler.GetAssetSubtree)
+		api.GET("/assets/:id/telemetry", handler.GetAssetTelemetry)
+		api.POST("/telemetry/ingest", handler.PostTelemetryIngest)
+		api.POST("/ingest/influx/write", handler.PostInfluxWrite)
+		api.POST("/ingest/influx/api/v2/write", handler.PostInfluxWrite)
+		api.POST("/ingest/prometheus/write", handler.PostPrometheusWrite)
 		api.GET("/telemetry/:company_id", handler.GetTelemetry)
+		api.GET("/telemetry/:company_id/revisions", handler.GetTelemetryRevisions)
+		api.GET("/metadata/tags", handler.GetTagVocabulary)
+		api.GET("/forecast", handler.GetForecast)
+		api.GET("/analytics/correlation", handler.GetCorrelation)
+		api.GET("/analytics/indices", handler.GetIndices)
+		api.GET("/analytics/indices/:id", handler.GetIndexSeries)
+		api.GET("/data-quality/completeness", handler.GetCompleteness)
 		api.POST("/control", handler.PostControl)
 		api.POST("/auth/verify-export-password", handler.VerifyExportPassword)
 	}
 
+	admin := api.Group("/admin", handler.AdminAuth)
+	{
+		admin.POST("/rollups/backfill", handler.BackfillRollups)
+		admin.GET("/retention", handler.GetRetentionPolicies)
+		admin.GET("/retention/report", handler.GetRetentionReport)
+		admin.PUT("/retention/:data_class", handler.PutRetentionPolicy)
+		admin.DELETE("/retention/:data_class", handler.DeleteRetentionPolicy)
+		admin.GET("/opcua/:connection/browse", handler.BrowseOPCUA)
+	}
+
 	// WebSocket endpoint
 	r.GET("/ws", handler.HandleWebSocket)
 }
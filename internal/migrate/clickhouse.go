@@ -0,0 +1,166 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// ClickHouseDriver хранит состояние миграций в ClickHouse. ClickHouse не поддерживает
+// транзакции DDL, поэтому выражения миграций должны быть идемпотентными (IF [NOT] EXISTS
+// или условие "-- +migrate if"): при сбое посередине миграцию можно безопасно применить повторно.
+type ClickHouseDriver struct {
+	conn clickhouse.Conn
+}
+
+// ClickHouseDatabase — база ClickHouse платформы. Имя зафиксировано: миграции и запросы
+// репозитория обращаются к таблицам по полному имени petrochemical.<таблица>.
+const ClickHouseDatabase = "petrochemical"
+
+// NewClickHouseDriver создает драйвер миграций ClickHouse
+func NewClickHouseDriver(conn clickhouse.Conn) *ClickHouseDriver {
+	return &ClickHouseDriver{conn: conn}
+}
+
+func (d *ClickHouseDriver) Name() string { return "clickhouse" }
+
+func (d *ClickHouseDriver) Init(ctx context.Context) error {
+	statements := []string{
+		fmt.Sprintf(`CREATE DATABASE IF NOT EXISTS %s`, ClickHouseDatabase),
+		// Строки не удаляются: откат и повторное применение записываются новой версией seq
+		fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s.schema_migrations (
+				version Int64,
+				name String,
+				checksum String,
+				applied UInt8,
+				applied_at DateTime('UTC'),
+				seq UInt64
+			) ENGINE = ReplacingMergeTree(seq)
+			ORDER BY version`, ClickHouseDatabase),
+		fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s.schema_migrations_lock (
+				owner String,
+				acquired_at DateTime64(3, 'UTC'),
+				released UInt8,
+				seq UInt64
+			) ENGINE = ReplacingMergeTree(seq)
+			ORDER BY owner
+			TTL toDateTime(acquired_at) + INTERVAL 1 DAY`, ClickHouseDatabase),
+	}
+
+	for _, stmt := range statements {
+		if err := d.conn.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create migration tables: %w", err)
+		}
+	}
+	return nil
+}
+
+// Lock реализует блокировку в ClickHouse без атомарных операций: процесс добавляет
+// свою заявку и считается владельцем, если его заявка — самая ранняя из активных
+func (d *ClickHouseDriver) Lock(ctx context.Context, owner string, ttl time.Duration) error {
+	holder, err := d.holder(ctx, ttl)
+	if err != nil {
+		return err
+	}
+	if holder != "" && holder != owner {
+		return ErrLocked
+	}
+
+	if err := d.conn.Exec(ctx,
+		fmt.Sprintf(`INSERT INTO %s.schema_migrations_lock (owner, acquired_at, released, seq) VALUES (?, ?, 0, ?)`, ClickHouseDatabase),
+		owner, time.Now(), uint64(time.Now().UnixNano())); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+
+	// Повторная проверка отсекает гонку двух процессов, прочитавших пустую блокировку
+	if holder, err = d.holder(ctx, ttl); err != nil {
+		return err
+	}
+	if holder != owner {
+		_ = d.Unlock(ctx, owner)
+		return ErrLocked
+	}
+	return nil
+}
+
+func (d *ClickHouseDriver) holder(ctx context.Context, ttl time.Duration) (string, error) {
+	rows, err := d.conn.Query(ctx, fmt.Sprintf(`
+		SELECT owner FROM %s.schema_migrations_lock FINAL
+		WHERE released = 0 AND acquired_at > ?
+		ORDER BY acquired_at, owner
+		LIMIT 1`, ClickHouseDatabase), time.Now().Add(-ttl))
+	if err != nil {
+		return "", fmt.Errorf("failed to read migration lock: %w", err)
+	}
+	defer rows.Close()
+
+	var owner string
+	if rows.Next() {
+		if err := rows.Scan(&owner); err != nil {
+			return "", err
+		}
+	}
+	return owner, rows.Err()
+}
+
+func (d *ClickHouseDriver) Unlock(ctx context.Context, owner string) error {
+	return d.conn.Exec(ctx,
+		fmt.Sprintf(`INSERT INTO %s.schema_migrations_lock (owner, acquired_at, released, seq) VALUES (?, ?, 1, ?)`, ClickHouseDatabase),
+		owner, time.Now(), uint64(time.Now().UnixNano()))
+}
+
+func (d *ClickHouseDriver) Applied(ctx context.Context) ([]Record, error) {
+	rows, err := d.conn.Query(ctx, fmt.Sprintf(`
+		SELECT version, name, checksum, applied_at
+		FROM %s.schema_migrations FINAL
+		WHERE applied = 1
+		ORDER BY version`, ClickHouseDatabase))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var r Record
+		if err := rows.Scan(&r.Version, &r.Name, &r.Checksum, &r.AppliedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+func (d *ClickHouseDriver) Apply(ctx context.Context, m Migration, up bool) error {
+	script := m.Up
+	if !up {
+		script = m.Down
+	}
+
+	for _, stmt := range splitStatements(script) {
+		if stmt.guard != "" {
+			var matched uint64
+			if err := d.conn.QueryRow(ctx, stmt.guard).Scan(&matched); err != nil {
+				return fmt.Errorf("failed to check migration condition: %w", err)
+			}
+			if matched == 0 {
+				continue
+			}
+		}
+		if err := d.conn.Exec(ctx, stmt.sql); err != nil {
+			return err
+		}
+	}
+
+	var applied uint8
+	if up {
+		applied = 1
+	}
+	return d.conn.Exec(ctx,
+		fmt.Sprintf(`INSERT INTO %s.schema_migrations (version, name, checksum, applied, applied_at, seq) VALUES (?, ?, ?, ?, ?, ?)`, ClickHouseDatabase),
+		m.Version, m.Name, m.Checksum, applied, time.Now(), uint64(time.Now().UnixNano()))
+}
@@ -0,0 +1,333 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+//go:embed migrations
+var embedded embed.FS
+
+// Встроенные наборы миграций
+const (
+	PostgresDir   = "migrations/postgres"
+	ClickHouseDir = "migrations/clickhouse"
+)
+
+var (
+	// ErrLocked возвращается, если миграции уже выполняет другой процесс
+	ErrLocked = errors.New("migrations are locked by another process")
+	// ErrChecksumMismatch возвращается, если примененная миграция была изменена после применения
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+)
+
+// lockTTL — время, после которого блокировка считается брошенной
+const lockTTL = 10 * time.Minute
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration — одна версионированная миграция
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // sha256 от Up
+}
+
+// Record — запись о примененной миграции в таблице schema_migrations
+type Record struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Status описывает состояние миграции
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Modified  bool       `json:"modified"` // Контрольная сумма не совпадает с примененной
+}
+
+// Driver реализует хранение состояния миграций и их применение для конкретной СУБД
+type Driver interface {
+	// Name возвращает название СУБД для логов
+	Name() string
+	// Init создает служебные таблицы schema_migrations и schema_migrations_lock
+	Init(ctx context.Context) error
+	// Lock захватывает блокировку от имени owner или возвращает ErrLocked
+	Lock(ctx context.Context, owner string, ttl time.Duration) error
+	// Unlock освобождает блокировку owner
+	Unlock(ctx context.Context, owner string) error
+	// Applied возвращает примененные миграции в порядке возрастания версий
+	Applied(ctx context.Context) ([]Record, error)
+	// Apply выполняет SQL миграции и записывает (up) или удаляет (down) запись о ней
+	Apply(ctx context.Context, m Migration, up bool) error
+}
+
+// Load читает встроенный набор миграций (PostgresDir или ClickHouseDir)
+func Load(dir string) ([]Migration, error) {
+	return LoadFS(embedded, dir)
+}
+
+// LoadFS читает миграции вида 0001_name.up.sql / 0001_name.down.sql из fsys
+func LoadFS(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations from %s: %w", dir, err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Migrator применяет и откатывает миграции через Driver
+type Migrator struct {
+	driver     Driver
+	migrations []Migration
+	owner      string
+	lockWait   time.Duration
+	logger     *zap.Logger
+}
+
+// NewMigrator создает мигратор. owner идентифицирует процесс в таблице блокировок.
+func NewMigrator(driver Driver, migrations []Migration, owner string, logger *zap.Logger) *Migrator {
+	return &Migrator{
+		driver:     driver,
+		migrations: migrations,
+		owner:      owner,
+		lockWait:   time.Minute,
+		logger:     logger,
+	}
+}
+
+// Up применяет все неприменённые миграции до версии target включительно (0 — до последней)
+func (m *Migrator) Up(ctx context.Context, target int64) error {
+	return m.withLock(ctx, func(applied map[int64]Record) error {
+		for _, mig := range m.migrations {
+			if target > 0 && mig.Version > target {
+				break
+			}
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+
+			m.logger.Info("Applying migration", zap.String("db", m.driver.Name()),
+				zap.Int64("version", mig.Version), zap.String("name", mig.Name))
+			if err := m.driver.Apply(ctx, mig, true); err != nil {
+				return fmt.Errorf("%s migration %d_%s failed: %w", m.driver.Name(), mig.Version, mig.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Down откатывает последние steps примененных миграций
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(applied map[int64]Record) error {
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("%s migration %d_%s has no down script", m.driver.Name(), mig.Version, mig.Name)
+			}
+
+			m.logger.Info("Reverting migration", zap.String("db", m.driver.Name()),
+				zap.Int64("version", mig.Version), zap.String("name", mig.Name))
+			if err := m.driver.Apply(ctx, mig, false); err != nil {
+				return fmt.Errorf("%s migration %d_%s revert failed: %w", m.driver.Name(), mig.Version, mig.Name, err)
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// Status возвращает состояние всех известных миграций
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.driver.Init(ctx); err != nil {
+		return nil, err
+	}
+
+	records, err := m.driver.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]Record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		status := Status{Version: mig.Version, Name: mig.Name}
+		if r, ok := applied[mig.Version]; ok {
+			appliedAt := r.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = r.Checksum != mig.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// withLock захватывает блокировку (ожидая до lockWait), проверяет контрольные суммы
+// примененных миграций и выполняет fn
+func (m *Migrator) withLock(ctx context.Context, fn func(applied map[int64]Record) error) error {
+	if err := m.driver.Init(ctx); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(m.lockWait)
+	for {
+		err := m.driver.Lock(ctx, m.owner, lockTTL)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrLocked) || time.Now().After(deadline) {
+			return err
+		}
+
+		m.logger.Info("Waiting for migration lock", zap.String("db", m.driver.Name()))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+	defer func() {
+		// Блокировку нужно снять даже при отмене исходного контекста
+		if err := m.driver.Unlock(context.Background(), m.owner); err != nil {
+			m.logger.Error("Failed to release migration lock", zap.Error(err), zap.String("db", m.driver.Name()))
+		}
+	}()
+
+	records, err := m.driver.Applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = mig
+	}
+
+	applied := make(map[int64]Record, len(records))
+	for _, r := range records {
+		if mig, ok := known[r.Version]; ok && mig.Checksum != r.Checksum {
+			return fmt.Errorf("%w: %s migration %d_%s", ErrChecksumMismatch, m.driver.Name(), r.Version, r.Name)
+		}
+		applied[r.Version] = r
+	}
+
+	return fn(applied)
+}
+
+// guardPrefix отмечает условие для следующего выражения скрипта ClickHouse:
+//
+//	-- +migrate if SELECT count() FROM system.tables WHERE ...
+//
+// Выражение выполняется, только если запрос вернул ненулевое число. Так шаги, которые
+// нельзя записать через IF [NOT] EXISTS (EXCHANGE TABLES, копирование данных), остаются
+// идемпотентными при повторном применении после сбоя.
+const guardPrefix = "-- +migrate if "
+
+// statement — выражение скрипта с необязательным условием выполнения
+type statement struct {
+	sql   string
+	guard string // Запрос, возвращающий UInt64; пусто — выполняется всегда
+}
+
+// splitStatements разбивает скрипт на отдельные выражения по ';' в конце строки.
+// Нужна для ClickHouse, который выполняет только одно выражение за запрос.
+func splitStatements(script string) []statement {
+	var statements []statement
+	var current strings.Builder
+	var guard string
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, guardPrefix) {
+			guard = strings.TrimSpace(strings.TrimPrefix(trimmed, guardPrefix))
+			continue
+		}
+		if strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if strings.HasSuffix(trimmed, ";") {
+			if stmt := strings.TrimSuffix(strings.TrimSpace(current.String()), ";"); stmt != "" {
+				statements = append(statements, statement{sql: stmt, guard: guard})
+			}
+			current.Reset()
+			guard = ""
+		}
+	}
+
+	if stmt := strings.TrimSpace(current.String()); stmt != "" {
+		statements = append(statements, statement{sql: stmt, guard: guard})
+	}
+
+	return statements
+}
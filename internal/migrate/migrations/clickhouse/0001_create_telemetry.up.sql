@@ -0,0 +1,13 @@
+-- Production/sales telemetry. Monthly partitions keep merges and TTL drops cheap;
+-- the sort key matches the (company, product, time range) access pattern.
+CREATE TABLE IF NOT EXISTS petrochemical.telemetry (
+    company_id LowCardinality(String),
+    product_name LowCardinality(String),
+    value Float64,
+    unit LowCardinality(String),
+    timestamp DateTime64(3, 'UTC'),
+    quality UInt16,
+    anomaly_score Float64 DEFAULT 0
+) ENGINE = MergeTree()
+PARTITION BY toYYYYMM(timestamp)
+ORDER BY (company_id, product_name, timestamp);
@@ -0,0 +1,8 @@
+-- Computed price indices; recomputation replaces earlier values by computed_at
+CREATE TABLE IF NOT EXISTS petrochemical.price_indices (
+    index_id LowCardinality(String),
+    timestamp DateTime('UTC'),
+    value Float64,
+    computed_at DateTime('UTC')
+) ENGINE = ReplacingMergeTree(computed_at)
+ORDER BY (index_id, timestamp);
@@ -0,0 +1,6 @@
+DROP VIEW IF EXISTS petrochemical.telemetry_monthly_mv;
+DROP TABLE IF EXISTS petrochemical.telemetry_monthly;
+DROP VIEW IF EXISTS petrochemical.telemetry_daily_mv;
+DROP TABLE IF EXISTS petrochemical.telemetry_daily;
+DROP VIEW IF EXISTS petrochemical.telemetry_hourly_mv;
+DROP TABLE IF EXISTS petrochemical.telemetry_hourly;
@@ -0,0 +1,90 @@
+-- Hourly, daily and monthly rollups of petrochemical.telemetry. The materialized
+-- views aggregate every insert into the raw table; rows inserted before a view
+-- existed are loaded with POST /api/v1/admin/rollups/backfill.
+
+CREATE TABLE IF NOT EXISTS petrochemical.telemetry_hourly (
+    company_id LowCardinality(String),
+    product_name LowCardinality(String),
+    bucket DateTime('UTC'),
+    unit SimpleAggregateFunction(anyLast, String),
+    value_avg AggregateFunction(avg, Float64),
+    value_min SimpleAggregateFunction(min, Float64),
+    value_max SimpleAggregateFunction(max, Float64),
+    value_sum SimpleAggregateFunction(sum, Float64),
+    samples SimpleAggregateFunction(sum, UInt64)
+) ENGINE = AggregatingMergeTree()
+PARTITION BY toYYYYMM(bucket)
+ORDER BY (company_id, product_name, bucket);
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS petrochemical.telemetry_hourly_mv
+TO petrochemical.telemetry_hourly AS
+SELECT
+    company_id,
+    product_name,
+    toStartOfHour(toDateTime(timestamp, 'UTC')) AS bucket,
+    anyLast(toString(unit)) AS unit,
+    avgState(value) AS value_avg,
+    min(value) AS value_min,
+    max(value) AS value_max,
+    sum(value) AS value_sum,
+    count() AS samples
+FROM petrochemical.telemetry
+GROUP BY company_id, product_name, bucket;
+
+CREATE TABLE IF NOT EXISTS petrochemical.telemetry_daily (
+    company_id LowCardinality(String),
+    product_name LowCardinality(String),
+    bucket DateTime('UTC'),
+    unit SimpleAggregateFunction(anyLast, String),
+    value_avg AggregateFunction(avg, Float64),
+    value_min SimpleAggregateFunction(min, Float64),
+    value_max SimpleAggregateFunction(max, Float64),
+    value_sum SimpleAggregateFunction(sum, Float64),
+    samples SimpleAggregateFunction(sum, UInt64)
+) ENGINE = AggregatingMergeTree()
+PARTITION BY toYear(bucket)
+ORDER BY (company_id, product_name, bucket);
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS petrochemical.telemetry_daily_mv
+TO petrochemical.telemetry_daily AS
+SELECT
+    company_id,
+    product_name,
+    toDateTime(toStartOfDay(timestamp), 'UTC') AS bucket,
+    anyLast(toString(unit)) AS unit,
+    avgState(value) AS value_avg,
+    min(value) AS value_min,
+    max(value) AS value_max,
+    sum(value) AS value_sum,
+    count() AS samples
+FROM petrochemical.telemetry
+GROUP BY company_id, product_name, bucket;
+
+CREATE TABLE IF NOT EXISTS petrochemical.telemetry_monthly (
+    company_id LowCardinality(String),
+    product_name LowCardinality(String),
+    bucket DateTime('UTC'),
+    unit SimpleAggregateFunction(anyLast, String),
+    value_avg AggregateFunction(avg, Float64),
+    value_min SimpleAggregateFunction(min, Float64),
+    value_max SimpleAggregateFunction(max, Float64),
+    value_sum SimpleAggregateFunction(sum, Float64),
+    samples SimpleAggregateFunction(sum, UInt64)
+) ENGINE = AggregatingMergeTree()
+PARTITION BY tuple()
+ORDER BY (company_id, product_name, bucket);
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS petrochemical.telemetry_monthly_mv
+TO petrochemical.telemetry_monthly AS
+SELECT
+    company_id,
+    product_name,
+    toDateTime(toStartOfMonth(timestamp), 'UTC') AS bucket,
+    anyLast(toString(unit)) AS unit,
+    avgState(value) AS value_avg,
+    min(value) AS value_min,
+    max(value) AS value_max,
+    sum(value) AS value_sum,
+    count() AS samples
+FROM petrochemical.telemetry
+GROUP BY company_id, product_name, bucket;
@@ -0,0 +1 @@
+ALTER TABLE petrochemical.telemetry DROP COLUMN IF EXISTS tags;
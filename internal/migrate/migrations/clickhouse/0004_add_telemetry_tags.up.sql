@@ -0,0 +1,3 @@
+-- Free-form tags for filtering raw telemetry (hasAll(tags, [...])).
+ALTER TABLE petrochemical.telemetry
+    ADD COLUMN IF NOT EXISTS tags Array(LowCardinality(String)) DEFAULT [] AFTER quality;
@@ -0,0 +1,4 @@
+ALTER TABLE petrochemical.telemetry DROP INDEX IF EXISTS idx_tags;
+ALTER TABLE petrochemical.telemetry DROP INDEX IF EXISTS idx_labels_values;
+ALTER TABLE petrochemical.telemetry DROP INDEX IF EXISTS idx_labels_keys;
+ALTER TABLE petrochemical.telemetry DROP COLUMN IF EXISTS labels;
@@ -0,0 +1,19 @@
+-- Key/value labels (plant, region, sales channel). Bloom filter skip indexes on
+-- the map keys/values and on tags let ClickHouse skip granules when filtering
+-- by labels['key'] = 'value' or hasAll(tags, [...]).
+ALTER TABLE petrochemical.telemetry
+    ADD COLUMN IF NOT EXISTS labels Map(LowCardinality(String), String) AFTER tags;
+
+ALTER TABLE petrochemical.telemetry
+    ADD INDEX IF NOT EXISTS idx_labels_keys mapKeys(labels) TYPE bloom_filter(0.01) GRANULARITY 4;
+
+ALTER TABLE petrochemical.telemetry
+    ADD INDEX IF NOT EXISTS idx_labels_values mapValues(labels) TYPE bloom_filter(0.01) GRANULARITY 4;
+
+ALTER TABLE petrochemical.telemetry
+    ADD INDEX IF NOT EXISTS idx_tags tags TYPE bloom_filter(0.01) GRANULARITY 4;
+
+-- Build the new indexes for parts written before this migration
+ALTER TABLE petrochemical.telemetry MATERIALIZE INDEX idx_labels_keys;
+ALTER TABLE petrochemical.telemetry MATERIALIZE INDEX idx_labels_values;
+ALTER TABLE petrochemical.telemetry MATERIALIZE INDEX idx_tags;
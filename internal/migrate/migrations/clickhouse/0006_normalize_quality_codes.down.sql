@@ -0,0 +1 @@
+-- Irreversible: rows written as 192 cannot be told apart from rewritten legacy rows.
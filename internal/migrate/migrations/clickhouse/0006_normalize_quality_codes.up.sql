@@ -0,0 +1,3 @@
+-- Quality used to be 0 = bad, 1 = good. Codes now follow OPC DA (192 = good),
+-- where 1 would read as "bad, low limited", so rewrite the legacy good value.
+ALTER TABLE petrochemical.telemetry UPDATE quality = 192 WHERE quality = 1;
@@ -0,0 +1,87 @@
+DROP TABLE IF EXISTS petrochemical.telemetry_revisions;
+
+-- Guarded like the up script: an interrupted revert can be applied again
+-- +migrate if SELECT count() FROM system.tables WHERE database = 'petrochemical' AND name = 'telemetry' AND engine = 'ReplacingMergeTree'
+DROP TABLE IF EXISTS petrochemical.telemetry_old;
+
+-- +migrate if SELECT count() FROM system.tables WHERE database = 'petrochemical' AND name = 'telemetry' AND engine = 'ReplacingMergeTree'
+CREATE TABLE IF NOT EXISTS petrochemical.telemetry_old (
+    company_id LowCardinality(String),
+    product_name LowCardinality(String),
+    value Float64,
+    unit LowCardinality(String),
+    timestamp DateTime64(3, 'UTC'),
+    quality UInt16,
+    tags Array(LowCardinality(String)) DEFAULT [],
+    labels Map(LowCardinality(String), String),
+    anomaly_score Float64 DEFAULT 0,
+    INDEX idx_labels_keys mapKeys(labels) TYPE bloom_filter(0.01) GRANULARITY 4,
+    INDEX idx_labels_values mapValues(labels) TYPE bloom_filter(0.01) GRANULARITY 4,
+    INDEX idx_tags tags TYPE bloom_filter(0.01) GRANULARITY 4
+) ENGINE = MergeTree()
+PARTITION BY toYYYYMM(timestamp)
+ORDER BY (company_id, product_name, timestamp);
+
+-- +migrate if SELECT count() FROM system.tables WHERE database = 'petrochemical' AND name = 'telemetry' AND engine = 'ReplacingMergeTree'
+INSERT INTO petrochemical.telemetry_old
+    (company_id, product_name, value, unit, timestamp, quality, tags, labels, anomaly_score)
+SELECT company_id, product_name, value, unit, timestamp, quality, tags, labels, anomaly_score
+FROM petrochemical.telemetry FINAL;
+
+-- +migrate if SELECT count() FROM system.tables WHERE database = 'petrochemical' AND name = 'telemetry' AND engine = 'ReplacingMergeTree'
+DROP VIEW IF EXISTS petrochemical.telemetry_hourly_mv;
+-- +migrate if SELECT count() FROM system.tables WHERE database = 'petrochemical' AND name = 'telemetry' AND engine = 'ReplacingMergeTree'
+DROP VIEW IF EXISTS petrochemical.telemetry_daily_mv;
+-- +migrate if SELECT count() FROM system.tables WHERE database = 'petrochemical' AND name = 'telemetry' AND engine = 'ReplacingMergeTree'
+DROP VIEW IF EXISTS petrochemical.telemetry_monthly_mv;
+
+-- +migrate if SELECT count() FROM system.tables WHERE database = 'petrochemical' AND name = 'telemetry' AND engine = 'ReplacingMergeTree'
+EXCHANGE TABLES petrochemical.telemetry AND petrochemical.telemetry_old;
+
+-- +migrate if SELECT count() FROM system.tables WHERE database = 'petrochemical' AND name = 'telemetry' AND engine = 'MergeTree'
+DROP TABLE IF EXISTS petrochemical.telemetry_old;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS petrochemical.telemetry_hourly_mv
+TO petrochemical.telemetry_hourly AS
+SELECT
+    company_id,
+    product_name,
+    toStartOfHour(toDateTime(timestamp, 'UTC')) AS bucket,
+    anyLast(toString(unit)) AS unit,
+    avgState(value) AS value_avg,
+    min(value) AS value_min,
+    max(value) AS value_max,
+    sum(value) AS value_sum,
+    count() AS samples
+FROM petrochemical.telemetry
+GROUP BY company_id, product_name, bucket;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS petrochemical.telemetry_daily_mv
+TO petrochemical.telemetry_daily AS
+SELECT
+    company_id,
+    product_name,
+    toDateTime(toStartOfDay(timestamp), 'UTC') AS bucket,
+    anyLast(toString(unit)) AS unit,
+    avgState(value) AS value_avg,
+    min(value) AS value_min,
+    max(value) AS value_max,
+    sum(value) AS value_sum,
+    count() AS samples
+FROM petrochemical.telemetry
+GROUP BY company_id, product_name, bucket;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS petrochemical.telemetry_monthly_mv
+TO petrochemical.telemetry_monthly AS
+SELECT
+    company_id,
+    product_name,
+    toDateTime(toStartOfMonth(timestamp), 'UTC') AS bucket,
+    anyLast(toString(unit)) AS unit,
+    avgState(value) AS value_avg,
+    min(value) AS value_min,
+    max(value) AS value_max,
+    sum(value) AS value_sum,
+    count() AS samples
+FROM petrochemical.telemetry
+GROUP BY company_id, product_name, bucket;
@@ -0,0 +1,301 @@
+-- Idempotent writes: telemetry becomes a ReplacingMergeTree keyed on
+-- (company_id, product_name, timestamp), where the row with the highest
+-- version wins. The engine cannot be altered in place, so the table is rebuilt
+-- and swapped. The rollup views are recreated around the swap so they keep
+-- reading from the new table. Retention TTLs are reapplied after migrations.
+--
+-- The copy and the swap are guarded by the engines of telemetry and telemetry_new,
+-- so a run interrupted at any point can be applied again: before the swap the copy
+-- starts over, after it the swap is never repeated. Rows written to the old table
+-- while the copy runs are copied again after the swap.
+--
+-- The rollups are then rebuilt from telemetry FINAL into staging tables that are
+-- swapped in: the views counted every duplicate written before this migration,
+-- and points ingested while the views are dropped would be missing. Buckets whose
+-- raw data has already expired by TTL are kept as they are. Points ingested during
+-- the rebuild do not reach the rollups, so pause ingestion for the migration or
+-- rebuild that window with POST /api/v1/admin/rollups/backfill afterwards.
+
+-- Leftover of an interrupted copy
+-- +migrate if SELECT count() FROM system.tables WHERE database = 'petrochemical' AND name = 'telemetry' AND engine = 'MergeTree'
+DROP TABLE IF EXISTS petrochemical.telemetry_new;
+
+-- +migrate if SELECT count() FROM system.tables WHERE database = 'petrochemical' AND name = 'telemetry' AND engine = 'MergeTree'
+CREATE TABLE IF NOT EXISTS petrochemical.telemetry_new (
+    company_id LowCardinality(String),
+    product_name LowCardinality(String),
+    value Float64,
+    unit LowCardinality(String),
+    timestamp DateTime64(3, 'UTC'),
+    quality UInt16,
+    tags Array(LowCardinality(String)) DEFAULT [],
+    labels Map(LowCardinality(String), String),
+    anomaly_score Float64 DEFAULT 0,
+    version UInt64 DEFAULT 0,
+    INDEX idx_labels_keys mapKeys(labels) TYPE bloom_filter(0.01) GRANULARITY 4,
+    INDEX idx_labels_values mapValues(labels) TYPE bloom_filter(0.01) GRANULARITY 4,
+    INDEX idx_tags tags TYPE bloom_filter(0.01) GRANULARITY 4
+) ENGINE = ReplacingMergeTree(version)
+PARTITION BY toYYYYMM(timestamp)
+ORDER BY (company_id, product_name, timestamp);
+
+-- Existing duplicates collapse to one row per key on merge
+-- +migrate if SELECT count() FROM system.tables WHERE database = 'petrochemical' AND name = 'telemetry' AND engine = 'MergeTree'
+INSERT INTO petrochemical.telemetry_new
+    (company_id, product_name, value, unit, timestamp, quality, tags, labels, anomaly_score, version)
+SELECT company_id, product_name, value, unit, timestamp, quality, tags, labels, anomaly_score, 0
+FROM petrochemical.telemetry;
+
+-- The views stay dropped until the rollups are rebuilt, also when the migration
+-- is applied again after the swap
+DROP VIEW IF EXISTS petrochemical.telemetry_hourly_mv;
+DROP VIEW IF EXISTS petrochemical.telemetry_daily_mv;
+DROP VIEW IF EXISTS petrochemical.telemetry_monthly_mv;
+
+-- +migrate if SELECT count() FROM system.tables WHERE database = 'petrochemical' AND name = 'telemetry' AND engine = 'MergeTree'
+EXCHANGE TABLES petrochemical.telemetry AND petrochemical.telemetry_new;
+
+-- After the swap telemetry_new is the old table: copy the rows written to it
+-- since the first copy. The views are not attached yet, so rows the old views
+-- already rolled up are not counted twice.
+-- +migrate if SELECT count() FROM system.tables WHERE database = 'petrochemical' AND name = 'telemetry_new' AND engine = 'MergeTree'
+INSERT INTO petrochemical.telemetry
+    (company_id, product_name, value, unit, timestamp, quality, tags, labels, anomaly_score, version)
+SELECT company_id, product_name, old.value, old.unit, timestamp, old.quality, old.tags, old.labels, old.anomaly_score, 0
+FROM petrochemical.telemetry_new AS old
+LEFT ANTI JOIN (
+    SELECT company_id, product_name, timestamp FROM petrochemical.telemetry
+) AS cur USING (company_id, product_name, timestamp);
+
+-- +migrate if SELECT count() FROM system.tables WHERE database = 'petrochemical' AND name = 'telemetry' AND engine = 'ReplacingMergeTree'
+DROP TABLE IF EXISTS petrochemical.telemetry_new;
+
+-- Hourly rollup. keep_before is the start of the first bucket fully covered by
+-- raw data for series whose rollups start earlier; older buckets are kept.
+DROP TABLE IF EXISTS petrochemical.telemetry_hourly_coverage;
+DROP TABLE IF EXISTS petrochemical.telemetry_hourly_rebuild;
+
+CREATE TABLE petrochemical.telemetry_hourly_coverage ENGINE = Memory AS
+SELECT company_id, product_name,
+    if(raw.has_raw = 0, toDateTime('2106-01-01 00:00:00', 'UTC'),
+        if(ru.rollup_start < raw.raw_floor,
+            if(raw.raw_start = toDateTime64(raw.raw_floor, 3, 'UTC'), raw.raw_floor, raw.raw_floor + INTERVAL 1 HOUR),
+            toDateTime(0, 'UTC'))) AS keep_before
+FROM (
+    SELECT company_id, product_name, min(bucket) AS rollup_start
+    FROM petrochemical.telemetry_hourly
+    GROUP BY company_id, product_name
+) AS ru
+LEFT JOIN (
+    SELECT company_id, product_name, 1 AS has_raw, min(timestamp) AS raw_start,
+        toStartOfHour(toDateTime(min(timestamp), 'UTC')) AS raw_floor
+    FROM petrochemical.telemetry
+    GROUP BY company_id, product_name
+) AS raw USING (company_id, product_name);
+
+CREATE TABLE petrochemical.telemetry_hourly_rebuild AS petrochemical.telemetry_hourly;
+
+INSERT INTO petrochemical.telemetry_hourly_rebuild
+    (company_id, product_name, bucket, unit, value_avg, value_min, value_max, value_sum, samples)
+SELECT company_id, product_name, bucket, unit, value_avg, value_min, value_max, value_sum, samples
+FROM petrochemical.telemetry_hourly
+INNER JOIN petrochemical.telemetry_hourly_coverage AS c USING (company_id, product_name)
+WHERE bucket < c.keep_before;
+
+INSERT INTO petrochemical.telemetry_hourly_rebuild
+    (company_id, product_name, bucket, unit, value_avg, value_min, value_max, value_sum, samples)
+SELECT company_id, product_name, bucket, unit, value_avg, value_min, value_max, value_sum, samples
+FROM (
+    SELECT
+        company_id,
+        product_name,
+        toStartOfHour(toDateTime(timestamp, 'UTC')) AS bucket,
+        anyLast(toString(unit)) AS unit,
+        avgState(value) AS value_avg,
+        min(value) AS value_min,
+        max(value) AS value_max,
+        sum(value) AS value_sum,
+        count() AS samples
+    FROM petrochemical.telemetry FINAL
+    GROUP BY company_id, product_name, bucket
+) AS agg
+LEFT JOIN petrochemical.telemetry_hourly_coverage AS c USING (company_id, product_name)
+WHERE agg.bucket >= c.keep_before;
+
+EXCHANGE TABLES petrochemical.telemetry_hourly AND petrochemical.telemetry_hourly_rebuild;
+DROP TABLE petrochemical.telemetry_hourly_rebuild;
+DROP TABLE petrochemical.telemetry_hourly_coverage;
+
+-- Daily rollup. keep_before is the start of the first bucket fully covered by
+-- raw data for series whose rollups start earlier; older buckets are kept.
+DROP TABLE IF EXISTS petrochemical.telemetry_daily_coverage;
+DROP TABLE IF EXISTS petrochemical.telemetry_daily_rebuild;
+
+CREATE TABLE petrochemical.telemetry_daily_coverage ENGINE = Memory AS
+SELECT company_id, product_name,
+    if(raw.has_raw = 0, toDateTime('2106-01-01 00:00:00', 'UTC'),
+        if(ru.rollup_start < raw.raw_floor,
+            if(raw.raw_start = toDateTime64(raw.raw_floor, 3, 'UTC'), raw.raw_floor, raw.raw_floor + INTERVAL 1 DAY),
+            toDateTime(0, 'UTC'))) AS keep_before
+FROM (
+    SELECT company_id, product_name, min(bucket) AS rollup_start
+    FROM petrochemical.telemetry_daily
+    GROUP BY company_id, product_name
+) AS ru
+LEFT JOIN (
+    SELECT company_id, product_name, 1 AS has_raw, min(timestamp) AS raw_start,
+        toDateTime(toStartOfDay(min(timestamp)), 'UTC') AS raw_floor
+    FROM petrochemical.telemetry
+    GROUP BY company_id, product_name
+) AS raw USING (company_id, product_name);
+
+CREATE TABLE petrochemical.telemetry_daily_rebuild AS petrochemical.telemetry_daily;
+
+INSERT INTO petrochemical.telemetry_daily_rebuild
+    (company_id, product_name, bucket, unit, value_avg, value_min, value_max, value_sum, samples)
+SELECT company_id, product_name, bucket, unit, value_avg, value_min, value_max, value_sum, samples
+FROM petrochemical.telemetry_daily
+INNER JOIN petrochemical.telemetry_daily_coverage AS c USING (company_id, product_name)
+WHERE bucket < c.keep_before;
+
+INSERT INTO petrochemical.telemetry_daily_rebuild
+    (company_id, product_name, bucket, unit, value_avg, value_min, value_max, value_sum, samples)
+SELECT company_id, product_name, bucket, unit, value_avg, value_min, value_max, value_sum, samples
+FROM (
+    SELECT
+        company_id,
+        product_name,
+        toDateTime(toStartOfDay(timestamp), 'UTC') AS bucket,
+        anyLast(toString(unit)) AS unit,
+        avgState(value) AS value_avg,
+        min(value) AS value_min,
+        max(value) AS value_max,
+        sum(value) AS value_sum,
+        count() AS samples
+    FROM petrochemical.telemetry FINAL
+    GROUP BY company_id, product_name, bucket
+) AS agg
+LEFT JOIN petrochemical.telemetry_daily_coverage AS c USING (company_id, product_name)
+WHERE agg.bucket >= c.keep_before;
+
+EXCHANGE TABLES petrochemical.telemetry_daily AND petrochemical.telemetry_daily_rebuild;
+DROP TABLE petrochemical.telemetry_daily_rebuild;
+DROP TABLE petrochemical.telemetry_daily_coverage;
+
+-- Monthly rollup. keep_before is the start of the first bucket fully covered by
+-- raw data for series whose rollups start earlier; older buckets are kept.
+DROP TABLE IF EXISTS petrochemical.telemetry_monthly_coverage;
+DROP TABLE IF EXISTS petrochemical.telemetry_monthly_rebuild;
+
+CREATE TABLE petrochemical.telemetry_monthly_coverage ENGINE = Memory AS
+SELECT company_id, product_name,
+    if(raw.has_raw = 0, toDateTime('2106-01-01 00:00:00', 'UTC'),
+        if(ru.rollup_start < raw.raw_floor,
+            if(raw.raw_start = toDateTime64(raw.raw_floor, 3, 'UTC'), raw.raw_floor, raw.raw_floor + INTERVAL 1 MONTH),
+            toDateTime(0, 'UTC'))) AS keep_before
+FROM (
+    SELECT company_id, product_name, min(bucket) AS rollup_start
+    FROM petrochemical.telemetry_monthly
+    GROUP BY company_id, product_name
+) AS ru
+LEFT JOIN (
+    SELECT company_id, product_name, 1 AS has_raw, min(timestamp) AS raw_start,
+        toDateTime(toStartOfMonth(min(timestamp)), 'UTC') AS raw_floor
+    FROM petrochemical.telemetry
+    GROUP BY company_id, product_name
+) AS raw USING (company_id, product_name);
+
+CREATE TABLE petrochemical.telemetry_monthly_rebuild AS petrochemical.telemetry_monthly;
+
+INSERT INTO petrochemical.telemetry_monthly_rebuild
+    (company_id, product_name, bucket, unit, value_avg, value_min, value_max, value_sum, samples)
+SELECT company_id, product_name, bucket, unit, value_avg, value_min, value_max, value_sum, samples
+FROM petrochemical.telemetry_monthly
+INNER JOIN petrochemical.telemetry_monthly_coverage AS c USING (company_id, product_name)
+WHERE bucket < c.keep_before;
+
+INSERT INTO petrochemical.telemetry_monthly_rebuild
+    (company_id, product_name, bucket, unit, value_avg, value_min, value_max, value_sum, samples)
+SELECT company_id, product_name, bucket, unit, value_avg, value_min, value_max, value_sum, samples
+FROM (
+    SELECT
+        company_id,
+        product_name,
+        toDateTime(toStartOfMonth(timestamp), 'UTC') AS bucket,
+        anyLast(toString(unit)) AS unit,
+        avgState(value) AS value_avg,
+        min(value) AS value_min,
+        max(value) AS value_max,
+        sum(value) AS value_sum,
+        count() AS samples
+    FROM petrochemical.telemetry FINAL
+    GROUP BY company_id, product_name, bucket
+) AS agg
+LEFT JOIN petrochemical.telemetry_monthly_coverage AS c USING (company_id, product_name)
+WHERE agg.bucket >= c.keep_before;
+
+EXCHANGE TABLES petrochemical.telemetry_monthly AND petrochemical.telemetry_monthly_rebuild;
+DROP TABLE petrochemical.telemetry_monthly_rebuild;
+DROP TABLE petrochemical.telemetry_monthly_coverage;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS petrochemical.telemetry_hourly_mv
+TO petrochemical.telemetry_hourly AS
+SELECT
+    company_id,
+    product_name,
+    toStartOfHour(toDateTime(timestamp, 'UTC')) AS bucket,
+    anyLast(toString(unit)) AS unit,
+    avgState(value) AS value_avg,
+    min(value) AS value_min,
+    max(value) AS value_max,
+    sum(value) AS value_sum,
+    count() AS samples
+FROM petrochemical.telemetry
+GROUP BY company_id, product_name, bucket;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS petrochemical.telemetry_daily_mv
+TO petrochemical.telemetry_daily AS
+SELECT
+    company_id,
+    product_name,
+    toDateTime(toStartOfDay(timestamp), 'UTC') AS bucket,
+    anyLast(toString(unit)) AS unit,
+    avgState(value) AS value_avg,
+    min(value) AS value_min,
+    max(value) AS value_max,
+    sum(value) AS value_sum,
+    count() AS samples
+FROM petrochemical.telemetry
+GROUP BY company_id, product_name, bucket;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS petrochemical.telemetry_monthly_mv
+TO petrochemical.telemetry_monthly AS
+SELECT
+    company_id,
+    product_name,
+    toDateTime(toStartOfMonth(timestamp), 'UTC') AS bucket,
+    anyLast(toString(unit)) AS unit,
+    avgState(value) AS value_avg,
+    min(value) AS value_min,
+    max(value) AS value_max,
+    sum(value) AS value_sum,
+    count() AS samples
+FROM petrochemical.telemetry
+GROUP BY company_id, product_name, bucket;
+
+-- Previous values replaced by revisions (write mode "revision")
+CREATE TABLE IF NOT EXISTS petrochemical.telemetry_revisions (
+    company_id LowCardinality(String),
+    product_name LowCardinality(String),
+    timestamp DateTime64(3, 'UTC'),
+    old_value Float64,
+    new_value Float64,
+    old_quality UInt16,
+    new_quality UInt16,
+    old_version UInt64,
+    new_version UInt64,
+    revised_at DateTime64(3, 'UTC'),
+    source LowCardinality(String)
+) ENGINE = MergeTree()
+PARTITION BY toYear(timestamp)
+ORDER BY (company_id, product_name, timestamp, revised_at);
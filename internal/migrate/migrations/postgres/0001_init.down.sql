@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS alerts;
+DROP TABLE IF EXISTS control_commands;
+DROP TABLE IF EXISTS assets;
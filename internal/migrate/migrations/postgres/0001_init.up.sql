@@ -0,0 +1,42 @@
+-- Assets (companies, refineries, plants)
+CREATE TABLE IF NOT EXISTS assets (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(100) NOT NULL,
+    location VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_assets_type ON assets(type);
+CREATE INDEX IF NOT EXISTS idx_assets_location ON assets(location);
+
+-- Control commands
+CREATE TABLE IF NOT EXISTS control_commands (
+    id VARCHAR(255) PRIMARY KEY,
+    equipment_id VARCHAR(255) NOT NULL,
+    command VARCHAR(255) NOT NULL,
+    parameters JSONB,
+    status VARCHAR(50) DEFAULT 'pending',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    executed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_control_commands_equipment_id ON control_commands(equipment_id);
+CREATE INDEX IF NOT EXISTS idx_control_commands_status ON control_commands(status);
+
+-- Alerts
+CREATE TABLE IF NOT EXISTS alerts (
+    id VARCHAR(255) PRIMARY KEY,
+    type VARCHAR(100) NOT NULL,
+    severity VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+    company_id VARCHAR(255),
+    asset_id VARCHAR(255),
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    acked BOOLEAN DEFAULT FALSE,
+    acked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_alerts_company_id ON alerts(company_id);
+CREATE INDEX IF NOT EXISTS idx_alerts_timestamp ON alerts(timestamp);
@@ -0,0 +1,8 @@
+DELETE FROM assets WHERE id IN (
+    'ROSNEFT_OMSK_REFINERY',
+    'GAZPROMNEFT_MOSCOW_REFINERY',
+    'LUKOIL_VOLGOGRAD_REFINERY',
+    'SIBUR_TOBOLSK_POLYMER',
+    'TATNEFT_ROMASHKINO_FIELD',
+    'NOVATEK_YAMAL_LNG'
+);
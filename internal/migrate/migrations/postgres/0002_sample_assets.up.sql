@@ -0,0 +1,8 @@
+INSERT INTO assets (id, name, type, location) VALUES
+('ROSNEFT_OMSK_REFINERY', 'Роснефть - Омский НПЗ', 'refinery', 'Омск'),
+('GAZPROMNEFT_MOSCOW_REFINERY', 'Газпромнефть - Московский НПЗ', 'refinery', 'Москва'),
+('LUKOIL_VOLGOGRAD_REFINERY', 'Лукойл - Волгоградский НПЗ', 'refinery', 'Волгоград'),
+('SIBUR_TOBOLSK_POLYMER', 'СИБУР - Тобольск Полимер', 'polymer_plant', 'Тобольск'),
+('TATNEFT_ROMASHKINO_FIELD', 'Татнефть - Ромашкинское месторождение', 'oilfield', 'Ромашкино'),
+('NOVATEK_YAMAL_LNG', 'Новатэк - Ямал СПГ', 'lng_plant', 'Ямал')
+ON CONFLICT (id) DO NOTHING;
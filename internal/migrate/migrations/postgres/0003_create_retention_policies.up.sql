@@ -0,0 +1,18 @@
+-- Retention policies for ClickHouse data classes. An empty company_id is the
+-- default policy of the class; other rows override it for one company.
+-- retain_days = 0 keeps data forever. Every class is kept forever by default:
+-- operators opt in to shorter retention through the admin API.
+CREATE TABLE IF NOT EXISTS retention_policies (
+    data_class VARCHAR(50) NOT NULL,
+    company_id VARCHAR(255) NOT NULL DEFAULT '',
+    retain_days INT NOT NULL CHECK (retain_days >= 0),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (data_class, company_id)
+);
+
+INSERT INTO retention_policies (data_class, company_id, retain_days) VALUES
+('raw', '', 0),
+('hourly', '', 0),
+('daily', '', 0),
+('monthly', '', 0)
+ON CONFLICT (data_class, company_id) DO NOTHING;
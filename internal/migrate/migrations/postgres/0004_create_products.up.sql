@@ -0,0 +1,104 @@
+-- Product catalog: products each company reports and their units. Ingested
+-- telemetry is validated against it.
+CREATE TABLE IF NOT EXISTS products (
+    id BIGSERIAL PRIMARY KEY,
+    company_id VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(100) NOT NULL,
+    unit VARCHAR(50) NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'active',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (company_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_products_company_id ON products(company_id);
+
+INSERT INTO products (company_id, name, type, unit) VALUES
+('SIBUR_TOBOLSK', 'Полипропилен', 'polymer', 'т/час'),
+('SIBUR_TOBOLSK', 'Полиэтилен', 'polymer', 'т/час'),
+('SIBUR_TOBOLSK', 'МТБЭ', 'chemical', 'т/час'),
+('SIBUR_TOBOLSK', 'Бутадиен', 'chemical', 'т/час'),
+('SIBUR_TOBOLSK', 'Бензол', 'chemical', 'т/час'),
+('SIBUR_TOBOLSK', 'Фенол', 'chemical', 'т/час'),
+('SIBUR_TOBOLSK', 'Стирол', 'chemical', 'т/час'),
+('NIZHNEKAMSKNEFTEKHIM', 'Синтетические каучуки', 'rubber', 'т/час'),
+('NIZHNEKAMSKNEFTEKHIM', 'Полиэтилен', 'polymer', 'т/час'),
+('NIZHNEKAMSKNEFTEKHIM', 'Стирол', 'chemical', 'т/час'),
+('NIZHNEKAMSKNEFTEKHIM', 'Полистирол', 'polymer', 'т/час'),
+('NIZHNEKAMSKNEFTEKHIM', 'АБС-пластик', 'polymer', 'т/час'),
+('ANHK', 'Полипропилен', 'polymer', 'т/час'),
+('ANHK', 'Полиэтилен', 'polymer', 'т/час'),
+('ANHK', 'Бензол', 'chemical', 'т/час'),
+('ANHK', 'Толуол', 'chemical', 'т/час'),
+('ANHK', 'Ксилолы', 'chemical', 'т/час'),
+('ZAPSIBNEFTEKHIM', 'Полипропилен', 'polymer', 'т/час'),
+('ZAPSIBNEFTEKHIM', 'Полиэтилен', 'polymer', 'т/час'),
+('NOVOKUYB', 'Полипропилен', 'polymer', 'т/час'),
+('NOVOKUYB', 'Бутиловые каучуки', 'rubber', 'т/час'),
+('NOVOKUYB', 'МТБЭ', 'chemical', 'т/час'),
+('STAVROLEN', 'Полипропилен', 'polymer', 'т/час'),
+('STAVROLEN', 'Полиэтилен', 'polymer', 'т/час'),
+('BALTIC_CHEMICAL', 'ПЭВД', 'polymer', 'т/час'),
+('BALTIC_CHEMICAL', 'Полистирол', 'polymer', 'т/час'),
+('STERLITAMAK_NHZ', 'Каучуки', 'rubber', 'т/час'),
+('STERLITAMAK_NHZ', 'Антиоксиданты', 'chemical', 'т/час'),
+('STERLITAMAK_NHZ', 'МТБЭ', 'chemical', 'т/час'),
+('KEMEROVO_KHZ', 'Кокс', 'coke', 'т/час'),
+('KEMEROVO_KHZ', 'Бензол', 'chemical', 'т/час'),
+('KEMEROVO_KHZ', 'Нафталин', 'coke', 'т/час'),
+('KAZANORG', 'Полиэтилен', 'polymer', 'т/час'),
+('KAZANORG', 'Этилен', 'chemical', 'т/час'),
+('KAZANORG', 'Пропилен', 'chemical', 'т/час'),
+('KAZANORG', 'Изопрен', 'chemical', 'т/час'),
+('TATNEFT_NK', 'Полипропилен', 'polymer', 'т/час'),
+('TATNEFT_NK', 'Бензол', 'chemical', 'т/час'),
+('TATNEFT_NK', 'Параксилол', 'chemical', 'т/час'),
+('TATNEFT_NK', 'Базовые масла', 'oil', 'т/час'),
+('URALCHEM', 'Аммиак', 'fertilizer', 'т/час'),
+('URALCHEM', 'Карбамид', 'fertilizer', 'т/час'),
+('URALCHEM', 'Аммиачная селитра', 'fertilizer', 'т/час'),
+('URALCHEM', 'Капролактам', 'chemical', 'т/час'),
+('UFAORG', 'Фенол', 'chemical', 'т/час'),
+('UFAORG', 'Ацетон', 'chemical', 'т/час'),
+('UFAORG', 'Бисфенол А', 'chemical', 'т/час'),
+('UFAORG', 'Поликарбонаты', 'polymer', 'т/час'),
+('TAIF_NK', 'Полипропилен', 'polymer', 'т/час'),
+('TAIF_NK', 'Автобензины', 'fuel', 'т/час'),
+('TAIF_NK', 'Дизельное топливо', 'fuel', 'т/час'),
+('TAIF_NK', 'Битум', 'oil', 'т/час'),
+('EVROKHIM', 'Аммиак', 'fertilizer', 'т/час'),
+('EVROKHIM', 'Карбамид', 'fertilizer', 'т/час'),
+('EVROKHIM', 'Аммиачная селитра', 'fertilizer', 'т/час'),
+('EVROKHIM', 'NPK удобрения', 'fertilizer', 'т/час'),
+('POLIPLASTIK', 'ПЭВД', 'polymer', 'т/час'),
+('POLIPLASTIK', 'Полистирол', 'polymer', 'т/час'),
+('POLIPLASTIK', 'Ударопрочный полистирол', 'polymer', 'т/час'),
+('ROSNEFT', 'Автобензины', 'fuel', 'т/час'),
+('ROSNEFT', 'Дизельное топливо', 'fuel', 'т/час'),
+('ROSNEFT', 'Авиакеросин', 'fuel', 'т/час'),
+('ROSNEFT', 'Базовые масла', 'oil', 'т/час'),
+('GAZPROMNEFT', 'Автобензины', 'fuel', 'т/час'),
+('GAZPROMNEFT', 'Дизельное топливо', 'fuel', 'т/час'),
+('GAZPROMNEFT', 'Авиакеросин', 'fuel', 'т/час'),
+('GAZPROMNEFT', 'Базовые масла', 'oil', 'т/час'),
+('TATNEFT', 'Нефть сырая', 'oil', 'т/час'),
+('TATNEFT', 'Автобензины', 'fuel', 'т/час'),
+('TATNEFT', 'Параксилол', 'chemical', 'т/час'),
+('TATNEFT', 'Бензол', 'chemical', 'т/час'),
+('SURGURNEFT', 'Нефть сырая', 'oil', 'т/час'),
+('SURGURNEFT', 'Газовый конденсат', 'gas', 'т/час'),
+('SURGURNEFT', 'Природный газ', 'gas', 'м³/час'),
+('LUKOIL', 'Автобензины', 'fuel', 'т/час'),
+('LUKOIL', 'Дизельное топливо', 'fuel', 'т/час'),
+('LUKOIL', 'Авиакеросин', 'fuel', 'т/час'),
+('LUKOIL', 'Битум', 'oil', 'т/час'),
+('NOVATEK', 'СПГ', 'gas', 'т/час'),
+('NOVATEK', 'Газовый конденсат', 'gas', 'т/час'),
+('NOVATEK', 'СУГ', 'gas', 'т/час'),
+('NOVATEK', 'Природный газ', 'gas', 'м³/час'),
+('VOSTOCHNAYA', 'Автобензины', 'fuel', 'т/час'),
+('VOSTOCHNAYA', 'Дизельное топливо', 'fuel', 'т/час'),
+('VOSTOCHNAYA', 'Авиакеросин', 'fuel', 'т/час'),
+('VOSTOCHNAYA', 'Битум', 'oil', 'т/час')
+ON CONFLICT (company_id, name) DO NOTHING;
@@ -0,0 +1,13 @@
+-- Processed price feed reports (exchange and price agency files). A report date of
+-- a source is loaded once: a file with the same checksum is a duplicate, a file
+-- with another checksum is a corrected report and replaces the values.
+CREATE TABLE IF NOT EXISTS feed_reports (
+    source VARCHAR(100) NOT NULL,
+    report_date DATE NOT NULL,
+    file_name VARCHAR(500) NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    quotes INT NOT NULL DEFAULT 0,
+    points INT NOT NULL DEFAULT 0,
+    processed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (source, report_date)
+);
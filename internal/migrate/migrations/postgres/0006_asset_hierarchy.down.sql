@@ -0,0 +1,27 @@
+ALTER TABLE assets DROP CONSTRAINT IF EXISTS assets_parent_not_self_check;
+ALTER TABLE assets DROP CONSTRAINT IF EXISTS assets_company_root_check;
+ALTER TABLE assets DROP CONSTRAINT IF EXISTS assets_level_check;
+
+DELETE FROM assets WHERE id IN ('SIBUR_TOBOLSK_PP_LINE1');
+DELETE FROM assets WHERE id IN ('SIBUR_TOBOLSK_PP');
+UPDATE assets SET parent_id = NULL;
+DELETE FROM assets WHERE id IN (
+    'SIBUR_TOBOLSK',
+    'ZAPSIBNEFTEKHIM',
+    'NIZHNEKAMSKNEFTEKHIM',
+    'TATNEFT_NK',
+    'SIBUR',
+    'ROSNEFT',
+    'GAZPROMNEFT',
+    'LUKOIL',
+    'TATNEFT',
+    'NOVATEK'
+);
+
+DROP INDEX IF EXISTS idx_assets_telemetry_company_id;
+DROP INDEX IF EXISTS idx_assets_parent_id;
+
+ALTER TABLE assets
+    DROP COLUMN IF EXISTS telemetry_company_id,
+    DROP COLUMN IF EXISTS level,
+    DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,50 @@
+-- Asset hierarchy: company → site → plant → unit → equipment. Companies are the
+-- roots; every other asset has a parent on a higher level. telemetry_company_id
+-- links an asset to the company_id of its telemetry series, so production can be
+-- rolled up along the tree.
+ALTER TABLE assets
+    ADD COLUMN IF NOT EXISTS parent_id VARCHAR(255) REFERENCES assets(id) ON DELETE RESTRICT,
+    ADD COLUMN IF NOT EXISTS level VARCHAR(50) NOT NULL DEFAULT 'site',
+    ADD COLUMN IF NOT EXISTS telemetry_company_id VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_assets_parent_id ON assets(parent_id);
+CREATE INDEX IF NOT EXISTS idx_assets_telemetry_company_id ON assets(telemetry_company_id);
+
+INSERT INTO assets (id, name, type, location, level, telemetry_company_id) VALUES
+('SIBUR', 'СИБУР Холдинг', 'company', 'Москва', 'company', NULL),
+('ROSNEFT', 'Роснефть', 'company', 'Москва', 'company', 'ROSNEFT'),
+('GAZPROMNEFT', 'Газпромнефть', 'company', 'Санкт-Петербург', 'company', 'GAZPROMNEFT'),
+('LUKOIL', 'Лукойл', 'company', 'Москва', 'company', 'LUKOIL'),
+('TATNEFT', 'Татнефть', 'company', 'Альметьевск', 'company', 'TATNEFT'),
+('NOVATEK', 'Новатэк', 'company', 'Москва', 'company', 'NOVATEK')
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO assets (id, name, type, location, parent_id, level, telemetry_company_id) VALUES
+('SIBUR_TOBOLSK', 'СИБУР - Тобольская площадка', 'petrochemical_site', 'Тобольск', 'SIBUR', 'site', 'SIBUR_TOBOLSK'),
+('ZAPSIBNEFTEKHIM', 'СИБУР - ЗапСибНефтехим', 'petrochemical_site', 'Тобольск', 'SIBUR', 'site', 'ZAPSIBNEFTEKHIM'),
+('NIZHNEKAMSKNEFTEKHIM', 'СИБУР - Нижнекамскнефтехим', 'petrochemical_site', 'Нижнекамск', 'SIBUR', 'site', 'NIZHNEKAMSKNEFTEKHIM'),
+('TATNEFT_NK', 'Татнефть - ТАНЕКО', 'refinery', 'Нижнекамск', 'TATNEFT', 'site', 'TATNEFT_NK')
+ON CONFLICT (id) DO NOTHING;
+
+UPDATE assets SET parent_id = 'ROSNEFT', level = 'site' WHERE id = 'ROSNEFT_OMSK_REFINERY';
+UPDATE assets SET parent_id = 'GAZPROMNEFT', level = 'site' WHERE id = 'GAZPROMNEFT_MOSCOW_REFINERY';
+UPDATE assets SET parent_id = 'LUKOIL', level = 'site' WHERE id = 'LUKOIL_VOLGOGRAD_REFINERY';
+UPDATE assets SET parent_id = 'SIBUR_TOBOLSK', level = 'plant' WHERE id = 'SIBUR_TOBOLSK_POLYMER';
+UPDATE assets SET parent_id = 'TATNEFT', level = 'site' WHERE id = 'TATNEFT_ROMASHKINO_FIELD';
+UPDATE assets SET parent_id = 'NOVATEK', level = 'site' WHERE id = 'NOVATEK_YAMAL_LNG';
+
+INSERT INTO assets (id, name, type, location, parent_id, level) VALUES
+('SIBUR_TOBOLSK_PP', 'Тобольск Полимер - установка полипропилена', 'polymerization_unit', 'Тобольск', 'SIBUR_TOBOLSK_POLYMER', 'unit'),
+('SIBUR_TOBOLSK_PP_LINE1', 'Тобольск Полимер - линия полипропилена 1', 'reactor', 'Тобольск', 'SIBUR_TOBOLSK_PP', 'equipment')
+ON CONFLICT (id) DO NOTHING;
+
+-- Assets created before the hierarchy without a parent become companies
+UPDATE assets SET level = 'company' WHERE parent_id IS NULL AND level <> 'company';
+
+ALTER TABLE assets ALTER COLUMN level DROP DEFAULT;
+ALTER TABLE assets ADD CONSTRAINT assets_level_check
+    CHECK (level IN ('company', 'site', 'plant', 'unit', 'equipment'));
+ALTER TABLE assets ADD CONSTRAINT assets_company_root_check
+    CHECK ((level = 'company') = (parent_id IS NULL));
+ALTER TABLE assets ADD CONSTRAINT assets_parent_not_self_check
+    CHECK (parent_id <> id);
@@ -0,0 +1,10 @@
+DELETE FROM products WHERE (company_id, name) IN (
+    ('ROSNEFT', 'Автобензины (цена)'),
+    ('ROSNEFT', 'Автобензины (объём)'),
+    ('ROSNEFT', 'Дизельное топливо (цена)'),
+    ('SIBUR_TOBOLSK', 'Полипропилен (цена)'),
+    ('KAZANORG', 'Полиэтилен (цена)'),
+    ('NIZHNEKAMSKNEFTEKHIM', 'Полистирол (цена)'),
+    ('URALCHEM', 'Карбамид (цена)'),
+    ('URALCHEM', 'Аммиак (цена)')
+);
@@ -0,0 +1,13 @@
+-- Series written by the default price feed rules (feeds.sources in config.yaml).
+-- Prices and trade volumes have their own units, so they are separate catalog
+-- entries rather than the production series of the same product (т/час).
+INSERT INTO products (company_id, name, type, unit) VALUES
+('ROSNEFT', 'Автобензины (цена)', 'price', 'руб/т'),
+('ROSNEFT', 'Автобензины (объём)', 'trade_volume', 'т'),
+('ROSNEFT', 'Дизельное топливо (цена)', 'price', 'руб/т'),
+('SIBUR_TOBOLSK', 'Полипропилен (цена)', 'price', 'руб/т'),
+('KAZANORG', 'Полиэтилен (цена)', 'price', 'руб/т'),
+('NIZHNEKAMSKNEFTEKHIM', 'Полистирол (цена)', 'price', 'руб/т'),
+('URALCHEM', 'Карбамид (цена)', 'price', 'руб/т'),
+('URALCHEM', 'Аммиак (цена)', 'price', 'руб/т')
+ON CONFLICT (company_id, name) DO NOTHING;
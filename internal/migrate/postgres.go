@@ -0,0 +1,115 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresDriver хранит состояние миграций в PostgreSQL. Каждая миграция
+// выполняется в отдельной транзакции вместе с записью в schema_migrations.
+type PostgresDriver struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresDriver создает драйвер миграций PostgreSQL
+func NewPostgresDriver(pool *pgxpool.Pool) *PostgresDriver {
+	return &PostgresDriver{pool: pool}
+}
+
+func (d *PostgresDriver) Name() string { return "postgresql" }
+
+func (d *PostgresDriver) Init(ctx context.Context) error {
+	_, err := d.pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+			id INT PRIMARY KEY,
+			owner VARCHAR(255) NOT NULL,
+			acquired_at TIMESTAMP WITH TIME ZONE NOT NULL
+		);`)
+	if err != nil {
+		return fmt.Errorf("failed to create migration tables: %w", err)
+	}
+	return nil
+}
+
+func (d *PostgresDriver) Lock(ctx context.Context, owner string, ttl time.Duration) error {
+	// Брошенная блокировка (процесс упал во время миграции) снимается по истечении ttl
+	if _, err := d.pool.Exec(ctx,
+		`DELETE FROM schema_migrations_lock WHERE id = 1 AND acquired_at < $1`,
+		time.Now().Add(-ttl)); err != nil {
+		return fmt.Errorf("failed to clear stale migration lock: %w", err)
+	}
+
+	tag, err := d.pool.Exec(ctx, `
+		INSERT INTO schema_migrations_lock (id, owner, acquired_at)
+		VALUES (1, $1, $2)
+		ON CONFLICT (id) DO NOTHING`, owner, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLocked
+	}
+	return nil
+}
+
+func (d *PostgresDriver) Unlock(ctx context.Context, owner string) error {
+	_, err := d.pool.Exec(ctx, `DELETE FROM schema_migrations_lock WHERE id = 1 AND owner = $1`, owner)
+	return err
+}
+
+func (d *PostgresDriver) Applied(ctx context.Context) ([]Record, error) {
+	rows, err := d.pool.Query(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var r Record
+		if err := rows.Scan(&r.Version, &r.Name, &r.Checksum, &r.AppliedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+func (d *PostgresDriver) Apply(ctx context.Context, m Migration, up bool) error {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	script := m.Up
+	if !up {
+		script = m.Down
+	}
+	// Без аргументов pgx использует простой протокол, поэтому скрипт может содержать несколько выражений
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return err
+	}
+
+	if up {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)`,
+			m.Version, m.Name, m.Checksum, time.Now())
+	} else {
+		_, err = tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,62 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// TTLRule задает срок хранения строк таблицы, удовлетворяющих условию Where
+type TTLRule struct {
+	Days  int
+	Where string // Пусто — правило для всех строк
+}
+
+// TTLClause собирает выражение для ALTER TABLE ... MODIFY TTL из правил
+func TTLClause(timeColumn string, rules []TTLRule) string {
+	parts := make([]string, 0, len(rules))
+	for _, r := range rules {
+		part := fmt.Sprintf("toDateTime(%s) + INTERVAL %d DAY DELETE", timeColumn, r.Days)
+		if r.Where != "" {
+			part += " WHERE " + r.Where
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// QuoteString экранирует строковый литерал для DDL, где параметры запроса недоступны
+func QuoteString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
+
+// ApplyTTL заменяет TTL таблицы на правила rules. Пустой список снимает TTL.
+// ClickHouse применяет новый TTL к существующим кускам в фоне.
+func (d *ClickHouseDriver) ApplyTTL(ctx context.Context, table, timeColumn string, rules []TTLRule) error {
+	if len(rules) > 0 {
+		query := fmt.Sprintf("ALTER TABLE %s.%s MODIFY TTL %s", ClickHouseDatabase, table, TTLClause(timeColumn, rules))
+		if err := d.conn.Exec(ctx, query); err != nil {
+			return fmt.Errorf("failed to modify TTL of %s: %w", table, err)
+		}
+		return nil
+	}
+
+	// REMOVE TTL завершается ошибкой для таблицы без TTL, поэтому сначала проверяем движок
+	var hasTTL uint8
+	row := d.conn.QueryRow(ctx,
+		"SELECT position(engine_full, ' TTL ') > 0 FROM system.tables WHERE database = ? AND name = ?",
+		ClickHouseDatabase, table)
+	if err := row.Scan(&hasTTL); err != nil {
+		return fmt.Errorf("failed to inspect TTL of %s: %w", table, err)
+	}
+	if hasTTL == 0 {
+		return nil
+	}
+
+	if err := d.conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s.%s REMOVE TTL", ClickHouseDatabase, table)); err != nil {
+		return fmt.Errorf("failed to remove TTL of %s: %w", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,396 @@
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Method определяет статистический метод оценки аномальности
+type Method string
+
+const (
+	MethodZScore   Method = "zscore"   // Скользящий z-score
+	MethodMAD      Method = "mad"      // Модифицированный z-score по медианному абсолютному отклонению
+	MethodSeasonal Method = "seasonal" // Отклонение от значений той же фазы сезона
+)
+
+// Season определяет фазу, по которой строится сезонная база
+type Season string
+
+const (
+	SeasonNone        Season = ""
+	SeasonHourOfDay   Season = "hour_of_day"
+	SeasonDayOfWeek   Season = "day_of_week"
+	SeasonMonthOfYear Season = "month_of_year"
+)
+
+// minSeasonalPoints — минимум наблюдений одной фазы для сезонной оценки
+const minSeasonalPoints = 3
+
+// Sensitivity задает чувствительность детектора для продукта
+type Sensitivity struct {
+	Threshold   float64  // Оценка, начиная с которой точка считается аномальной
+	Window      int      // Размер скользящего окна
+	MinPoints   int      // Минимум точек в окне до начала оценки
+	Methods     []Method // Пустой список — все методы
+	Season      Season
+	RaiseAlerts bool
+}
+
+// Override задает переопределение настроек: нулевые поля наследуются от базовых.
+// RaiseAlerts — указатель, чтобы переопределение могло и включить, и выключить оповещения.
+type Override struct {
+	Threshold   float64
+	Window      int
+	MinPoints   int
+	Methods     []Method
+	Season      Season
+	RaiseAlerts *bool
+}
+
+// Validate проверяет названия методов и сезона
+func (o Override) Validate() error {
+	for _, m := range o.Methods {
+		switch m {
+		case MethodZScore, MethodMAD, MethodSeasonal:
+		default:
+			return fmt.Errorf("unknown anomaly method %q: use zscore, mad or seasonal", m)
+		}
+	}
+	switch o.Season {
+	case SeasonNone, SeasonHourOfDay, SeasonDayOfWeek, SeasonMonthOfYear:
+	default:
+		return fmt.Errorf("unknown anomaly season %q: use hour_of_day, day_of_week or month_of_year", o.Season)
+	}
+	return nil
+}
+
+// DefaultSensitivity возвращает настройки по умолчанию для помесячных данных
+func DefaultSensitivity() Sensitivity {
+	return Sensitivity{
+		Threshold: 3.5,
+		Window:    48,
+		MinPoints: 12,
+		Methods:   []Method{MethodZScore, MethodMAD, MethodSeasonal},
+		Season:    SeasonMonthOfYear,
+	}
+}
+
+// Result содержит оценку одной точки
+type Result struct {
+	Score    float64 // Максимальная оценка среди включенных методов
+	Method   Method  // Метод, давший максимальную оценку
+	Baseline float64 // Ожидаемое значение по этому методу
+	Anomaly  bool
+}
+
+// Detector — потоковый детектор аномалий с отдельным состоянием для каждой пары компания/продукт.
+// Безопасен для конкурентного использования.
+type Detector struct {
+	mu        sync.Mutex
+	defaults  Sensitivity
+	overrides map[string]Sensitivity
+	series    map[string]*seriesState
+}
+
+type seriesState struct {
+	window   *ring
+	seasonal map[int]*ring
+}
+
+// NewDetector создает детектор. Ключи overrides — названия продуктов (без учета регистра).
+func NewDetector(defaults Sensitivity, overrides map[string]Override) *Detector {
+	normalized := make(map[string]Sensitivity, len(overrides))
+	for product, s := range overrides {
+		normalized[strings.ToLower(product)] = defaults.Merge(s)
+	}
+
+	return &Detector{
+		defaults:  defaults,
+		overrides: normalized,
+		series:    make(map[string]*seriesState),
+	}
+}
+
+// Sensitivity возвращает действующие настройки для продукта
+func (d *Detector) Sensitivity(product string) Sensitivity {
+	if s, ok := d.overrides[strings.ToLower(product)]; ok {
+		return s
+	}
+	return d.defaults
+}
+
+// Batch начинает оценку пачки точек. Оценки не меняют состояние детектора до Commit,
+// поэтому точки, которые так и не были записаны, не попадают в базовую линию.
+func (d *Detector) Batch() *Batch {
+	return &Batch{d: d, series: make(map[string]*seriesState)}
+}
+
+// Batch оценивает точки пачки на копиях состояний рядов. Не безопасен для конкурентного
+// использования; разные пачки могут оцениваться параллельно.
+type Batch struct {
+	d       *Detector
+	series  map[string]*seriesState // Копии состояний рядов с учетом выученных точек пачки
+	learned []observation
+}
+
+type observation struct {
+	key     string
+	product string
+	ts      time.Time
+	value   float64
+}
+
+// Score оценивает точку относительно истории ряда и выученных ранее точек пачки.
+// При learn точка добавляется в историю пачки и попадает в детектор при Commit.
+func (b *Batch) Score(companyID, product string, ts time.Time, value float64, learn bool) Result {
+	cfg := b.d.Sensitivity(product)
+	key := companyID + "\x00" + product
+
+	state, ok := b.series[key]
+	if !ok {
+		b.d.mu.Lock()
+		if live, ok := b.d.series[key]; ok {
+			state = live.clone()
+		} else {
+			state = newSeriesState(cfg)
+		}
+		b.d.mu.Unlock()
+		b.series[key] = state
+	}
+
+	result := state.score(cfg, ts, value)
+	if learn {
+		state.learn(cfg, ts, value)
+		b.learned = append(b.learned, observation{key: key, product: product, ts: ts, value: value})
+	}
+	return result
+}
+
+// Commit добавляет выученные точки пачки в историю детектора. Вызывается после записи пачки.
+func (b *Batch) Commit() {
+	if len(b.learned) == 0 {
+		return
+	}
+
+	b.d.mu.Lock()
+	defer b.d.mu.Unlock()
+	for _, o := range b.learned {
+		cfg := b.d.Sensitivity(o.product)
+		state, ok := b.d.series[o.key]
+		if !ok {
+			state = newSeriesState(cfg)
+			b.d.series[o.key] = state
+		}
+		state.learn(cfg, o.ts, o.value)
+	}
+	b.learned = nil
+}
+
+func newSeriesState(cfg Sensitivity) *seriesState {
+	return &seriesState{window: newRing(cfg.Window), seasonal: make(map[int]*ring)}
+}
+
+func (s *seriesState) clone() *seriesState {
+	c := &seriesState{window: s.window.clone(), seasonal: make(map[int]*ring, len(s.seasonal))}
+	for phase, r := range s.seasonal {
+		c.seasonal[phase] = r.clone()
+	}
+	return c
+}
+
+// score оценивает точку относительно накопленной истории ряда
+func (s *seriesState) score(cfg Sensitivity, ts time.Time, value float64) Result {
+	var result Result
+	consider := func(method Method, score, baseline float64) {
+		if score > result.Score {
+			result.Score, result.Method, result.Baseline = score, method, baseline
+		}
+	}
+
+	values := s.window.values()
+	phase, seasonal := phaseOf(cfg.Season, ts)
+
+	for _, method := range methodsOf(cfg) {
+		switch method {
+		case MethodZScore:
+			if len(values) >= cfg.MinPoints {
+				m, sd := meanStd(values)
+				if sd > 0 {
+					consider(method, math.Abs(value-m)/sd, m)
+				}
+			}
+		case MethodMAD:
+			if len(values) >= cfg.MinPoints {
+				score, med := madScore(values, value)
+				consider(method, score, med)
+			}
+		case MethodSeasonal:
+			if !seasonal {
+				continue
+			}
+			if r, ok := s.seasonal[phase]; ok && r.len() >= minSeasonalPoints {
+				m, sd := meanStd(r.values())
+				if sd > 0 {
+					consider(method, math.Abs(value-m)/sd, m)
+				}
+			}
+		}
+	}
+	result.Anomaly = result.Score >= cfg.Threshold
+
+	return result
+}
+
+// learn добавляет точку в историю ряда
+func (s *seriesState) learn(cfg Sensitivity, ts time.Time, value float64) {
+	s.window.push(value)
+	if phase, seasonal := phaseOf(cfg.Season, ts); seasonal {
+		r, ok := s.seasonal[phase]
+		if !ok {
+			r = newRing(max(cfg.Window/12, minSeasonalPoints*2))
+			s.seasonal[phase] = r
+		}
+		r.push(value)
+	}
+}
+
+// Reset сбрасывает накопленную историю ряда
+func (d *Detector) Reset(companyID, product string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.series, companyID+"\x00"+product)
+}
+
+// Merge возвращает копию настроек, в которой заданные поля override заменяют текущие
+func (base Sensitivity) Merge(override Override) Sensitivity {
+	if override.Threshold > 0 {
+		base.Threshold = override.Threshold
+	}
+	if override.Window > 0 {
+		base.Window = override.Window
+	}
+	if override.MinPoints > 0 {
+		base.MinPoints = override.MinPoints
+	}
+	if len(override.Methods) > 0 {
+		base.Methods = override.Methods
+	}
+	if override.Season != SeasonNone {
+		base.Season = override.Season
+	}
+	if override.RaiseAlerts != nil {
+		base.RaiseAlerts = *override.RaiseAlerts
+	}
+	return base
+}
+
+func methodsOf(cfg Sensitivity) []Method {
+	if len(cfg.Methods) == 0 {
+		return []Method{MethodZScore, MethodMAD, MethodSeasonal}
+	}
+	return cfg.Methods
+}
+
+func phaseOf(season Season, ts time.Time) (int, bool) {
+	ts = ts.UTC()
+	switch season {
+	case SeasonHourOfDay:
+		return ts.Hour(), true
+	case SeasonDayOfWeek:
+		return int(ts.Weekday()), true
+	case SeasonMonthOfYear:
+		return int(ts.Month()), true
+	}
+	return 0, false
+}
+
+func meanStd(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	m := sum / float64(len(values))
+
+	var sq float64
+	for _, v := range values {
+		sq += (v - m) * (v - m)
+	}
+	if len(values) < 2 {
+		return m, 0
+	}
+	return m, math.Sqrt(sq / float64(len(values)-1))
+}
+
+// madScore вычисляет модифицированный z-score (Iglewicz, Hoaglin). Если MAD равно нулю
+// (например, после залипания значения), в качестве масштаба берется среднее абсолютное отклонение.
+func madScore(values []float64, value float64) (float64, float64) {
+	med := median(values)
+
+	deviations := make([]float64, len(values))
+	var meanAbs float64
+	for i, v := range values {
+		deviations[i] = math.Abs(v - med)
+		meanAbs += deviations[i]
+	}
+	meanAbs /= float64(len(values))
+
+	if mad := median(deviations); mad > 0 {
+		return 0.6745 * math.Abs(value-med) / mad, med
+	}
+	if meanAbs > 0 {
+		return math.Abs(value-med) / (1.2533 * meanAbs), med
+	}
+	return 0, med
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// ring — кольцевой буфер фиксированного размера
+type ring struct {
+	data []float64
+	next int
+	full bool
+}
+
+func newRing(size int) *ring {
+	return &ring{data: make([]float64, max(size, 1))}
+}
+
+func (r *ring) push(v float64) {
+	r.data[r.next] = v
+	r.next = (r.next + 1) % len(r.data)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *ring) len() int {
+	if r.full {
+		return len(r.data)
+	}
+	return r.next
+}
+
+func (r *ring) clone() *ring {
+	c := *r
+	c.data = append([]float64(nil), r.data...)
+	return &c
+}
+
+func (r *ring) values() []float64 {
+	return append([]float64(nil), r.data[:r.len()]...)
+}
@@ -0,0 +1,152 @@
+package forecast
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Method определяет алгоритм прогнозирования
+type Method string
+
+const (
+	MethodHoltWinters Method = "holt-winters"
+	MethodSTL         Method = "stl"
+)
+
+// ErrNotEnoughData возвращается, если ряд слишком короткий для выбранной модели
+var ErrNotEnoughData = errors.New("not enough data points for forecast")
+
+// Options задает параметры прогноза
+type Options struct {
+	Method     Method
+	Period     int     // Длина сезона в точках (12 для помесячных данных)
+	Horizon    int     // Количество прогнозируемых точек
+	Confidence float64 // Уровень доверительного интервала, например 0.95
+}
+
+// Result содержит прогноз и доверительные интервалы
+type Result struct {
+	Values []float64
+	Lower  []float64
+	Upper  []float64
+	Sigma  float64 // Стандартное отклонение ошибки одношагового прогноза
+}
+
+// Metrics содержит метрики точности прогноза на отложенной выборке
+type Metrics struct {
+	MAPE    float64 // Средняя абсолютная процентная ошибка, %
+	RMSE    float64 // Корень из среднеквадратичной ошибки
+	Holdout int     // Размер отложенной выборки
+}
+
+// Forecast строит прогноз ряда выбранным методом
+func Forecast(series []float64, opts Options) (*Result, error) {
+	if opts.Horizon < 1 {
+		return nil, fmt.Errorf("horizon must be positive")
+	}
+	if opts.Confidence <= 0 || opts.Confidence >= 1 {
+		return nil, fmt.Errorf("confidence must be between 0 and 1")
+	}
+
+	var values []float64
+	var sigma float64
+
+	switch opts.Method {
+	case MethodHoltWinters:
+		model, err := FitHoltWinters(series, opts.Period)
+		if err != nil {
+			return nil, err
+		}
+		values = model.Forecast(opts.Horizon)
+		sigma = model.Sigma()
+	case MethodSTL:
+		dec, err := Decompose(series, opts.Period)
+		if err != nil {
+			return nil, err
+		}
+		values = dec.Forecast(opts.Horizon)
+		sigma = stddev(dec.Remainder)
+	default:
+		return nil, fmt.Errorf("unknown forecast method %q", opts.Method)
+	}
+
+	z := math.Sqrt2 * math.Erfinv(opts.Confidence)
+	result := &Result{
+		Values: values,
+		Lower:  make([]float64, len(values)),
+		Upper:  make([]float64, len(values)),
+		Sigma:  sigma,
+	}
+
+	// Неопределенность растет с горизонтом приблизительно как sqrt(h)
+	for h, v := range values {
+		width := z * sigma * math.Sqrt(float64(h+1))
+		result.Lower[h] = v - width
+		result.Upper[h] = v + width
+	}
+
+	return result, nil
+}
+
+// Backtest обучает модель на ряде без последних holdout точек и сравнивает прогноз с фактом
+func Backtest(series []float64, opts Options, holdout int) (*Metrics, error) {
+	if holdout < 1 || holdout >= len(series) {
+		return nil, fmt.Errorf("holdout must be between 1 and %d", len(series)-1)
+	}
+
+	train, test := series[:len(series)-holdout], series[len(series)-holdout:]
+	opts.Horizon = holdout
+
+	result, err := Forecast(train, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return Evaluate(test, result.Values), nil
+}
+
+// Evaluate рассчитывает MAPE и RMSE. Нулевые фактические значения исключаются из MAPE.
+func Evaluate(actual, predicted []float64) *Metrics {
+	var sqSum, pctSum float64
+	var pctCount int
+
+	for i := range actual {
+		diff := actual[i] - predicted[i]
+		sqSum += diff * diff
+		if actual[i] != 0 {
+			pctSum += math.Abs(diff / actual[i])
+			pctCount++
+		}
+	}
+
+	metrics := &Metrics{
+		RMSE:    math.Sqrt(sqSum / float64(len(actual))),
+		Holdout: len(actual),
+	}
+	if pctCount > 0 {
+		metrics.MAPE = pctSum / float64(pctCount) * 100
+	}
+
+	return metrics
+}
+
+func mean(xs []float64) float64 {
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+func stddev(xs []float64) float64 {
+	if len(xs) < 2 {
+		return 0
+	}
+	m := mean(xs)
+	var sum float64
+	for _, x := range xs {
+		sum += (x - m) * (x - m)
+	}
+	return math.Sqrt(sum / float64(len(xs)-1))
+}
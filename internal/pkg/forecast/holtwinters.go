@@ -0,0 +1,127 @@
+package forecast
+
+import (
+	"fmt"
+	"math"
+)
+
+// HoltWinters — аддитивная модель тройного экспоненциального сглаживания.
+// При Period == 0 вырождается в линейную модель Холта без сезонности.
+type HoltWinters struct {
+	Alpha, Beta, Gamma float64
+	Period             int
+
+	level    float64
+	trend    float64
+	seasonal []float64
+	n        int
+	sse      float64
+}
+
+// gridSteps задает число шагов сетки параметров сглаживания: перебираются 0.1 … 0.9
+const gridSteps = 10
+
+// FitHoltWinters подбирает параметры сглаживания перебором по сетке, минимизируя
+// сумму квадратов одношаговых ошибок. Period < 2 означает модель без сезонности;
+// для сезонной модели нужно минимум два сезона.
+func FitHoltWinters(series []float64, period int) (*HoltWinters, error) {
+	if period < 2 {
+		period = 0
+	}
+	if period > 0 && len(series) < 2*period {
+		return nil, fmt.Errorf("%w: seasonal model with period %d needs at least %d points, got %d",
+			ErrNotEnoughData, period, 2*period, len(series))
+	}
+	if len(series) < 3 {
+		return nil, ErrNotEnoughData
+	}
+
+	gammas := []float64{0}
+	if period > 0 {
+		gammas = grid()
+	}
+
+	var best *HoltWinters
+	for _, alpha := range grid() {
+		for _, beta := range grid() {
+			for _, gamma := range gammas {
+				model := &HoltWinters{Alpha: alpha, Beta: beta, Gamma: gamma, Period: period}
+				model.fit(series)
+				if best == nil || model.sse < best.sse {
+					best = model
+				}
+			}
+		}
+	}
+
+	return best, nil
+}
+
+func grid() []float64 {
+	values := make([]float64, 0, gridSteps-1)
+	for i := 1; i < gridSteps; i++ {
+		values = append(values, float64(i)/gridSteps)
+	}
+	return values
+}
+
+func (m *HoltWinters) fit(series []float64) {
+	m.n = len(series)
+	m.sse = 0
+	start := 1
+
+	if m.Period > 0 {
+		p := m.Period
+		first, second := mean(series[:p]), mean(series[p:2*p])
+		m.level = first
+		m.trend = (second - first) / float64(p)
+		m.seasonal = make([]float64, p)
+		for i := 0; i < p; i++ {
+			m.seasonal[i] = series[i] - first
+		}
+		start = p
+	} else {
+		m.level = series[0]
+		m.trend = series[1] - series[0]
+	}
+
+	for t := start; t < len(series); t++ {
+		season := 0.0
+		if m.Period > 0 {
+			season = m.seasonal[t%m.Period]
+		}
+
+		predicted := m.level + m.trend + season
+		err := series[t] - predicted
+		m.sse += err * err
+
+		prevLevel := m.level
+		m.level = m.Alpha*(series[t]-season) + (1-m.Alpha)*(m.level+m.trend)
+		m.trend = m.Beta*(m.level-prevLevel) + (1-m.Beta)*m.trend
+		if m.Period > 0 {
+			m.seasonal[t%m.Period] = m.Gamma*(series[t]-m.level) + (1-m.Gamma)*season
+		}
+	}
+}
+
+// Forecast возвращает прогноз на horizon точек вперед
+func (m *HoltWinters) Forecast(horizon int) []float64 {
+	values := make([]float64, horizon)
+	for h := 1; h <= horizon; h++ {
+		v := m.level + float64(h)*m.trend
+		if m.Period > 0 {
+			v += m.seasonal[(m.n+h-1)%m.Period]
+		}
+		values[h-1] = v
+	}
+	return values
+}
+
+// Sigma возвращает оценку стандартного отклонения одношаговой ошибки
+func (m *HoltWinters) Sigma() float64 {
+	observations := m.n - max(m.Period, 1)
+	if observations < 1 {
+		return 0
+	}
+	return math.Sqrt(m.sse / float64(observations))
+}
@@ -0,0 +1,223 @@
+package forecast
+
+import (
+	"math"
+	"sort"
+)
+
+// Decomposition содержит компоненты STL-разложения: ряд = тренд + сезонность + остаток
+type Decomposition struct {
+	Period    int
+	Trend     []float64
+	Seasonal  []float64
+	Remainder []float64
+}
+
+const (
+	stlSeasonalSpan = 7 // Окно LOESS для сглаживания подрядов одного сезона
+	stlInnerLoops   = 2
+	stlOuterLoops   = 1 // Итерации с робастными весами, снижающими влияние выбросов
+)
+
+// Decompose выполняет упрощенное STL-разложение (Cleveland et al., 1990) с LOESS-сглаживанием
+func Decompose(series []float64, period int) (*Decomposition, error) {
+	if period < 2 || len(series) < 2*period {
+		return nil, ErrNotEnoughData
+	}
+
+	n := len(series)
+	trend := make([]float64, n)
+	seasonal := make([]float64, n)
+	weights := make([]float64, n)
+	for i := range weights {
+		weights[i] = 1
+	}
+
+	trendSpan := oddCeil(1.5 * float64(period) / (1 - 1.5/float64(stlSeasonalSpan)))
+	lowPassSpan := oddCeil(float64(period) + 1)
+
+	for outer := 0; outer <= stlOuterLoops; outer++ {
+		for inner := 0; inner < stlInnerLoops; inner++ {
+			detrended := make([]float64, n)
+			for i := range series {
+				detrended[i] = series[i] - trend[i]
+			}
+
+			// Сглаживание подрядов: все январи, все феврали и т.д.
+			cycle := make([]float64, n)
+			for phase := 0; phase < period; phase++ {
+				var sub, subWeights []float64
+				for i := phase; i < n; i += period {
+					sub = append(sub, detrended[i])
+					subWeights = append(subWeights, weights[i])
+				}
+				smoothed := loess(sub, subWeights, stlSeasonalSpan)
+				for k, i := 0, phase; i < n; k, i = k+1, i+period {
+					cycle[i] = smoothed[k]
+				}
+			}
+
+			// Низкочастотная составляющая сезонного ряда вычитается, чтобы она не попала в сезонность
+			lowPass := loess(movingAverage(movingAverage(movingAverage(cycle, period), period), 3), ones(n), lowPassSpan)
+			for i := range seasonal {
+				seasonal[i] = cycle[i] - lowPass[i]
+			}
+
+			deseasonalized := make([]float64, n)
+			for i := range series {
+				deseasonalized[i] = series[i] - seasonal[i]
+			}
+			trend = loess(deseasonalized, weights, trendSpan)
+		}
+
+		remainder := make([]float64, n)
+		for i := range series {
+			remainder[i] = series[i] - trend[i] - seasonal[i]
+		}
+		weights = robustnessWeights(remainder)
+	}
+
+	dec := &Decomposition{
+		Period:    period,
+		Trend:     trend,
+		Seasonal:  seasonal,
+		Remainder: make([]float64, n),
+	}
+	for i := range series {
+		dec.Remainder[i] = series[i] - trend[i] - seasonal[i]
+	}
+
+	return dec, nil
+}
+
+// Forecast продлевает тренд линейно по последнему сезону и повторяет последний сезонный цикл
+func (d *Decomposition) Forecast(horizon int) []float64 {
+	n := len(d.Trend)
+	slope := (d.Trend[n-1] - d.Trend[n-d.Period]) / float64(d.Period-1)
+
+	values := make([]float64, horizon)
+	for h := 1; h <= horizon; h++ {
+		season := d.Seasonal[n-d.Period+(h-1)%d.Period]
+		values[h-1] = d.Trend[n-1] + float64(h)*slope + season
+	}
+	return values
+}
+
+// loess — локально-линейная регрессия с трикубическими весами по span ближайшим точкам
+func loess(y, weights []float64, span int) []float64 {
+	n := len(y)
+	result := make([]float64, n)
+	if n == 0 {
+		return result
+	}
+	if span > n {
+		span = n
+	}
+
+	for i := 0; i < n; i++ {
+		lo := i - span/2
+		if lo < 0 {
+			lo = 0
+		}
+		hi := lo + span - 1
+		if hi >= n {
+			hi = n - 1
+			lo = max(0, hi-span+1)
+		}
+
+		maxDist := math.Max(float64(i-lo), float64(hi-i)) + 1
+		var sw, sx, sy, sxx, sxy float64
+		for j := lo; j <= hi; j++ {
+			d := math.Abs(float64(j-i)) / maxDist
+			w := math.Pow(1-d*d*d, 3) * weights[j]
+			x := float64(j - i)
+			sw += w
+			sx += w * x
+			sy += w * y[j]
+			sxx += w * x * x
+			sxy += w * x * y[j]
+		}
+
+		if sw == 0 {
+			result[i] = y[i]
+			continue
+		}
+
+		// Значение локальной прямой в точке x = 0
+		denom := sw*sxx - sx*sx
+		if math.Abs(denom) < 1e-12 {
+			result[i] = sy / sw
+		} else {
+			result[i] = (sxx*sy - sx*sxy) / denom
+		}
+	}
+
+	return result
+}
+
+// movingAverage — центрированное скользящее среднее с укороченным окном на краях
+func movingAverage(y []float64, window int) []float64 {
+	n := len(y)
+	result := make([]float64, n)
+	for i := range y {
+		lo := max(0, i-window/2)
+		hi := min(n-1, lo+window-1)
+		var sum float64
+		for j := lo; j <= hi; j++ {
+			sum += y[j]
+		}
+		result[i] = sum / float64(hi-lo+1)
+	}
+	return result
+}
+
+// robustnessWeights вычисляет бисквадратные веса по остаткам (h = 6 * медиана |остатка|)
+func robustnessWeights(remainder []float64) []float64 {
+	abs := make([]float64, len(remainder))
+	for i, r := range remainder {
+		abs[i] = math.Abs(r)
+	}
+	sorted := append([]float64(nil), abs...)
+	sort.Float64s(sorted)
+	h := 6 * median(sorted)
+
+	weights := make([]float64, len(remainder))
+	for i, a := range abs {
+		if h == 0 {
+			weights[i] = 1
+			continue
+		}
+		u := a / h
+		if u < 1 {
+			weights[i] = (1 - u*u) * (1 - u*u)
+		}
+	}
+	return weights
+}
+
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+func oddCeil(x float64) int {
+	n := int(math.Ceil(x))
+	if n%2 == 0 {
+		n++
+	}
+	return n
+}
+
+func ones(n int) []float64 {
+	result := make([]float64, n)
+	for i := range result {
+		result[i] = 1
+	}
+	return result
+}
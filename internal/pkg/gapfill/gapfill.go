@@ -0,0 +1,102 @@
+// Package gapfill выравнивает временной ряд по сетке интервалов и заполняет пропуски
+package gapfill
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Mode определяет способ заполнения пропущенных интервалов
+type Mode string
+
+const (
+	ModeNone     Mode = ""         // Без выравнивания: только интервалы с данными
+	ModeNull     Mode = "null"     // Пропуск остается пустым значением
+	ModePrevious Mode = "previous" // Последнее известное значение
+	ModeLinear   Mode = "linear"   // Линейная интерполяция между соседними значениями
+	ModeZero     Mode = "zero"     // Ноль
+)
+
+// ParseMode проверяет значение параметра fill
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(s))); m {
+	case ModeNone, ModeNull, ModePrevious, ModeLinear, ModeZero:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown fill mode %q: use null, previous, linear or zero", s)
+}
+
+// Point — значение ряда в узле сетки. Value равен nil, если интервал заполнить нечем
+// (режим null, а также края ряда в режимах previous и linear).
+type Point struct {
+	Timestamp time.Time
+	Value     *float64
+	Synthetic bool // Значение не измерено, а получено заполнением
+}
+
+// Fill выравнивает значения по сетке grid (отсортированной по возрастанию).
+// Значения вне сетки отбрасываются; узлы без значения заполняются согласно mode.
+func Fill(grid []time.Time, values map[time.Time]float64, mode Mode) []Point {
+	points := make([]Point, len(grid))
+	known := make([]bool, len(grid))
+
+	for i, t := range grid {
+		points[i].Timestamp = t
+		if v, ok := values[t]; ok {
+			points[i].Value = &v
+			known[i] = true
+		} else {
+			points[i].Synthetic = true
+		}
+	}
+
+	switch mode {
+	case ModeZero:
+		for i := range points {
+			if !known[i] {
+				zero := 0.0
+				points[i].Value = &zero
+			}
+		}
+
+	case ModePrevious:
+		var last *float64
+		for i := range points {
+			if known[i] {
+				last = points[i].Value
+			} else if last != nil {
+				v := *last
+				points[i].Value = &v
+			}
+		}
+
+	case ModeLinear:
+		prev := -1
+		for i := range points {
+			if !known[i] {
+				continue
+			}
+			if prev >= 0 && i-prev > 1 {
+				interpolate(points, prev, i)
+			}
+			prev = i
+		}
+	}
+
+	return points
+}
+
+// interpolate заполняет узлы строго между from и to пропорционально времени,
+// так что неравные интервалы (месяцы разной длины) учитываются корректно
+func interpolate(points []Point, from, to int) {
+	t0, t1 := points[from].Timestamp, points[to].Timestamp
+	v0, v1 := *points[from].Value, *points[to].Value
+	span := t1.Sub(t0).Seconds()
+
+	for i := from + 1; i < to; i++ {
+		frac := points[i].Timestamp.Sub(t0).Seconds() / span
+		v := v0 + (v1-v0)*frac
+		points[i].Value = &v
+	}
+}
@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Основы названий месяцев в родительном, именительном и сокращенном виде
+var russianMonths = []struct {
+	prefix string
+	number string
+}{
+	{"янв", "01"}, {"фев", "02"}, {"мар", "03"}, {"апр", "04"},
+	{"май", "05"}, {"мая", "05"}, {"июн", "06"}, {"июл", "07"},
+	{"авг", "08"}, {"сен", "09"}, {"окт", "10"}, {"ноя", "11"}, {"дек", "12"},
+}
+
+// replaceMonthNames заменяет русские названия месяцев номерами: "Январь 2020" -> "01 2020",
+// "15 марта 2021 г." -> "15 03 2021", "янв.21" -> "01 21"
+func replaceMonthNames(s string) string {
+	var b strings.Builder
+	for i, word := range strings.Fields(strings.ToLower(s)) {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(replaceMonthWord(word))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func replaceMonthWord(word string) string {
+	// Слово может содержать точку сокращения и год: "янв.21"
+	letters := strings.IndexFunc(word, func(r rune) bool { return !unicode.IsLetter(r) })
+	head, tail := word, ""
+	if letters >= 0 {
+		head, tail = word[:letters], word[letters:]
+	}
+	if head == "г" || head == "года" {
+		return strings.Trim(tail, ".")
+	}
+
+	for _, m := range russianMonths {
+		if strings.HasPrefix(head, m.prefix) {
+			tail = strings.TrimPrefix(tail, ".")
+			if tail == "" {
+				return m.number
+			}
+			return m.number + " " + tail
+		}
+	}
+	return word
+}
+
+// Диапазон серийных номеров дат Excel, которые принимаются за даты (1900-01-01 .. 9999-12-31)
+const (
+	excelMinSerial = 1
+	excelMaxSerial = 2958465
+)
+
+// parseDate разбирает дату по форматам профиля; даты XLSX приходят серийными номерами Excel
+func parseDate(s string, formats []string, loc *time.Location, date1904 bool) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	candidates := []string{s}
+	if normalized := replaceMonthNames(s); normalized != s {
+		candidates = append(candidates, normalized)
+	}
+
+	// Сначала форматы профиля, затем они же с двузначным годом: "янв.21", "15.03.21"
+	layouts := append([]string{}, formats...)
+	for _, layout := range formats {
+		if strings.Contains(layout, "2006") {
+			layouts = append(layouts, strings.ReplaceAll(layout, "2006", "06"))
+		}
+	}
+
+	for _, c := range candidates {
+		for _, layout := range layouts {
+			if t, err := time.ParseInLocation(layout, c, loc); err == nil {
+				return t, nil
+			}
+		}
+	}
+
+	if serial, err := strconv.ParseFloat(s, 64); err == nil && serial >= excelMinSerial && serial <= excelMaxSerial {
+		return excelDate(serial, date1904, loc), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// excelDate переводит серийный номер Excel в дату. В системе 1900 Excel считает 1900 год
+// високосным, поэтому отсчет для дат после февраля 1900 идет от 1899-12-30.
+func excelDate(serial float64, date1904 bool, loc *time.Location) time.Time {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, loc)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, loc)
+	}
+
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 86400)
+	return epoch.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+}
@@ -0,0 +1,303 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"petrochemical-data-platform/internal/pkg/parser"
+	"petrochemical-data-platform/internal/pkg/quality"
+)
+
+// Issue — ошибка в строке или ячейке таблицы
+type Issue struct {
+	Row     int
+	Column  string
+	Message string
+}
+
+func (i Issue) String() string {
+	if i.Column == "" {
+		return fmt.Sprintf("row %d: %s", i.Row, i.Message)
+	}
+	return fmt.Sprintf("row %d, column %q: %s", i.Row, i.Column, i.Message)
+}
+
+// Importer преобразует строки таблицы в точки по профилю
+type Importer struct {
+	profile  *Profile
+	sheet    *Sheet
+	loc      *time.Location
+	columns  map[string]int // Нормализованный заголовок -> номер столбца
+	dataFrom int            // Индекс первой строки данных в sheet.Rows
+	seen     map[pointKey]int
+}
+
+// pointKey — ключ точки в ClickHouse: точки с одинаковым ключом заменяют друг друга
+type pointKey struct {
+	companyID, productName string
+	timestamp              int64
+}
+
+// New находит строку заголовка и проверяет, что все столбцы профиля есть в таблице
+func New(p *Profile, sheet *Sheet) (*Importer, error) {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	im := &Importer{profile: p, sheet: sheet, loc: loc, dataFrom: -1, seen: make(map[pointKey]int)}
+	for i, row := range sheet.Rows {
+		if row.Number == p.HeaderRow {
+			im.columns = make(map[string]int, len(row.Cells))
+			for col, name := range row.Cells {
+				if key := normalizeHeader(name); key != "" {
+					if _, dup := im.columns[key]; !dup {
+						im.columns[key] = col
+					}
+				}
+			}
+			im.dataFrom = i + 1
+			break
+		}
+	}
+	if im.columns == nil {
+		return nil, fmt.Errorf("header row %d not found", p.HeaderRow)
+	}
+
+	var missing []string
+	for _, name := range p.columnNames() {
+		if _, ok := im.columns[normalizeHeader(name)]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("columns not found in header row %d: %s", p.HeaderRow, strings.Join(missing, ", "))
+	}
+
+	return im, nil
+}
+
+// Rows возвращает строки данных (после заголовка)
+func (im *Importer) Rows() []Row {
+	return im.sheet.Rows[im.dataFrom:]
+}
+
+// Convert преобразует строку в точки. Строки без значений (пустые, примечания, итоги
+// без даты) пропускаются без ошибок; пустые ячейки значений означают отсутствие данных.
+// Строки должны передаваться по порядку: повтор точки из предыдущей строки — ошибка.
+func (im *Importer) Convert(row Row) ([]parser.DataPoint, []Issue) {
+	p := im.profile
+
+	hasValues := false
+	for _, v := range p.Values {
+		if !im.empty(im.cell(row, v.Column)) {
+			hasValues = true
+			break
+		}
+	}
+	if !hasValues {
+		return nil, nil
+	}
+
+	var issues []Issue
+	issue := func(column, format string, args ...interface{}) {
+		issues = append(issues, Issue{Row: row.Number, Column: column, Message: fmt.Sprintf(format, args...)})
+	}
+
+	ts, err := parseDate(im.cell(row, p.Timestamp.Column), p.DateFormats, im.loc, im.sheet.Date1904)
+	if err != nil {
+		issue(p.Timestamp.Column, "%v", err)
+	}
+	companyID := im.value(row, p.CompanyID)
+	if companyID == "" {
+		issue(p.CompanyID.Column, "company_id is empty")
+	}
+	if len(issues) > 0 {
+		return nil, issues
+	}
+
+	var points []parser.DataPoint
+	for _, v := range p.Values {
+		raw := im.cell(row, v.Column)
+		if im.empty(raw) {
+			continue
+		}
+
+		value, err := parser.ParseNumber(raw, p.Decimal)
+		if err != nil {
+			issue(v.Column, "%v", err)
+			continue
+		}
+		if v.Scale != 0 {
+			value *= v.Scale
+		}
+
+		product := im.value(row, v.ProductName)
+		if product == "" {
+			issue(v.ProductName.Column, "product_name is empty")
+			continue
+		}
+
+		key := pointKey{companyID: companyID, productName: product, timestamp: ts.UnixMilli()}
+		if prev, dup := im.seen[key]; dup {
+			issue(v.Column, "duplicate point %s/%s at %s (first seen in row %d)",
+				companyID, product, ts.Format(time.DateTime), prev)
+			continue
+		}
+		im.seen[key] = row.Number
+
+		point := parser.DataPoint{
+			CompanyID:   companyID,
+			ProductName: product,
+			Value:       value,
+			Unit:        im.value(row, v.Unit),
+			Timestamp:   ts.UTC(),
+			Quality:     uint16(quality.Good),
+			Tags:        append(slices.Clone(p.Tags), v.Tags...),
+		}
+		if len(p.Labels)+len(v.Labels) > 0 {
+			point.Labels = maps.Clone(p.Labels)
+			if point.Labels == nil {
+				point.Labels = make(map[string]string, len(v.Labels))
+			}
+			maps.Copy(point.Labels, v.Labels)
+		}
+		points = append(points, point)
+	}
+
+	return points, issues
+}
+
+func (im *Importer) cell(row Row, column string) string {
+	col, ok := im.columns[normalizeHeader(column)]
+	if !ok || col >= len(row.Cells) {
+		return ""
+	}
+	return strings.TrimSpace(row.Cells[col])
+}
+
+func (im *Importer) value(row Row, s Source) string {
+	if s.Column != "" {
+		return im.cell(row, s.Column)
+	}
+	return s.Value
+}
+
+func (im *Importer) empty(s string) bool {
+	return s == "" || slices.ContainsFunc(im.profile.EmptyValues, func(e string) bool { return strings.EqualFold(e, s) })
+}
+
+// columnNames возвращает заголовки всех столбцов, на которые ссылается профиль
+func (p *Profile) columnNames() []string {
+	names := []string{p.Timestamp.Column, p.CompanyID.Column}
+	for _, v := range p.Values {
+		names = append(names, v.Column, v.ProductName.Column, v.Unit.Column)
+	}
+	return slices.DeleteFunc(names, func(s string) bool { return s == "" })
+}
+
+// normalizeHeader сравнивает заголовки без учета регистра и переносов строк
+func normalizeHeader(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// SeriesSummary — сводка по ряду в отчете импорта
+type SeriesSummary struct {
+	CompanyID   string
+	ProductName string
+	Unit        string
+	First, Last time.Time
+	Points      int
+}
+
+// Report — итог проверки или импорта
+type Report struct {
+	Profile string
+	File    string
+	Rows    int // Строк данных с значениями
+	Points  int
+	Issues  []Issue
+	series  map[[2]string]*SeriesSummary
+	loc     *time.Location
+}
+
+// NewReport создает пустой отчет импорта файла по профилю
+func (im *Importer) NewReport(file string) *Report {
+	return &Report{Profile: im.profile.Name, File: file, series: make(map[[2]string]*SeriesSummary), loc: im.loc}
+}
+
+// Add учитывает результат преобразования строки. Строка с ошибками не загружается
+// целиком, поэтому ее точки в отчет не попадают.
+func (r *Report) Add(points []parser.DataPoint, issues []Issue) {
+	if len(points) == 0 && len(issues) == 0 {
+		return
+	}
+	r.Rows++
+	if len(issues) > 0 {
+		r.Issues = append(r.Issues, issues...)
+		return
+	}
+	r.Points += len(points)
+
+	for _, p := range points {
+		key := [2]string{p.CompanyID, p.ProductName}
+		s, ok := r.series[key]
+		if !ok {
+			s = &SeriesSummary{CompanyID: p.CompanyID, ProductName: p.ProductName, Unit: p.Unit, First: p.Timestamp, Last: p.Timestamp}
+			r.series[key] = s
+		}
+		s.Points++
+		if p.Timestamp.Before(s.First) {
+			s.First = p.Timestamp
+		}
+		if p.Timestamp.After(s.Last) {
+			s.Last = p.Timestamp
+		}
+	}
+}
+
+// Series возвращает сводки рядов, упорядоченные по компании и продукту
+func (r *Report) Series() []SeriesSummary {
+	series := make([]SeriesSummary, 0, len(r.series))
+	for _, s := range r.series {
+		series = append(series, *s)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].CompanyID != series[j].CompanyID {
+			return series[i].CompanyID < series[j].CompanyID
+		}
+		return series[i].ProductName < series[j].ProductName
+	})
+	return series
+}
+
+// Print выводит отчет в текстовом виде; выводится не более maxIssues ошибок
+func (r *Report) Print(w io.Writer, maxIssues int) {
+	fmt.Fprintf(w, "Profile: %s\nFile:    %s\nRows:    %d\nPoints:  %d\nIssues:  %d\n\n", r.Profile, r.File, r.Rows, r.Points, len(r.Issues))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMPANY\tPRODUCT\tUNIT\tFIRST\tLAST\tPOINTS")
+	for _, s := range r.Series() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\n", s.CompanyID, s.ProductName, s.Unit,
+			s.First.In(r.loc).Format(time.DateOnly), s.Last.In(r.loc).Format(time.DateOnly), s.Points)
+	}
+	tw.Flush()
+
+	if len(r.Issues) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	for i, issue := range r.Issues {
+		if i == maxIssues {
+			fmt.Fprintf(w, "... and %d more\n", len(r.Issues)-maxIssues)
+			break
+		}
+		fmt.Fprintln(w, issue)
+	}
+}
@@ -0,0 +1,157 @@
+// Package importer загружает исторические данные из CSV и XLSX (отраслевые отчеты,
+// выгрузки из таблиц) по профилям сопоставления столбцов
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Source задает значение поля точки: столбец таблицы или постоянное значение
+type Source struct {
+	Column string `mapstructure:"column"` // Заголовок столбца
+	Value  string `mapstructure:"value"`  // Постоянное значение, если столбец не задан
+}
+
+func (s Source) isSet() bool {
+	return s.Column != "" || s.Value != ""
+}
+
+// ValueMapping описывает столбец со значениями. Для «широких» таблиц (продукт в заголовке)
+// задается по одному сопоставлению на столбец с постоянным product_name, для «длинных»
+// (продукт в отдельном столбце) — одно сопоставление с product_name из столбца.
+type ValueMapping struct {
+	Column      string            `mapstructure:"column"`
+	ProductName Source            `mapstructure:"product_name"`
+	Unit        Source            `mapstructure:"unit"`
+	Scale       float64           `mapstructure:"scale"` // Множитель значения (тыс. т -> т); 0 — без изменения
+	Tags        []string          `mapstructure:"tags"`
+	Labels      map[string]string `mapstructure:"labels"`
+}
+
+// Profile — профиль импорта одного вида отчета
+type Profile struct {
+	Name        string            `mapstructure:"name"`
+	Format      string            `mapstructure:"format"`       // csv или xlsx; пусто — по расширению файла
+	Sheet       string            `mapstructure:"sheet"`        // Лист XLSX; пусто — первый
+	HeaderRow   int               `mapstructure:"header_row"`   // Номер строки заголовка с единицы
+	Delimiter   string            `mapstructure:"delimiter"`    // Разделитель CSV, по умолчанию ";"
+	Encoding    string            `mapstructure:"encoding"`     // utf-8 или windows-1251
+	Decimal     string            `mapstructure:"decimal"`      // Десятичный разделитель, по умолчанию ","
+	DateFormats []string          `mapstructure:"date_formats"` // Форматы дат Go в порядке проверки
+	Timezone    string            `mapstructure:"timezone"`     // Часовой пояс дат без смещения
+	EmptyValues []string          `mapstructure:"empty_values"` // Значения, означающие отсутствие данных
+	Timestamp   Source            `mapstructure:"timestamp"`
+	CompanyID   Source            `mapstructure:"company_id"`
+	Values      []ValueMapping    `mapstructure:"values"`
+	Tags        []string          `mapstructure:"tags"`   // Теги всех точек
+	Labels      map[string]string `mapstructure:"labels"` // Метки всех точек
+}
+
+// Форматы дат русскоязычных отчетов по умолчанию. Названия месяцев
+// («январь 2020», «янв.20») предварительно заменяются номерами.
+var defaultDateFormats = []string{
+	"02.01.2006",
+	"02.01.2006 15:04",
+	"02.01.2006 15:04:05",
+	"01.2006",
+	"01 2006",
+	"02 01 2006",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+	time.RFC3339,
+}
+
+var defaultEmptyValues = []string{"-", "–", "—", "н/д", "нд", "х", "x", "n/a"}
+
+// LoadProfile читает профиль из YAML-файла и заполняет значения по умолчанию
+func LoadProfile(path string) (*Profile, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read profile: %w", err)
+	}
+
+	var p Profile
+	if err := v.Unmarshal(&p); err != nil {
+		return nil, fmt.Errorf("failed to parse profile: %w", err)
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	return &p, p.validate()
+}
+
+func (p *Profile) validate() error {
+	if p.HeaderRow <= 0 {
+		p.HeaderRow = 1
+	}
+	if p.Delimiter == "" {
+		p.Delimiter = ";"
+	}
+	if p.Decimal == "" {
+		p.Decimal = ","
+	}
+	if len(p.DateFormats) == 0 {
+		p.DateFormats = defaultDateFormats
+	}
+	if p.Timezone == "" {
+		p.Timezone = "Europe/Moscow"
+	}
+	if p.EmptyValues == nil {
+		p.EmptyValues = defaultEmptyValues
+	}
+
+	switch {
+	case p.Decimal != "," && p.Decimal != ".":
+		return fmt.Errorf("profile %s: decimal must be \",\" or \".\"", p.Name)
+	case len([]rune(p.Delimiter)) != 1:
+		return fmt.Errorf("profile %s: delimiter must be a single character", p.Name)
+	case p.Timestamp.Column == "":
+		return fmt.Errorf("profile %s: timestamp.column is required", p.Name)
+	case !p.CompanyID.isSet():
+		return fmt.Errorf("profile %s: company_id is required", p.Name)
+	case len(p.Values) == 0:
+		return fmt.Errorf("profile %s: at least one value column is required", p.Name)
+	}
+
+	for i, v := range p.Values {
+		if v.Column == "" {
+			return fmt.Errorf("profile %s: values[%d].column is required", p.Name, i)
+		}
+		if !v.ProductName.isSet() {
+			return fmt.Errorf("profile %s: values[%d].product_name is required", p.Name, i)
+		}
+	}
+
+	if _, err := time.LoadLocation(p.Timezone); err != nil {
+		return fmt.Errorf("profile %s: %w", p.Name, err)
+	}
+	if _, err := p.format(""); err != nil && p.Format != "" {
+		return fmt.Errorf("profile %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+// format возвращает формат файла из профиля или по расширению
+func (p *Profile) format(path string) (string, error) {
+	format := strings.ToLower(p.Format)
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	switch format {
+	case "csv", "xlsx":
+		return format, nil
+	case "":
+		return "", errors.New("file format is not set")
+	}
+	return "", fmt.Errorf("unsupported file format %q: use csv or xlsx", format)
+}
@@ -0,0 +1,257 @@
+package importer
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Row — строка таблицы с номером строки файла (с единицы)
+type Row struct {
+	Number int
+	Cells  []string
+}
+
+// Sheet — прочитанная таблица
+type Sheet struct {
+	Rows     []Row
+	Date1904 bool // Даты XLSX отсчитываются от 1904 года (книги Excel для Mac)
+}
+
+// ReadFile читает CSV или XLSX согласно профилю
+func ReadFile(filename string, p *Profile) (*Sheet, error) {
+	format, err := p.format(filename)
+	if err != nil {
+		return nil, err
+	}
+	if format == "xlsx" {
+		return readXLSX(filename, p.Sheet)
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+	return readCSV(f, p)
+}
+
+func readCSV(r io.Reader, p *Profile) (*Sheet, error) {
+	switch strings.ToLower(p.Encoding) {
+	case "", "utf-8", "utf8":
+		br := bufio.NewReader(r)
+		if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+			br.Discard(3)
+		}
+		r = br
+	case "windows-1251", "cp1251":
+		r = charmap.Windows1251.NewDecoder().Reader(r)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q: use utf-8 or windows-1251", p.Encoding)
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = []rune(p.Delimiter)[0]
+	reader.FieldsPerRecord = -1 // Отчеты часто содержат строки примечаний разной ширины
+	reader.LazyQuotes = true
+
+	sheet := &Sheet{}
+	for {
+		cells, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		sheet.Rows = append(sheet.Rows, Row{Number: line, Cells: cells})
+	}
+	return sheet, nil
+}
+
+// Минимальное чтение XLSX (Office Open XML): книга, связи листов, общие строки и ячейки листа.
+// Формулы не вычисляются: используется сохраненное в файле значение.
+
+type xlsxWorkbook struct {
+	Properties struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.Text)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Number int `xml:"r,attr"`
+		Cells  []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSX(filename, sheetName string) (*Sheet, error) {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open XLSX: %w", err)
+	}
+	defer zr.Close()
+
+	var workbook xlsxWorkbook
+	if err := readXML(&zr.Reader, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	var rels xlsxRelationships
+	if err := readXML(&zr.Reader, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	var shared xlsxSharedStrings
+	if err := readXML(&zr.Reader, "xl/sharedStrings.xml", &shared); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if len(workbook.Sheets) == 0 {
+		return nil, errors.New("XLSX workbook has no sheets")
+	}
+	rid := workbook.Sheets[0].RID
+	if sheetName != "" {
+		rid = ""
+		for _, s := range workbook.Sheets {
+			if s.Name == sheetName {
+				rid = s.RID
+			}
+		}
+		if rid == "" {
+			return nil, fmt.Errorf("sheet %q not found", sheetName)
+		}
+	}
+
+	var target string
+	for _, r := range rels.Relationships {
+		if r.ID == rid {
+			target = r.Target
+		}
+	}
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimPrefix(target, "/")
+	} else {
+		target = path.Join("xl", target)
+	}
+
+	var ws xlsxWorksheet
+	if err := readXML(&zr.Reader, target, &ws); err != nil {
+		return nil, err
+	}
+
+	sheet := &Sheet{Date1904: workbook.Properties.Date1904}
+	for i, r := range ws.Rows {
+		row := Row{Number: r.Number}
+		if row.Number == 0 {
+			row.Number = i + 1
+		}
+
+		for j, c := range r.Cells {
+			col := j
+			if c.Ref != "" {
+				if col, err = columnIndex(c.Ref); err != nil {
+					return nil, err
+				}
+			}
+			for len(row.Cells) <= col {
+				row.Cells = append(row.Cells, "")
+			}
+
+			value := c.Value
+			switch c.Type {
+			case "s":
+				idx, err := strconv.Atoi(c.Value)
+				if err != nil || idx < 0 || idx >= len(shared.Items) {
+					return nil, fmt.Errorf("cell %s: invalid shared string index %q", c.Ref, c.Value)
+				}
+				value = shared.Items[idx].String()
+			case "inlineStr":
+				value = c.Inline.String()
+			case "b":
+				value = map[string]string{"1": "TRUE", "0": "FALSE"}[c.Value]
+			}
+			row.Cells[col] = value
+		}
+		sheet.Rows = append(sheet.Rows, row)
+	}
+	return sheet, nil
+}
+
+func readXML(zr *zip.Reader, name string, v interface{}) error {
+	f, err := zr.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer f.Close()
+
+	if err := xml.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// columnIndex переводит ссылку на ячейку ("AB12") в номер столбца с нуля
+func columnIndex(ref string) (int, error) {
+	col := 0
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return col - 1, nil
+}
@@ -0,0 +1,168 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"petrochemical-data-platform/internal/domain"
+)
+
+// Method определяет методику расчета индекса
+type Method string
+
+const (
+	MethodFixedBase Method = "fixed" // Отношение цен к базовому периоду
+	MethodChain     Method = "chain" // Цепной индекс: произведение период-к-периоду
+)
+
+// Component — позиция корзины индекса
+type Component struct {
+	CompanyID   string
+	ProductName string
+	Weight      float64
+}
+
+// Definition описывает индекс и его корзину
+type Definition struct {
+	ID         string
+	Name       string
+	Sector     string // polymers, fuels, fertilizers; пусто для общего индекса
+	Method     Method
+	BaseDate   time.Time
+	BaseValue  float64
+	Components []Component
+}
+
+// Validate проверяет корректность определения индекса
+func (d Definition) Validate() error {
+	if d.ID == "" {
+		return fmt.Errorf("index id is required")
+	}
+	if d.Method != MethodFixedBase && d.Method != MethodChain {
+		return fmt.Errorf("index %s: unknown method %q", d.ID, d.Method)
+	}
+	if d.BaseValue <= 0 {
+		return fmt.Errorf("index %s: base value must be positive", d.ID)
+	}
+	if len(d.Components) == 0 {
+		return fmt.Errorf("index %s: basket is empty", d.ID)
+	}
+	for _, c := range d.Components {
+		if c.Weight <= 0 {
+			return fmt.Errorf("index %s: weight of %s/%s must be positive", d.ID, c.CompanyID, c.ProductName)
+		}
+	}
+	return nil
+}
+
+// Prices содержит цены позиций корзины по периодам; индекс среза совпадает с индексом Components
+type Prices []map[time.Time]float64
+
+// Compute рассчитывает значения индекса по периодам. Базовый период — первый период
+// не раньше BaseDate, в котором есть цены хотя бы одной позиции. Позиции без цены в
+// периоде исключаются, а веса остальных перенормируются.
+func Compute(def Definition, prices Prices) ([]domain.SeriesPoint, error) {
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	if len(prices) != len(def.Components) {
+		return nil, fmt.Errorf("index %s: expected prices for %d components, got %d", def.ID, len(def.Components), len(prices))
+	}
+
+	periods := unionPeriods(prices)
+	base := sort.Search(len(periods), func(i int) bool { return !periods[i].Before(def.BaseDate) })
+	if base == len(periods) {
+		return nil, fmt.Errorf("index %s: no prices on or after base date %s", def.ID, def.BaseDate.Format("2006-01-02"))
+	}
+
+	values := make([]float64, len(periods))
+	valid := make([]bool, len(periods))
+	values[base], valid[base] = def.BaseValue, true
+
+	switch def.Method {
+	case MethodFixedBase:
+		for t := range periods {
+			if relative, ok := relativeChange(def.Components, prices, periods[base], periods[t]); ok {
+				values[t], valid[t] = def.BaseValue*relative, true
+			}
+		}
+	case MethodChain:
+		// Вперед от базового периода
+		for t, prev := base+1, base; t < len(periods); t++ {
+			if relative, ok := relativeChange(def.Components, prices, periods[prev], periods[t]); ok {
+				values[t], valid[t] = values[prev]*relative, true
+				prev = t
+			}
+		}
+		// Назад от базового периода
+		for t, next := base-1, base; t >= 0; t-- {
+			if relative, ok := relativeChange(def.Components, prices, periods[t], periods[next]); ok && relative != 0 {
+				values[t], valid[t] = values[next]/relative, true
+				next = t
+			}
+		}
+	}
+
+	var points []domain.SeriesPoint
+	for t, p := range periods {
+		if valid[t] {
+			points = append(points, domain.SeriesPoint{Timestamp: p, Value: values[t]})
+		}
+	}
+	return points, nil
+}
+
+// ErrNoRebaseValue возвращается, если для перебазирования нет ненулевого значения индекса
+var ErrNoRebaseValue = errors.New("no index value to rebase to")
+
+// Rebase пересчитывает ряд так, чтобы значение base (точка индекса в периоде
+// перебазирования, не обязательно входящая в points) стало равно value
+func Rebase(points []domain.SeriesPoint, base domain.SeriesPoint, value float64) ([]domain.SeriesPoint, error) {
+	if base.Value == 0 {
+		return nil, fmt.Errorf("%w: index is zero on %s", ErrNoRebaseValue, base.Timestamp.Format("2006-01-02"))
+	}
+
+	factor := value / base.Value
+	result := make([]domain.SeriesPoint, len(points))
+	for j, p := range points {
+		result[j] = domain.SeriesPoint{Timestamp: p.Timestamp, Value: p.Value * factor}
+	}
+	return result, nil
+}
+
+// relativeChange возвращает взвешенное среднее отношений цен to/from по позициям,
+// у которых есть цены в обоих периодах
+func relativeChange(components []Component, prices Prices, from, to time.Time) (float64, bool) {
+	var weighted, weights float64
+	for i, c := range components {
+		p0, ok0 := prices[i][from]
+		p1, ok1 := prices[i][to]
+		if !ok0 || !ok1 || p0 == 0 {
+			continue
+		}
+		weighted += c.Weight * p1 / p0
+		weights += c.Weight
+	}
+
+	if weights == 0 {
+		return 0, false
+	}
+	return weighted / weights, true
+}
+
+func unionPeriods(prices Prices) []time.Time {
+	seen := make(map[time.Time]bool)
+	var periods []time.Time
+	for _, series := range prices {
+		for t := range series {
+			if !seen[t] {
+				seen[t] = true
+				periods = append(periods, t)
+			}
+		}
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].Before(periods[j]) })
+	return periods
+}
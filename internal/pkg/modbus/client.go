@@ -0,0 +1,227 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Client — клиент Modbus TCP одного устройства. Запросы выполняются последовательно;
+// после сетевой ошибки соединение закрывается и восстанавливается при следующем запросе.
+type Client struct {
+	addr    string
+	unitID  byte
+	timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	tid  uint16
+}
+
+// NewClient создает клиент устройства addr (host:port) с идентификатором unitID.
+// Соединение устанавливается при первом запросе.
+func NewClient(addr string, unitID byte, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &Client{addr: addr, unitID: unitID, timeout: timeout}
+}
+
+// Close закрывает соединение
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeLocked()
+}
+
+func (c *Client) closeLocked() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// ReadRegisters читает qty регистров области holding или input
+func (c *Client) ReadRegisters(ctx context.Context, t RegisterType, address, qty uint16) ([]uint16, error) {
+	if t.Bit() {
+		return nil, fmt.Errorf("register type %s is not a register area", t)
+	}
+	if qty == 0 || qty > MaxReadRegisters {
+		return nil, fmt.Errorf("register quantity must be between 1 and %d", MaxReadRegisters)
+	}
+
+	resp, err := c.do(ctx, t.readFunction(), readRequest(address, qty))
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) != 1+2*int(qty) || int(resp[0]) != 2*int(qty) {
+		return nil, fmt.Errorf("%w: unexpected byte count in read response", ErrProtocol)
+	}
+	return bytesToRegisters(resp[1:]), nil
+}
+
+// ReadBits читает qty битов области coil или discrete
+func (c *Client) ReadBits(ctx context.Context, t RegisterType, address, qty uint16) ([]bool, error) {
+	if !t.Bit() {
+		return nil, fmt.Errorf("register type %s is not a bit area", t)
+	}
+	if qty == 0 || qty > MaxReadBits {
+		return nil, fmt.Errorf("bit quantity must be between 1 and %d", MaxReadBits)
+	}
+
+	resp, err := c.do(ctx, t.readFunction(), readRequest(address, qty))
+	if err != nil {
+		return nil, err
+	}
+	n := (int(qty) + 7) / 8
+	if len(resp) != 1+n || int(resp[0]) != n {
+		return nil, fmt.Errorf("%w: unexpected byte count in read response", ErrProtocol)
+	}
+	return bytesToBits(resp[1:], int(qty)), nil
+}
+
+// WriteRegisters записывает регистры holding: одиночный регистр функцией 0x06, несколько — 0x10
+func (c *Client) WriteRegisters(ctx context.Context, address uint16, values []uint16) error {
+	if len(values) == 0 || len(values) > MaxWriteRegisters {
+		return fmt.Errorf("register quantity must be between 1 and %d", MaxWriteRegisters)
+	}
+
+	if len(values) == 1 {
+		req := make([]byte, 4)
+		binary.BigEndian.PutUint16(req, address)
+		binary.BigEndian.PutUint16(req[2:], values[0])
+		resp, err := c.do(ctx, FuncWriteSingleRegister, req)
+		if err != nil {
+			return err
+		}
+		if string(resp) != string(req) {
+			return fmt.Errorf("%w: write response does not echo the request", ErrProtocol)
+		}
+		return nil
+	}
+
+	req := make([]byte, 5, 5+2*len(values))
+	binary.BigEndian.PutUint16(req, address)
+	binary.BigEndian.PutUint16(req[2:], uint16(len(values)))
+	req[4] = byte(2 * len(values))
+	req = append(req, registersToBytes(values)...)
+
+	resp, err := c.do(ctx, FuncWriteMultipleRegisters, req)
+	if err != nil {
+		return err
+	}
+	if len(resp) != 4 || string(resp) != string(req[:4]) {
+		return fmt.Errorf("%w: write response does not echo the request", ErrProtocol)
+	}
+	return nil
+}
+
+// WriteCoil записывает бит области coil
+func (c *Client) WriteCoil(ctx context.Context, address uint16, value bool) error {
+	req := make([]byte, 4)
+	binary.BigEndian.PutUint16(req, address)
+	if value {
+		binary.BigEndian.PutUint16(req[2:], 0xFF00)
+	}
+
+	resp, err := c.do(ctx, FuncWriteSingleCoil, req)
+	if err != nil {
+		return err
+	}
+	if string(resp) != string(req) {
+		return fmt.Errorf("%w: write response does not echo the request", ErrProtocol)
+	}
+	return nil
+}
+
+func readRequest(address, qty uint16) []byte {
+	req := make([]byte, 4)
+	binary.BigEndian.PutUint16(req, address)
+	binary.BigEndian.PutUint16(req[2:], qty)
+	return req
+}
+
+// do отправляет запрос и возвращает данные ответа без кода функции
+func (c *Client) do(ctx context.Context, function byte, data []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		dialer := net.Dialer{Timeout: c.timeout}
+		conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", c.addr, err)
+		}
+		c.conn = conn
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+
+	c.tid++
+	frame := make([]byte, mbapHeaderLen+1, mbapHeaderLen+1+len(data))
+	binary.BigEndian.PutUint16(frame, c.tid)
+	binary.BigEndian.PutUint16(frame[4:], uint16(2+len(data)))
+	frame[6] = c.unitID
+	frame[7] = function
+	frame = append(frame, data...)
+
+	if _, err := c.conn.Write(frame); err != nil {
+		c.closeLocked()
+		return nil, fmt.Errorf("failed to send request to %s: %w", c.addr, err)
+	}
+
+	for {
+		header, pdu, err := readFrame(c.conn)
+		if err != nil {
+			c.closeLocked()
+			return nil, fmt.Errorf("failed to read response from %s: %w", c.addr, err)
+		}
+		if binary.BigEndian.Uint16(header) != c.tid {
+			continue // Опоздавший ответ на запрос, прерванный по таймауту
+		}
+
+		switch {
+		case len(pdu) == 0 || pdu[0]&0x7F != function:
+			c.closeLocked()
+			return nil, fmt.Errorf("%w: response function does not match the request", ErrProtocol)
+		case pdu[0]&0x80 != 0:
+			if len(pdu) != 2 {
+				c.closeLocked()
+				return nil, fmt.Errorf("%w: malformed exception response", ErrProtocol)
+			}
+			return nil, &ExceptionError{Function: function, Code: pdu[1]}
+		}
+		return pdu[1:], nil
+	}
+}
+
+// readFrame читает кадр Modbus TCP и возвращает заголовок MBAP и PDU
+func readFrame(r io.Reader) (header, pdu []byte, err error) {
+	header = make([]byte, mbapHeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, nil, err
+	}
+	if binary.BigEndian.Uint16(header[2:]) != 0 {
+		return nil, nil, fmt.Errorf("%w: unknown protocol id", ErrProtocol)
+	}
+	length := int(binary.BigEndian.Uint16(header[4:]))
+	if length < 2 || length > maxADULen-mbapHeaderLen+1 {
+		return nil, nil, fmt.Errorf("%w: invalid frame length %d", ErrProtocol, length)
+	}
+
+	pdu = make([]byte, length-1)
+	if _, err := io.ReadFull(r, pdu); err != nil {
+		return nil, nil, err
+	}
+	return header, pdu, nil
+}
@@ -0,0 +1,186 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DataType — тип значения, хранящегося в одном или нескольких регистрах
+type DataType string
+
+const (
+	Uint16  DataType = "uint16"
+	Int16   DataType = "int16"
+	Uint32  DataType = "uint32"
+	Int32   DataType = "int32"
+	Float32 DataType = "float32"
+	Uint64  DataType = "uint64"
+	Int64   DataType = "int64"
+	Float64 DataType = "float64"
+	Bool    DataType = "bool" // Бит области coil или discrete
+)
+
+// ParseDataType проверяет название типа; пусто — uint16
+func ParseDataType(s string) (DataType, error) {
+	if s == "" {
+		return Uint16, nil
+	}
+	switch t := DataType(strings.ToLower(s)); t {
+	case Uint16, Int16, Uint32, Int32, Float32, Uint64, Int64, Float64, Bool:
+		return t, nil
+	}
+	return "", fmt.Errorf("unknown data type %q", s)
+}
+
+// Registers возвращает число регистров, занимаемых значением
+func (t DataType) Registers() int {
+	switch t {
+	case Uint32, Int32, Float32:
+		return 2
+	case Uint64, Int64, Float64:
+		return 4
+	}
+	return 1
+}
+
+// ByteOrder — порядок байтов многорегистрового значения. Буквы обозначают байты значения
+// от старшего (A) к младшему в порядке их следования в регистрах: ABCD — big-endian
+// (стандарт Modbus), CDAB — переставлены слова (многие ПЛК), BADC — переставлены байты
+// в словах, DCBA — little-endian. Для 64-битных значений порядок распространяется
+// на четыре регистра.
+type ByteOrder string
+
+const (
+	BigEndian        ByteOrder = "ABCD"
+	WordSwap         ByteOrder = "CDAB"
+	ByteSwap         ByteOrder = "BADC"
+	LittleEndian     ByteOrder = "DCBA"
+	defaultByteOrder           = BigEndian
+)
+
+// ParseByteOrder проверяет порядок байтов; пусто — ABCD
+func ParseByteOrder(s string) (ByteOrder, error) {
+	if s == "" {
+		return defaultByteOrder, nil
+	}
+	switch o := ByteOrder(strings.ToUpper(s)); o {
+	case BigEndian, WordSwap, ByteSwap, LittleEndian:
+		return o, nil
+	}
+	return "", fmt.Errorf("unknown byte order %q: use ABCD, CDAB, BADC or DCBA", s)
+}
+
+// toBigEndian переставляет байты регистров в порядок big-endian
+func (o ByteOrder) toBigEndian(b []byte) []byte {
+	out := make([]byte, len(b))
+	copy(out, b)
+	if o == WordSwap || o == LittleEndian {
+		// Обратный порядок слов
+		for i, j := 0, len(out)-2; i < j; i, j = i+2, j-2 {
+			out[i], out[i+1], out[j], out[j+1] = out[j], out[j+1], out[i], out[i+1]
+		}
+	}
+	if o == ByteSwap || o == LittleEndian {
+		for i := 0; i+1 < len(out); i += 2 {
+			out[i], out[i+1] = out[i+1], out[i]
+		}
+	}
+	return out
+}
+
+// fromBigEndian — обратное преобразование (перестановки симметричны)
+func (o ByteOrder) fromBigEndian(b []byte) []byte {
+	return o.toBigEndian(b)
+}
+
+// Decode переводит регистры в число. Для однорегистровых типов порядок байтов
+// учитывается только перестановкой байтов (BADC, DCBA).
+func Decode(registers []uint16, t DataType, order ByteOrder) (float64, error) {
+	if len(registers) != t.Registers() {
+		return 0, fmt.Errorf("%s needs %d registers, got %d", t, t.Registers(), len(registers))
+	}
+	b := order.toBigEndian(registersToBytes(registers))
+
+	switch t {
+	case Uint16:
+		return float64(binary.BigEndian.Uint16(b)), nil
+	case Int16:
+		return float64(int16(binary.BigEndian.Uint16(b))), nil
+	case Uint32:
+		return float64(binary.BigEndian.Uint32(b)), nil
+	case Int32:
+		return float64(int32(binary.BigEndian.Uint32(b))), nil
+	case Float32:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case Uint64:
+		return float64(binary.BigEndian.Uint64(b)), nil
+	case Int64:
+		return float64(int64(binary.BigEndian.Uint64(b))), nil
+	case Float64:
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	}
+	return 0, fmt.Errorf("data type %s is not stored in registers", t)
+}
+
+// Encode переводит число в регистры. Целые типы округляются; значения вне диапазона типа
+// отклоняются, чтобы уставка не была записана с переполнением.
+func Encode(value float64, t DataType, order ByteOrder) ([]uint16, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, fmt.Errorf("value must be a finite number")
+	}
+
+	b := make([]byte, 2*t.Registers())
+	rounded := math.Round(value)
+	inRange := func(lo, hi float64) error {
+		if rounded < lo || rounded > hi {
+			return fmt.Errorf("value %g is out of range for %s", value, t)
+		}
+		return nil
+	}
+
+	switch t {
+	case Uint16:
+		if err := inRange(0, math.MaxUint16); err != nil {
+			return nil, err
+		}
+		binary.BigEndian.PutUint16(b, uint16(rounded))
+	case Int16:
+		if err := inRange(math.MinInt16, math.MaxInt16); err != nil {
+			return nil, err
+		}
+		binary.BigEndian.PutUint16(b, uint16(int16(rounded)))
+	case Uint32:
+		if err := inRange(0, math.MaxUint32); err != nil {
+			return nil, err
+		}
+		binary.BigEndian.PutUint32(b, uint32(rounded))
+	case Int32:
+		if err := inRange(math.MinInt32, math.MaxInt32); err != nil {
+			return nil, err
+		}
+		binary.BigEndian.PutUint32(b, uint32(int32(rounded)))
+	case Float32:
+		if math.Abs(value) > math.MaxFloat32 {
+			return nil, fmt.Errorf("value %g is out of range for %s", value, t)
+		}
+		binary.BigEndian.PutUint32(b, math.Float32bits(float32(value)))
+	case Uint64:
+		if err := inRange(0, math.MaxUint64); err != nil {
+			return nil, err
+		}
+		binary.BigEndian.PutUint64(b, uint64(rounded))
+	case Int64:
+		if err := inRange(math.MinInt64, math.MaxInt64); err != nil {
+			return nil, err
+		}
+		binary.BigEndian.PutUint64(b, uint64(int64(rounded)))
+	case Float64:
+		binary.BigEndian.PutUint64(b, math.Float64bits(value))
+	default:
+		return nil, fmt.Errorf("data type %s is not stored in registers", t)
+	}
+
+	return bytesToRegisters(order.fromBigEndian(b)), nil
+}
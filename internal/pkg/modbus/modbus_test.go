@@ -0,0 +1,245 @@
+package modbus
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func startServer(t *testing.T, size int) (*Server, *Client) {
+	t.Helper()
+	srv := NewServer(size)
+	if err := srv.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	client := NewClient(srv.Addr(), 1, time.Second)
+	t.Cleanup(func() {
+		client.Close()
+		srv.Close()
+	})
+	return srv, client
+}
+
+func TestCodecByteOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float64
+		typ   DataType
+		order ByteOrder
+		regs  []uint16
+	}{
+		{"uint16 ABCD", 0x0102, Uint16, BigEndian, []uint16{0x0102}},
+		{"int16 BADC", -2, Int16, ByteSwap, []uint16{0xFEFF}},
+		{"uint32 ABCD", 0x01020304, Uint32, BigEndian, []uint16{0x0102, 0x0304}},
+		{"uint32 CDAB", 0x01020304, Uint32, WordSwap, []uint16{0x0304, 0x0102}},
+		{"uint32 BADC", 0x01020304, Uint32, ByteSwap, []uint16{0x0201, 0x0403}},
+		{"uint32 DCBA", 0x01020304, Uint32, LittleEndian, []uint16{0x0403, 0x0201}},
+		{"int32 CDAB", -100000, Int32, WordSwap, []uint16{0x7960, 0xFFFE}},
+		{"float32 ABCD", 1.5, Float32, BigEndian, []uint16{0x3FC0, 0x0000}},
+		{"float32 CDAB", 1.5, Float32, WordSwap, []uint16{0x0000, 0x3FC0}},
+		{"float32 DCBA", 1.5, Float32, LittleEndian, []uint16{0x0000, 0xC03F}},
+		{"uint64 CDAB", 0x0001020304050607, Uint64, WordSwap, []uint16{0x0607, 0x0405, 0x0203, 0x0001}},
+		{"float64 ABCD", 1.5, Float64, BigEndian, []uint16{0x3FF8, 0, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regs, err := Encode(tt.value, tt.typ, tt.order)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if !reflect.DeepEqual(regs, tt.regs) {
+				t.Errorf("Encode = %#04x, want %#04x", regs, tt.regs)
+			}
+			value, err := Decode(tt.regs, tt.typ, tt.order)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if value != tt.value {
+				t.Errorf("Decode = %v, want %v", value, tt.value)
+			}
+		})
+	}
+}
+
+func TestEncodeOutOfRange(t *testing.T) {
+	tests := []struct {
+		value float64
+		typ   DataType
+	}{
+		{-1, Uint16},
+		{65536, Uint16},
+		{40000, Int16},
+		{-1, Uint32},
+		{1e40, Float32},
+	}
+	for _, tt := range tests {
+		if _, err := Encode(tt.value, tt.typ, BigEndian); err == nil {
+			t.Errorf("Encode(%g, %s) succeeded, want an out of range error", tt.value, tt.typ)
+		}
+	}
+}
+
+func TestReadRegisters(t *testing.T) {
+	srv, client := startServer(t, 32)
+	ctx := context.Background()
+
+	if err := srv.SetValue(InputRegister, 4, 87.25, Float32, WordSwap); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.SetValue(HoldingRegister, 10, -1234, Int32, ByteSwap); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.SetBits(DiscreteInput, 3, true, false, true); err != nil {
+		t.Fatal(err)
+	}
+
+	regs, err := client.ReadRegisters(ctx, InputRegister, 4, 2)
+	if err != nil {
+		t.Fatalf("ReadRegisters(input): %v", err)
+	}
+	if v, err := Decode(regs, Float32, WordSwap); err != nil || v != 87.25 {
+		t.Errorf("input 4 = %v (%v), want 87.25", v, err)
+	}
+	// Неверный порядок байтов дает другое число
+	if v, _ := Decode(regs, Float32, BigEndian); v == 87.25 {
+		t.Errorf("input 4 decoded as ABCD = %v, want a different value", v)
+	}
+
+	regs, err = client.ReadRegisters(ctx, HoldingRegister, 10, 2)
+	if err != nil {
+		t.Fatalf("ReadRegisters(holding): %v", err)
+	}
+	if v, err := Decode(regs, Int32, ByteSwap); err != nil || v != -1234 {
+		t.Errorf("holding 10 = %v (%v), want -1234", v, err)
+	}
+
+	bits, err := client.ReadBits(ctx, DiscreteInput, 3, 3)
+	if err != nil {
+		t.Fatalf("ReadBits: %v", err)
+	}
+	if want := []bool{true, false, true}; !reflect.DeepEqual(bits, want) {
+		t.Errorf("discrete 3..5 = %v, want %v", bits, want)
+	}
+}
+
+func TestExceptionResponses(t *testing.T) {
+	_, client := startServer(t, 16)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		code byte
+	}{
+		{"read past the end", func() error {
+			_, err := client.ReadRegisters(ctx, HoldingRegister, 15, 2)
+			return err
+		}, ExceptionIllegalAddress},
+		{"read bits past the end", func() error {
+			_, err := client.ReadBits(ctx, Coil, 16, 1)
+			return err
+		}, ExceptionIllegalAddress},
+		{"write past the end", func() error {
+			return client.WriteRegisters(ctx, 14, []uint16{1, 2, 3})
+		}, ExceptionIllegalAddress},
+		{"unsupported function", func() error {
+			_, err := client.do(ctx, 0x2B, []byte{0x0E, 0x01, 0x00})
+			return err
+		}, ExceptionIllegalFunction},
+		{"zero quantity", func() error {
+			_, err := client.do(ctx, FuncReadHoldingRegisters, readRequest(0, 0))
+			return err
+		}, ExceptionIllegalValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if !IsException(err, tt.code) {
+				t.Fatalf("error = %v, want exception 0x%02x", err, tt.code)
+			}
+		})
+	}
+
+	// Соединение остается рабочим после исключений
+	if _, err := client.ReadRegisters(ctx, HoldingRegister, 0, 1); err != nil {
+		t.Fatalf("ReadRegisters after exceptions: %v", err)
+	}
+}
+
+func TestWrites(t *testing.T) {
+	srv, client := startServer(t, 16)
+	ctx := context.Background()
+
+	type write struct {
+		typ     RegisterType
+		address uint16
+		count   int
+	}
+	writes := make(chan write, 4)
+	srv.OnWrite(func(t RegisterType, address uint16, count int) { writes <- write{t, address, count} })
+
+	regs, err := Encode(42.5, Float32, WordSwap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.WriteRegisters(ctx, 2, regs); err != nil {
+		t.Fatalf("WriteRegisters: %v", err)
+	}
+	if v, err := srv.Value(HoldingRegister, 2, Float32, WordSwap); err != nil || v != 42.5 {
+		t.Errorf("holding 2 = %v (%v), want 42.5", v, err)
+	}
+	if w := <-writes; w != (write{HoldingRegister, 2, 2}) {
+		t.Errorf("OnWrite = %+v, want holding 2 x2", w)
+	}
+
+	if err := client.WriteRegisters(ctx, 7, []uint16{300}); err != nil {
+		t.Fatalf("WriteRegisters (single): %v", err)
+	}
+	if w := <-writes; w != (write{HoldingRegister, 7, 1}) {
+		t.Errorf("OnWrite = %+v, want holding 7 x1", w)
+	}
+
+	if err := client.WriteCoil(ctx, 5, true); err != nil {
+		t.Fatalf("WriteCoil: %v", err)
+	}
+	if bits, _ := srv.Bits(Coil, 5, 1); !bits[0] {
+		t.Error("coil 5 is not set")
+	}
+	if w := <-writes; w != (write{Coil, 5, 1}) {
+		t.Errorf("OnWrite = %+v, want coil 5 x1", w)
+	}
+}
+
+func TestClientReconnectsAfterServerRestart(t *testing.T) {
+	srv := NewServer(8)
+	if err := srv.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	addr := srv.Addr()
+	client := NewClient(addr, 1, time.Second)
+	defer client.Close()
+	ctx := context.Background()
+
+	if _, err := client.ReadRegisters(ctx, HoldingRegister, 0, 1); err != nil {
+		t.Fatalf("ReadRegisters: %v", err)
+	}
+	srv.Close()
+	if _, err := client.ReadRegisters(ctx, HoldingRegister, 0, 1); err == nil {
+		t.Fatal("ReadRegisters succeeded with the server stopped")
+	}
+
+	restarted := NewServer(8)
+	if err := restarted.Listen(addr); err != nil {
+		t.Skipf("address %s is not available again: %v", addr, err)
+	}
+	defer restarted.Close()
+	restarted.SetRegisters(HoldingRegister, 0, 7)
+
+	regs, err := client.ReadRegisters(ctx, HoldingRegister, 0, 1)
+	if err != nil || regs[0] != 7 {
+		t.Fatalf("ReadRegisters after restart = %v, %v; want [7]", regs, err)
+	}
+}
@@ -0,0 +1,152 @@
+// Package modbus реализует клиент Modbus TCP для опроса оборудования и записи уставок,
+// кодирование значений в регистры и встроенный симулятор устройства (Server) для проверки
+// карт регистров без реального оборудования
+package modbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Функции Modbus, поддерживаемые клиентом и симулятором
+const (
+	FuncReadCoils              byte = 0x01
+	FuncReadDiscreteInputs     byte = 0x02
+	FuncReadHoldingRegisters   byte = 0x03
+	FuncReadInputRegisters     byte = 0x04
+	FuncWriteSingleCoil        byte = 0x05
+	FuncWriteSingleRegister    byte = 0x06
+	FuncWriteMultipleRegisters byte = 0x10
+)
+
+// Коды исключений Modbus
+const (
+	ExceptionIllegalFunction    byte = 0x01
+	ExceptionIllegalAddress     byte = 0x02
+	ExceptionIllegalValue       byte = 0x03
+	ExceptionDeviceFailure      byte = 0x04
+	ExceptionDeviceBusy         byte = 0x06
+	ExceptionGatewayUnavailable byte = 0x0A
+	ExceptionGatewayNoResponse  byte = 0x0B
+)
+
+// Ограничения протокола на число элементов в одном запросе
+const (
+	MaxReadRegisters  = 125
+	MaxWriteRegisters = 123
+	MaxReadBits       = 2000
+)
+
+// mbapHeaderLen — длина заголовка MBAP: transaction id, protocol id, length, unit id
+const mbapHeaderLen = 7
+
+// maxADULen — максимальная длина кадра Modbus TCP
+const maxADULen = 260
+
+// ErrProtocol возвращается для ответов, нарушающих протокол
+var ErrProtocol = errors.New("modbus protocol error")
+
+// ExceptionError — ответ устройства с кодом исключения
+type ExceptionError struct {
+	Function byte
+	Code     byte
+}
+
+func (e *ExceptionError) Error() string {
+	names := map[byte]string{
+		ExceptionIllegalFunction:    "illegal function",
+		ExceptionIllegalAddress:     "illegal data address",
+		ExceptionIllegalValue:       "illegal data value",
+		ExceptionDeviceFailure:      "server device failure",
+		ExceptionDeviceBusy:         "server device busy",
+		ExceptionGatewayUnavailable: "gateway path unavailable",
+		ExceptionGatewayNoResponse:  "gateway target device failed to respond",
+	}
+	name, ok := names[e.Code]
+	if !ok {
+		name = fmt.Sprintf("exception 0x%02x", e.Code)
+	}
+	return fmt.Sprintf("modbus function 0x%02x: %s", e.Function, name)
+}
+
+// IsException сообщает, является ли err исключением устройства с указанным кодом
+func IsException(err error, code byte) bool {
+	var e *ExceptionError
+	return errors.As(err, &e) && e.Code == code
+}
+
+// RegisterType — область данных Modbus
+type RegisterType string
+
+const (
+	Coil            RegisterType = "coil"
+	DiscreteInput   RegisterType = "discrete"
+	HoldingRegister RegisterType = "holding"
+	InputRegister   RegisterType = "input"
+)
+
+// ParseRegisterType проверяет название области данных
+func ParseRegisterType(s string) (RegisterType, error) {
+	switch t := RegisterType(s); t {
+	case Coil, DiscreteInput, HoldingRegister, InputRegister:
+		return t, nil
+	}
+	return "", fmt.Errorf("unknown register type %q: use holding, input, coil or discrete", s)
+}
+
+// Bit сообщает, что область хранит биты, а не 16-битные регистры
+func (t RegisterType) Bit() bool {
+	return t == Coil || t == DiscreteInput
+}
+
+// Writable сообщает, доступна ли область для записи
+func (t RegisterType) Writable() bool {
+	return t == Coil || t == HoldingRegister
+}
+
+func (t RegisterType) readFunction() byte {
+	switch t {
+	case Coil:
+		return FuncReadCoils
+	case DiscreteInput:
+		return FuncReadDiscreteInputs
+	case InputRegister:
+		return FuncReadInputRegisters
+	}
+	return FuncReadHoldingRegisters
+}
+
+func registersToBytes(values []uint16) []byte {
+	b := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint16(b[2*i:], v)
+	}
+	return b
+}
+
+func bytesToRegisters(b []byte) []uint16 {
+	values := make([]uint16, len(b)/2)
+	for i := range values {
+		values[i] = binary.BigEndian.Uint16(b[2*i:])
+	}
+	return values
+}
+
+func bitsToBytes(bits []bool) []byte {
+	b := make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		if bit {
+			b[i/8] |= 1 << (i % 8)
+		}
+	}
+	return b
+}
+
+func bytesToBits(b []byte, n int) []bool {
+	bits := make([]bool, n)
+	for i := range bits {
+		bits[i] = b[i/8]&(1<<(i%8)) != 0
+	}
+	return bits
+}
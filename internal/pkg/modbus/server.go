@@ -0,0 +1,320 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+)
+
+// Server — симулятор устройства Modbus TCP: хранит области данных в памяти и отвечает
+// на запросы любых идентификаторов устройства. Используется для проверки карт регистров
+// и команд управления без оборудования; значения задаются и читаются методами SetValue/Value, SetRegisters, SetBits.
+type Server struct {
+	mu       sync.RWMutex
+	coils    []bool
+	discrete []bool
+	holding  []uint16
+	input    []uint16
+	onWrite  func(t RegisterType, address uint16, count int)
+
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+	closed   bool
+}
+
+// NewServer создает симулятор с областями по size элементов (адреса 0..size-1)
+func NewServer(size int) *Server {
+	return &Server{
+		coils:    make([]bool, size),
+		discrete: make([]bool, size),
+		holding:  make([]uint16, size),
+		input:    make([]uint16, size),
+		conns:    make(map[net.Conn]struct{}),
+	}
+}
+
+// OnWrite задает обработчик записи клиентом (вызывается после изменения области)
+func (s *Server) OnWrite(fn func(t RegisterType, address uint16, count int)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onWrite = fn
+}
+
+// Listen начинает принимать соединения на addr ("127.0.0.1:0" — свободный порт)
+func (s *Server) Listen(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.listener = l
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.accept(l)
+	return nil
+}
+
+// Addr возвращает адрес, на котором слушает симулятор
+func (s *Server) Addr() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Close останавливает симулятор и закрывает соединения клиентов
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// SetRegisters записывает значения в область holding или input
+func (s *Server) SetRegisters(t RegisterType, address uint16, values ...uint16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	area := s.registers(t)
+	if int(address)+len(values) > len(area) {
+		return &ExceptionError{Code: ExceptionIllegalAddress}
+	}
+	copy(area[address:], values)
+	return nil
+}
+
+// Registers возвращает копию qty регистров области holding или input
+func (s *Server) Registers(t RegisterType, address uint16, qty int) ([]uint16, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	area := s.registers(t)
+	if int(address)+qty > len(area) {
+		return nil, &ExceptionError{Code: ExceptionIllegalAddress}
+	}
+	return append([]uint16(nil), area[address:int(address)+qty]...), nil
+}
+
+// SetValue кодирует значение и записывает его в регистры
+func (s *Server) SetValue(t RegisterType, address uint16, value float64, dt DataType, order ByteOrder) error {
+	if t.Bit() {
+		return s.SetBits(t, address, value != 0)
+	}
+	regs, err := Encode(value, dt, order)
+	if err != nil {
+		return err
+	}
+	return s.SetRegisters(t, address, regs...)
+}
+
+// Value читает и декодирует значение регистров
+func (s *Server) Value(t RegisterType, address uint16, dt DataType, order ByteOrder) (float64, error) {
+	if t.Bit() {
+		bits, err := s.Bits(t, address, 1)
+		if err != nil || !bits[0] {
+			return 0, err
+		}
+		return 1, nil
+	}
+	regs, err := s.Registers(t, address, dt.Registers())
+	if err != nil {
+		return 0, err
+	}
+	return Decode(regs, dt, order)
+}
+
+// SetBits записывает биты области coil или discrete
+func (s *Server) SetBits(t RegisterType, address uint16, values ...bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	area := s.bits(t)
+	if int(address)+len(values) > len(area) {
+		return &ExceptionError{Code: ExceptionIllegalAddress}
+	}
+	copy(area[address:], values)
+	return nil
+}
+
+// Bits возвращает копию qty битов области coil или discrete
+func (s *Server) Bits(t RegisterType, address uint16, qty int) ([]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	area := s.bits(t)
+	if int(address)+qty > len(area) {
+		return nil, &ExceptionError{Code: ExceptionIllegalAddress}
+	}
+	return append([]bool(nil), area[address:int(address)+qty]...), nil
+}
+
+func (s *Server) registers(t RegisterType) []uint16 {
+	if t == InputRegister {
+		return s.input
+	}
+	return s.holding
+}
+
+func (s *Server) bits(t RegisterType) []bool {
+	if t == DiscreteInput {
+		return s.discrete
+	}
+	return s.coils
+}
+
+func (s *Server) accept(l net.Listener) {
+	defer s.wg.Done()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		header, pdu, err := readFrame(conn)
+		if err != nil {
+			return // Клиент закрыл соединение или прислал некорректный кадр
+		}
+
+		resp := s.handle(pdu)
+		frame := make([]byte, mbapHeaderLen, mbapHeaderLen+len(resp))
+		copy(frame, header)
+		binary.BigEndian.PutUint16(frame[4:], uint16(1+len(resp)))
+		frame = append(frame, resp...)
+		if _, err := conn.Write(frame); err != nil {
+			return
+		}
+	}
+}
+
+// handle выполняет запрос и возвращает PDU ответа
+func (s *Server) handle(pdu []byte) []byte {
+	function := pdu[0]
+	data := pdu[1:]
+	exception := func(code byte) []byte { return []byte{function | 0x80, code} }
+
+	switch function {
+	case FuncReadCoils, FuncReadDiscreteInputs, FuncReadHoldingRegisters, FuncReadInputRegisters:
+		if len(data) != 4 {
+			return exception(ExceptionIllegalValue)
+		}
+		address, qty := binary.BigEndian.Uint16(data), binary.BigEndian.Uint16(data[2:])
+
+		if function == FuncReadCoils || function == FuncReadDiscreteInputs {
+			t := Coil
+			if function == FuncReadDiscreteInputs {
+				t = DiscreteInput
+			}
+			if qty == 0 || qty > MaxReadBits {
+				return exception(ExceptionIllegalValue)
+			}
+			bits, err := s.Bits(t, address, int(qty))
+			if err != nil {
+				return exception(ExceptionIllegalAddress)
+			}
+			b := bitsToBytes(bits)
+			return append([]byte{function, byte(len(b))}, b...)
+		}
+
+		t := HoldingRegister
+		if function == FuncReadInputRegisters {
+			t = InputRegister
+		}
+		if qty == 0 || qty > MaxReadRegisters {
+			return exception(ExceptionIllegalValue)
+		}
+		regs, err := s.Registers(t, address, int(qty))
+		if err != nil {
+			return exception(ExceptionIllegalAddress)
+		}
+		b := registersToBytes(regs)
+		return append([]byte{function, byte(len(b))}, b...)
+
+	case FuncWriteSingleCoil:
+		if len(data) != 4 {
+			return exception(ExceptionIllegalValue)
+		}
+		address, value := binary.BigEndian.Uint16(data), binary.BigEndian.Uint16(data[2:])
+		if value != 0 && value != 0xFF00 {
+			return exception(ExceptionIllegalValue)
+		}
+		if err := s.SetBits(Coil, address, value == 0xFF00); err != nil {
+			return exception(ExceptionIllegalAddress)
+		}
+		s.notify(Coil, address, 1)
+		return pdu
+
+	case FuncWriteSingleRegister:
+		if len(data) != 4 {
+			return exception(ExceptionIllegalValue)
+		}
+		address := binary.BigEndian.Uint16(data)
+		if err := s.SetRegisters(HoldingRegister, address, binary.BigEndian.Uint16(data[2:])); err != nil {
+			return exception(ExceptionIllegalAddress)
+		}
+		s.notify(HoldingRegister, address, 1)
+		return pdu
+
+	case FuncWriteMultipleRegisters:
+		if len(data) < 5 {
+			return exception(ExceptionIllegalValue)
+		}
+		address, qty := binary.BigEndian.Uint16(data), binary.BigEndian.Uint16(data[2:])
+		if qty == 0 || qty > MaxWriteRegisters || int(data[4]) != 2*int(qty) || len(data) != 5+2*int(qty) {
+			return exception(ExceptionIllegalValue)
+		}
+		if err := s.SetRegisters(HoldingRegister, address, bytesToRegisters(data[5:])...); err != nil {
+			return exception(ExceptionIllegalAddress)
+		}
+		s.notify(HoldingRegister, address, int(qty))
+		return append([]byte{function}, data[:4]...)
+	}
+
+	return exception(ExceptionIllegalFunction)
+}
+
+func (s *Server) notify(t RegisterType, address uint16, count int) {
+	s.mu.RLock()
+	fn := s.onWrite
+	s.mu.RUnlock()
+	if fn != nil {
+		fn(t, address, count)
+	}
+}
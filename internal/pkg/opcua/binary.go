@@ -0,0 +1,657 @@
+package opcua
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Кодирование встроенных типов OPC UA Binary (часть 6 спецификации): числа little-endian,
+// строки и массивы с длиной Int32 (-1 — null), DateTime — интервалы по 100 нс с 1601 года.
+
+// errTruncated возвращается при чтении за концом сообщения
+var errTruncated = fmt.Errorf("truncated message: %w", StatusBadDecodingError)
+
+// epoch1601 — смещение Unix-времени от 1601-01-01 в интервалах по 100 нс
+const epoch1601 = 116444736000000000
+
+// Типы значений Variant
+const (
+	variantNull byte = iota
+	variantBoolean
+	variantSByte
+	variantByte
+	variantInt16
+	variantUInt16
+	variantInt32
+	variantUInt32
+	variantInt64
+	variantUInt64
+	variantFloat
+	variantDouble
+	variantString
+	variantDateTime
+	variantGUID
+	variantByteString
+	variantXMLElement
+	variantNodeID
+	variantExpandedNodeID
+	variantStatusCode
+	variantQualifiedName
+	variantLocalizedText
+	variantExtensionObject
+	variantDataValue
+	variantVariant
+	variantDiagnosticInfo
+)
+
+// nodeID — двоичное представление идентификатора узла
+type nodeID struct {
+	ns   uint16
+	kind byte   // 'i', 's', 'g', 'b'
+	num  uint32 // Числовой идентификатор
+	str  string // Строка, 16 байт GUID в порядке текстовой записи или байты идентификатора
+}
+
+func numericNodeID(ns uint16, id uint32) nodeID {
+	return nodeID{ns: ns, kind: 'i', num: id}
+}
+
+// parseNodeID разбирает строковую форму NodeID
+func parseNodeID(id NodeID) (nodeID, error) {
+	s := string(id)
+	var n nodeID
+	if rest, ok := strings.CutPrefix(s, "ns="); ok {
+		idx, tail, ok := strings.Cut(rest, ";")
+		if !ok {
+			return nodeID{}, fmt.Errorf("invalid node id %q", s)
+		}
+		ns, err := strconv.ParseUint(idx, 10, 16)
+		if err != nil {
+			return nodeID{}, fmt.Errorf("invalid namespace of node id %q", s)
+		}
+		n.ns, s = uint16(ns), tail
+	}
+	if len(s) < 2 || s[1] != '=' {
+		return nodeID{}, fmt.Errorf("invalid node id %q", id)
+	}
+
+	n.kind, s = s[0], s[2:]
+	switch n.kind {
+	case 'i':
+		v, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nodeID{}, fmt.Errorf("invalid numeric node id %q", id)
+		}
+		n.num = uint32(v)
+	case 's':
+		n.str = s
+	case 'g':
+		b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+		if err != nil || len(b) != 16 {
+			return nodeID{}, fmt.Errorf("invalid GUID node id %q", id)
+		}
+		n.str = string(b)
+	case 'b':
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nodeID{}, fmt.Errorf("invalid opaque node id %q", id)
+		}
+		n.str = string(b)
+	default:
+		return nodeID{}, fmt.Errorf("invalid node id %q", id)
+	}
+	return n, nil
+}
+
+// NodeID возвращает строковую форму идентификатора
+func (n nodeID) NodeID() NodeID {
+	var prefix string
+	if n.ns != 0 {
+		prefix = fmt.Sprintf("ns=%d;", n.ns)
+	}
+	switch n.kind {
+	case 's':
+		return NodeID(prefix + "s=" + n.str)
+	case 'g':
+		h := hex.EncodeToString([]byte(n.str))
+		return NodeID(fmt.Sprintf("%sg=%s-%s-%s-%s-%s", prefix, h[0:8], h[8:12], h[12:16], h[16:20], h[20:32]))
+	case 'b':
+		return NodeID(prefix + "b=" + base64.StdEncoding.EncodeToString([]byte(n.str)))
+	}
+	return NodeID(fmt.Sprintf("%si=%d", prefix, n.num))
+}
+
+// encoder собирает сообщение OPC UA Binary
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) byte(v byte) { e.buf = append(e.buf, v) }
+
+func (e *encoder) boolean(v bool) {
+	if v {
+		e.byte(1)
+	} else {
+		e.byte(0)
+	}
+}
+
+func (e *encoder) uint16(v uint16) { e.buf = binary.LittleEndian.AppendUint16(e.buf, v) }
+func (e *encoder) uint32(v uint32) { e.buf = binary.LittleEndian.AppendUint32(e.buf, v) }
+func (e *encoder) int32(v int32)   { e.uint32(uint32(v)) }
+func (e *encoder) uint64(v uint64) { e.buf = binary.LittleEndian.AppendUint64(e.buf, v) }
+func (e *encoder) int64(v int64)   { e.uint64(uint64(v)) }
+func (e *encoder) float(v float32) { e.uint32(math.Float32bits(v)) }
+func (e *encoder) double(v float64) {
+	e.uint64(math.Float64bits(v))
+}
+
+// string записывает строку; пустая строка записывается как null
+func (e *encoder) string(s string) {
+	if s == "" {
+		e.int32(-1)
+		return
+	}
+	e.int32(int32(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// bytes записывает ByteString; nil записывается как null
+func (e *encoder) bytes(b []byte) {
+	if b == nil {
+		e.int32(-1)
+		return
+	}
+	e.int32(int32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) time(t time.Time) {
+	if t.IsZero() {
+		e.int64(0)
+		return
+	}
+	e.int64(t.UnixNano()/100 + epoch1601)
+}
+
+func (e *encoder) guid(b string) {
+	e.uint32(binary.BigEndian.Uint32([]byte(b[0:4])))
+	e.uint16(binary.BigEndian.Uint16([]byte(b[4:6])))
+	e.uint16(binary.BigEndian.Uint16([]byte(b[6:8])))
+	e.buf = append(e.buf, b[8:16]...)
+}
+
+func (e *encoder) nodeID(n nodeID) {
+	e.nodeIDFlags(n, 0)
+}
+
+func (e *encoder) nodeIDFlags(n nodeID, flags byte) {
+	switch n.kind {
+	case 's':
+		e.byte(0x03 | flags)
+		e.uint16(n.ns)
+		e.string(n.str)
+	case 'g':
+		e.byte(0x04 | flags)
+		e.uint16(n.ns)
+		e.guid(n.str)
+	case 'b':
+		e.byte(0x05 | flags)
+		e.uint16(n.ns)
+		e.bytes([]byte(n.str))
+	default:
+		switch {
+		case n.ns == 0 && n.num <= math.MaxUint8:
+			e.byte(0x00 | flags)
+			e.byte(byte(n.num))
+		case n.ns <= math.MaxUint8 && n.num <= math.MaxUint16:
+			e.byte(0x01 | flags)
+			e.byte(byte(n.ns))
+			e.uint16(uint16(n.num))
+		default:
+			e.byte(0x02 | flags)
+			e.uint16(n.ns)
+			e.uint32(n.num)
+		}
+	}
+}
+
+func (e *encoder) qualifiedName(ns uint16, name string) {
+	e.uint16(ns)
+	e.string(name)
+}
+
+func (e *encoder) localizedText(text string) {
+	if text == "" {
+		e.byte(0)
+		return
+	}
+	e.byte(0x02)
+	e.string(text)
+}
+
+// extensionObject записывает структуру с двоичным кодированием typeID; body == nil — пустой объект
+func (e *encoder) extensionObject(typeID uint32, body func(*encoder)) {
+	if body == nil {
+		e.nodeID(numericNodeID(0, 0))
+		e.byte(0)
+		return
+	}
+	e.nodeID(numericNodeID(0, typeID))
+	e.byte(0x01)
+	var inner encoder
+	body(&inner)
+	e.bytes(inner.buf)
+}
+
+// variant записывает скалярное значение; неподдерживаемые типы записываются как null
+func (e *encoder) variant(v interface{}) {
+	switch x := v.(type) {
+	case bool:
+		e.byte(variantBoolean)
+		e.boolean(x)
+	case int8:
+		e.byte(variantSByte)
+		e.byte(byte(x))
+	case uint8:
+		e.byte(variantByte)
+		e.byte(x)
+	case int16:
+		e.byte(variantInt16)
+		e.uint16(uint16(x))
+	case uint16:
+		e.byte(variantUInt16)
+		e.uint16(x)
+	case int32:
+		e.byte(variantInt32)
+		e.int32(x)
+	case uint32:
+		e.byte(variantUInt32)
+		e.uint32(x)
+	case int64:
+		e.byte(variantInt64)
+		e.int64(x)
+	case int:
+		e.byte(variantInt64)
+		e.int64(int64(x))
+	case uint64:
+		e.byte(variantUInt64)
+		e.uint64(x)
+	case float32:
+		e.byte(variantFloat)
+		e.float(x)
+	case float64:
+		e.byte(variantDouble)
+		e.double(x)
+	case string:
+		e.byte(variantString)
+		e.string(x)
+	case time.Time:
+		e.byte(variantDateTime)
+		e.time(x)
+	case []byte:
+		e.byte(variantByteString)
+		e.bytes(x)
+	case StatusCode:
+		e.byte(variantStatusCode)
+		e.uint32(uint32(x))
+	default:
+		e.byte(variantNull)
+	}
+}
+
+func (e *encoder) dataValue(v DataValue) {
+	var mask byte
+	if v.Value != nil {
+		mask |= 0x01
+	}
+	if v.Status != StatusGood {
+		mask |= 0x02
+	}
+	if !v.SourceTimestamp.IsZero() {
+		mask |= 0x04
+	}
+	if !v.ServerTimestamp.IsZero() {
+		mask |= 0x08
+	}
+
+	e.byte(mask)
+	if v.Value != nil {
+		e.variant(v.Value)
+	}
+	if v.Status != StatusGood {
+		e.uint32(uint32(v.Status))
+	}
+	if !v.SourceTimestamp.IsZero() {
+		e.time(v.SourceTimestamp)
+	}
+	if !v.ServerTimestamp.IsZero() {
+		e.time(v.ServerTimestamp)
+	}
+}
+
+// decoder читает сообщение OPC UA Binary. Первая ошибка запоминается, дальнейшие чтения
+// возвращают нулевые значения, поэтому ошибку достаточно проверить в конце.
+type decoder struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (d *decoder) read(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf)-d.pos < n {
+		d.err = errTruncated
+		return nil
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *decoder) byte() byte {
+	if b := d.read(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) boolean() bool { return d.byte() != 0 }
+
+func (d *decoder) uint16() uint16 {
+	if b := d.read(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *decoder) uint32() uint32 {
+	if b := d.read(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) int32() int32 { return int32(d.uint32()) }
+
+func (d *decoder) uint64() uint64 {
+	if b := d.read(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) int64() int64    { return int64(d.uint64()) }
+func (d *decoder) float() float32  { return math.Float32frombits(d.uint32()) }
+func (d *decoder) double() float64 { return math.Float64frombits(d.uint64()) }
+
+// length читает длину строки или массива; null возвращается как 0
+func (d *decoder) length() int {
+	n := d.int32()
+	if n < 0 {
+		return 0
+	}
+	if int(n) > len(d.buf)-d.pos && d.err == nil {
+		d.err = errTruncated
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) string() string { return string(d.read(d.length())) }
+
+func (d *decoder) bytes() []byte {
+	n := d.int32()
+	if n < 0 {
+		return nil
+	}
+	return append([]byte(nil), d.read(int(n))...)
+}
+
+func (d *decoder) time() time.Time {
+	ticks := d.int64()
+	if ticks <= 0 || ticks == math.MaxInt64 {
+		return time.Time{}
+	}
+	return time.Unix(0, (ticks-epoch1601)*100).UTC()
+}
+
+func (d *decoder) guid() string {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint32(b[0:4], d.uint32())
+	binary.BigEndian.PutUint16(b[4:6], d.uint16())
+	binary.BigEndian.PutUint16(b[6:8], d.uint16())
+	copy(b[8:], d.read(8))
+	return string(b)
+}
+
+func (d *decoder) nodeID() nodeID {
+	n, _ := d.nodeIDFlags()
+	return n
+}
+
+func (d *decoder) nodeIDFlags() (nodeID, byte) {
+	enc := d.byte()
+	var n nodeID
+	switch enc & 0x0F {
+	case 0x00:
+		n = numericNodeID(0, uint32(d.byte()))
+	case 0x01:
+		ns := d.byte()
+		n = numericNodeID(uint16(ns), uint32(d.uint16()))
+	case 0x02:
+		ns := d.uint16()
+		n = numericNodeID(ns, d.uint32())
+	case 0x03:
+		n = nodeID{ns: d.uint16(), kind: 's'}
+		n.str = d.string()
+	case 0x04:
+		n = nodeID{ns: d.uint16(), kind: 'g'}
+		n.str = d.guid()
+	case 0x05:
+		n = nodeID{ns: d.uint16(), kind: 'b'}
+		n.str = string(d.bytes())
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("unknown node id encoding 0x%02X: %w", enc, StatusBadDecodingError)
+		}
+	}
+	return n, enc & 0xC0
+}
+
+// expandedNodeID читает ExpandedNodeId; URI пространства имен и индекс сервера пропускаются
+func (d *decoder) expandedNodeID() nodeID {
+	n, flags := d.nodeIDFlags()
+	if flags&0x80 != 0 {
+		d.string()
+	}
+	if flags&0x40 != 0 {
+		d.uint32()
+	}
+	return n
+}
+
+func (d *decoder) qualifiedName() string {
+	d.uint16()
+	return d.string()
+}
+
+func (d *decoder) localizedText() string {
+	mask := d.byte()
+	if mask&0x01 != 0 {
+		d.string()
+	}
+	if mask&0x02 != 0 {
+		return d.string()
+	}
+	return ""
+}
+
+// extensionObject возвращает идентификатор типа и тело структуры
+func (d *decoder) extensionObject() (uint32, []byte) {
+	typeID := d.nodeID()
+	switch d.byte() {
+	case 0x00:
+		return typeID.num, nil
+	case 0x01, 0x02:
+		return typeID.num, d.read(d.length())
+	}
+	if d.err == nil {
+		d.err = fmt.Errorf("unknown extension object encoding: %w", StatusBadDecodingError)
+	}
+	return 0, nil
+}
+
+func (d *decoder) diagnosticInfo() {
+	mask := d.byte()
+	for _, bit := range []byte{0x01, 0x02, 0x04, 0x08} {
+		if mask&bit != 0 {
+			d.int32()
+		}
+	}
+	if mask&0x10 != 0 {
+		d.string()
+	}
+	if mask&0x20 != 0 {
+		d.uint32()
+	}
+	if mask&0x40 != 0 && d.err == nil {
+		d.diagnosticInfo()
+	}
+}
+
+func (d *decoder) diagnosticInfos() {
+	for n := d.length(); n > 0 && d.err == nil; n-- {
+		d.diagnosticInfo()
+	}
+}
+
+func (d *decoder) statusCodes() []StatusCode {
+	codes := make([]StatusCode, d.length())
+	for i := range codes {
+		codes[i] = StatusCode(d.uint32())
+	}
+	return codes
+}
+
+// variant читает значение; массивы возвращаются как []interface{}
+func (d *decoder) variant() interface{} {
+	enc := d.byte()
+	kind := enc & 0x3F
+	if enc&0x80 == 0 {
+		return d.scalar(kind)
+	}
+
+	values := make([]interface{}, d.length())
+	for i := range values {
+		values[i] = d.scalar(kind)
+	}
+	if enc&0x40 != 0 {
+		for n := d.length(); n > 0; n-- {
+			d.int32()
+		}
+	}
+	return values
+}
+
+func (d *decoder) scalar(kind byte) interface{} {
+	switch kind {
+	case variantNull:
+		return nil
+	case variantBoolean:
+		return d.boolean()
+	case variantSByte:
+		return int8(d.byte())
+	case variantByte:
+		return d.byte()
+	case variantInt16:
+		return int16(d.uint16())
+	case variantUInt16:
+		return d.uint16()
+	case variantInt32:
+		return d.int32()
+	case variantUInt32:
+		return d.uint32()
+	case variantInt64:
+		return d.int64()
+	case variantUInt64:
+		return d.uint64()
+	case variantFloat:
+		return d.float()
+	case variantDouble:
+		return d.double()
+	case variantString, variantXMLElement:
+		return d.string()
+	case variantDateTime:
+		return d.time()
+	case variantGUID:
+		return nodeID{kind: 'g', str: d.guid()}.NodeID()[2:]
+	case variantByteString:
+		return d.bytes()
+	case variantNodeID:
+		return d.nodeID().NodeID()
+	case variantExpandedNodeID:
+		return d.expandedNodeID().NodeID()
+	case variantStatusCode:
+		return StatusCode(d.uint32())
+	case variantQualifiedName:
+		return d.qualifiedName()
+	case variantLocalizedText:
+		return d.localizedText()
+	case variantExtensionObject:
+		d.extensionObject()
+		return nil
+	case variantDataValue:
+		return d.dataValue()
+	case variantVariant:
+		return d.variant()
+	case variantDiagnosticInfo:
+		d.diagnosticInfo()
+		return nil
+	}
+	if d.err == nil {
+		d.err = fmt.Errorf("unknown variant type %d: %w", kind, StatusBadDecodingError)
+	}
+	return nil
+}
+
+func (d *decoder) dataValue() DataValue {
+	var v DataValue
+	mask := d.byte()
+	if mask&0x01 != 0 {
+		v.Value = d.variant()
+	}
+	if mask&0x02 != 0 {
+		v.Status = StatusCode(d.uint32())
+	}
+	if mask&0x04 != 0 {
+		v.SourceTimestamp = d.time()
+	}
+	if mask&0x10 != 0 {
+		d.uint16()
+	}
+	if mask&0x08 != 0 {
+		v.ServerTimestamp = d.time()
+	}
+	if mask&0x20 != 0 {
+		d.uint16()
+	}
+	return v
+}
+
+// errDecoding оборачивает ошибку разбора сообщения сервиса
+func errDecoding(service string, err error) error {
+	if errors.Is(err, StatusBadDecodingError) {
+		return fmt.Errorf("failed to decode %s: %w", service, err)
+	}
+	return fmt.Errorf("failed to decode %s: %v: %w", service, err, StatusBadDecodingError)
+}
@@ -0,0 +1,280 @@
+// Package opcua описывает клиентскую модель OPC UA, с которой работает коннектор:
+// идентификаторы узлов, значения со StatusCode и метками времени, просмотр адресного
+// пространства и подписки на изменения. Транспорт подключается драйвером по схеме адреса
+// сервера (RegisterDriver). Драйвер opc.tcp работает по протоколу OPC UA Binary с политикой
+// безопасности None (анонимно или по имени пользователя); серверы, требующие подписи
+// и шифрования канала, не поддерживаются. В пакет входит стенд сервера (Server): in-process
+// по схеме sim:// и по opc.tcp (ListenTCP) для проверки конфигурации и восстановления
+// сессий без оборудования.
+package opcua
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ErrNoDriver возвращается для адреса, схема которого не обслуживается драйвером
+var ErrNoDriver = errors.New("no OPC UA driver for endpoint scheme")
+
+// NodeID — идентификатор узла в строковой форме OPC UA: "i=85", "ns=2;s=Unit1.Flow",
+// "ns=3;i=1001", "ns=1;g=<guid>", "ns=1;b=<base64>"
+type NodeID string
+
+var nodeIDPattern = regexp.MustCompile(`^(ns=\d+;)?[isgb]=.+$`)
+
+// ObjectsFolder — корень объектов адресного пространства
+const ObjectsFolder NodeID = "i=85"
+
+// ParseNodeID проверяет строковую форму идентификатора узла
+func ParseNodeID(s string) (NodeID, error) {
+	if !nodeIDPattern.MatchString(s) {
+		return "", fmt.Errorf("invalid node id %q: expected ns=<index>;<i|s|g|b>=<identifier>", s)
+	}
+	return NodeID(s), nil
+}
+
+// StatusCode — код результата OPC UA: старшие два бита — серьезность (good, uncertain, bad),
+// следующие 14 — код, младшие — информационные биты (в т.ч. ограничение значения)
+type StatusCode uint32
+
+// Коды OPC UA, используемые коннектором и стендом
+const (
+	StatusGood                      StatusCode = 0x00000000
+	StatusUncertainLastUsableValue  StatusCode = 0x40900000
+	StatusBadCommunicationError     StatusCode = 0x80050000
+	StatusBadDecodingError          StatusCode = 0x80070000
+	StatusBadTimeout                StatusCode = 0x800A0000
+	StatusBadServiceUnsupported     StatusCode = 0x800B0000
+	StatusBadUserAccessDenied       StatusCode = 0x801F0000
+	StatusBadIdentityTokenRejected  StatusCode = 0x80210000
+	StatusBadSecureChannelIDInvalid StatusCode = 0x80220000
+	StatusBadSessionIDInvalid       StatusCode = 0x80250000
+	StatusBadSessionClosed          StatusCode = 0x80260000
+	StatusBadSessionNotActivated    StatusCode = 0x80270000
+	StatusBadSubscriptionIDInvalid  StatusCode = 0x80280000
+	StatusBadWaitingForInitialData  StatusCode = 0x80320000
+	StatusBadNodeIDInvalid          StatusCode = 0x80330000
+	StatusBadNodeIDUnknown          StatusCode = 0x80340000
+	StatusBadSecurityPolicyRejected StatusCode = 0x80550000
+	StatusBadTooManyPublishRequests StatusCode = 0x80780000
+	StatusBadNoSubscription         StatusCode = 0x80790000
+	StatusBadTCPMessageTooLarge     StatusCode = 0x80800000
+	StatusBadNotConnected           StatusCode = 0x808A0000
+	StatusBadSensorFailure          StatusCode = 0x808C0000
+	StatusBadConnectionClosed       StatusCode = 0x80AE0000
+)
+
+var statusNames = map[StatusCode]string{
+	StatusGood:                      "Good",
+	StatusUncertainLastUsableValue:  "UncertainLastUsableValue",
+	StatusBadCommunicationError:     "BadCommunicationError",
+	StatusBadDecodingError:          "BadDecodingError",
+	StatusBadTimeout:                "BadTimeout",
+	StatusBadServiceUnsupported:     "BadServiceUnsupported",
+	StatusBadUserAccessDenied:       "BadUserAccessDenied",
+	StatusBadIdentityTokenRejected:  "BadIdentityTokenRejected",
+	StatusBadSecureChannelIDInvalid: "BadSecureChannelIdInvalid",
+	StatusBadSessionIDInvalid:       "BadSessionIdInvalid",
+	StatusBadSessionClosed:          "BadSessionClosed",
+	StatusBadSessionNotActivated:    "BadSessionNotActivated",
+	StatusBadSubscriptionIDInvalid:  "BadSubscriptionIdInvalid",
+	StatusBadWaitingForInitialData:  "BadWaitingForInitialData",
+	StatusBadNodeIDInvalid:          "BadNodeIdInvalid",
+	StatusBadNodeIDUnknown:          "BadNodeIdUnknown",
+	StatusBadSecurityPolicyRejected: "BadSecurityPolicyRejected",
+	StatusBadTooManyPublishRequests: "BadTooManyPublishRequests",
+	StatusBadNoSubscription:         "BadNoSubscription",
+	StatusBadTCPMessageTooLarge:     "BadTcpMessageTooLarge",
+	StatusBadNotConnected:           "BadNotConnected",
+	StatusBadSensorFailure:          "BadSensorFailure",
+	StatusBadConnectionClosed:       "BadConnectionClosed",
+}
+
+// StatusCode используется и как ошибка операций сессии
+func (s StatusCode) Error() string {
+	if name, ok := statusNames[s&0xFFFF0000]; ok {
+		return name
+	}
+	return fmt.Sprintf("StatusCode 0x%08X", uint32(s))
+}
+
+// IsGood сообщает, что код относится к классу good
+func (s StatusCode) IsGood() bool { return s&0xC0000000 == 0 }
+
+// IsBad сообщает, что код относится к классу bad
+func (s StatusCode) IsBad() bool { return s&0x80000000 != 0 }
+
+// DataValue — значение узла с кодом состояния и метками времени источника и сервера
+type DataValue struct {
+	Value           interface{}
+	Status          StatusCode
+	SourceTimestamp time.Time
+	ServerTimestamp time.Time
+}
+
+// Float64 переводит числовое или логическое значение в float64
+func (v DataValue) Float64() (float64, bool) {
+	switch x := v.Value.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int8:
+		return float64(x), true
+	case int16:
+		return float64(x), true
+	case int32:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case uint8:
+		return float64(x), true
+	case uint16:
+		return float64(x), true
+	case uint32:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case bool:
+		if x {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// Timestamp возвращает метку времени источника, а при ее отсутствии — метку сервера
+func (v DataValue) Timestamp() time.Time {
+	if !v.SourceTimestamp.IsZero() {
+		return v.SourceTimestamp
+	}
+	return v.ServerTimestamp
+}
+
+// NodeClass — класс узла
+type NodeClass uint32
+
+const (
+	NodeClassObject   NodeClass = 1
+	NodeClassVariable NodeClass = 2
+)
+
+func (c NodeClass) String() string {
+	switch c {
+	case NodeClassObject:
+		return "Object"
+	case NodeClassVariable:
+		return "Variable"
+	}
+	return fmt.Sprintf("NodeClass(%d)", uint32(c))
+}
+
+// MarshalText выводит класс узла названием
+func (c NodeClass) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// Reference — дочерний узел, найденный при просмотре
+type Reference struct {
+	NodeID      NodeID    `json:"node_id"`
+	BrowseName  string    `json:"browse_name"`
+	DisplayName string    `json:"display_name"`
+	NodeClass   NodeClass `json:"node_class"`
+}
+
+// Notification — изменение значения отслеживаемого узла
+type Notification struct {
+	NodeID NodeID
+	Value  DataValue
+}
+
+// Session — сессия с сервером OPC UA. Методы безопасны для одновременного вызова.
+// После потери сессии методы возвращают StatusCode причины.
+type Session interface {
+	// Browse возвращает прямые дочерние узлы (иерархические ссылки)
+	Browse(ctx context.Context, node NodeID) ([]Reference, error)
+	// Read читает текущие значения; ошибки отдельных узлов возвращаются в DataValue.Status
+	Read(ctx context.Context, nodes []NodeID) ([]DataValue, error)
+	// Subscribe создает подписку с периодом публикации interval. Первыми приходят текущие
+	// значения узлов, далее — изменения.
+	Subscribe(ctx context.Context, interval time.Duration, nodes []NodeID) (Subscription, error)
+	// Close закрывает сессию
+	Close() error
+}
+
+// Subscription — подписка на изменения значений
+type Subscription interface {
+	// Notifications возвращает канал уведомлений; канал закрывается при закрытии подписки
+	// или потере сессии
+	Notifications() <-chan Notification
+	// Err возвращает причину закрытия канала (nil после Close)
+	Err() error
+	// Close удаляет подписку
+	Close() error
+}
+
+// Options — параметры подключения к серверу
+type Options struct {
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+// Driver открывает сессию с сервером по адресу
+type Driver func(ctx context.Context, endpoint string, opts Options) (Session, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Driver)
+)
+
+// RegisterDriver регистрирует драйвер для схемы адреса ("opc.tcp", "sim")
+func RegisterDriver(scheme string, d Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	drivers[scheme] = d
+}
+
+func driver(endpoint string) (Driver, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+
+	driversMu.RLock()
+	d, ok := drivers[u.Scheme]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrNoDriver, u.Scheme)
+	}
+	return d, nil
+}
+
+// CheckEndpoint проверяет, что для адреса зарегистрирован драйвер
+func CheckEndpoint(endpoint string) error {
+	_, err := driver(endpoint)
+	return err
+}
+
+// Dial открывает сессию драйвером, соответствующим схеме адреса
+func Dial(ctx context.Context, endpoint string, opts Options) (Session, error) {
+	d, err := driver(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	return d(ctx, endpoint, opts)
+}
@@ -0,0 +1,428 @@
+package opcua
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// simScheme — схема адресов стенда: sim://<имя>
+const simScheme = "sim"
+
+// notificationBuffer — емкость канала уведомлений подписки стенда
+const notificationBuffer = 1024
+
+var (
+	simMu      sync.Mutex
+	simServers = make(map[string]*Server)
+)
+
+func init() {
+	RegisterDriver(simScheme, dialSim)
+}
+
+// Server — in-process стенд сервера OPC UA: адресное пространство в памяти, сессии,
+// подписки с периодом публикации. Позволяет имитировать потерю связи (SetAvailable)
+// и сброс сессий сервером (DropSessions), чтобы проверить восстановление коннектора.
+// ListenTCP открывает тот же стенд по протоколу OPC UA Binary (opc.tcp).
+type Server struct {
+	name string
+
+	mu        sync.Mutex
+	nodes     map[NodeID]*simNode
+	sessions  map[*simSession]struct{}
+	available bool
+	closed    bool
+	listeners []net.Listener
+	conns     map[net.Conn]struct{}
+	users     map[string]string
+}
+
+type simNode struct {
+	ref      Reference
+	children []NodeID
+	value    DataValue
+}
+
+// NewServer создает стенд, доступный по адресу sim://<name>
+func NewServer(name string) (*Server, error) {
+	s := &Server{
+		name:      name,
+		nodes:     make(map[NodeID]*simNode),
+		sessions:  make(map[*simSession]struct{}),
+		available: true,
+		conns:     make(map[net.Conn]struct{}),
+		users:     make(map[string]string),
+	}
+	s.nodes[ObjectsFolder] = &simNode{ref: Reference{NodeID: ObjectsFolder, BrowseName: "Objects", DisplayName: "Objects", NodeClass: NodeClassObject}}
+
+	simMu.Lock()
+	defer simMu.Unlock()
+	if _, ok := simServers[name]; ok {
+		return nil, fmt.Errorf("OPC UA stand-in %q already exists", name)
+	}
+	simServers[name] = s
+	return s, nil
+}
+
+// Endpoint возвращает адрес стенда для конфигурации коннектора
+func (s *Server) Endpoint() string {
+	return simScheme + "://" + s.name
+}
+
+// AddObject добавляет объект (папку, установку) в узел parent
+func (s *Server) AddObject(parent, id NodeID, name string) error {
+	return s.add(parent, &simNode{ref: Reference{NodeID: id, BrowseName: name, DisplayName: name, NodeClass: NodeClassObject}})
+}
+
+// AddVariable добавляет переменную с начальным значением в узел parent
+func (s *Server) AddVariable(parent, id NodeID, name string, value interface{}) error {
+	now := time.Now().UTC()
+	return s.add(parent, &simNode{
+		ref:   Reference{NodeID: id, BrowseName: name, DisplayName: name, NodeClass: NodeClassVariable},
+		value: DataValue{Value: value, Status: StatusGood, SourceTimestamp: now, ServerTimestamp: now},
+	})
+}
+
+func (s *Server) add(parent NodeID, n *simNode) error {
+	if _, err := ParseNodeID(string(n.ref.NodeID)); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.nodes[parent]
+	if !ok {
+		return fmt.Errorf("parent node %s: %w", parent, StatusBadNodeIDUnknown)
+	}
+	if _, ok := s.nodes[n.ref.NodeID]; ok {
+		return fmt.Errorf("node %s already exists", n.ref.NodeID)
+	}
+	s.nodes[n.ref.NodeID] = n
+	p.children = append(p.children, n.ref.NodeID)
+	return nil
+}
+
+// SetValue изменяет значение переменной и уведомляет подписки. Нулевая метка источника
+// заменяется текущим временем.
+func (s *Server) SetValue(id NodeID, value interface{}, status StatusCode, sourceTime time.Time) error {
+	now := time.Now().UTC()
+	if sourceTime.IsZero() {
+		sourceTime = now
+	}
+	dv := DataValue{Value: value, Status: status, SourceTimestamp: sourceTime, ServerTimestamp: now}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.nodes[id]
+	if !ok || n.ref.NodeClass != NodeClassVariable {
+		return fmt.Errorf("variable %s: %w", id, StatusBadNodeIDUnknown)
+	}
+	n.value = dv
+
+	for sess := range s.sessions {
+		sess.notify(id, dv)
+	}
+	return nil
+}
+
+// SetAvailable включает и отключает доступность стенда. При отключении сессии
+// разрываются с BadConnectionClosed, а новые подключения отклоняются с BadNotConnected.
+func (s *Server) SetAvailable(available bool) {
+	s.mu.Lock()
+	s.available = available
+	s.mu.Unlock()
+
+	if !available {
+		s.DropSessions(StatusBadConnectionClosed)
+		s.closeConns()
+	}
+}
+
+// DropSessions завершает все сессии с кодом status (например, при перезапуске сервера)
+func (s *Server) DropSessions(status StatusCode) {
+	s.mu.Lock()
+	sessions := make([]*simSession, 0, len(s.sessions))
+	for sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.sessions = make(map[*simSession]struct{})
+	s.mu.Unlock()
+
+	for _, sess := range sessions {
+		sess.terminate(status)
+	}
+}
+
+// Sessions возвращает число открытых сессий
+func (s *Server) Sessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// Close завершает сессии и освобождает адрес стенда
+func (s *Server) Close() error {
+	simMu.Lock()
+	delete(simServers, s.name)
+	simMu.Unlock()
+
+	s.mu.Lock()
+	s.closed = true
+	listeners := s.listeners
+	s.listeners = nil
+	s.mu.Unlock()
+
+	for _, l := range listeners {
+		l.Close()
+	}
+	s.DropSessions(StatusBadConnectionClosed)
+	s.closeConns()
+	return nil
+}
+
+func dialSim(ctx context.Context, endpoint string, _ Options) (Session, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	simMu.Lock()
+	s, ok := simServers[u.Host]
+	simMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("failed to connect to %s: %w", endpoint, StatusBadNotConnected)
+	}
+
+	sess, err := s.connect()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", endpoint, err)
+	}
+	return sess, nil
+}
+
+// connect открывает сессию стенда
+func (s *Server) connect() (*simSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || !s.available {
+		return nil, StatusBadNotConnected
+	}
+	sess := &simSession{server: s, subs: make(map[*simSubscription]struct{})}
+	s.sessions[sess] = struct{}{}
+	return sess, nil
+}
+
+// simSession — сессия стенда
+type simSession struct {
+	server *Server
+
+	mu     sync.Mutex
+	status StatusCode // Причина завершения; StatusGood — сессия открыта
+	done   bool
+	subs   map[*simSubscription]struct{}
+}
+
+func (c *simSession) check() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done {
+		return c.status
+	}
+	return nil
+}
+
+func (c *simSession) Browse(ctx context.Context, node NodeID) ([]Reference, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+
+	s := c.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.nodes[node]
+	if !ok {
+		return nil, StatusBadNodeIDUnknown
+	}
+	refs := make([]Reference, len(n.children))
+	for i, id := range n.children {
+		refs[i] = s.nodes[id].ref
+	}
+	return refs, nil
+}
+
+func (c *simSession) Read(ctx context.Context, nodes []NodeID) ([]DataValue, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+
+	s := c.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make([]DataValue, len(nodes))
+	for i, id := range nodes {
+		n, ok := s.nodes[id]
+		switch {
+		case !ok:
+			values[i] = DataValue{Status: StatusBadNodeIDUnknown}
+		case n.ref.NodeClass != NodeClassVariable:
+			values[i] = DataValue{Status: StatusBadNodeIDInvalid}
+		default:
+			values[i] = n.value
+		}
+	}
+	return values, nil
+}
+
+func (c *simSession) Subscribe(ctx context.Context, interval time.Duration, nodes []NodeID) (Subscription, error) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	initial, err := c.Read(ctx, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &simSubscription{
+		session: c,
+		nodes:   make(map[NodeID]bool, len(nodes)),
+		ch:      make(chan Notification, notificationBuffer),
+		stop:    make(chan struct{}),
+	}
+	for i, id := range nodes {
+		sub.nodes[id] = true
+		sub.pending = append(sub.pending, Notification{NodeID: id, Value: initial[i]})
+	}
+
+	c.mu.Lock()
+	if c.done {
+		c.mu.Unlock()
+		return nil, c.status
+	}
+	c.subs[sub] = struct{}{}
+	c.mu.Unlock()
+
+	go sub.publish(interval)
+	return sub, nil
+}
+
+func (c *simSession) Close() error {
+	s := c.server
+	s.mu.Lock()
+	delete(s.sessions, c)
+	s.mu.Unlock()
+
+	c.terminate(StatusBadSessionClosed)
+	return nil
+}
+
+func (c *simSession) notify(id NodeID, dv DataValue) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for sub := range c.subs {
+		sub.enqueue(id, dv)
+	}
+}
+
+func (c *simSession) terminate(status StatusCode) {
+	c.mu.Lock()
+	if c.done {
+		c.mu.Unlock()
+		return
+	}
+	c.done = true
+	c.status = status
+	subs := c.subs
+	c.subs = nil
+	c.mu.Unlock()
+
+	for sub := range subs {
+		sub.close(status)
+	}
+}
+
+// simSubscription — подписка стенда: изменения копятся и публикуются раз в период
+type simSubscription struct {
+	session *simSession
+	nodes   map[NodeID]bool
+	ch      chan Notification
+	stop    chan struct{}
+
+	mu      sync.Mutex
+	pending []Notification
+	err     error
+	closed  bool
+}
+
+func (sub *simSubscription) Notifications() <-chan Notification { return sub.ch }
+
+func (sub *simSubscription) Err() error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.err
+}
+
+func (sub *simSubscription) Close() error {
+	c := sub.session
+	c.mu.Lock()
+	delete(c.subs, sub)
+	c.mu.Unlock()
+
+	sub.close(nil)
+	return nil
+}
+
+func (sub *simSubscription) enqueue(id NodeID, dv DataValue) {
+	if !sub.nodes[id] {
+		return
+	}
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	sub.pending = append(sub.pending, Notification{NodeID: id, Value: dv})
+}
+
+func (sub *simSubscription) close(err error) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	sub.err = err
+	close(sub.stop)
+}
+
+// publish передает накопленные уведомления раз в период и закрывает канал при закрытии подписки
+func (sub *simSubscription) publish(interval time.Duration) {
+	defer close(sub.ch)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		sub.mu.Lock()
+		batch := sub.pending
+		sub.pending = nil
+		sub.mu.Unlock()
+
+		for _, n := range batch {
+			select {
+			case sub.ch <- n:
+			case <-sub.stop:
+				return
+			}
+		}
+
+		select {
+		case <-sub.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package opcua
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	reactorTemp NodeID = "ns=2;s=TOB.PP.R101.Temperature"
+	reactorUnit NodeID = "ns=2;s=TOB.PP.R101"
+)
+
+// forEachTransport выполняет тест со стендом in-process (sim://) и по OPC UA Binary (opc.tcp://)
+func forEachTransport(t *testing.T, test func(t *testing.T, scheme string)) {
+	for _, scheme := range []string{simScheme, tcpScheme} {
+		t.Run(scheme, func(t *testing.T) { test(t, scheme) })
+	}
+}
+
+// serverName — имя стенда теста; "/" подтестов недопустим в адресе sim://
+func serverName(t *testing.T) string {
+	return strings.ReplaceAll(t.Name(), "/", "-")
+}
+
+// startServer запускает стенд с установкой и переменной температуры реактора и возвращает
+// его адрес для транспорта scheme
+func startServer(t *testing.T, scheme string) (*Server, string) {
+	t.Helper()
+	srv, err := NewServer(serverName(t))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	if err := srv.AddObject(ObjectsFolder, reactorUnit, "R101"); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.AddVariable(reactorUnit, reactorTemp, "Temperature", 71.5); err != nil {
+		t.Fatal(err)
+	}
+	return srv, listen(t, srv, scheme, "127.0.0.1:0")
+}
+
+// listen возвращает адрес стенда: sim:// или opc.tcp:// на адресе addr
+func listen(t *testing.T, srv *Server, scheme, addr string) string {
+	t.Helper()
+	if scheme == simScheme {
+		return srv.Endpoint()
+	}
+	endpoint, err := srv.ListenTCP(addr)
+	if err != nil {
+		t.Fatalf("ListenTCP: %v", err)
+	}
+	return endpoint
+}
+
+func dial(t *testing.T, endpoint string) Session {
+	t.Helper()
+	session, err := Dial(context.Background(), endpoint, Options{})
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func receive(t *testing.T, sub Subscription) Notification {
+	t.Helper()
+	select {
+	case n, ok := <-sub.Notifications():
+		if !ok {
+			t.Fatalf("subscription closed: %v", sub.Err())
+		}
+		return n
+	case <-time.After(2 * time.Second):
+		t.Fatal("no notification within 2s")
+	}
+	return Notification{}
+}
+
+func TestBrowseAndRead(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, scheme string) {
+		_, endpoint := startServer(t, scheme)
+		session := dial(t, endpoint)
+		ctx := context.Background()
+
+		refs, err := session.Browse(ctx, ObjectsFolder)
+		if err != nil {
+			t.Fatalf("Browse: %v", err)
+		}
+		if len(refs) != 1 || refs[0].NodeID != reactorUnit || refs[0].NodeClass != NodeClassObject {
+			t.Fatalf("Browse(Objects) = %+v, want the R101 object", refs)
+		}
+
+		values, err := session.Read(ctx, []NodeID{reactorTemp, reactorUnit, "ns=2;s=TOB.PP.R102"})
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if v, ok := values[0].Float64(); !ok || v != 71.5 || values[0].Status != StatusGood {
+			t.Errorf("Read(temperature) = %+v, want 71.5 good", values[0])
+		}
+		// Ошибки отдельных узлов возвращаются в статусе значения
+		if values[1].Status != StatusBadNodeIDInvalid {
+			t.Errorf("Read(object) status = %v, want BadNodeIdInvalid", values[1].Status)
+		}
+		if values[2].Status != StatusBadNodeIDUnknown {
+			t.Errorf("Read(unknown) status = %v, want BadNodeIdUnknown", values[2].Status)
+		}
+	})
+}
+
+func TestSubscriptionDelivery(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, scheme string) {
+		srv, endpoint := startServer(t, scheme)
+		session := dial(t, endpoint)
+
+		sub, err := session.Subscribe(context.Background(), 10*time.Millisecond, []NodeID{reactorTemp})
+		if err != nil {
+			t.Fatalf("Subscribe: %v", err)
+		}
+		defer sub.Close()
+
+		// Первым приходит текущее значение
+		if n := receive(t, sub); n.NodeID != reactorTemp || n.Value.Value != 71.5 {
+			t.Fatalf("initial notification = %+v, want 71.5", n)
+		}
+
+		// Метка и статус источника передаются без изменений
+		source := time.Date(2025, 3, 14, 8, 30, 0, 0, time.UTC)
+		if err := srv.SetValue(reactorTemp, 73.25, StatusUncertainLastUsableValue, source); err != nil {
+			t.Fatal(err)
+		}
+		n := receive(t, sub)
+		if n.Value.Value != 73.25 || n.Value.Status != StatusUncertainLastUsableValue {
+			t.Errorf("notification = %+v, want 73.25 UncertainLastUsableValue", n.Value)
+		}
+		if !n.Value.SourceTimestamp.Equal(source) || !n.Value.Timestamp().Equal(source) {
+			t.Errorf("source timestamp = %v, want %v", n.Value.SourceTimestamp, source)
+		}
+		if n.Value.ServerTimestamp.Before(source) {
+			t.Errorf("server timestamp %v is before the source timestamp", n.Value.ServerTimestamp)
+		}
+
+		// Изменения за период публикуются по порядку
+		srv.SetValue(reactorTemp, 74.0, StatusGood, time.Time{})
+		srv.SetValue(reactorTemp, 0.0, StatusBadSensorFailure, time.Time{})
+		if n := receive(t, sub); n.Value.Value != 74.0 || !n.Value.Status.IsGood() {
+			t.Errorf("notification = %+v, want 74 good", n.Value)
+		}
+		if n := receive(t, sub); !n.Value.Status.IsBad() || n.Value.SourceTimestamp.IsZero() {
+			t.Errorf("notification = %+v, want BadSensorFailure with a source timestamp", n.Value)
+		}
+	})
+}
+
+func TestDropSessions(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, scheme string) {
+		srv, endpoint := startServer(t, scheme)
+		session := dial(t, endpoint)
+		ctx := context.Background()
+
+		sub, err := session.Subscribe(ctx, 10*time.Millisecond, []NodeID{reactorTemp})
+		if err != nil {
+			t.Fatalf("Subscribe: %v", err)
+		}
+		receive(t, sub)
+
+		srv.DropSessions(StatusBadSessionIDInvalid)
+		if srv.Sessions() != 0 {
+			t.Errorf("Sessions = %d after drop, want 0", srv.Sessions())
+		}
+
+		// Канал закрывается, причина доступна в Err и в ошибках сессии
+		select {
+		case _, ok := <-sub.Notifications():
+			if ok {
+				t.Fatal("notification after the session was dropped")
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("subscription channel is not closed")
+		}
+		if !errors.Is(sub.Err(), StatusBadSessionIDInvalid) {
+			t.Errorf("Err = %v, want BadSessionIdInvalid", sub.Err())
+		}
+		if _, err := session.Read(ctx, []NodeID{reactorTemp}); !errors.Is(err, StatusBadSessionIDInvalid) {
+			t.Errorf("Read after drop: %v, want BadSessionIdInvalid", err)
+		}
+
+		// Новая сессия получает значения, записанные после сброса
+		srv.SetValue(reactorTemp, 75.0, StatusGood, time.Time{})
+		values, err := dial(t, endpoint).Read(ctx, []NodeID{reactorTemp})
+		if err != nil || values[0].Value != 75.0 {
+			t.Fatalf("Read in a new session = %+v, %v; want 75", values, err)
+		}
+	})
+}
+
+func TestServerUnavailable(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, scheme string) {
+		srv, endpoint := startServer(t, scheme)
+		session := dial(t, endpoint)
+		ctx := context.Background()
+
+		sub, err := session.Subscribe(ctx, 10*time.Millisecond, []NodeID{reactorTemp})
+		if err != nil {
+			t.Fatalf("Subscribe: %v", err)
+		}
+		receive(t, sub)
+
+		srv.SetAvailable(false)
+		for range sub.Notifications() {
+		}
+		if !errors.Is(sub.Err(), StatusBadConnectionClosed) {
+			t.Errorf("Err = %v, want BadConnectionClosed", sub.Err())
+		}
+		if _, err := Dial(ctx, endpoint, Options{}); !errors.Is(err, StatusBadNotConnected) {
+			t.Fatalf("Dial while unavailable: %v, want BadNotConnected", err)
+		}
+
+		srv.SetAvailable(true)
+		dial(t, endpoint)
+		if srv.Sessions() != 1 {
+			t.Errorf("Sessions = %d, want 1", srv.Sessions())
+		}
+	})
+}
+
+func TestServerRestart(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, scheme string) {
+		srv, err := NewServer(serverName(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewServer(serverName(t)); err == nil {
+			t.Fatal("NewServer with a taken name succeeded")
+		}
+		endpoint := listen(t, srv, scheme, "127.0.0.1:0")
+		srv.Close()
+
+		if _, err := Dial(context.Background(), endpoint, Options{}); !errors.Is(err, StatusBadNotConnected) {
+			t.Fatalf("Dial to a closed server: %v, want BadNotConnected", err)
+		}
+
+		// Закрытый стенд освобождает имя и адрес
+		restarted, err := NewServer(serverName(t))
+		if err != nil {
+			t.Fatalf("NewServer after Close: %v", err)
+		}
+		defer restarted.Close()
+		if got := listen(t, restarted, scheme, strings.TrimPrefix(endpoint, tcpScheme+"://")); got != endpoint {
+			t.Fatalf("Endpoint = %s, want %s", got, endpoint)
+		}
+		dial(t, endpoint)
+	})
+}
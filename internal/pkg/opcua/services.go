@@ -0,0 +1,896 @@
+package opcua
+
+import "time"
+
+// Сообщения сервисов OPC UA, которые используют драйвер opc.tcp и стенд. Каждая структура
+// кодирует и разбирает тело сообщения после заголовка запроса или ответа; поля, которые
+// не нужны коннектору, записываются пустыми и пропускаются при чтении.
+
+// Идентификаторы двоичного кодирования (пространство имен 0)
+const (
+	idServiceFault                    uint32 = 397
+	idAnonymousIdentityToken          uint32 = 321
+	idUserNameIdentityToken           uint32 = 324
+	idOpenSecureChannelRequest        uint32 = 446
+	idOpenSecureChannelResponse       uint32 = 449
+	idCloseSecureChannelRequest       uint32 = 452
+	idCreateSessionRequest            uint32 = 461
+	idCreateSessionResponse           uint32 = 464
+	idActivateSessionRequest          uint32 = 467
+	idActivateSessionResponse         uint32 = 470
+	idCloseSessionRequest             uint32 = 473
+	idCloseSessionResponse            uint32 = 476
+	idBrowseRequest                   uint32 = 527
+	idBrowseResponse                  uint32 = 530
+	idBrowseNextRequest               uint32 = 533
+	idBrowseNextResponse              uint32 = 536
+	idReadRequest                     uint32 = 631
+	idReadResponse                    uint32 = 634
+	idCreateMonitoredItemsRequest     uint32 = 751
+	idCreateMonitoredItemsResponse    uint32 = 754
+	idCreateSubscriptionRequest       uint32 = 787
+	idCreateSubscriptionResponse      uint32 = 790
+	idDataChangeNotification          uint32 = 811
+	idStatusChangeNotification        uint32 = 820
+	idPublishRequest                  uint32 = 826
+	idPublishResponse                 uint32 = 829
+	idDeleteSubscriptionsRequest      uint32 = 847
+	idDeleteSubscriptionsResponse     uint32 = 850
+	idHierarchicalReferences          uint32 = 33
+	idOrganizes                       uint32 = 35
+	idHasComponent                    uint32 = 47
+	idFolderType                      uint32 = 61
+	idBaseDataVariableType            uint32 = 63
+	attributeValue                    uint32 = 13
+	timestampsBoth                    uint32 = 2
+	monitoringReporting               uint32 = 2
+	securityModeNone                  uint32 = 1
+	tokenAnonymous                    uint32 = 0
+	tokenUserName                     uint32 = 1
+	requestIssue                      uint32 = 0
+	requestRenew                      uint32 = 1
+	browseResultAll                   uint32 = 0x3F
+	transportProfileBinary                   = "http://opcfoundation.org/UA-Profile/Transport/uatcp-uasc-uabinary"
+	statusBadAttributeIDInvalid              = StatusCode(0x80350000)
+	statusBadContinuationPointInvalid        = StatusCode(0x804A0000)
+)
+
+// service — тело сообщения сервиса
+type service interface {
+	typeID() uint32
+	encode(e *encoder)
+	decode(d *decoder)
+}
+
+// emptyArray записывает массив без элементов
+func (e *encoder) emptyArray() { e.int32(0) }
+
+// signatureData записывает пустую подпись: без политики безопасности подписи не нужны
+func (e *encoder) signatureData() {
+	e.string("")
+	e.bytes(nil)
+}
+
+func (d *decoder) signatureData() {
+	d.string()
+	d.bytes()
+}
+
+type requestHeader struct {
+	authToken   nodeID
+	timestamp   time.Time
+	handle      uint32
+	timeoutHint uint32 // мс; 0 — без ограничения
+}
+
+func (h *requestHeader) encode(e *encoder) {
+	e.nodeID(h.authToken)
+	e.time(h.timestamp)
+	e.uint32(h.handle)
+	e.uint32(0) // ReturnDiagnostics
+	e.string("")
+	e.uint32(h.timeoutHint)
+	e.extensionObject(0, nil)
+}
+
+func (h *requestHeader) decode(d *decoder) {
+	h.authToken = d.nodeID()
+	h.timestamp = d.time()
+	h.handle = d.uint32()
+	d.uint32()
+	d.string()
+	h.timeoutHint = d.uint32()
+	d.extensionObject()
+}
+
+type responseHeader struct {
+	timestamp time.Time
+	handle    uint32
+	result    StatusCode
+}
+
+func (h *responseHeader) encode(e *encoder) {
+	e.time(h.timestamp)
+	e.uint32(h.handle)
+	e.uint32(uint32(h.result))
+	e.byte(0) // ServiceDiagnostics
+	e.emptyArray()
+	e.extensionObject(0, nil)
+}
+
+func (h *responseHeader) decode(d *decoder) {
+	h.timestamp = d.time()
+	h.handle = d.uint32()
+	h.result = StatusCode(d.uint32())
+	d.diagnosticInfo()
+	for n := d.length(); n > 0 && d.err == nil; n-- {
+		d.string()
+	}
+	d.extensionObject()
+}
+
+// serviceFault — ответ на запрос, завершившийся ошибкой: код в заголовке ответа
+type serviceFault struct{}
+
+func (*serviceFault) typeID() uint32  { return idServiceFault }
+func (*serviceFault) encode(*encoder) {}
+func (*serviceFault) decode(*decoder) {}
+
+type openSecureChannelRequest struct {
+	requestType uint32
+	lifetime    uint32 // мс
+}
+
+func (*openSecureChannelRequest) typeID() uint32 { return idOpenSecureChannelRequest }
+
+func (r *openSecureChannelRequest) encode(e *encoder) {
+	e.uint32(0) // ClientProtocolVersion
+	e.uint32(r.requestType)
+	e.uint32(securityModeNone)
+	e.bytes(nil)
+	e.uint32(r.lifetime)
+}
+
+func (r *openSecureChannelRequest) decode(d *decoder) {
+	d.uint32()
+	r.requestType = d.uint32()
+	d.uint32()
+	d.bytes()
+	r.lifetime = d.uint32()
+}
+
+type openSecureChannelResponse struct {
+	channelID uint32
+	tokenID   uint32
+	createdAt time.Time
+	lifetime  uint32 // мс
+}
+
+func (*openSecureChannelResponse) typeID() uint32 { return idOpenSecureChannelResponse }
+
+func (r *openSecureChannelResponse) encode(e *encoder) {
+	e.uint32(0) // ServerProtocolVersion
+	e.uint32(r.channelID)
+	e.uint32(r.tokenID)
+	e.time(r.createdAt)
+	e.uint32(r.lifetime)
+	e.bytes(nil)
+}
+
+func (r *openSecureChannelResponse) decode(d *decoder) {
+	d.uint32()
+	r.channelID = d.uint32()
+	r.tokenID = d.uint32()
+	r.createdAt = d.time()
+	r.lifetime = d.uint32()
+	d.bytes()
+}
+
+// closeSecureChannelRequest отправляется сообщением CLO и не получает ответа
+type closeSecureChannelRequest struct{}
+
+func (*closeSecureChannelRequest) typeID() uint32  { return idCloseSecureChannelRequest }
+func (*closeSecureChannelRequest) encode(*encoder) {}
+func (*closeSecureChannelRequest) decode(*decoder) {}
+
+// applicationDescription записывает описание приложения
+func (e *encoder) applicationDescription(uri, name string, appType uint32) {
+	e.string(uri)
+	e.string(uri) // ProductUri
+	e.localizedText(name)
+	e.uint32(appType)
+	e.string("") // GatewayServerUri
+	e.string("") // DiscoveryProfileUri
+	e.emptyArray()
+}
+
+func (d *decoder) applicationDescription() {
+	d.string()
+	d.string()
+	d.localizedText()
+	d.uint32()
+	d.string()
+	d.string()
+	for n := d.length(); n > 0 && d.err == nil; n-- {
+		d.string()
+	}
+}
+
+type createSessionRequest struct {
+	endpointURL string
+	sessionName string
+	nonce       []byte
+	timeout     float64 // мс
+}
+
+func (*createSessionRequest) typeID() uint32 { return idCreateSessionRequest }
+
+func (r *createSessionRequest) encode(e *encoder) {
+	e.applicationDescription(clientApplicationURI, clientApplicationName, 1)
+	e.string("") // ServerUri
+	e.string(r.endpointURL)
+	e.string(r.sessionName)
+	e.bytes(r.nonce)
+	e.bytes(nil) // ClientCertificate
+	e.double(r.timeout)
+	e.uint32(0) // MaxResponseMessageSize
+}
+
+func (r *createSessionRequest) decode(d *decoder) {
+	d.applicationDescription()
+	d.string()
+	r.endpointURL = d.string()
+	r.sessionName = d.string()
+	r.nonce = d.bytes()
+	d.bytes()
+	r.timeout = d.double()
+	d.uint32()
+}
+
+type userTokenPolicy struct {
+	policyID       string
+	tokenType      uint32
+	securityPolicy string
+}
+
+type endpointDescription struct {
+	url            string
+	securityMode   uint32
+	securityPolicy string
+	tokens         []userTokenPolicy
+}
+
+func (ep *endpointDescription) encode(e *encoder) {
+	e.string(ep.url)
+	e.applicationDescription(serverApplicationURI, serverApplicationName, 0)
+	e.bytes(nil) // ServerCertificate
+	e.uint32(ep.securityMode)
+	e.string(ep.securityPolicy)
+	e.int32(int32(len(ep.tokens)))
+	for _, t := range ep.tokens {
+		e.string(t.policyID)
+		e.uint32(t.tokenType)
+		e.string("") // IssuedTokenType
+		e.string("") // IssuerEndpointUrl
+		e.string(t.securityPolicy)
+	}
+	e.string(transportProfileBinary)
+	e.byte(0) // SecurityLevel
+}
+
+func (ep *endpointDescription) decode(d *decoder) {
+	ep.url = d.string()
+	d.applicationDescription()
+	d.bytes()
+	ep.securityMode = d.uint32()
+	ep.securityPolicy = d.string()
+	ep.tokens = make([]userTokenPolicy, d.length())
+	for i := range ep.tokens {
+		t := &ep.tokens[i]
+		t.policyID = d.string()
+		t.tokenType = d.uint32()
+		d.string()
+		d.string()
+		t.securityPolicy = d.string()
+	}
+	d.string()
+	d.byte()
+}
+
+type createSessionResponse struct {
+	sessionID nodeID
+	authToken nodeID
+	timeout   float64 // мс
+	endpoints []endpointDescription
+}
+
+func (*createSessionResponse) typeID() uint32 { return idCreateSessionResponse }
+
+func (r *createSessionResponse) encode(e *encoder) {
+	e.nodeID(r.sessionID)
+	e.nodeID(r.authToken)
+	e.double(r.timeout)
+	e.bytes(nil) // ServerNonce
+	e.bytes(nil) // ServerCertificate
+	e.int32(int32(len(r.endpoints)))
+	for i := range r.endpoints {
+		r.endpoints[i].encode(e)
+	}
+	e.emptyArray() // ServerSoftwareCertificates
+	e.signatureData()
+	e.uint32(0) // MaxRequestMessageSize
+}
+
+func (r *createSessionResponse) decode(d *decoder) {
+	r.sessionID = d.nodeID()
+	r.authToken = d.nodeID()
+	r.timeout = d.double()
+	d.bytes()
+	d.bytes()
+	r.endpoints = make([]endpointDescription, d.length())
+	for i := range r.endpoints {
+		r.endpoints[i].decode(d)
+	}
+	for n := d.length(); n > 0 && d.err == nil; n-- {
+		d.bytes()
+		d.bytes()
+	}
+	d.signatureData()
+	d.uint32()
+}
+
+// activateSessionRequest предъявляет анонимный токен или имя пользователя с паролем.
+// Без политики безопасности пароль передается открытым текстом.
+type activateSessionRequest struct {
+	tokenType uint32
+	policyID  string
+	username  string
+	password  string
+}
+
+func (*activateSessionRequest) typeID() uint32 { return idActivateSessionRequest }
+
+func (r *activateSessionRequest) encode(e *encoder) {
+	e.signatureData()
+	e.emptyArray() // ClientSoftwareCertificates
+	e.emptyArray() // LocaleIds
+	if r.tokenType == tokenUserName {
+		e.extensionObject(idUserNameIdentityToken, func(e *encoder) {
+			e.string(r.policyID)
+			e.string(r.username)
+			e.bytes([]byte(r.password))
+			e.string("") // EncryptionAlgorithm
+		})
+	} else {
+		e.extensionObject(idAnonymousIdentityToken, func(e *encoder) {
+			e.string(r.policyID)
+		})
+	}
+	e.signatureData()
+}
+
+func (r *activateSessionRequest) decode(d *decoder) {
+	d.signatureData()
+	for n := d.length(); n > 0 && d.err == nil; n-- {
+		d.bytes()
+		d.bytes()
+	}
+	for n := d.length(); n > 0 && d.err == nil; n-- {
+		d.string()
+	}
+	typeID, body := d.extensionObject()
+	token := &decoder{buf: body}
+	r.policyID = token.string()
+	if typeID == idUserNameIdentityToken {
+		r.tokenType = tokenUserName
+		r.username = token.string()
+		r.password = string(token.bytes())
+	}
+	d.signatureData()
+}
+
+type activateSessionResponse struct{}
+
+func (*activateSessionResponse) typeID() uint32 { return idActivateSessionResponse }
+
+func (*activateSessionResponse) encode(e *encoder) {
+	e.bytes(nil) // ServerNonce
+	e.emptyArray()
+	e.emptyArray()
+}
+
+func (*activateSessionResponse) decode(d *decoder) {
+	d.bytes()
+	d.statusCodes()
+	d.diagnosticInfos()
+}
+
+type closeSessionRequest struct {
+	deleteSubscriptions bool
+}
+
+func (*closeSessionRequest) typeID() uint32      { return idCloseSessionRequest }
+func (r *closeSessionRequest) encode(e *encoder) { e.boolean(r.deleteSubscriptions) }
+func (r *closeSessionRequest) decode(d *decoder) { r.deleteSubscriptions = d.boolean() }
+
+type closeSessionResponse struct{}
+
+func (*closeSessionResponse) typeID() uint32  { return idCloseSessionResponse }
+func (*closeSessionResponse) encode(*encoder) {}
+func (*closeSessionResponse) decode(*decoder) {}
+
+// readValueID записывает ссылку на атрибут узла
+func (e *encoder) readValueID(node nodeID, attribute uint32) {
+	e.nodeID(node)
+	e.uint32(attribute)
+	e.string("") // IndexRange
+	e.qualifiedName(0, "")
+}
+
+func (d *decoder) readValueID() (nodeID, uint32) {
+	node := d.nodeID()
+	attribute := d.uint32()
+	d.string()
+	d.qualifiedName()
+	return node, attribute
+}
+
+type readRequest struct {
+	nodes      []nodeID
+	attributes []uint32 // Заполняется при разборе; при записи читается атрибут Value
+}
+
+func (*readRequest) typeID() uint32 { return idReadRequest }
+
+func (r *readRequest) encode(e *encoder) {
+	e.double(0) // MaxAge
+	e.uint32(timestampsBoth)
+	e.int32(int32(len(r.nodes)))
+	for _, n := range r.nodes {
+		e.readValueID(n, attributeValue)
+	}
+}
+
+func (r *readRequest) decode(d *decoder) {
+	d.double()
+	d.uint32()
+	n := d.length()
+	r.nodes = make([]nodeID, n)
+	r.attributes = make([]uint32, n)
+	for i := range r.nodes {
+		r.nodes[i], r.attributes[i] = d.readValueID()
+	}
+}
+
+type readResponse struct {
+	values []DataValue
+}
+
+func (*readResponse) typeID() uint32 { return idReadResponse }
+
+func (r *readResponse) encode(e *encoder) {
+	e.int32(int32(len(r.values)))
+	for _, v := range r.values {
+		e.dataValue(v)
+	}
+	e.emptyArray()
+}
+
+func (r *readResponse) decode(d *decoder) {
+	r.values = make([]DataValue, d.length())
+	for i := range r.values {
+		r.values[i] = d.dataValue()
+	}
+	d.diagnosticInfos()
+}
+
+type browseRequest struct {
+	nodes []nodeID
+}
+
+func (*browseRequest) typeID() uint32 { return idBrowseRequest }
+
+func (r *browseRequest) encode(e *encoder) {
+	e.nodeID(numericNodeID(0, 0)) // View
+	e.time(time.Time{})
+	e.uint32(0)
+	e.uint32(0) // RequestedMaxReferencesPerNode
+	e.int32(int32(len(r.nodes)))
+	for _, n := range r.nodes {
+		e.nodeID(n)
+		e.uint32(0) // Forward
+		e.nodeID(numericNodeID(0, idHierarchicalReferences))
+		e.boolean(true) // IncludeSubtypes
+		e.uint32(0)     // NodeClassMask: все классы
+		e.uint32(browseResultAll)
+	}
+}
+
+func (r *browseRequest) decode(d *decoder) {
+	d.nodeID()
+	d.time()
+	d.uint32()
+	d.uint32()
+	r.nodes = make([]nodeID, d.length())
+	for i := range r.nodes {
+		r.nodes[i] = d.nodeID()
+		d.uint32()
+		d.nodeID()
+		d.boolean()
+		d.uint32()
+		d.uint32()
+	}
+}
+
+type browseResult struct {
+	status       StatusCode
+	continuation []byte
+	refs         []Reference
+}
+
+func (r *browseResult) encode(e *encoder) {
+	e.uint32(uint32(r.status))
+	e.bytes(r.continuation)
+	e.int32(int32(len(r.refs)))
+	for _, ref := range r.refs {
+		id, _ := parseNodeID(ref.NodeID)
+		refType, typeDef := idOrganizes, idFolderType
+		if ref.NodeClass == NodeClassVariable {
+			refType, typeDef = idHasComponent, idBaseDataVariableType
+		}
+		e.nodeID(numericNodeID(0, refType))
+		e.boolean(true) // IsForward
+		e.nodeID(id)
+		e.qualifiedName(id.ns, ref.BrowseName)
+		e.localizedText(ref.DisplayName)
+		e.uint32(uint32(ref.NodeClass))
+		e.nodeID(numericNodeID(0, typeDef))
+	}
+}
+
+func (r *browseResult) decode(d *decoder) {
+	r.status = StatusCode(d.uint32())
+	r.continuation = d.bytes()
+	r.refs = make([]Reference, d.length())
+	for i := range r.refs {
+		ref := &r.refs[i]
+		d.nodeID()
+		d.boolean()
+		ref.NodeID = d.expandedNodeID().NodeID()
+		ref.BrowseName = d.qualifiedName()
+		ref.DisplayName = d.localizedText()
+		ref.NodeClass = NodeClass(d.uint32())
+		d.expandedNodeID()
+	}
+}
+
+type browseResponse struct {
+	results []browseResult
+}
+
+func (*browseResponse) typeID() uint32 { return idBrowseResponse }
+
+func (r *browseResponse) encode(e *encoder) {
+	e.int32(int32(len(r.results)))
+	for i := range r.results {
+		r.results[i].encode(e)
+	}
+	e.emptyArray()
+}
+
+func (r *browseResponse) decode(d *decoder) {
+	r.results = make([]browseResult, d.length())
+	for i := range r.results {
+		r.results[i].decode(d)
+	}
+	d.diagnosticInfos()
+}
+
+type browseNextRequest struct {
+	release bool
+	points  [][]byte
+}
+
+func (*browseNextRequest) typeID() uint32 { return idBrowseNextRequest }
+
+func (r *browseNextRequest) encode(e *encoder) {
+	e.boolean(r.release)
+	e.int32(int32(len(r.points)))
+	for _, p := range r.points {
+		e.bytes(p)
+	}
+}
+
+func (r *browseNextRequest) decode(d *decoder) {
+	r.release = d.boolean()
+	r.points = make([][]byte, d.length())
+	for i := range r.points {
+		r.points[i] = d.bytes()
+	}
+}
+
+// browseNextResponse совпадает по составу с ответом Browse
+type browseNextResponse struct {
+	browseResponse
+}
+
+func (*browseNextResponse) typeID() uint32 { return idBrowseNextResponse }
+
+type createSubscriptionRequest struct {
+	interval  float64 // мс
+	lifetime  uint32
+	keepAlive uint32
+}
+
+func (*createSubscriptionRequest) typeID() uint32 { return idCreateSubscriptionRequest }
+
+func (r *createSubscriptionRequest) encode(e *encoder) {
+	e.double(r.interval)
+	e.uint32(r.lifetime)
+	e.uint32(r.keepAlive)
+	e.uint32(0)     // MaxNotificationsPerPublish
+	e.boolean(true) // PublishingEnabled
+	e.byte(0)       // Priority
+}
+
+func (r *createSubscriptionRequest) decode(d *decoder) {
+	r.interval = d.double()
+	r.lifetime = d.uint32()
+	r.keepAlive = d.uint32()
+	d.uint32()
+	d.boolean()
+	d.byte()
+}
+
+type createSubscriptionResponse struct {
+	id        uint32
+	interval  float64 // мс
+	lifetime  uint32
+	keepAlive uint32
+}
+
+func (*createSubscriptionResponse) typeID() uint32 { return idCreateSubscriptionResponse }
+
+func (r *createSubscriptionResponse) encode(e *encoder) {
+	e.uint32(r.id)
+	e.double(r.interval)
+	e.uint32(r.lifetime)
+	e.uint32(r.keepAlive)
+}
+
+func (r *createSubscriptionResponse) decode(d *decoder) {
+	r.id = d.uint32()
+	r.interval = d.double()
+	r.lifetime = d.uint32()
+	r.keepAlive = d.uint32()
+}
+
+type monitoredItem struct {
+	node     nodeID
+	handle   uint32
+	sampling float64 // мс
+	queue    uint32
+}
+
+type createMonitoredItemsRequest struct {
+	subscriptionID uint32
+	items          []monitoredItem
+}
+
+func (*createMonitoredItemsRequest) typeID() uint32 { return idCreateMonitoredItemsRequest }
+
+func (r *createMonitoredItemsRequest) encode(e *encoder) {
+	e.uint32(r.subscriptionID)
+	e.uint32(timestampsBoth)
+	e.int32(int32(len(r.items)))
+	for _, it := range r.items {
+		e.readValueID(it.node, attributeValue)
+		e.uint32(monitoringReporting)
+		e.uint32(it.handle)
+		e.double(it.sampling)
+		e.extensionObject(0, nil) // Filter
+		e.uint32(it.queue)
+		e.boolean(true) // DiscardOldest
+	}
+}
+
+func (r *createMonitoredItemsRequest) decode(d *decoder) {
+	r.subscriptionID = d.uint32()
+	d.uint32()
+	r.items = make([]monitoredItem, d.length())
+	for i := range r.items {
+		it := &r.items[i]
+		it.node, _ = d.readValueID()
+		d.uint32()
+		it.handle = d.uint32()
+		it.sampling = d.double()
+		d.extensionObject()
+		it.queue = d.uint32()
+		d.boolean()
+	}
+}
+
+type monitoredItemResult struct {
+	status StatusCode
+	id     uint32
+}
+
+type createMonitoredItemsResponse struct {
+	results []monitoredItemResult
+}
+
+func (*createMonitoredItemsResponse) typeID() uint32 { return idCreateMonitoredItemsResponse }
+
+func (r *createMonitoredItemsResponse) encode(e *encoder) {
+	e.int32(int32(len(r.results)))
+	for _, res := range r.results {
+		e.uint32(uint32(res.status))
+		e.uint32(res.id)
+		e.double(0) // RevisedSamplingInterval
+		e.uint32(0) // RevisedQueueSize
+		e.extensionObject(0, nil)
+	}
+	e.emptyArray()
+}
+
+func (r *createMonitoredItemsResponse) decode(d *decoder) {
+	r.results = make([]monitoredItemResult, d.length())
+	for i := range r.results {
+		res := &r.results[i]
+		res.status = StatusCode(d.uint32())
+		res.id = d.uint32()
+		d.double()
+		d.uint32()
+		d.extensionObject()
+	}
+	d.diagnosticInfos()
+}
+
+type subscriptionAck struct {
+	subscriptionID uint32
+	sequence       uint32
+}
+
+type publishRequest struct {
+	acks []subscriptionAck
+}
+
+func (*publishRequest) typeID() uint32 { return idPublishRequest }
+
+func (r *publishRequest) encode(e *encoder) {
+	e.int32(int32(len(r.acks)))
+	for _, a := range r.acks {
+		e.uint32(a.subscriptionID)
+		e.uint32(a.sequence)
+	}
+}
+
+func (r *publishRequest) decode(d *decoder) {
+	r.acks = make([]subscriptionAck, d.length())
+	for i := range r.acks {
+		r.acks[i] = subscriptionAck{subscriptionID: d.uint32(), sequence: d.uint32()}
+	}
+}
+
+// itemValue — значение отслеживаемого элемента в DataChangeNotification
+type itemValue struct {
+	handle uint32
+	value  DataValue
+}
+
+// publishResponse — сообщение уведомлений подписки. Сообщение без уведомлений
+// (keep-alive) только подтверждает, что подписка жива, и не требует подтверждения.
+type publishResponse struct {
+	subscriptionID uint32
+	sequence       uint32
+	publishTime    time.Time
+	values         []itemValue
+	status         StatusCode // StatusChangeNotification: подписка завершена сервером
+	hasStatus      bool
+	keepAlive      bool
+	acks           []StatusCode
+}
+
+func (*publishResponse) typeID() uint32 { return idPublishResponse }
+
+func (r *publishResponse) encode(e *encoder) {
+	e.uint32(r.subscriptionID)
+	e.emptyArray()   // AvailableSequenceNumbers
+	e.boolean(false) // MoreNotifications
+	e.uint32(r.sequence)
+	e.time(r.publishTime)
+	switch {
+	case r.hasStatus:
+		e.int32(1)
+		e.extensionObject(idStatusChangeNotification, func(e *encoder) {
+			e.uint32(uint32(r.status))
+			e.byte(0)
+		})
+	case r.keepAlive:
+		e.emptyArray()
+	default:
+		e.int32(1)
+		e.extensionObject(idDataChangeNotification, func(e *encoder) {
+			e.int32(int32(len(r.values)))
+			for _, v := range r.values {
+				e.uint32(v.handle)
+				e.dataValue(v.value)
+			}
+			e.emptyArray()
+		})
+	}
+	e.int32(int32(len(r.acks)))
+	for _, s := range r.acks {
+		e.uint32(uint32(s))
+	}
+	e.emptyArray()
+}
+
+func (r *publishResponse) decode(d *decoder) {
+	r.subscriptionID = d.uint32()
+	for n := d.length(); n > 0; n-- {
+		d.uint32()
+	}
+	d.boolean()
+	r.sequence = d.uint32()
+	r.publishTime = d.time()
+
+	n := d.length()
+	r.keepAlive = n == 0
+	for ; n > 0 && d.err == nil; n-- {
+		typeID, body := d.extensionObject()
+		inner := &decoder{buf: body}
+		switch typeID {
+		case idDataChangeNotification:
+			for m := inner.length(); m > 0 && inner.err == nil; m-- {
+				r.values = append(r.values, itemValue{handle: inner.uint32(), value: inner.dataValue()})
+			}
+		case idStatusChangeNotification:
+			r.status, r.hasStatus = StatusCode(inner.uint32()), true
+		}
+		if inner.err != nil {
+			d.err = inner.err
+		}
+	}
+	r.acks = d.statusCodes()
+	d.diagnosticInfos()
+}
+
+type deleteSubscriptionsRequest struct {
+	ids []uint32
+}
+
+func (*deleteSubscriptionsRequest) typeID() uint32 { return idDeleteSubscriptionsRequest }
+
+func (r *deleteSubscriptionsRequest) encode(e *encoder) {
+	e.int32(int32(len(r.ids)))
+	for _, id := range r.ids {
+		e.uint32(id)
+	}
+}
+
+func (r *deleteSubscriptionsRequest) decode(d *decoder) {
+	r.ids = make([]uint32, d.length())
+	for i := range r.ids {
+		r.ids[i] = d.uint32()
+	}
+}
+
+type deleteSubscriptionsResponse struct {
+	results []StatusCode
+}
+
+func (*deleteSubscriptionsResponse) typeID() uint32 { return idDeleteSubscriptionsResponse }
+
+func (r *deleteSubscriptionsResponse) encode(e *encoder) {
+	e.int32(int32(len(r.results)))
+	for _, s := range r.results {
+		e.uint32(uint32(s))
+	}
+	e.emptyArray()
+}
+
+func (r *deleteSubscriptionsResponse) decode(d *decoder) {
+	r.results = d.statusCodes()
+	d.diagnosticInfos()
+}
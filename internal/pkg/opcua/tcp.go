@@ -0,0 +1,686 @@
+package opcua
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// tcpScheme — схема адресов серверов OPC UA по протоколу OPC UA Binary
+const tcpScheme = "opc.tcp"
+
+const (
+	clientApplicationURI  = "urn:petrochemical-data-platform:opcua-connector"
+	clientApplicationName = "Petrochemical Data Platform"
+
+	// sessionTimeout — запрашиваемое время жизни сессии без запросов
+	sessionTimeout = 2 * time.Minute
+	// keepAlivePeriod — период, за который сервер сообщает о живой подписке без изменений
+	keepAlivePeriod = 5 * time.Second
+	// monitoredQueueSize — очередь изменений элемента между публикациями
+	monitoredQueueSize = 10
+)
+
+// channelLifetime — запрашиваемое время жизни токена канала; канал обновляется на 3/4 срока
+var channelLifetime = time.Hour
+
+func init() {
+	RegisterDriver(tcpScheme, dialTCP)
+}
+
+// dialTCP открывает канал без политики безопасности и сессию с анонимным токеном или
+// именем пользователя. Ошибки до открытия сессии возвращаются с BadNotConnected, если
+// сервер не сообщил собственный код.
+func dialTCP(ctx context.Context, endpoint string, opts Options) (Session, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q: no host", endpoint)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v: %w", endpoint, err, StatusBadNotConnected)
+	}
+
+	s := &tcpSession{
+		ch:       newChannel(conn),
+		endpoint: endpoint,
+		timeout:  opts.Timeout,
+		pending:  make(map[uint32]chan message),
+		subs:     make(map[uint32]*tcpSubscription),
+		done:     make(chan struct{}),
+	}
+
+	// Установление связи прерывается по контексту: до запуска чтения ответов
+	// единственный способ прервать ожидание — срок операций соединения
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	err = s.ch.hello(endpoint)
+	if !stop() {
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, dialError(endpoint, err)
+	}
+	go s.receive()
+
+	lifetime, err := s.open(ctx, requestIssue)
+	if err == nil {
+		err = s.createSession(ctx, opts)
+	}
+	if err != nil {
+		s.fail(StatusBadConnectionClosed)
+		return nil, dialError(endpoint, err)
+	}
+
+	go s.renew(lifetime)
+	return s, nil
+}
+
+// dialError сохраняет код, полученный от сервера, а прочие ошибки относит к BadNotConnected
+func dialError(endpoint string, err error) error {
+	var status StatusCode
+	if errors.As(err, &status) && status != StatusBadConnectionClosed {
+		return fmt.Errorf("failed to connect to %s: %w", endpoint, err)
+	}
+	return fmt.Errorf("failed to connect to %s: %v: %w", endpoint, err, StatusBadNotConnected)
+}
+
+// tcpSession — сессия с сервером по opc.tcp. Ответы читает одна горутина и передает их
+// ожидающим запросам по номеру запроса; уведомления подписок приходят в ответах Publish,
+// которые запрашивает горутина публикации, пока у сессии есть подписки.
+type tcpSession struct {
+	ch       *channel
+	endpoint string
+	timeout  time.Duration
+
+	mu         sync.Mutex
+	authToken  nodeID
+	requestID  uint32
+	handle     uint32
+	pending    map[uint32]chan message
+	subs       map[uint32]*tcpSubscription
+	publishing bool
+	err        error // Причина завершения сессии
+	done       chan struct{}
+}
+
+func (s *tcpSession) check() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// fail завершает сессию: закрывает соединение, прерывает ожидающие запросы и закрывает
+// подписки с причиной err
+func (s *tcpSession) fail(err error) {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.err = err
+	subs := s.subs
+	s.subs = nil
+	close(s.done)
+	s.mu.Unlock()
+
+	s.ch.conn.Close()
+	for _, sub := range subs {
+		sub.close(err)
+	}
+}
+
+// receive читает ответы до разрыва соединения
+func (s *tcpSession) receive() {
+	for {
+		m, err := s.ch.receive()
+		if err != nil {
+			var status StatusCode
+			if !errors.As(err, &status) {
+				err = fmt.Errorf("connection to %s lost: %v: %w", s.endpoint, err, StatusBadConnectionClosed)
+			}
+			s.fail(err)
+			return
+		}
+
+		s.mu.Lock()
+		wait, ok := s.pending[m.requestID]
+		delete(s.pending, m.requestID)
+		s.mu.Unlock()
+		if ok {
+			wait <- m
+		}
+	}
+}
+
+// call выполняет запрос сервиса по каналу и разбирает ответ в resp
+func (s *tcpSession) call(ctx context.Context, msgType string, req, resp service) error {
+	wait := make(chan message, 1)
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return s.err
+	}
+	s.requestID++
+	s.handle++
+	id := s.requestID
+	header := requestHeader{authToken: s.authToken, timestamp: time.Now(), handle: s.handle}
+	s.pending[id] = wait
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		header.timeoutHint = uint32(max(time.Until(deadline).Milliseconds(), 1))
+	}
+	if err := s.ch.send(msgType, id, &header, req); err != nil {
+		err = fmt.Errorf("failed to send request to %s: %v: %w", s.endpoint, err, StatusBadConnectionClosed)
+		s.fail(err)
+		return err
+	}
+
+	var m message
+	select {
+	case m = <-wait:
+	case <-ctx.Done():
+		return fmt.Errorf("request to %s: %w: %w", s.endpoint, ctx.Err(), StatusBadTimeout)
+	case <-s.done:
+		return s.check()
+	}
+	if m.err != nil {
+		return m.err
+	}
+
+	var rh responseHeader
+	rh.decode(m.body)
+	if m.body.err != nil {
+		return errDecoding("response header", m.body.err)
+	}
+	switch {
+	case m.typeID == idServiceFault:
+		if !rh.result.IsBad() {
+			return StatusBadCommunicationError
+		}
+		return rh.result
+	case m.typeID != resp.typeID():
+		return fmt.Errorf("response %d to request %d: %w", m.typeID, req.typeID(), StatusBadDecodingError)
+	case rh.result.IsBad():
+		return rh.result
+	}
+
+	resp.decode(m.body)
+	if m.body.err != nil {
+		return errDecoding("response", m.body.err)
+	}
+	return nil
+}
+
+// open открывает канал (requestIssue) или обновляет его токен (requestRenew)
+// и возвращает время жизни токена
+func (s *tcpSession) open(ctx context.Context, requestType uint32) (time.Duration, error) {
+	var resp openSecureChannelResponse
+	req := openSecureChannelRequest{requestType: requestType, lifetime: uint32(channelLifetime.Milliseconds())}
+	if err := s.call(ctx, "OPN", &req, &resp); err != nil {
+		return 0, fmt.Errorf("failed to open secure channel: %w", err)
+	}
+	s.ch.setToken(resp.channelID, resp.tokenID)
+
+	lifetime := time.Duration(resp.lifetime) * time.Millisecond
+	if lifetime <= 0 {
+		lifetime = channelLifetime
+	}
+	return lifetime, nil
+}
+
+// renew обновляет токен канала до истечения срока его жизни
+func (s *tcpSession) renew(lifetime time.Duration) {
+	for {
+		timer := time.NewTimer(lifetime * 3 / 4)
+		select {
+		case <-s.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		next, err := s.open(ctx, requestRenew)
+		cancel()
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		lifetime = next
+	}
+}
+
+// createSession создает и активирует сессию с токеном, который предлагает сервер
+func (s *tcpSession) createSession(ctx context.Context, opts Options) error {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate session nonce: %w", err)
+	}
+
+	var created createSessionResponse
+	if err := s.call(ctx, "MSG", &createSessionRequest{
+		endpointURL: s.endpoint,
+		sessionName: clientApplicationName,
+		nonce:       nonce,
+		timeout:     float64(sessionTimeout.Milliseconds()),
+	}, &created); err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	s.mu.Lock()
+	s.authToken = created.authToken
+	s.mu.Unlock()
+
+	activate := activateSessionRequest{tokenType: tokenAnonymous, username: opts.Username, password: opts.Password}
+	if opts.Username != "" {
+		activate.tokenType = tokenUserName
+	}
+	policy, err := userTokenPolicyOf(created.endpoints, activate.tokenType)
+	if err == nil {
+		activate.policyID = policy
+		if err = s.call(ctx, "MSG", &activate, &activateSessionResponse{}); err != nil {
+			err = fmt.Errorf("failed to activate session: %w", err)
+		}
+	}
+	if err != nil {
+		// Отклоненная сессия закрывается сразу, чтобы не занимать место на сервере до таймаута
+		s.call(ctx, "MSG", &closeSessionRequest{deleteSubscriptions: true}, &closeSessionResponse{})
+		return err
+	}
+	return nil
+}
+
+// userTokenPolicyOf выбирает политику токена пользователя среди точек подключения без
+// политики безопасности. Имя пользователя передается только без шифрования пароля.
+func userTokenPolicyOf(endpoints []endpointDescription, tokenType uint32) (string, error) {
+	for _, ep := range endpoints {
+		if ep.securityPolicy != securityPolicyNone {
+			continue
+		}
+		for _, t := range ep.tokens {
+			if t.tokenType != tokenType {
+				continue
+			}
+			if tokenType == tokenUserName && t.securityPolicy != "" && t.securityPolicy != securityPolicyNone {
+				continue
+			}
+			return t.policyID, nil
+		}
+	}
+
+	if tokenType == tokenUserName {
+		return "", fmt.Errorf("server offers no unencrypted user name token: %w", StatusBadIdentityTokenRejected)
+	}
+	return "", fmt.Errorf("server offers no anonymous token: %w", StatusBadIdentityTokenRejected)
+}
+
+func (s *tcpSession) Browse(ctx context.Context, node NodeID) ([]Reference, error) {
+	id, err := parseNodeID(node)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, StatusBadNodeIDInvalid)
+	}
+
+	var resp browseResponse
+	if err := s.call(ctx, "MSG", &browseRequest{nodes: []nodeID{id}}, &resp); err != nil {
+		return nil, err
+	}
+
+	var refs []Reference
+	for {
+		if len(resp.results) != 1 {
+			return nil, fmt.Errorf("browse returned %d results for 1 node: %w", len(resp.results), StatusBadCommunicationError)
+		}
+		result := resp.results[0]
+		if result.status.IsBad() {
+			return nil, result.status
+		}
+		refs = append(refs, result.refs...)
+		if len(result.continuation) == 0 {
+			return refs, nil
+		}
+
+		next := browseNextResponse{}
+		if err := s.call(ctx, "MSG", &browseNextRequest{points: [][]byte{result.continuation}}, &next); err != nil {
+			return nil, err
+		}
+		resp = next.browseResponse
+	}
+}
+
+func (s *tcpSession) Read(ctx context.Context, nodes []NodeID) ([]DataValue, error) {
+	values := make([]DataValue, len(nodes))
+	ids, index := parseNodeIDs(nodes, values)
+	if len(ids) == 0 {
+		return values, s.check()
+	}
+
+	var resp readResponse
+	if err := s.call(ctx, "MSG", &readRequest{nodes: ids}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.values) != len(ids) {
+		return nil, fmt.Errorf("read returned %d values for %d nodes: %w", len(resp.values), len(ids), StatusBadCommunicationError)
+	}
+	for i, v := range resp.values {
+		values[index[i]] = v
+	}
+	return values, nil
+}
+
+// parseNodeIDs разбирает идентификаторы узлов запроса. Значения нечитаемых
+// идентификаторов получают BadNodeIdInvalid; index связывает разобранные узлы с nodes.
+func parseNodeIDs(nodes []NodeID, values []DataValue) (ids []nodeID, index []int) {
+	for i, node := range nodes {
+		id, err := parseNodeID(node)
+		if err != nil {
+			values[i] = DataValue{Status: StatusBadNodeIDInvalid}
+			continue
+		}
+		ids = append(ids, id)
+		index = append(index, i)
+	}
+	return ids, index
+}
+
+func (s *tcpSession) Subscribe(ctx context.Context, interval time.Duration, nodes []NodeID) (Subscription, error) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	keepAlive := uint32(max(keepAlivePeriod/interval, 1))
+
+	var resp createSubscriptionResponse
+	if err := s.call(ctx, "MSG", &createSubscriptionRequest{
+		interval:  float64(interval) / float64(time.Millisecond),
+		lifetime:  keepAlive * 3,
+		keepAlive: keepAlive,
+	}, &resp); err != nil {
+		return nil, fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	sub := &tcpSubscription{
+		session:   s,
+		id:        resp.id,
+		nodes:     nodes,
+		keepAlive: time.Duration(float64(resp.keepAlive)*resp.interval) * time.Millisecond,
+		ch:        make(chan Notification, notificationBuffer),
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+	}
+	go sub.forward()
+
+	// Подписка регистрируется до создания элементов: начальные значения могут прийти
+	// в ответе Publish, запрошенном для другой подписки сессии
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		sub.close(s.err)
+		return nil, s.err
+	}
+	s.subs[sub.id] = sub
+	s.mu.Unlock()
+
+	initial := make([]DataValue, len(nodes))
+	ids, index := parseNodeIDs(nodes, initial)
+	items := make([]monitoredItem, len(ids))
+	for i, id := range ids {
+		items[i] = monitoredItem{
+			node:     id,
+			handle:   uint32(index[i]),
+			sampling: float64(interval) / float64(time.Millisecond),
+			queue:    monitoredQueueSize,
+		}
+	}
+
+	var created createMonitoredItemsResponse
+	err := s.call(ctx, "MSG", &createMonitoredItemsRequest{subscriptionID: sub.id, items: items}, &created)
+	if err == nil && len(created.results) != len(items) {
+		err = fmt.Errorf("%d results for %d monitored items: %w", len(created.results), len(items), StatusBadCommunicationError)
+	}
+	if err != nil {
+		sub.Close()
+		return nil, fmt.Errorf("failed to create monitored items: %w", err)
+	}
+
+	// Узлы, которые сервер не принял, получают начальное значение с кодом отказа, как при
+	// чтении; начальные значения остальных приходят первой публикацией
+	monitored := make([]bool, len(nodes))
+	for i, res := range created.results {
+		if res.status.IsBad() {
+			initial[index[i]] = DataValue{Status: res.status}
+		} else {
+			monitored[index[i]] = true
+		}
+	}
+	var rejected []Notification
+	for i, v := range initial {
+		if !monitored[i] {
+			rejected = append(rejected, Notification{NodeID: nodes[i], Value: v})
+		}
+	}
+	sub.enqueue(rejected)
+
+	s.startPublishing()
+	return sub, nil
+}
+
+// startPublishing запускает горутину публикации, если она еще не запущена
+func (s *tcpSession) startPublishing() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.publishing || s.err != nil {
+		return
+	}
+	s.publishing = true
+	go s.publish()
+}
+
+// publish запрашивает уведомления, пока у сессии есть подписки. Ответ Publish сервер
+// задерживает до изменений или keep-alive; если ответа нет дольше двух периодов
+// keep-alive, соединение считается потерянным.
+func (s *tcpSession) publish() {
+	var acks []subscriptionAck
+	for {
+		s.mu.Lock()
+		if s.err != nil || len(s.subs) == 0 {
+			s.publishing = false
+			s.mu.Unlock()
+			return
+		}
+		wait := keepAlivePeriod
+		for _, sub := range s.subs {
+			wait = max(wait, sub.keepAlive)
+		}
+		s.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*wait+s.timeout)
+		var resp publishResponse
+		err := s.call(ctx, "MSG", &publishRequest{acks: acks}, &resp)
+		expired := ctx.Err() != nil
+		cancel()
+		acks = nil
+
+		switch {
+		case err == nil:
+		case expired:
+			s.fail(fmt.Errorf("no publish response from %s: %w", s.endpoint, StatusBadTimeout))
+			return
+		case errors.Is(err, StatusBadNoSubscription), errors.Is(err, StatusBadTooManyPublishRequests),
+			errors.Is(err, StatusBadTimeout):
+			// Подписки удалены или сервер отклонил лишний запрос: цикл проверит подписки заново
+			select {
+			case <-s.done:
+			case <-time.After(100 * time.Millisecond):
+			}
+			continue
+		default:
+			s.fail(err)
+			return
+		}
+
+		s.mu.Lock()
+		sub := s.subs[resp.subscriptionID]
+		if sub != nil && resp.hasStatus {
+			delete(s.subs, resp.subscriptionID)
+		}
+		s.mu.Unlock()
+		if sub == nil {
+			continue
+		}
+		if !resp.keepAlive {
+			acks = append(acks, subscriptionAck{subscriptionID: resp.subscriptionID, sequence: resp.sequence})
+		}
+		if resp.hasStatus {
+			sub.close(resp.status)
+			continue
+		}
+		sub.deliver(resp.values)
+	}
+}
+
+func (s *tcpSession) Close() error {
+	if s.check() != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	err := s.call(ctx, "MSG", &closeSessionRequest{deleteSubscriptions: true}, &closeSessionResponse{})
+
+	s.mu.Lock()
+	s.requestID++
+	id := s.requestID
+	s.mu.Unlock()
+	s.ch.send("CLO", id, &requestHeader{timestamp: time.Now()}, &closeSecureChannelRequest{})
+
+	// Ожидающие Publish сервер завершает с BadSessionClosed раньше ответа на CloseSession
+	s.fail(StatusBadSessionClosed)
+	if err != nil && !errors.Is(err, StatusBadSessionClosed) {
+		return fmt.Errorf("failed to close session: %w", err)
+	}
+	return nil
+}
+
+// tcpSubscription — подписка сессии opc.tcp. Уведомления из ответов Publish копятся
+// в очереди и передаются в канал отдельной горутиной, чтобы медленный потребитель
+// одной подписки не задерживал публикацию остальных.
+type tcpSubscription struct {
+	session   *tcpSession
+	id        uint32
+	nodes     []NodeID // Узлы по client handle отслеживаемых элементов
+	keepAlive time.Duration
+	ch        chan Notification
+	wake      chan struct{}
+	stop      chan struct{}
+
+	mu      sync.Mutex
+	pending []Notification
+	err     error
+	closed  bool
+}
+
+func (sub *tcpSubscription) Notifications() <-chan Notification { return sub.ch }
+
+func (sub *tcpSubscription) Err() error {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.err
+}
+
+func (sub *tcpSubscription) Close() error {
+	s := sub.session
+	s.mu.Lock()
+	_, ok := s.subs[sub.id]
+	delete(s.subs, sub.id)
+	s.mu.Unlock()
+	sub.close(nil)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	var resp deleteSubscriptionsResponse
+	if err := s.call(ctx, "MSG", &deleteSubscriptionsRequest{ids: []uint32{sub.id}}, &resp); err != nil {
+		return fmt.Errorf("failed to delete subscription: %w", err)
+	}
+	return nil
+}
+
+// deliver ставит в очередь значения из DataChangeNotification
+func (sub *tcpSubscription) deliver(values []itemValue) {
+	batch := make([]Notification, 0, len(values))
+	for _, v := range values {
+		if int(v.handle) < len(sub.nodes) {
+			batch = append(batch, Notification{NodeID: sub.nodes[v.handle], Value: v.value})
+		}
+	}
+	sub.enqueue(batch)
+}
+
+func (sub *tcpSubscription) enqueue(batch []Notification) {
+	if len(batch) == 0 {
+		return
+	}
+	sub.mu.Lock()
+	sub.pending = append(sub.pending, batch...)
+	sub.mu.Unlock()
+
+	select {
+	case sub.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (sub *tcpSubscription) close(err error) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	sub.err = err
+	close(sub.stop)
+}
+
+// forward передает уведомления из очереди в канал и закрывает канал при закрытии подписки
+func (sub *tcpSubscription) forward() {
+	defer close(sub.ch)
+
+	for {
+		sub.mu.Lock()
+		batch := sub.pending
+		sub.pending = nil
+		sub.mu.Unlock()
+
+		for _, n := range batch {
+			select {
+			case sub.ch <- n:
+			case <-sub.stop:
+				return
+			}
+		}
+
+		select {
+		case <-sub.stop:
+			return
+		case <-sub.wake:
+		}
+	}
+}
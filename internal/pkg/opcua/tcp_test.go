@@ -0,0 +1,158 @@
+package opcua
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNodeIDEncoding(t *testing.T) {
+	tests := []struct {
+		id   NodeID
+		size int // Размер двоичной формы
+	}{
+		{ObjectsFolder, 2},
+		{"ns=2;i=1001", 4},
+		{"ns=3;i=70000", 7},
+		{"ns=300;i=5", 7},
+		{"ns=2;s=TOB.PP.Line1.Throughput", 30},
+		{"ns=1;g=09087e75-8e5e-499b-954f-f2a9603db28a", 19},
+		{"ns=1;b=M/RbKBsRVkePCePcx24oRA==", 23},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.id), func(t *testing.T) {
+			n, err := parseNodeID(tt.id)
+			if err != nil {
+				t.Fatalf("parseNodeID: %v", err)
+			}
+			if n.NodeID() != tt.id {
+				t.Errorf("NodeID() = %s, want %s", n.NodeID(), tt.id)
+			}
+
+			var e encoder
+			e.nodeID(n)
+			if len(e.buf) != tt.size {
+				t.Errorf("encoded size = %d, want %d", len(e.buf), tt.size)
+			}
+			d := &decoder{buf: e.buf}
+			if got := d.nodeID(); d.err != nil || got.NodeID() != tt.id {
+				t.Errorf("decoded %s, %v; want %s", got.NodeID(), d.err, tt.id)
+			}
+		})
+	}
+
+	for _, id := range []NodeID{"x=1", "ns=2;i=abc", "ns=2;g=123", "ns=70000;i=1"} {
+		if _, err := parseNodeID(id); err == nil {
+			t.Errorf("parseNodeID(%s) succeeded", id)
+		}
+	}
+}
+
+func TestDataValueEncoding(t *testing.T) {
+	source := time.Date(2025, 3, 14, 8, 30, 0, 123456700, time.UTC)
+	values := []DataValue{
+		{Value: 71.5, SourceTimestamp: source, ServerTimestamp: source.Add(time.Second)},
+		{Value: int32(-8750), Status: StatusUncertainLastUsableValue | 0x200, SourceTimestamp: source},
+		{Value: float32(0.25)},
+		{Value: true},
+		{Value: "n/a"},
+		{Status: StatusBadSensorFailure, ServerTimestamp: source},
+	}
+
+	var e encoder
+	for _, v := range values {
+		e.dataValue(v)
+	}
+	d := &decoder{buf: e.buf}
+	for _, want := range values {
+		got := d.dataValue()
+		if got.Value != want.Value || got.Status != want.Status ||
+			!got.SourceTimestamp.Equal(want.SourceTimestamp) || !got.ServerTimestamp.Equal(want.ServerTimestamp) {
+			t.Errorf("decoded %+v, want %+v", got, want)
+		}
+	}
+	if d.err != nil || d.pos != len(e.buf) {
+		t.Errorf("decoder stopped at %d of %d: %v", d.pos, len(e.buf), d.err)
+	}
+
+	// Обрезанное сообщение дает ошибку разбора, а не панику
+	d = &decoder{buf: e.buf[:5]}
+	d.dataValue()
+	if !errors.Is(d.err, StatusBadDecodingError) {
+		t.Errorf("truncated value: %v, want BadDecodingError", d.err)
+	}
+}
+
+func TestTCPUserIdentity(t *testing.T) {
+	srv, endpoint := startServer(t, tcpScheme)
+	srv.AddUser("connector", "s3cret")
+	ctx := context.Background()
+
+	if _, err := Dial(ctx, endpoint, Options{}); !errors.Is(err, StatusBadIdentityTokenRejected) {
+		t.Errorf("anonymous Dial: %v, want BadIdentityTokenRejected", err)
+	}
+	if _, err := Dial(ctx, endpoint, Options{Username: "connector", Password: "wrong"}); !errors.Is(err, StatusBadUserAccessDenied) {
+		t.Errorf("Dial with a wrong password: %v, want BadUserAccessDenied", err)
+	}
+
+	session, err := Dial(ctx, endpoint, Options{Username: "connector", Password: "s3cret"})
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer session.Close()
+	if srv.Sessions() != 1 {
+		t.Errorf("Sessions = %d, want 1 (rejected sessions must be released)", srv.Sessions())
+	}
+}
+
+// TestTCPChunking проверяет сообщения больше буфера приема: запрос и ответ
+// передаются несколькими фрагментами
+func TestTCPChunking(t *testing.T) {
+	srv, endpoint := startServer(t, tcpScheme)
+	nodes := make([]NodeID, 3000)
+	for i := range nodes {
+		nodes[i] = NodeID(fmt.Sprintf("ns=2;s=TOB.PP.R101.Sensors.Temperature.Zone%04d", i))
+		if err := srv.AddVariable(reactorUnit, nodes[i], fmt.Sprintf("Zone%04d", i), float64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	session := dial(t, endpoint)
+	ctx := context.Background()
+
+	refs, err := session.Browse(ctx, reactorUnit)
+	if err != nil {
+		t.Fatalf("Browse: %v", err)
+	}
+	if len(refs) != len(nodes)+1 || refs[len(refs)-1].NodeID != nodes[len(nodes)-1] {
+		t.Fatalf("Browse returned %d references, want %d", len(refs), len(nodes)+1)
+	}
+
+	values, err := session.Read(ctx, nodes)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	for i, v := range values {
+		if v.Value != float64(i) {
+			t.Fatalf("Read(%s) = %+v, want %d", nodes[i], v, i)
+		}
+	}
+}
+
+func TestTCPChannelRenewal(t *testing.T) {
+	lifetime := channelLifetime
+	channelLifetime = 100 * time.Millisecond
+	defer func() { channelLifetime = lifetime }()
+
+	_, endpoint := startServer(t, tcpScheme)
+	session := dial(t, endpoint)
+
+	// Токен обновляется несколько раз, сессия продолжает работать
+	time.Sleep(350 * time.Millisecond)
+	values, err := session.Read(context.Background(), []NodeID{reactorTemp})
+	if err != nil || values[0].Value != 71.5 {
+		t.Fatalf("Read after renewal = %+v, %v; want 71.5", values, err)
+	}
+}
@@ -0,0 +1,639 @@
+package opcua
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	serverApplicationURI  = "urn:petrochemical-data-platform:opcua-stand"
+	serverApplicationName = "OPC UA stand-in"
+
+	anonymousPolicyID = "anonymous"
+	userNamePolicyID  = "username"
+
+	// maxQueuedPublish — предел запросов Publish, ожидающих уведомлений в сессии стенда
+	maxQueuedPublish = 16
+)
+
+// standIDs выдает идентификаторы каналов, токенов, сессий и подписок стенда
+var standIDs atomic.Uint32
+
+// ListenTCP открывает стенд по протоколу OPC UA Binary без политики безопасности и
+// возвращает адрес opc.tcp://host:port для конфигурации коннектора. Адрес "127.0.0.1:0"
+// выбирает свободный порт. Сессии по opc.tcp — те же сессии стенда: на них действуют
+// SetAvailable, DropSessions и Close.
+func (s *Server) ListenTCP(addr string) (string, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return "", fmt.Errorf("OPC UA stand-in %q is closed", s.name)
+	}
+	s.listeners = append(s.listeners, l)
+	s.mu.Unlock()
+
+	go s.serve(l)
+	return tcpScheme + "://" + l.Addr().String(), nil
+}
+
+// AddUser разрешает вход по имени пользователя и паролю. Пока пользователей нет, стенд
+// принимает анонимные сессии, после добавления первого — только вход по имени.
+func (s *Server) AddUser(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[username] = password
+}
+
+func (s *Server) serve(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		// Недоступный стенд разрывает соединение сразу, как сервер без связи
+		s.mu.Lock()
+		if s.closed || !s.available {
+			s.mu.Unlock()
+			conn.Close()
+			continue
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// closeConns разрывает соединения opc.tcp
+func (s *Server) closeConns() {
+	s.mu.Lock()
+	conns := s.conns
+	s.conns = make(map[net.Conn]struct{})
+	s.mu.Unlock()
+
+	for conn := range conns {
+		conn.Close()
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	c := &standConn{server: s, ch: newChannel(conn), sessions: make(map[uint32]*standSession)}
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		c.closeSessions()
+	}()
+
+	endpoint, err := c.ch.acknowledge()
+	if err != nil {
+		return
+	}
+	c.endpoint = endpoint
+
+	for {
+		m, err := c.ch.receive()
+		if err != nil {
+			var status StatusCode
+			if errors.As(err, &status) {
+				c.ch.writeError(status, err.Error())
+			}
+			return
+		}
+
+		switch {
+		case m.err != nil:
+			// Клиент прервал передачу запроса
+		case m.msgType == "CLO":
+			return
+		case m.msgType == "OPN":
+			if err := c.open(m); err != nil {
+				c.ch.writeError(StatusBadDecodingError, err.Error())
+				return
+			}
+		default:
+			c.handle(m)
+		}
+	}
+}
+
+// standConn — соединение opc.tcp стенда: один защищенный канал и сессии, созданные в нем.
+// Запросы обрабатываются по очереди горутиной чтения; ответы Publish отправляют подписки.
+type standConn struct {
+	server   *Server
+	ch       *channel
+	endpoint string
+
+	channelID uint32
+
+	mu       sync.Mutex
+	sessions map[uint32]*standSession // По числу токена аутентификации
+}
+
+// open открывает канал или выдает новый токен
+func (c *standConn) open(m message) error {
+	var hdr requestHeader
+	var req openSecureChannelRequest
+	hdr.decode(m.body)
+	req.decode(m.body)
+	if m.body.err != nil {
+		return errDecoding("open secure channel request", m.body.err)
+	}
+
+	if req.requestType == requestIssue {
+		if c.channelID != 0 {
+			return errors.New("secure channel is already open")
+		}
+		c.channelID = standIDs.Add(1)
+	} else if c.channelID == 0 {
+		return errors.New("renew of a secure channel that is not open")
+	}
+	if req.lifetime == 0 {
+		req.lifetime = uint32(time.Hour.Milliseconds())
+	}
+
+	token := standIDs.Add(1)
+	c.ch.setToken(c.channelID, token)
+	return c.ch.send("OPN", m.requestID, &responseHeader{timestamp: time.Now(), handle: hdr.handle}, &openSecureChannelResponse{
+		channelID: c.channelID,
+		tokenID:   token,
+		createdAt: time.Now(),
+		lifetime:  req.lifetime,
+	})
+}
+
+func (c *standConn) reply(requestID, handle uint32, resp service) {
+	c.ch.send("MSG", requestID, &responseHeader{timestamp: time.Now(), handle: handle}, resp)
+}
+
+func (c *standConn) fault(requestID, handle uint32, status StatusCode) {
+	c.ch.send("MSG", requestID, &responseHeader{timestamp: time.Now(), handle: handle, result: status}, &serviceFault{})
+}
+
+// handle разбирает запрос сервиса и отвечает на него
+func (c *standConn) handle(m message) {
+	var hdr requestHeader
+	hdr.decode(m.body)
+
+	var req service
+	switch m.typeID {
+	case idCreateSessionRequest:
+		req = &createSessionRequest{}
+	case idActivateSessionRequest:
+		req = &activateSessionRequest{}
+	case idCloseSessionRequest:
+		req = &closeSessionRequest{}
+	case idReadRequest:
+		req = &readRequest{}
+	case idBrowseRequest:
+		req = &browseRequest{}
+	case idBrowseNextRequest:
+		req = &browseNextRequest{}
+	case idCreateSubscriptionRequest:
+		req = &createSubscriptionRequest{}
+	case idCreateMonitoredItemsRequest:
+		req = &createMonitoredItemsRequest{}
+	case idPublishRequest:
+		req = &publishRequest{}
+	case idDeleteSubscriptionsRequest:
+		req = &deleteSubscriptionsRequest{}
+	default:
+		c.fault(m.requestID, hdr.handle, StatusBadServiceUnsupported)
+		return
+	}
+	req.decode(m.body)
+	if m.body.err != nil {
+		c.fault(m.requestID, hdr.handle, StatusBadDecodingError)
+		return
+	}
+
+	resp, err := c.serveRequest(m.requestID, hdr, req)
+	if err != nil {
+		status := StatusBadCommunicationError
+		errors.As(err, &status)
+		c.fault(m.requestID, hdr.handle, status)
+		return
+	}
+	// Publish без ответа ждет уведомлений подписки
+	if resp != nil {
+		c.reply(m.requestID, hdr.handle, resp)
+	}
+}
+
+func (c *standConn) serveRequest(requestID uint32, hdr requestHeader, req service) (service, error) {
+	if r, ok := req.(*createSessionRequest); ok {
+		return c.createSession(r)
+	}
+
+	sess, err := c.session(hdr.authToken)
+	if err != nil {
+		return nil, err
+	}
+	switch r := req.(type) {
+	case *activateSessionRequest:
+		return sess.activate(r)
+	case *closeSessionRequest:
+		c.mu.Lock()
+		delete(c.sessions, hdr.authToken.num)
+		c.mu.Unlock()
+		sess.close(StatusBadSessionClosed)
+		return &closeSessionResponse{}, nil
+	}
+	if !sess.activated {
+		return nil, StatusBadSessionNotActivated
+	}
+
+	ctx := context.Background()
+	switch r := req.(type) {
+	case *readRequest:
+		nodes := make([]NodeID, len(r.nodes))
+		for i, n := range r.nodes {
+			nodes[i] = n.NodeID()
+		}
+		values, err := sess.sim.Read(ctx, nodes)
+		if err != nil {
+			return nil, err
+		}
+		for i, attribute := range r.attributes {
+			if attribute != attributeValue {
+				values[i] = DataValue{Status: statusBadAttributeIDInvalid}
+			}
+		}
+		return &readResponse{values: values}, nil
+
+	case *browseRequest:
+		resp := &browseResponse{results: make([]browseResult, len(r.nodes))}
+		for i, n := range r.nodes {
+			refs, err := sess.sim.Browse(ctx, n.NodeID())
+			if err != nil {
+				resp.results[i].status = StatusBadNodeIDUnknown
+				errors.As(err, &resp.results[i].status)
+				continue
+			}
+			resp.results[i].refs = refs
+		}
+		return resp, nil
+
+	case *browseNextRequest:
+		// Стенд возвращает ссылки одним ответом и не выдает точек продолжения
+		resp := &browseNextResponse{}
+		resp.results = make([]browseResult, len(r.points))
+		for i := range resp.results {
+			resp.results[i].status = statusBadContinuationPointInvalid
+		}
+		return resp, nil
+
+	case *createSubscriptionRequest:
+		return sess.subscribe(r), nil
+
+	case *createMonitoredItemsRequest:
+		return sess.monitor(r)
+
+	case *publishRequest:
+		return nil, sess.queuePublish(requestID, hdr.handle)
+
+	case *deleteSubscriptionsRequest:
+		return sess.unsubscribe(r), nil
+	}
+	return nil, StatusBadServiceUnsupported
+}
+
+// session находит сессию по токену аутентификации
+func (c *standConn) session(token nodeID) (*standSession, error) {
+	c.mu.Lock()
+	sess, ok := c.sessions[token.num]
+	c.mu.Unlock()
+	if !ok || token.ns != 1 || token.kind != 'i' {
+		return nil, StatusBadSessionIDInvalid
+	}
+	if err := sess.sim.check(); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+func (c *standConn) createSession(r *createSessionRequest) (service, error) {
+	sim, err := c.server.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	sess := &standSession{conn: c, sim: sim, subs: make(map[uint32]*standSubscription)}
+	id, token := standIDs.Add(1), standIDs.Add(1)
+	c.mu.Lock()
+	c.sessions[token] = sess
+	c.mu.Unlock()
+
+	return &createSessionResponse{
+		sessionID: numericNodeID(1, id),
+		authToken: numericNodeID(1, token),
+		timeout:   r.timeout,
+		endpoints: []endpointDescription{{
+			url:            c.endpoint,
+			securityMode:   securityModeNone,
+			securityPolicy: securityPolicyNone,
+			tokens: []userTokenPolicy{
+				{policyID: anonymousPolicyID, tokenType: tokenAnonymous},
+				{policyID: userNamePolicyID, tokenType: tokenUserName},
+			},
+		}},
+	}, nil
+}
+
+// closeSessions закрывает сессии разорванного соединения
+func (c *standConn) closeSessions() {
+	c.mu.Lock()
+	sessions := c.sessions
+	c.sessions = make(map[uint32]*standSession)
+	c.mu.Unlock()
+
+	for _, sess := range sessions {
+		sess.close(StatusBadConnectionClosed)
+	}
+}
+
+// standSession — сессия стенда, открытая по opc.tcp
+type standSession struct {
+	conn      *standConn
+	sim       *simSession
+	activated bool // Меняется только горутиной чтения соединения
+
+	mu      sync.Mutex
+	subs    map[uint32]*standSubscription
+	publish []publishSlot
+	done    bool
+}
+
+// publishSlot — запрос Publish, ожидающий уведомлений
+type publishSlot struct {
+	requestID uint32
+	handle    uint32
+}
+
+func (sess *standSession) activate(r *activateSessionRequest) (service, error) {
+	s := sess.conn.server
+	s.mu.Lock()
+	password, known := s.users[r.username]
+	anonymous := len(s.users) == 0
+	s.mu.Unlock()
+
+	switch {
+	case r.tokenType == tokenUserName && r.policyID == userNamePolicyID:
+		if !known || password != r.password {
+			return nil, StatusBadUserAccessDenied
+		}
+	case r.tokenType == tokenAnonymous && r.policyID == anonymousPolicyID:
+		if !anonymous {
+			return nil, StatusBadIdentityTokenRejected
+		}
+	default:
+		return nil, StatusBadIdentityTokenRejected
+	}
+	sess.activated = true
+	return &activateSessionResponse{}, nil
+}
+
+func (sess *standSession) subscribe(r *createSubscriptionRequest) service {
+	interval := time.Duration(r.interval * float64(time.Millisecond))
+	if interval < time.Millisecond {
+		interval = time.Second
+	}
+	sub := &standSubscription{
+		session:   sess,
+		id:        standIDs.Add(1),
+		interval:  interval,
+		keepAlive: max(r.keepAlive, 1),
+		handles:   make(map[NodeID][]uint32),
+		stop:      make(chan struct{}),
+	}
+
+	sess.mu.Lock()
+	sess.subs[sub.id] = sub
+	sess.mu.Unlock()
+	go sub.run()
+
+	return &createSubscriptionResponse{
+		id:        sub.id,
+		interval:  float64(interval) / float64(time.Millisecond),
+		lifetime:  r.lifetime,
+		keepAlive: sub.keepAlive,
+	}
+}
+
+// monitor подписывает сессию стенда на узлы элементов: начальные значения и изменения
+// приходят в подписку opc.tcp с client handle элементов
+func (sess *standSession) monitor(r *createMonitoredItemsRequest) (service, error) {
+	sess.mu.Lock()
+	sub, ok := sess.subs[r.subscriptionID]
+	sess.mu.Unlock()
+	if !ok {
+		return nil, StatusBadSubscriptionIDInvalid
+	}
+
+	nodes := make([]NodeID, len(r.items))
+	sub.mu.Lock()
+	for i, it := range r.items {
+		nodes[i] = it.node.NodeID()
+		sub.handles[nodes[i]] = append(sub.handles[nodes[i]], it.handle)
+	}
+	sub.mu.Unlock()
+
+	sim, err := sess.sim.Subscribe(context.Background(), sub.interval, nodes)
+	if err != nil {
+		return nil, err
+	}
+	sub.mu.Lock()
+	sub.sims = append(sub.sims, sim)
+	sub.mu.Unlock()
+	go sub.watch(sim)
+
+	resp := &createMonitoredItemsResponse{results: make([]monitoredItemResult, len(r.items))}
+	for i := range resp.results {
+		resp.results[i].id = standIDs.Add(1)
+	}
+	return resp, nil
+}
+
+func (sess *standSession) unsubscribe(r *deleteSubscriptionsRequest) service {
+	resp := &deleteSubscriptionsResponse{results: make([]StatusCode, len(r.ids))}
+	var deleted []*standSubscription
+
+	sess.mu.Lock()
+	for i, id := range r.ids {
+		sub, ok := sess.subs[id]
+		if !ok {
+			resp.results[i] = StatusBadSubscriptionIDInvalid
+			continue
+		}
+		delete(sess.subs, id)
+		deleted = append(deleted, sub)
+	}
+	// Запросы Publish без подписок больше не получат уведомлений
+	var slots []publishSlot
+	if len(sess.subs) == 0 {
+		slots, sess.publish = sess.publish, nil
+	}
+	sess.mu.Unlock()
+
+	for _, sub := range deleted {
+		sub.close()
+	}
+	for _, slot := range slots {
+		sess.conn.fault(slot.requestID, slot.handle, StatusBadNoSubscription)
+	}
+	return resp
+}
+
+func (sess *standSession) queuePublish(requestID, handle uint32) error {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	switch {
+	case len(sess.subs) == 0:
+		return StatusBadNoSubscription
+	case len(sess.publish) >= maxQueuedPublish:
+		return StatusBadTooManyPublishRequests
+	}
+	sess.publish = append(sess.publish, publishSlot{requestID: requestID, handle: handle})
+	return nil
+}
+
+func (sess *standSession) takePublish() (publishSlot, bool) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if len(sess.publish) == 0 {
+		return publishSlot{}, false
+	}
+	slot := sess.publish[0]
+	sess.publish = sess.publish[1:]
+	return slot, true
+}
+
+// terminate завершает сессию: ожидающие запросы Publish получают код status, поэтому
+// клиент узнает о сбросе сессии без нового запроса
+func (sess *standSession) terminate(status StatusCode) {
+	sess.mu.Lock()
+	if sess.done {
+		sess.mu.Unlock()
+		return
+	}
+	sess.done = true
+	slots, subs := sess.publish, sess.subs
+	sess.publish, sess.subs = nil, make(map[uint32]*standSubscription)
+	sess.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.close()
+	}
+	for _, slot := range slots {
+		sess.conn.fault(slot.requestID, slot.handle, status)
+	}
+}
+
+func (sess *standSession) close(status StatusCode) {
+	sess.sim.Close()
+	sess.terminate(status)
+}
+
+// standSubscription — подписка opc.tcp стенда. Значения из подписок сессии стенда копятся
+// и раз в период уходят ответом на ожидающий запрос Publish; без изменений за keepAlive
+// периодов отправляется keep-alive.
+type standSubscription struct {
+	session   *standSession
+	id        uint32
+	interval  time.Duration
+	keepAlive uint32
+	stop      chan struct{}
+
+	mu      sync.Mutex
+	handles map[NodeID][]uint32
+	sims    []Subscription
+	queue   []itemValue
+	closed  bool
+}
+
+// watch переносит уведомления подписки стенда в очередь. Закрытие подписки с ошибкой
+// означает, что стенд завершил сессию.
+func (sub *standSubscription) watch(sim Subscription) {
+	for n := range sim.Notifications() {
+		sub.mu.Lock()
+		for _, h := range sub.handles[n.NodeID] {
+			sub.queue = append(sub.queue, itemValue{handle: h, value: n.Value})
+		}
+		sub.mu.Unlock()
+	}
+
+	if err := sim.Err(); err != nil {
+		status := StatusBadSessionClosed
+		errors.As(err, &status)
+		sub.session.terminate(status)
+	}
+}
+
+func (sub *standSubscription) run() {
+	ticker := time.NewTicker(sub.interval)
+	defer ticker.Stop()
+
+	var idle, sequence uint32
+	for {
+		select {
+		case <-sub.stop:
+			return
+		case <-ticker.C:
+		}
+
+		sub.mu.Lock()
+		values := sub.queue
+		sub.queue = nil
+		sub.mu.Unlock()
+		if len(values) == 0 {
+			if idle++; idle < sub.keepAlive {
+				continue
+			}
+		}
+
+		slot, ok := sub.session.takePublish()
+		if !ok {
+			sub.mu.Lock()
+			sub.queue = append(values, sub.queue...)
+			sub.mu.Unlock()
+			continue
+		}
+		idle = 0
+
+		// Keep-alive несет номер следующего сообщения с уведомлениями
+		resp := &publishResponse{subscriptionID: sub.id, sequence: sequence + 1, publishTime: time.Now(), values: values, keepAlive: len(values) == 0}
+		if !resp.keepAlive {
+			sequence++
+		}
+		sub.session.conn.reply(slot.requestID, slot.handle, resp)
+	}
+}
+
+func (sub *standSubscription) close() {
+	sub.mu.Lock()
+	if sub.closed {
+		sub.mu.Unlock()
+		return
+	}
+	sub.closed = true
+	sims := sub.sims
+	sub.mu.Unlock()
+
+	close(sub.stop)
+	for _, sim := range sims {
+		sim.Close()
+	}
+}
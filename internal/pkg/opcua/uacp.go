@@ -0,0 +1,298 @@
+package opcua
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// Транспорт OPC UA TCP и защищенный канал с политикой безопасности None: обмен
+// Hello/Acknowledge, открытие канала (OPN) и сообщения сервисов (MSG), разбитые на
+// фрагменты не больше буфера приема собеседника. Подпись и шифрование не поддерживаются.
+
+const (
+	securityPolicyNone = "http://opcfoundation.org/UA/SecurityPolicy#None"
+
+	// bufferSize — размер буферов приема и передачи фрагментов
+	bufferSize = 1 << 16
+	// minBufferSize — минимальный буфер, который обязан поддерживать собеседник
+	minBufferSize = 8192
+	// maxMessageSize — предельный размер собранного сообщения
+	maxMessageSize = 16 << 20
+
+	chunkHeaderSize = 8
+	// symmetricHeaderSize — заголовок фрагмента MSG: канал, токен, номер фрагмента и запрос
+	symmetricHeaderSize = 16
+)
+
+// message — собранное сообщение сервиса
+type message struct {
+	msgType   string // OPN, MSG или CLO
+	requestID uint32
+	typeID    uint32
+	body      *decoder
+	err       error // Собеседник прервал передачу сообщения (фрагмент Abort)
+}
+
+// channel — защищенный канал поверх TCP-соединения. Запись безопасна из нескольких
+// горутин, чтение ведет одна горутина.
+type channel struct {
+	conn net.Conn
+
+	wmu      sync.Mutex
+	sendSize int // Предельный фрагмент, который принимает собеседник
+	id       uint32
+	token    uint32
+	seq      uint32
+
+	partial map[uint32][]byte // Фрагменты незавершенных сообщений по запросам
+}
+
+func newChannel(conn net.Conn) *channel {
+	return &channel{conn: conn, sendSize: minBufferSize, partial: make(map[uint32][]byte)}
+}
+
+// setToken задает канал и токен для следующих сообщений
+func (c *channel) setToken(channelID, tokenID uint32) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.id, c.token = channelID, tokenID
+}
+
+func (c *channel) writeChunk(msgType string, chunk byte, body []byte) error {
+	buf := make([]byte, 0, chunkHeaderSize+len(body))
+	buf = append(buf, msgType...)
+	buf = append(buf, chunk)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(chunkHeaderSize+len(body)))
+	buf = append(buf, body...)
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+func (c *channel) readChunk() (msgType string, chunk byte, body []byte, err error) {
+	var hdr [chunkHeaderSize]byte
+	if _, err := io.ReadFull(c.conn, hdr[:]); err != nil {
+		return "", 0, nil, err
+	}
+	size := binary.LittleEndian.Uint32(hdr[4:])
+	if size < chunkHeaderSize || size > bufferSize {
+		return "", 0, nil, fmt.Errorf("chunk of %d bytes: %w", size, StatusBadTCPMessageTooLarge)
+	}
+	body = make([]byte, size-chunkHeaderSize)
+	if _, err := io.ReadFull(c.conn, body); err != nil {
+		return "", 0, nil, err
+	}
+	return string(hdr[:3]), hdr[3], body, nil
+}
+
+// errorMessage разбирает сообщение ERR
+func errorMessage(body []byte) error {
+	d := &decoder{buf: body}
+	status := StatusCode(d.uint32())
+	if reason := d.string(); reason != "" {
+		return fmt.Errorf("%s: %w", reason, status)
+	}
+	return status
+}
+
+// writeError отправляет сообщение ERR перед закрытием соединения
+func (c *channel) writeError(status StatusCode, reason string) error {
+	var e encoder
+	e.uint32(uint32(status))
+	e.string(reason)
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return c.writeChunk("ERR", 'F', e.buf)
+}
+
+// hello начинает соединение со стороны клиента: Hello и ожидание Acknowledge
+func (c *channel) hello(endpoint string) error {
+	var e encoder
+	e.uint32(0) // ProtocolVersion
+	e.uint32(bufferSize)
+	e.uint32(bufferSize)
+	e.uint32(maxMessageSize)
+	e.uint32(0) // MaxChunkCount
+	e.string(endpoint)
+	if err := c.writeChunk("HEL", 'F', e.buf); err != nil {
+		return err
+	}
+
+	msgType, _, body, err := c.readChunk()
+	if err != nil {
+		return err
+	}
+	switch msgType {
+	case "ACK":
+	case "ERR":
+		return errorMessage(body)
+	default:
+		return fmt.Errorf("unexpected %s instead of ACK: %w", msgType, StatusBadDecodingError)
+	}
+
+	d := &decoder{buf: body}
+	d.uint32()
+	receive := d.uint32()
+	if d.err != nil {
+		return errDecoding("acknowledge", d.err)
+	}
+	if receive < minBufferSize {
+		return fmt.Errorf("server receive buffer of %d bytes: %w", receive, StatusBadDecodingError)
+	}
+	c.sendSize = int(min(receive, bufferSize))
+	return nil
+}
+
+// acknowledge принимает Hello со стороны сервера и возвращает адрес, указанный клиентом
+func (c *channel) acknowledge() (string, error) {
+	msgType, _, body, err := c.readChunk()
+	if err != nil {
+		return "", err
+	}
+	if msgType != "HEL" {
+		return "", fmt.Errorf("unexpected %s instead of HEL: %w", msgType, StatusBadDecodingError)
+	}
+
+	d := &decoder{buf: body}
+	d.uint32()
+	receive := d.uint32()
+	d.uint32()
+	d.uint32()
+	d.uint32()
+	endpoint := d.string()
+	if d.err != nil {
+		return "", errDecoding("hello", d.err)
+	}
+	if receive < minBufferSize {
+		return "", fmt.Errorf("client receive buffer of %d bytes: %w", receive, StatusBadDecodingError)
+	}
+	c.sendSize = int(min(receive, bufferSize))
+
+	var e encoder
+	e.uint32(0)
+	e.uint32(bufferSize)
+	e.uint32(uint32(c.sendSize))
+	e.uint32(maxMessageSize)
+	e.uint32(0)
+	return endpoint, c.writeChunk("ACK", 'F', e.buf)
+}
+
+// send отправляет сообщение сервиса: OPN — одним фрагментом с асимметричным заголовком,
+// MSG и CLO — фрагментами с заголовком токена
+func (c *channel) send(msgType string, requestID uint32, header interface{ encode(*encoder) }, body service) error {
+	var e encoder
+	e.nodeID(numericNodeID(0, body.typeID()))
+	header.encode(&e)
+	body.encode(&e)
+	payload := e.buf
+	if len(payload) > maxMessageSize {
+		return fmt.Errorf("message of %d bytes: %w", len(payload), StatusBadTCPMessageTooLarge)
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if msgType == "OPN" {
+		var h encoder
+		h.uint32(c.id)
+		h.string(securityPolicyNone)
+		h.bytes(nil) // SenderCertificate
+		h.bytes(nil) // ReceiverCertificateThumbprint
+		c.seq++
+		h.uint32(c.seq)
+		h.uint32(requestID)
+		h.buf = append(h.buf, payload...)
+		if chunkHeaderSize+len(h.buf) > c.sendSize {
+			return fmt.Errorf("open secure channel message of %d bytes: %w", len(h.buf), StatusBadTCPMessageTooLarge)
+		}
+		return c.writeChunk(msgType, 'F', h.buf)
+	}
+
+	limit := c.sendSize - chunkHeaderSize - symmetricHeaderSize
+	for {
+		part, chunk := payload, byte('F')
+		if len(part) > limit {
+			part, chunk = payload[:limit], 'C'
+		}
+		payload = payload[len(part):]
+
+		var h encoder
+		h.uint32(c.id)
+		h.uint32(c.token)
+		c.seq++
+		h.uint32(c.seq)
+		h.uint32(requestID)
+		h.buf = append(h.buf, part...)
+		if err := c.writeChunk(msgType, chunk, h.buf); err != nil {
+			return err
+		}
+		if chunk == 'F' {
+			return nil
+		}
+	}
+}
+
+// receive читает фрагменты до завершения очередного сообщения
+func (c *channel) receive() (message, error) {
+	for {
+		msgType, chunk, body, err := c.readChunk()
+		if err != nil {
+			return message{}, err
+		}
+
+		d := &decoder{buf: body}
+		switch msgType {
+		case "ERR":
+			return message{}, errorMessage(body)
+		case "OPN":
+			d.uint32()
+			policy := d.string()
+			d.bytes()
+			d.bytes()
+			if d.err == nil && policy != securityPolicyNone {
+				return message{}, fmt.Errorf("security policy %s: %w", policy, StatusBadSecurityPolicyRejected)
+			}
+		case "MSG", "CLO":
+			d.uint32()
+			d.uint32()
+		default:
+			return message{}, fmt.Errorf("unexpected message type %q: %w", msgType, StatusBadDecodingError)
+		}
+		d.uint32() // SequenceNumber
+		requestID := d.uint32()
+		if d.err != nil {
+			return message{}, errDecoding("message header", d.err)
+		}
+		data := body[d.pos:]
+
+		switch chunk {
+		case 'C':
+			buffered := append(c.partial[requestID], data...)
+			if len(buffered) > maxMessageSize {
+				return message{}, fmt.Errorf("message of more than %d bytes: %w", maxMessageSize, StatusBadTCPMessageTooLarge)
+			}
+			c.partial[requestID] = buffered
+			continue
+		case 'A':
+			delete(c.partial, requestID)
+			return message{msgType: msgType, requestID: requestID, err: errorMessage(data)}, nil
+		case 'F':
+			if buffered, ok := c.partial[requestID]; ok {
+				data = append(buffered, data...)
+				delete(c.partial, requestID)
+			}
+		default:
+			return message{}, fmt.Errorf("unexpected chunk type %q: %w", chunk, StatusBadDecodingError)
+		}
+
+		md := &decoder{buf: data}
+		typeID := md.nodeID()
+		if md.err != nil {
+			return message{}, errDecoding("message type", md.err)
+		}
+		return message{msgType: msgType, requestID: requestID, typeID: typeID.num, body: md}, nil
+	}
+}
@@ -0,0 +1,16 @@
+package parser
+
+import "time"
+
+// DataPoint представляет единичное значение, полученное от источника данных
+// (симулятор, MQTT, парсеры отчётов) до записи в хранилище
+type DataPoint struct {
+	CompanyID   string            `json:"company_id"`
+	ProductName string            `json:"product_name"`
+	Value       float64           `json:"value"`
+	Unit        string            `json:"unit"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Quality     uint16            `json:"quality"`
+	Tags        []string          `json:"tags,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
@@ -0,0 +1,294 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"petrochemical-data-platform/internal/pkg/quality"
+)
+
+// Format — формат пачки точек
+type Format string
+
+const (
+	FormatJSON   Format = "json"   // JSON-массив объектов
+	FormatNDJSON Format = "ndjson" // Один JSON-объект на строку
+	FormatCSV    Format = "csv"    // CSV с заголовком
+
+	FormatInflux      Format = "influx"     // Протокол строк InfluxDB (требует сопоставления)
+	FormatRemoteWrite Format = "prometheus" // Prometheus remote_write (требует сопоставления)
+)
+
+// ErrTooManyRecords возвращается, если пачка содержит больше записей, чем разрешено
+var ErrTooManyRecords = errors.New("too many records")
+
+// ParseFormat проверяет название формата пачки точек (json, ndjson, csv)
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatJSON, FormatNDJSON, FormatCSV:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q: use json, ndjson or csv", s)
+}
+
+// FormatFromContentType определяет формат по заголовку Content-Type
+func FormatFromContentType(contentType string) (Format, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+
+	switch mediaType {
+	case "application/json":
+		return FormatJSON, true
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return FormatNDJSON, true
+	case "text/csv", "application/csv":
+		return FormatCSV, true
+	}
+	return "", false
+}
+
+// Record — запись пачки: разобранная точка или ошибка разбора
+type Record struct {
+	Index int // Номер записи во входных данных с нуля (строка заголовка CSV не учитывается)
+	Point DataPoint
+	Err   error
+}
+
+// wirePoint — точка во входных JSON-форматах. Качество по умолчанию — good.
+type wirePoint struct {
+	CompanyID   string            `json:"company_id"`
+	ProductName string            `json:"product_name"`
+	Value       *float64          `json:"value"`
+	Unit        string            `json:"unit"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Quality     *uint16           `json:"quality"`
+	Tags        []string          `json:"tags"`
+	Labels      map[string]string `json:"labels"`
+}
+
+func (w wirePoint) point() (DataPoint, error) {
+	p := DataPoint{
+		CompanyID:   strings.TrimSpace(w.CompanyID),
+		ProductName: strings.TrimSpace(w.ProductName),
+		Unit:        strings.TrimSpace(w.Unit),
+		Timestamp:   w.Timestamp,
+		Quality:     uint16(quality.Good),
+		Tags:        w.Tags,
+		Labels:      w.Labels,
+	}
+	if w.Value == nil {
+		return p, errors.New("value is required")
+	}
+	p.Value = *w.Value
+	if w.Quality != nil {
+		p.Quality = *w.Quality
+	}
+	return p, nil
+}
+
+// Decode разбирает пачку точек. Ошибки отдельных записей возвращаются в Record.Err;
+// ошибка функции означает, что пачку невозможно разобрать целиком.
+// maxRecords <= 0 снимает ограничение на число записей.
+func Decode(r io.Reader, format Format, maxRecords int) ([]Record, error) {
+	switch format {
+	case FormatJSON:
+		return decodeJSON(r, maxRecords)
+	case FormatNDJSON:
+		return decodeNDJSON(r, maxRecords)
+	case FormatCSV:
+		return decodeCSV(r, maxRecords)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+func decodeJSON(r io.Reader, maxRecords int) ([]Record, error) {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, errors.New("expected a JSON array of points")
+	}
+
+	var records []Record
+	for dec.More() {
+		if maxRecords > 0 && len(records) == maxRecords {
+			return nil, ErrTooManyRecords
+		}
+
+		// Ошибка типа поля не прерывает разбор: декодер дочитывает объект до конца
+		var w wirePoint
+		rec := Record{Index: len(records)}
+		if err := dec.Decode(&w); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				return nil, fmt.Errorf("record %d: %w", rec.Index, err)
+			}
+			rec.Err = fieldError(typeErr)
+		} else {
+			rec.Point, rec.Err = w.point()
+		}
+		records = append(records, rec)
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("unterminated JSON array: %w", err)
+	}
+	return records, nil
+}
+
+func decodeNDJSON(r io.Reader, maxRecords int) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var records []Record
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if maxRecords > 0 && len(records) == maxRecords {
+			return nil, ErrTooManyRecords
+		}
+
+		var w wirePoint
+		rec := Record{Index: len(records)}
+		var typeErr *json.UnmarshalTypeError
+		if err := json.Unmarshal(line, &w); errors.As(err, &typeErr) {
+			rec.Err = fieldError(typeErr)
+		} else if err != nil {
+			rec.Err = err
+		} else {
+			rec.Point, rec.Err = w.point()
+		}
+		records = append(records, rec)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDJSON: %w", err)
+	}
+	return records, nil
+}
+
+// fieldError описывает ошибку типа поля без внутренних имен типов
+func fieldError(err *json.UnmarshalTypeError) error {
+	return fmt.Errorf("invalid %s: got JSON %s, expected %s", err.Field, err.Value, err.Type)
+}
+
+// Столбцы CSV. Теги разделяются ";", метки задаются как "ключ:значение;ключ:значение".
+var (
+	csvColumns  = []string{"company_id", "product_name", "value", "unit", "timestamp", "quality", "tags", "labels"}
+	csvRequired = []string{"company_id", "product_name", "value", "timestamp"}
+)
+
+func decodeCSV(r io.Reader, maxRecords int) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(csvColumns, name) {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range csvRequired {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV column %q is required", name)
+		}
+	}
+
+	var records []Record
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if maxRecords > 0 && len(records) == maxRecords {
+			return nil, ErrTooManyRecords
+		}
+
+		rec := Record{Index: len(records)}
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			rec.Err = parseErr.Err
+		case err != nil:
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		default:
+			rec.Point, rec.Err = csvPoint(row, columns)
+		}
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+func csvPoint(row []string, columns map[string]int) (DataPoint, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	p := DataPoint{
+		CompanyID:   field("company_id"),
+		ProductName: field("product_name"),
+		Unit:        field("unit"),
+		Quality:     uint16(quality.Good),
+	}
+
+	var err error
+	if p.Value, err = strconv.ParseFloat(field("value"), 64); err != nil {
+		return p, fmt.Errorf("invalid value %q", field("value"))
+	}
+	if ts := field("timestamp"); ts != "" {
+		if p.Timestamp, err = time.Parse(time.RFC3339, ts); err != nil {
+			return p, fmt.Errorf("invalid timestamp %q: use RFC 3339", ts)
+		}
+	}
+	if q := field("quality"); q != "" {
+		code, err := strconv.ParseUint(q, 10, 16)
+		if err != nil {
+			return p, fmt.Errorf("invalid quality %q", q)
+		}
+		p.Quality = uint16(code)
+	}
+
+	for _, tag := range strings.Split(field("tags"), ";") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			p.Tags = append(p.Tags, tag)
+		}
+	}
+	for _, label := range strings.Split(field("labels"), ";") {
+		if strings.TrimSpace(label) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(label, ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			return p, fmt.Errorf("invalid label %q: use key:value", label)
+		}
+		if p.Labels == nil {
+			p.Labels = make(map[string]string)
+		}
+		p.Labels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return p, nil
+}
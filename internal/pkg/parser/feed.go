@@ -0,0 +1,425 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"petrochemical-data-platform/internal/pkg/quality"
+)
+
+// Адаптеры ценовых лент: разбор файлов дневных отчетов бирж и ценовых агентств
+// (итоги торгов, котировки) в котировки инструментов и сопоставление инструментов
+// с продуктами каталога. Адаптеры не обращаются к сети и хранилищам: на вход — файл,
+// на выходе — отчет, поэтому каждый формат проверяется на файлах-образцах
+// (testdata/feeds).
+
+// Quote — котировка инструмента из отчета
+type Quote struct {
+	Line       int                // Строка или номер записи во входном файле
+	Instrument string             // Код инструмента источника
+	Name       string             // Наименование инструмента
+	Values     map[string]float64 // Значения по полям отчета (price, volume, ...); пустые ячейки пропущены
+}
+
+// FeedReport — разобранный файл отчета за одну дату
+type FeedReport struct {
+	Date   time.Time // Дата отчета (торгов), полночь в часовом поясе источника
+	Quotes []Quote
+}
+
+// FeedAdapter разбирает файл отчета одного формата
+type FeedAdapter interface {
+	Parse(r io.Reader, filename string) (*FeedReport, error)
+}
+
+// FeedOptions задает расположение данных в файле источника. Имена полей сравниваются
+// без учета регистра и лишних пробелов: для CSV и HTML это заголовки столбцов, для XML —
+// атрибуты или дочерние элементы записи.
+type FeedOptions struct {
+	Encoding    string            // utf-8 или windows-1251
+	Delimiter   string            // Разделитель CSV, по умолчанию ";"
+	Decimal     string            // Десятичный разделитель, по умолчанию ","
+	Record      string            // XML: имя элемента записи
+	Table       int               // HTML: номер таблицы с единицы; 0 — первая таблица со столбцом инструмента
+	Instrument  string            // Поле кода инструмента
+	Name        string            // Поле наименования инструмента (необязательно)
+	Fields      map[string]string // Поле значения -> поле файла: price: "Средневзвешенная цена, руб./т"
+	Date        string            // Поле с датой отчета; пусто — дата ищется по DatePattern
+	DatePattern string            // Регулярное выражение с группой даты: сначала по имени файла, затем по тексту вне таблицы
+	DateFormat  string            // Формат даты Go, по умолчанию "02.01.2006"
+	Timezone    string            // По умолчанию Europe/Moscow
+	EmptyValues []string          // Значения, означающие отсутствие сделок
+}
+
+// FeedFactory создает адаптер формата по настройкам
+type FeedFactory func(opts FeedOptions) (FeedAdapter, error)
+
+var (
+	feedFormatsMu sync.RWMutex
+	feedFormats   = make(map[string]FeedFactory)
+)
+
+// RegisterFeedFormat регистрирует формат отчетов. Встроенные форматы: csv, xml, html.
+func RegisterFeedFormat(name string, factory FeedFactory) {
+	feedFormatsMu.Lock()
+	defer feedFormatsMu.Unlock()
+	feedFormats[name] = factory
+}
+
+// NewFeedAdapter создает адаптер зарегистрированного формата
+func NewFeedAdapter(format string, opts FeedOptions) (FeedAdapter, error) {
+	feedFormatsMu.RLock()
+	factory, ok := feedFormats[format]
+	feedFormatsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown feed format %q", format)
+	}
+
+	if err := opts.setDefaults(); err != nil {
+		return nil, err
+	}
+	return factory(opts)
+}
+
+func init() {
+	RegisterFeedFormat("csv", newCSVFeed)
+	RegisterFeedFormat("xml", newXMLFeed)
+	RegisterFeedFormat("html", newHTMLFeed)
+}
+
+var defaultFeedEmptyValues = []string{"-", "–", "—", "н/д", "n/a"}
+
+func (o *FeedOptions) setDefaults() error {
+	if o.Delimiter == "" {
+		o.Delimiter = ";"
+	}
+	if o.Decimal == "" {
+		o.Decimal = ","
+	}
+	if o.DateFormat == "" {
+		o.DateFormat = "02.01.2006"
+	}
+	if o.Timezone == "" {
+		o.Timezone = "Europe/Moscow"
+	}
+	if o.EmptyValues == nil {
+		o.EmptyValues = defaultFeedEmptyValues
+	}
+
+	switch {
+	case o.Instrument == "":
+		return errors.New("instrument field is required")
+	case len(o.Fields) == 0:
+		return errors.New("at least one value field is required")
+	case o.Date == "" && o.DatePattern == "":
+		return errors.New("date field or date_pattern is required")
+	case o.Decimal != "," && o.Decimal != ".":
+		return errors.New(`decimal must be "," or "."`)
+	case len([]rune(o.Delimiter)) != 1:
+		return errors.New("delimiter must be a single character")
+	}
+	if o.DatePattern != "" {
+		if _, err := regexp.Compile(o.DatePattern); err != nil {
+			return fmt.Errorf("invalid date_pattern: %w", err)
+		}
+	}
+	if _, err := time.LoadLocation(o.Timezone); err != nil {
+		return err
+	}
+	return nil
+}
+
+// feedRecord — запись файла: значения по нормализованным именам полей
+type feedRecord struct {
+	line   int
+	fields map[string]string
+}
+
+// feedTable — промежуточный результат разбора файла любым адаптером
+type feedTable struct {
+	records []feedRecord
+	doc     map[string]string // Поля документа вне записей (атрибуты корня XML)
+	text    string            // Текст вне записей: заголовок отчета, строки над таблицей
+}
+
+// report собирает отчет из записей: дата, инструменты и значения полей
+func (o *FeedOptions) report(filename string, t feedTable) (*FeedReport, error) {
+	date, err := o.reportDate(filename, t)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FeedReport{Date: date}
+	instrument, name := normalizeField(o.Instrument), normalizeField(o.Name)
+	for _, rec := range t.records {
+		code := strings.TrimSpace(rec.fields[instrument])
+		if code == "" {
+			continue // Итоговые и разделительные строки таблицы
+		}
+
+		q := Quote{Line: rec.line, Instrument: code, Name: strings.TrimSpace(rec.fields[name]), Values: make(map[string]float64)}
+		for field, column := range o.Fields {
+			raw := strings.TrimSpace(rec.fields[normalizeField(column)])
+			if raw == "" || slices.Contains(o.EmptyValues, raw) {
+				continue
+			}
+			v, err := ParseNumber(raw, o.Decimal)
+			if err != nil {
+				return nil, fmt.Errorf("line %d, %s: %w", rec.line, column, err)
+			}
+			q.Values[field] = v
+		}
+		report.Quotes = append(report.Quotes, q)
+	}
+
+	if len(report.Quotes) == 0 {
+		return nil, errors.New("report contains no quotes")
+	}
+	return report, nil
+}
+
+// reportDate ищет дату отчета: в поле записей или документа, затем по шаблону в имени
+// файла и в тексте вне таблицы. Все записи отчета должны относиться к одной дате.
+func (o *FeedOptions) reportDate(filename string, t feedTable) (time.Time, error) {
+	loc, _ := time.LoadLocation(o.Timezone)
+	parse := func(s string) (time.Time, error) {
+		d, err := time.ParseInLocation(o.DateFormat, strings.TrimSpace(s), loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid report date %q: expected format %s", s, o.DateFormat)
+		}
+		return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc), nil
+	}
+
+	if o.Date != "" {
+		field := normalizeField(o.Date)
+		var dates []string
+		for _, rec := range t.records {
+			if v := strings.TrimSpace(rec.fields[field]); v != "" && !slices.Contains(dates, v) {
+				dates = append(dates, v)
+			}
+		}
+		if v := strings.TrimSpace(t.doc[field]); v != "" && len(dates) == 0 {
+			dates = append(dates, v)
+		}
+
+		switch len(dates) {
+		case 1:
+			return parse(dates[0])
+		case 0:
+			if o.DatePattern == "" {
+				return time.Time{}, fmt.Errorf("report date field %q not found", o.Date)
+			}
+		default:
+			return time.Time{}, fmt.Errorf("report contains several dates: %s", strings.Join(dates, ", "))
+		}
+	}
+
+	re := regexp.MustCompile(o.DatePattern)
+	for _, s := range []string{filepath.Base(filename), t.text} {
+		if m := re.FindStringSubmatch(s); m != nil {
+			return parse(m[len(m)-1])
+		}
+	}
+	return time.Time{}, errors.New("report date not found in file name or report header")
+}
+
+// tableRecords находит в строках таблицы заголовок (первую строку со столбцом инструмента)
+// и превращает последующие строки в записи. Строки над заголовком возвращаются как текст.
+func (o *FeedOptions) tableRecords(rows [][]string, lines []int) ([]feedRecord, string, error) {
+	instrument := normalizeField(o.Instrument)
+
+	var text []string
+	for i, row := range rows {
+		header := make([]string, len(row))
+		found := false
+		for j, cell := range row {
+			header[j] = normalizeField(cell)
+			found = found || header[j] == instrument
+		}
+		if !found {
+			text = append(text, strings.Join(row, " "))
+			continue
+		}
+
+		records := make([]feedRecord, 0, len(rows)-i-1)
+		for k, cells := range rows[i+1:] {
+			if filled(cells) < 2 {
+				continue // Разделы, примечания и источники данных внутри таблицы
+			}
+			rec := feedRecord{line: lines[i+1+k], fields: make(map[string]string, len(header))}
+			for j, name := range header {
+				if j < len(cells) && name != "" {
+					if _, dup := rec.fields[name]; !dup {
+						rec.fields[name] = cells[j]
+					}
+				}
+			}
+			records = append(records, rec)
+		}
+		return records, strings.Join(text, "\n"), nil
+	}
+	return nil, "", fmt.Errorf("header with column %q not found", o.Instrument)
+}
+
+func filled(cells []string) int {
+	n := 0
+	for _, c := range cells {
+		if strings.TrimSpace(c) != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func normalizeField(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// InstrumentRule сопоставляет инструмент с продуктом каталога. Инструмент задается
+// кодом или префиксом кода (например, все базисы поставки одного вида топлива).
+type InstrumentRule struct {
+	Code        string
+	Prefix      string
+	Field       string // Поле значения отчета; по умолчанию price
+	CompanyID   string
+	ProductName string // Продукт каталога
+	SeriesName  string // Название записываемого ряда; по умолчанию ProductName
+	Unit        string
+	Aggregate   string // Объединение нескольких инструментов одного ряда: avg (по умолчанию), sum, min, max
+}
+
+// InstrumentMapper сопоставляет котировки отчета с рядами. Для каждого инструмента
+// применяются все подходящие правила: одно правило на поле (цена, объем).
+type InstrumentMapper struct {
+	rules []InstrumentRule
+}
+
+// NewInstrumentMapper проверяет правила
+func NewInstrumentMapper(rules []InstrumentRule) (*InstrumentMapper, error) {
+	m := &InstrumentMapper{rules: make([]InstrumentRule, len(rules))}
+	for i, r := range rules {
+		switch {
+		case (r.Code == "") == (r.Prefix == ""):
+			return nil, fmt.Errorf("instrument rule %d: exactly one of code and prefix is required", i)
+		case r.CompanyID == "" || r.ProductName == "":
+			return nil, fmt.Errorf("instrument rule %d: company_id and product_name are required", i)
+		}
+		if r.Field == "" {
+			r.Field = "price"
+		}
+		if r.SeriesName == "" {
+			r.SeriesName = r.ProductName
+		}
+		switch r.Aggregate {
+		case "":
+			r.Aggregate = "avg"
+		case "avg", "sum", "min", "max":
+		default:
+			return nil, fmt.Errorf("instrument rule %d: unknown aggregate %q: use avg, sum, min or max", i, r.Aggregate)
+		}
+		m.rules[i] = r
+	}
+	return m, nil
+}
+
+// Rules возвращает проверенные правила (с заполненными значениями по умолчанию)
+func (m *InstrumentMapper) Rules() []InstrumentRule {
+	return m.rules
+}
+
+// Map превращает котировки отчета в точки. Инструменты без правил возвращаются в unmapped:
+// отчеты бирж содержат много инструментов, не относящихся к платформе.
+func (m *InstrumentMapper) Map(report *FeedReport, source string) (points []DataPoint, unmapped []string) {
+	type series struct {
+		rule        InstrumentRule
+		values      []float64
+		instruments []string
+	}
+	bySeries := make(map[[2]string]*series)
+	var order [][2]string
+
+	for _, q := range report.Quotes {
+		matched := false
+		for _, r := range m.rules {
+			if r.Code != "" && r.Code != q.Instrument || r.Prefix != "" && !strings.HasPrefix(q.Instrument, r.Prefix) {
+				continue
+			}
+			matched = true
+
+			v, ok := q.Values[r.Field]
+			if !ok {
+				continue // Нет сделок по инструменту
+			}
+			key := [2]string{r.CompanyID, r.SeriesName}
+			s, ok := bySeries[key]
+			if !ok {
+				s = &series{rule: r}
+				bySeries[key] = s
+				order = append(order, key)
+			}
+			s.values = append(s.values, v)
+			s.instruments = append(s.instruments, q.Instrument)
+		}
+		if !matched {
+			unmapped = append(unmapped, q.Instrument)
+		}
+	}
+
+	for _, key := range order {
+		s := bySeries[key]
+		sort.Strings(s.instruments)
+		points = append(points, DataPoint{
+			CompanyID:   s.rule.CompanyID,
+			ProductName: s.rule.SeriesName,
+			Value:       aggregate(s.rule.Aggregate, s.values),
+			Unit:        s.rule.Unit,
+			Timestamp:   report.Date.UTC(),
+			Quality:     uint16(quality.Good),
+			Labels: map[string]string{
+				"source":     source,
+				"instrument": strings.Join(slices.Compact(s.instruments), ","),
+			},
+		})
+	}
+	return points, unmapped
+}
+
+func aggregate(kind string, values []float64) float64 {
+	result := values[0]
+	for _, v := range values[1:] {
+		switch kind {
+		case "sum", "avg":
+			result += v
+		case "min":
+			result = min(result, v)
+		case "max":
+			result = max(result, v)
+		}
+	}
+	if kind == "avg" {
+		result /= float64(len(values))
+	}
+	return result
+}
+
+// FeedSource — источник отчетов: шаблон имени файла, адаптер формата и сопоставление инструментов
+type FeedSource struct {
+	Name    string
+	Pattern string // Шаблон имени файла (filepath.Match)
+	Adapter FeedAdapter
+	Mapper  *InstrumentMapper
+}
+
+// Match сообщает, относится ли файл к источнику
+func (s *FeedSource) Match(filename string) bool {
+	ok, _ := filepath.Match(s.Pattern, filepath.Base(filename))
+	return ok
+}
@@ -0,0 +1,327 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// feedReader декодирует файл в UTF-8 и убирает BOM
+func feedReader(r io.Reader, encoding string) (io.Reader, error) {
+	switch strings.ToLower(encoding) {
+	case "", "utf-8", "utf8":
+		br := bufio.NewReader(r)
+		if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+			br.Discard(3)
+		}
+		return br, nil
+	case "windows-1251", "cp1251":
+		return charmap.Windows1251.NewDecoder().Reader(r), nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %q: use utf-8 or windows-1251", encoding)
+}
+
+// csvFeed разбирает CSV-выгрузки: строки заголовка отчета, затем таблица
+type csvFeed struct {
+	opts FeedOptions
+}
+
+func newCSVFeed(opts FeedOptions) (FeedAdapter, error) {
+	return &csvFeed{opts: opts}, nil
+}
+
+func (f *csvFeed) Parse(r io.Reader, filename string) (*FeedReport, error) {
+	r, err := feedReader(r, f.opts.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = []rune(f.opts.Delimiter)[0]
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var rows [][]string
+	var lines []int
+	for {
+		cells, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, cells)
+		lines = append(lines, line)
+	}
+
+	records, text, err := f.opts.tableRecords(rows, lines)
+	if err != nil {
+		return nil, err
+	}
+	return f.opts.report(filename, feedTable{records: records, text: text})
+}
+
+// xmlFeed разбирает XML-отчеты: записи — элементы Record, поля — их атрибуты и дочерние
+// элементы. Атрибуты и текст остальных элементов доступны как поля документа (дата отчета).
+type xmlFeed struct {
+	opts FeedOptions
+}
+
+func newXMLFeed(opts FeedOptions) (FeedAdapter, error) {
+	if opts.Record == "" {
+		return nil, fmt.Errorf("xml feed: record element is required")
+	}
+	return &xmlFeed{opts: opts}, nil
+}
+
+func (f *xmlFeed) Parse(r io.Reader, filename string) (*FeedReport, error) {
+	decoder := xml.NewDecoder(r)
+	if f.opts.Encoding != "" && !strings.EqualFold(f.opts.Encoding, "utf-8") {
+		// Кодировка задана в источнике: объявление в файле не учитывается
+		decoded, err := feedReader(r, f.opts.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		decoder = xml.NewDecoder(decoded)
+		decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	} else {
+		decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+			return feedReader(input, charset)
+		}
+	}
+
+	record := normalizeField(f.opts.Record)
+	t := feedTable{doc: make(map[string]string)}
+	var current *feedRecord
+	var path []string // Элементы внутри текущей записи
+	var text strings.Builder
+	n := 0
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse XML: %w", err)
+		}
+
+		switch el := tok.(type) {
+		case xml.StartElement:
+			name := normalizeField(el.Name.Local)
+			switch {
+			case current == nil && name == record:
+				n++
+				current = &feedRecord{line: n, fields: make(map[string]string)}
+				path = path[:0] // Путь внутри записи; конец записи — закрывающий тег при пустом пути
+				for _, a := range el.Attr {
+					current.fields[normalizeField(a.Name.Local)] = a.Value
+				}
+			case current != nil:
+				path = append(path, name)
+				for _, a := range el.Attr {
+					current.fields[name+"."+normalizeField(a.Name.Local)] = a.Value
+				}
+			default:
+				for _, a := range el.Attr {
+					if _, dup := t.doc[normalizeField(a.Name.Local)]; !dup {
+						t.doc[normalizeField(a.Name.Local)] = a.Value
+					}
+				}
+				path = append(path[:0], name)
+			}
+		case xml.CharData:
+			s := strings.TrimSpace(string(el))
+			switch {
+			case s == "":
+			case current != nil && len(path) > 0:
+				current.fields[path[len(path)-1]] += s
+			case current == nil && len(path) > 0:
+				if _, dup := t.doc[path[len(path)-1]]; !dup {
+					t.doc[path[len(path)-1]] = s
+				}
+				text.WriteString(s + "\n")
+			}
+		case xml.EndElement:
+			switch {
+			case current != nil && len(path) > 0:
+				path = path[:len(path)-1]
+			case current != nil:
+				t.records = append(t.records, *current)
+				current = nil
+			default:
+				path = path[:0]
+			}
+		}
+	}
+
+	t.text = text.String()
+	return f.opts.report(filename, t)
+}
+
+// htmlFeed разбирает HTML-страницы с таблицей итогов торгов
+type htmlFeed struct {
+	opts FeedOptions
+}
+
+func newHTMLFeed(opts FeedOptions) (FeedAdapter, error) {
+	if opts.Table < 0 {
+		return nil, fmt.Errorf("html feed: table must be positive")
+	}
+	return &htmlFeed{opts: opts}, nil
+}
+
+// htmlTable — ячейки строк таблицы; объединенные по горизонтали ячейки дополняются
+// пустыми, вложенные таблицы не разбираются
+type htmlTable struct {
+	rows [][]string
+}
+
+func (f *htmlFeed) Parse(r io.Reader, filename string) (*FeedReport, error) {
+	r, err := feedReader(r, f.opts.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, text, err := readHTMLTables(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.opts.Table > 0 {
+		if f.opts.Table > len(tables) {
+			return nil, fmt.Errorf("table %d not found: page has %d tables", f.opts.Table, len(tables))
+		}
+		tables = tables[f.opts.Table-1 : f.opts.Table]
+	}
+
+	// Страницы содержат таблицы разметки и навигации: берется первая таблица со столбцом инструмента
+	var lastErr error
+	for _, table := range tables {
+		lines := make([]int, len(table.rows))
+		for i := range lines {
+			lines[i] = i + 1
+		}
+		records, above, err := f.opts.tableRecords(table.rows, lines)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return f.opts.report(filename, feedTable{records: records, text: text + "\n" + above})
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("page has no tables")
+	}
+	return nil, lastErr
+}
+
+// readHTMLTables собирает таблицы страницы и текст вне таблиц
+func readHTMLTables(r io.Reader) ([]htmlTable, string, error) {
+	z := html.NewTokenizer(r)
+	var tables []htmlTable
+	var text strings.Builder
+	depth := 0 // Вложенность таблиц
+	var row []string
+	var cell *strings.Builder
+	cellIndex := 0
+	skip := 0 // Внутри script и style
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return tables, text.String(), nil
+			}
+			return nil, "", fmt.Errorf("failed to parse HTML: %w", z.Err())
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Script, atom.Style:
+				if tt == html.StartTagToken {
+					skip++
+				}
+			case atom.Table:
+				depth++
+				if depth == 1 {
+					tables = append(tables, htmlTable{})
+				}
+			case atom.Tr:
+				if depth == 1 {
+					row = nil
+				}
+			case atom.Td, atom.Th:
+				if depth == 1 {
+					cell = &strings.Builder{}
+					cellIndex = len(row)
+					row = append(row, "")
+					if span := colspan(z); span > 1 {
+						row = append(row, make([]string, span-1)...)
+					}
+				}
+			case atom.Br:
+				if cell != nil {
+					cell.WriteByte(' ')
+				}
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Script, atom.Style:
+				skip = max(skip-1, 0)
+			case atom.Table:
+				depth = max(depth-1, 0)
+			case atom.Td, atom.Th:
+				if depth == 1 && cell != nil {
+					row[cellIndex] = strings.Join(strings.Fields(cell.String()), " ")
+					cell = nil
+				}
+			case atom.Tr:
+				if depth == 1 && len(tables) > 0 && len(row) > 0 {
+					t := &tables[len(tables)-1]
+					t.rows = append(t.rows, row)
+					row = nil
+				}
+			}
+
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			s := html.UnescapeString(string(z.Text()))
+			switch {
+			case cell != nil:
+				cell.WriteString(s)
+			case depth == 0 && strings.TrimSpace(s) != "":
+				text.WriteString(strings.TrimSpace(s) + "\n")
+			}
+		}
+	}
+}
+
+func colspan(z *html.Tokenizer) int {
+	for {
+		key, val, more := z.TagAttr()
+		if string(key) == "colspan" {
+			if n, err := strconv.Atoi(string(val)); err == nil && n > 1 && n < 100 {
+				return n
+			}
+		}
+		if !more {
+			return 1
+		}
+	}
+}
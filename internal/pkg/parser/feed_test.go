@@ -0,0 +1,314 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Настройки источников повторяют примеры из configs/config.yaml
+var (
+	spimexOptions = FeedOptions{
+		Encoding:   "windows-1251",
+		Instrument: "Код инструмента",
+		Name:       "Наименование инструмента",
+		Fields: map[string]string{
+			"price":  "Средневзвешенная цена, руб./т",
+			"volume": "Объем договоров, т",
+		},
+		DatePattern: `(\d{8})`,
+		DateFormat:  "20060102",
+	}
+	polymersOptions = FeedOptions{
+		Record:     "instrument",
+		Instrument: "code",
+		Name:       "name",
+		Fields:     map[string]string{"price": "price", "volume": "volume"},
+		Date:       "date",
+	}
+	chemOptions = FeedOptions{
+		Instrument:  "Код",
+		Name:        "Наименование",
+		Fields:      map[string]string{"price": "Цена средняя"},
+		DatePattern: `на (\d{2}\.\d{2}\.\d{4})`,
+	}
+)
+
+func reportDate(t *testing.T) time.Time {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return time.Date(2025, 3, 14, 0, 0, 0, 0, loc)
+}
+
+func parseFixture(t *testing.T, format string, opts FeedOptions, file, name string) (*FeedReport, error) {
+	t.Helper()
+	adapter, err := NewFeedAdapter(format, opts)
+	if err != nil {
+		t.Fatalf("NewFeedAdapter(%s): %v", format, err)
+	}
+	f, err := os.Open(filepath.Join("testdata", "feeds", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	return adapter.Parse(f, name)
+}
+
+func TestFeedAdapters(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		opts   FeedOptions
+		file   string
+		want   []Quote
+	}{
+		{
+			name:   "csv windows-1251, date from file name",
+			format: "csv",
+			opts:   spimexOptions,
+			file:   "spimex_fuels_20250314.csv",
+			want: []Quote{
+				{Line: 4, Instrument: "A592UFM060F", Name: "Аи-92 экологического класса К5", Values: map[string]float64{"price": 61250, "volume": 1200}},
+				{Line: 5, Instrument: "A592ANG060F", Name: "Аи-92 экологического класса К5", Values: map[string]float64{"price": 63180, "volume": 540}},
+				{Line: 6, Instrument: "A595UFM060F", Name: "Аи-95 экологического класса К5", Values: map[string]float64{"price": 68310.5, "volume": 780}},
+				{Line: 7, Instrument: "DSC5UFM065F", Name: "ДТ летнее экологического класса К5", Values: map[string]float64{}},
+				{Line: 8, Instrument: "DSC5ANG065F", Name: "ДТ летнее экологического класса К5", Values: map[string]float64{"price": 57040, "volume": 960}},
+				{Line: 9, Instrument: "MAZM100BLZ", Name: "Мазут топочный М-100", Values: map[string]float64{"price": 22115, "volume": 2400}},
+			},
+		},
+		{
+			name:   "xml, date element",
+			format: "xml",
+			opts:   polymersOptions,
+			file:   "polymers_2025-03-14.xml",
+			want: []Quote{
+				{Line: 1, Instrument: "PP-H030-TOB", Name: "Полипропилен H030GP, FCA Тобольск", Values: map[string]float64{"price": 95400, "volume": 120}},
+				{Line: 2, Instrument: "PP-H030-TOM", Name: "Полипропилен H030GP, FCA Томск", Values: map[string]float64{"price": 96100, "volume": 64}},
+				{Line: 3, Instrument: "PE-273-KAZ", Name: "Полиэтилен 273-83, FCA Казань", Values: map[string]float64{"price": 112750, "volume": 88}},
+				{Line: 4, Instrument: "PS-525-NKM", Name: "Полистирол 525, FCA Нижнекамск", Values: map[string]float64{"volume": 0}},
+			},
+		},
+		{
+			name:   "html, date from report header",
+			format: "html",
+			opts:   chemOptions,
+			file:   "chem_prices_14032025.html",
+			want: []Quote{
+				{Line: 3, Instrument: "UREA-46", Name: "Карбамид марки Б", Values: map[string]float64{"price": 31200}},
+				{Line: 4, Instrument: "NH3-LIQ", Name: "Аммиак жидкий технический", Values: map[string]float64{"price": 28650}},
+				{Line: 5, Instrument: "MEOH-A", Name: "Метанол марки А", Values: map[string]float64{}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := parseFixture(t, tt.format, tt.opts, tt.file, tt.file)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if want := reportDate(t); !report.Date.Equal(want) {
+				t.Errorf("Date = %v, want %v", report.Date, want)
+			}
+			if !reflect.DeepEqual(report.Quotes, tt.want) {
+				t.Errorf("Quotes =\n%+v\nwant\n%+v", report.Quotes, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeedAdapterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		opts   FeedOptions
+		file   string
+		input  string
+		want   string
+	}{
+		{
+			name:   "csv without instrument column",
+			format: "csv",
+			opts:   FeedOptions{Instrument: "Код", Fields: map[string]string{"price": "Цена"}, DatePattern: `(\d{8})`, DateFormat: "20060102"},
+			file:   "fuels_20250314.csv",
+			input:  "Инструмент;Цена\nA592;61 250,00\n",
+			want:   `header with column "Код" not found`,
+		},
+		{
+			name:   "csv with malformed number",
+			format: "csv",
+			opts:   FeedOptions{Instrument: "Код", Fields: map[string]string{"price": "Цена"}, DatePattern: `(\d{8})`, DateFormat: "20060102"},
+			file:   "fuels_20250314.csv",
+			input:  "Код;Цена\nA592;61,250,00\n",
+			want:   "line 2, Цена",
+		},
+		{
+			name:   "csv without report date",
+			format: "csv",
+			opts:   FeedOptions{Instrument: "Код", Fields: map[string]string{"price": "Цена"}, DatePattern: `(\d{8})`, DateFormat: "20060102"},
+			file:   "fuels.csv",
+			input:  "Код;Цена\nA592;61 250,00\n",
+			want:   "report date not found",
+		},
+		{
+			name:   "xml records of several dates",
+			format: "xml",
+			opts:   FeedOptions{Record: "instrument", Instrument: "code", Fields: map[string]string{"price": "price"}, Date: "date"},
+			file:   "polymers.xml",
+			input: `<report>
+  <instrument code="PP"><date>14.03.2025</date><price>95400</price></instrument>
+  <instrument code="PE"><date>13.03.2025</date><price>112750</price></instrument>
+</report>`,
+			want: "report contains several dates",
+		},
+		{
+			name:   "html without quotes",
+			format: "html",
+			opts:   chemOptions,
+			file:   "chem_prices.html",
+			input:  `<p>Цены на 14.03.2025</p><table><tr><th>Код</th><th>Цена средняя</th></tr></table>`,
+			want:   "report contains no quotes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, err := NewFeedAdapter(tt.format, tt.opts)
+			if err != nil {
+				t.Fatalf("NewFeedAdapter: %v", err)
+			}
+			_, err = adapter.Parse(strings.NewReader(tt.input), tt.file)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Parse error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// Запись сразу после корневого элемента, дата — атрибут корня
+func TestXMLFeedRecordsUnderRoot(t *testing.T) {
+	adapter, err := NewFeedAdapter("xml", polymersOptions)
+	if err != nil {
+		t.Fatalf("NewFeedAdapter: %v", err)
+	}
+	input := `<report date="14.03.2025"><instrument code="PP"><price>95 400,00</price></instrument><instrument code="PE"><price>112 750,00</price></instrument></report>`
+	report, err := adapter.Parse(strings.NewReader(input), "polymers.xml")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := []Quote{
+		{Line: 1, Instrument: "PP", Values: map[string]float64{"price": 95400}},
+		{Line: 2, Instrument: "PE", Values: map[string]float64{"price": 112750}},
+	}
+	if !reflect.DeepEqual(report.Quotes, want) {
+		t.Errorf("Quotes = %+v, want %+v", report.Quotes, want)
+	}
+	if !report.Date.Equal(reportDate(t)) {
+		t.Errorf("Date = %v, want %v", report.Date, reportDate(t))
+	}
+}
+
+func TestInstrumentMapperFixtures(t *testing.T) {
+	report, err := parseFixture(t, "csv", spimexOptions, "spimex_fuels_20250314.csv", "spimex_fuels_20250314.csv")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	mapper, err := NewInstrumentMapper([]InstrumentRule{
+		{Prefix: "A592", CompanyID: "ROSNEFT", ProductName: "Автобензины", Unit: "руб/т"},
+		{Prefix: "A592", Field: "volume", CompanyID: "ROSNEFT", ProductName: "Автобензины", SeriesName: "Автобензины (объём)", Unit: "т", Aggregate: "sum"},
+		{Prefix: "DSC5", CompanyID: "ROSNEFT", ProductName: "Дизельное топливо", Unit: "руб/т"},
+	})
+	if err != nil {
+		t.Fatalf("NewInstrumentMapper: %v", err)
+	}
+
+	points, unmapped := mapper.Map(report, "spimex_fuels")
+	at := reportDate(t).UTC()
+	want := []DataPoint{
+		{CompanyID: "ROSNEFT", ProductName: "Автобензины", Value: 62215, Unit: "руб/т", Timestamp: at, Quality: 192,
+			Labels: map[string]string{"source": "spimex_fuels", "instrument": "A592ANG060F,A592UFM060F"}},
+		{CompanyID: "ROSNEFT", ProductName: "Автобензины (объём)", Value: 1740, Unit: "т", Timestamp: at, Quality: 192,
+			Labels: map[string]string{"source": "spimex_fuels", "instrument": "A592ANG060F,A592UFM060F"}},
+		{CompanyID: "ROSNEFT", ProductName: "Дизельное топливо", Value: 57040, Unit: "руб/т", Timestamp: at, Quality: 192,
+			Labels: map[string]string{"source": "spimex_fuels", "instrument": "DSC5ANG065F"}},
+	}
+	if !reflect.DeepEqual(points, want) {
+		t.Errorf("points =\n%+v\nwant\n%+v", points, want)
+	}
+	if wantUnmapped := []string{"A595UFM060F", "MAZM100BLZ"}; !reflect.DeepEqual(unmapped, wantUnmapped) {
+		t.Errorf("unmapped = %v, want %v", unmapped, wantUnmapped)
+	}
+}
+
+// Повторная загрузка отчета за ту же дату — переименованная копия или исправленный файл —
+// дает точки с теми же ключами (компания, ряд, дата отчета), поэтому при приеме
+// они заменяют прежние значения, а не дублируют их
+func TestFeedReportDeduplicationByDate(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		opts   FeedOptions
+		file   string
+		copy   string
+	}{
+		{"csv renamed copy", "csv", spimexOptions, "spimex_fuels_20250314.csv", "incoming/spimex_fuels_20250314 (1).csv"},
+		{"xml date from content", "xml", polymersOptions, "polymers_2025-03-14.xml", "polymers_resent.xml"},
+		{"html date from header", "html", chemOptions, "chem_prices_14032025.html", "chem_prices_latest.html"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := parseFixture(t, tt.format, tt.opts, tt.file, tt.file)
+			if err != nil {
+				t.Fatalf("Parse %s: %v", tt.file, err)
+			}
+			second, err := parseFixture(t, tt.format, tt.opts, tt.file, tt.copy)
+			if err != nil {
+				t.Fatalf("Parse %s: %v", tt.copy, err)
+			}
+			if !first.Date.Equal(second.Date) {
+				t.Fatalf("report dates differ: %v and %v", first.Date, second.Date)
+			}
+		})
+	}
+
+	report, err := parseFixture(t, "xml", polymersOptions, "polymers_2025-03-14.xml", "polymers_2025-03-14.xml")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	mapper, err := NewInstrumentMapper([]InstrumentRule{
+		{Code: "PP-H030-TOB", CompanyID: "SIBUR_TOBOLSK", ProductName: "Полипропилен"},
+		{Code: "PP-H030-TOM", CompanyID: "SIBUR_TOBOLSK", ProductName: "Полипропилен"},
+	})
+	if err != nil {
+		t.Fatalf("NewInstrumentMapper: %v", err)
+	}
+
+	// Несколько инструментов одного ряда за дату дают одну точку
+	points, _ := mapper.Map(report, "polymers")
+	if len(points) != 1 {
+		t.Fatalf("got %d points, want 1: %+v", len(points), points)
+	}
+	if p := points[0]; p.Value != 95750 || !p.Timestamp.Equal(reportDate(t)) {
+		t.Errorf("point = %+v, want value 95750 at %v", p, reportDate(t))
+	}
+
+	corrected := *report
+	corrected.Quotes = append([]Quote(nil), report.Quotes...)
+	corrected.Quotes[0].Values = map[string]float64{"price": 95000}
+	revised, _ := mapper.Map(&corrected, "polymers")
+	if len(revised) != 1 {
+		t.Fatalf("got %d points for the corrected report, want 1", len(revised))
+	}
+	if r, p := revised[0], points[0]; r.CompanyID != p.CompanyID || r.ProductName != p.ProductName || !r.Timestamp.Equal(p.Timestamp) {
+		t.Errorf("corrected point %+v has a different key than %+v", r, p)
+	}
+}
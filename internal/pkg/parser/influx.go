@@ -0,0 +1,205 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParsePrecision разбирает точность временных меток протокола InfluxDB
+// (n, ns, u, us, ms, s, m, h; пусто — наносекунды)
+func ParsePrecision(s string) (time.Duration, error) {
+	switch strings.TrimSpace(s) {
+	case "", "n", "ns":
+		return time.Nanosecond, nil
+	case "u", "us", "µ":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	}
+	return 0, fmt.Errorf("unknown precision %q: use ns, us, ms or s", s)
+}
+
+// ParseLineProtocol разбирает строки протокола InfluxDB:
+//
+//	measurement[,tag=value...] field=value[,field=value...] [timestamp]
+//
+// Каждое числовое или логическое поле дает отдельный образец; строковые поля пропускаются.
+// Index образца — номер строки с нуля. Строки без метки времени получают время now.
+func ParseLineProtocol(r io.Reader, precision time.Duration, now time.Time, maxSamples int) ([]SampleRecord, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var records []SampleRecord
+	for line := 0; scanner.Scan(); line++ {
+		text := string(bytes.TrimSpace(scanner.Bytes()))
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		samples, err := parseLine(text, precision, now)
+		if err != nil {
+			records = append(records, SampleRecord{Index: line, Err: err})
+			continue
+		}
+		for _, s := range samples {
+			if maxSamples > 0 && len(records) == maxSamples {
+				return nil, ErrTooManyRecords
+			}
+			records = append(records, SampleRecord{Index: line, Sample: s})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read line protocol: %w", err)
+	}
+	return records, nil
+}
+
+func parseLine(line string, precision time.Duration, now time.Time) ([]Sample, error) {
+	sections := splitEscaped(line, ' ', true)
+	if len(sections) < 2 || len(sections) > 3 {
+		return nil, errors.New("expected measurement, fields and optional timestamp")
+	}
+
+	series := splitEscaped(sections[0], ',', false)
+	measurement := unescape(series[0])
+	if measurement == "" {
+		return nil, errors.New("measurement is required")
+	}
+
+	tags := make(map[string]string, len(series)-1)
+	for _, pair := range series[1:] {
+		key, value, ok := cutEscaped(pair, '=')
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid tag %q", pair)
+		}
+		tags[unescape(key)] = unescape(value)
+	}
+
+	timestamp := now
+	if len(sections) == 3 {
+		ts, err := strconv.ParseInt(sections[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q", sections[2])
+		}
+		timestamp = time.Unix(0, ts*int64(precision)).UTC()
+	}
+
+	var samples []Sample
+	for _, pair := range splitEscaped(sections[1], ',', true) {
+		key, raw, ok := cutEscaped(pair, '=')
+		if !ok || key == "" || raw == "" {
+			return nil, fmt.Errorf("invalid field %q", pair)
+		}
+
+		value, numeric, err := fieldValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", unescape(key), err)
+		}
+		if !numeric {
+			continue
+		}
+		samples = append(samples, Sample{
+			Measurement: measurement,
+			Field:       unescape(key),
+			Tags:        tags,
+			Value:       value,
+			Timestamp:   timestamp,
+		})
+	}
+
+	return samples, nil
+}
+
+// fieldValue разбирает значение поля; numeric = false для строковых значений
+func fieldValue(raw string) (float64, bool, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		if len(raw) < 2 || !strings.HasSuffix(raw, `"`) {
+			return 0, false, errors.New("unterminated string value")
+		}
+		return 0, false, nil
+	case raw == "t" || raw == "T" || strings.EqualFold(raw, "true"):
+		return 1, true, nil
+	case raw == "f" || raw == "F" || strings.EqualFold(raw, "false"):
+		return 0, true, nil
+	case strings.HasSuffix(raw, "i"):
+		v, err := strconv.ParseInt(strings.TrimSuffix(raw, "i"), 10, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid integer %q", raw)
+		}
+		return float64(v), true, nil
+	case strings.HasSuffix(raw, "u"):
+		v, err := strconv.ParseUint(strings.TrimSuffix(raw, "u"), 10, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		return float64(v), true, nil
+	}
+
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid number %q", raw)
+	}
+	return v, true, nil
+}
+
+// splitEscaped делит s по sep, пропуская экранированные разделители
+// и (при quotes) разделители внутри строк в двойных кавычках
+func splitEscaped(s string, sep byte, quotes bool) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case c == '"' && quotes:
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// cutEscaped делит s по первому неэкранированному sep
+func cutEscaped(s string, sep byte) (string, string, bool) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// unescape убирает обратную косую черту перед экранированными символами
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(` ,="\`, s[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,204 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"petrochemical-data-platform/internal/pkg/quality"
+)
+
+// Sample — значение метрики из протоколов InfluxDB и Prometheus до сопоставления с продуктом
+type Sample struct {
+	Measurement string            // Измерение InfluxDB или имя метрики Prometheus
+	Field       string            // Поле InfluxDB; пусто для Prometheus
+	Tags        map[string]string // Теги InfluxDB или метки Prometheus
+	Value       float64
+	Timestamp   time.Time
+}
+
+// SampleRecord — образец или ошибка разбора с номером записи во входных данных
+type SampleRecord struct {
+	Index  int
+	Sample Sample
+	Err    error
+}
+
+// MappingRule сопоставляет образцы с полями точки. Match и Field — регулярные выражения
+// для измерения и поля (пусто — любое значение). Остальные поля — шаблоны с подстановками
+// {measurement}, {field} и {tag:имя}.
+type MappingRule struct {
+	Match       string
+	Field       string
+	CompanyID   string
+	ProductName string
+	Unit        string
+	Tags        []string          // Пустые после подстановки теги отбрасываются
+	Labels      map[string]string // Пустые после подстановки метки отбрасываются
+}
+
+// Mapper применяет первое подходящее правило
+type Mapper struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	match       *regexp.Regexp
+	field       *regexp.Regexp
+	companyID   template
+	productName template
+	unit        template
+	tags        []template
+	labels      map[string]template
+}
+
+// NewMapper проверяет и компилирует правила
+func NewMapper(rules []MappingRule) (*Mapper, error) {
+	m := &Mapper{}
+	for i, r := range rules {
+		var c compiledRule
+		var err error
+
+		if c.match, err = anchored(r.Match); err != nil {
+			return nil, fmt.Errorf("mapping rule %d: invalid match: %w", i, err)
+		}
+		if c.field, err = anchored(r.Field); err != nil {
+			return nil, fmt.Errorf("mapping rule %d: invalid field: %w", i, err)
+		}
+		if r.CompanyID == "" || r.ProductName == "" {
+			return nil, fmt.Errorf("mapping rule %d: company_id and product_name are required", i)
+		}
+
+		if c.companyID, err = parseTemplate(r.CompanyID); err != nil {
+			return nil, fmt.Errorf("mapping rule %d: company_id: %w", i, err)
+		}
+		if c.productName, err = parseTemplate(r.ProductName); err != nil {
+			return nil, fmt.Errorf("mapping rule %d: product_name: %w", i, err)
+		}
+		if c.unit, err = parseTemplate(r.Unit); err != nil {
+			return nil, fmt.Errorf("mapping rule %d: unit: %w", i, err)
+		}
+		for _, t := range r.Tags {
+			tag, err := parseTemplate(t)
+			if err != nil {
+				return nil, fmt.Errorf("mapping rule %d: tag: %w", i, err)
+			}
+			c.tags = append(c.tags, tag)
+		}
+		c.labels = make(map[string]template, len(r.Labels))
+		for key, t := range r.Labels {
+			if c.labels[key], err = parseTemplate(t); err != nil {
+				return nil, fmt.Errorf("mapping rule %d: label %s: %w", i, key, err)
+			}
+		}
+
+		m.rules = append(m.rules, c)
+	}
+	return m, nil
+}
+
+// Map сопоставляет образец с точкой. ok = false, если ни одно правило не подходит:
+// такие образцы (например, системные метрики агента) не относятся к платформе.
+func (m *Mapper) Map(s Sample) (p DataPoint, ok bool, err error) {
+	for _, r := range m.rules {
+		if !r.match.MatchString(s.Measurement) || !r.field.MatchString(s.Field) {
+			continue
+		}
+
+		p = DataPoint{
+			CompanyID:   r.companyID.expand(s),
+			ProductName: r.productName.expand(s),
+			Unit:        r.unit.expand(s),
+			Value:       s.Value,
+			Timestamp:   s.Timestamp,
+			Quality:     uint16(quality.Good),
+		}
+		for _, t := range r.tags {
+			if tag := t.expand(s); tag != "" {
+				p.Tags = append(p.Tags, tag)
+			}
+		}
+		for key, t := range r.labels {
+			if value := t.expand(s); value != "" {
+				if p.Labels == nil {
+					p.Labels = make(map[string]string)
+				}
+				p.Labels[key] = value
+			}
+		}
+
+		switch {
+		case p.CompanyID == "":
+			return p, true, errors.New("mapping produced an empty company_id")
+		case p.ProductName == "":
+			return p, true, errors.New("mapping produced an empty product_name")
+		}
+		return p, true, nil
+	}
+	return p, false, nil
+}
+
+func anchored(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		expr = ".*"
+	}
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+// template — шаблон значения: чередование текста и подстановок
+type template []templatePart
+
+type templatePart struct {
+	text string
+	kind string // "", "measurement", "field" или "tag"
+	tag  string
+}
+
+func parseTemplate(s string) (template, error) {
+	var t template
+	for s != "" {
+		open := strings.IndexByte(s, '{')
+		if open < 0 {
+			t = append(t, templatePart{text: s})
+			break
+		}
+		if open > 0 {
+			t = append(t, templatePart{text: s[:open]})
+		}
+
+		end := strings.IndexByte(s[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed placeholder in %q", s)
+		}
+		name := s[open+1 : open+end]
+		switch {
+		case name == "measurement" || name == "field":
+			t = append(t, templatePart{kind: name})
+		case strings.HasPrefix(name, "tag:") && len(name) > len("tag:"):
+			t = append(t, templatePart{kind: "tag", tag: strings.TrimPrefix(name, "tag:")})
+		default:
+			return nil, fmt.Errorf("unknown placeholder {%s}: use {measurement}, {field} or {tag:name}", name)
+		}
+		s = s[open+end+1:]
+	}
+	return t, nil
+}
+
+func (t template) expand(s Sample) string {
+	var b strings.Builder
+	for _, part := range t {
+		switch part.kind {
+		case "measurement":
+			b.WriteString(s.Measurement)
+		case "field":
+			b.WriteString(s.Field)
+		case "tag":
+			b.WriteString(s.Tags[part.tag])
+		default:
+			b.WriteString(part.text)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
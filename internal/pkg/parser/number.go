@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParseNumber разбирает число в русском или английском написании: "1 234,5", "1.234,5",
+// "(12,5)" (отрицательное), "12,5%". Пробелы любых видов и апострофы считаются разделителями
+// разрядов. При десятичной запятой точка считается разделителем разрядов, только если
+// в числе есть запятая; иначе это десятичная точка (так записывает числа XLSX).
+func ParseNumber(s, decimal string) (float64, error) {
+	raw := s
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '\'' || r == '’' {
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.TrimSuffix(s, "%")
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+	s = strings.Replace(s, "−", "-", 1) // Типографский минус
+
+	if decimal == "," {
+		if strings.Contains(s, ",") {
+			s = strings.ReplaceAll(s, ".", "")
+			s = strings.Replace(s, ",", ".", 1)
+		}
+	} else {
+		s = strings.ReplaceAll(s, ",", "")
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid number %q", raw)
+	}
+	if negative {
+		v = -v
+	}
+	return v, nil
+}
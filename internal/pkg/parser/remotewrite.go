@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// staleNaN — маркер устаревшего ряда Prometheus, а не измеренное значение
+const staleNaN uint64 = 0x7ff0000000000002
+
+// Номера полей сообщений prometheus.WriteRequest, TimeSeries, Label и Sample
+const (
+	writeRequestTimeseries = 1
+	timeSeriesLabels       = 1
+	timeSeriesSamples      = 2
+	labelName              = 1
+	labelValue             = 2
+	sampleValue            = 1
+	sampleTimestamp        = 2
+)
+
+// ParseRemoteWrite разбирает распакованное (после snappy) сообщение WriteRequest
+// Prometheus remote_write 1.0. Метка __name__ становится Measurement, остальные — Tags.
+// Index образца — номер ряда с нуля. Маркеры устаревших рядов пропускаются.
+func ParseRemoteWrite(b []byte, maxSamples int) ([]SampleRecord, error) {
+	var records []SampleRecord
+	series := 0
+
+	err := consumeMessage(b, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if num != writeRequestTimeseries || typ != protowire.BytesType {
+			return nil
+		}
+
+		samples, err := parseTimeSeries(v)
+		if err != nil {
+			records = append(records, SampleRecord{Index: series, Err: err})
+		}
+		for _, s := range samples {
+			if maxSamples > 0 && len(records) == maxSamples {
+				return ErrTooManyRecords
+			}
+			records = append(records, SampleRecord{Index: series, Sample: s})
+		}
+		series++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func parseTimeSeries(b []byte) ([]Sample, error) {
+	tags := make(map[string]string)
+	var measurement string
+	var samples []Sample
+
+	err := consumeMessage(b, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+
+		switch num {
+		case timeSeriesLabels:
+			var name, value string
+			err := consumeMessage(v, func(num protowire.Number, typ protowire.Type, v []byte) error {
+				switch {
+				case num == labelName && typ == protowire.BytesType:
+					name = string(v)
+				case num == labelValue && typ == protowire.BytesType:
+					value = string(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if name == "__name__" {
+				measurement = value
+			} else {
+				tags[name] = value
+			}
+
+		case timeSeriesSamples:
+			var bits uint64
+			var ts int64
+			err := consumeMessage(v, func(num protowire.Number, typ protowire.Type, v []byte) error {
+				switch {
+				case num == sampleValue && typ == protowire.Fixed64Type:
+					bits, _ = protowire.ConsumeFixed64(v)
+				case num == sampleTimestamp && typ == protowire.VarintType:
+					u, _ := protowire.ConsumeVarint(v)
+					ts = int64(u)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if bits == staleNaN {
+				return nil
+			}
+			samples = append(samples, Sample{Value: math.Float64frombits(bits), Timestamp: time.UnixMilli(ts).UTC()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if measurement == "" {
+		return nil, errors.New("time series has no __name__ label")
+	}
+	for i := range samples {
+		samples[i].Measurement = measurement
+		samples[i].Tags = tags
+	}
+	return samples, nil
+}
+
+// consumeMessage обходит поля сообщения protobuf. Для полей длины передается содержимое,
+// для остальных — закодированное значение.
+func consumeMessage(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("malformed protobuf: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		var v []byte
+		if typ == protowire.BytesType {
+			v, n = protowire.ConsumeBytes(b)
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n >= 0 {
+				v = b[:n]
+			}
+		}
+		if n < 0 {
+			return fmt.Errorf("malformed protobuf: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		if err := fn(num, typ, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+// Package quality описывает коды качества данных в формате OPC DA (совместимом с OPC UA).
+//
+// Младший байт кода имеет вид QQSSSSLL: QQ — класс (good, uncertain, bad),
+// SSSS — подстатус, LL — признак ограничения значения. Старший байт зарезервирован
+// за поставщиком данных и сохраняется без изменений.
+package quality
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Code — 16-битный код качества точки
+type Code uint16
+
+// Class — класс качества
+type Class uint8
+
+const (
+	ClassBad       Class = 0x00
+	ClassUncertain Class = 0x40
+	ClassGood      Class = 0xC0
+)
+
+// Limit — признак ограничения значения
+type Limit uint8
+
+const (
+	LimitNone     Limit = 0 // Значение не ограничено
+	LimitLow      Limit = 1 // Значение на нижней границе
+	LimitHigh     Limit = 2 // Значение на верхней границе
+	LimitConstant Limit = 3 // Значение не может меняться
+)
+
+// Маски полей кода
+const (
+	ClassMask     Code = 0xC0
+	SubstatusMask Code = 0x3C
+	LimitMask     Code = 0x03
+)
+
+// Стандартные коды OPC DA
+const (
+	Bad                   Code = 0x00
+	BadConfigError        Code = 0x04
+	BadNotConnected       Code = 0x08
+	BadDeviceFailure      Code = 0x0C
+	BadSensorFailure      Code = 0x10
+	BadLastKnownValue     Code = 0x14
+	BadCommFailure        Code = 0x18
+	BadOutOfService       Code = 0x1C
+	BadWaitingInitialData Code = 0x20
+
+	Uncertain                  Code = 0x40
+	UncertainLastUsableValue   Code = 0x44
+	UncertainSensorNotAccurate Code = 0x50
+	UncertainEUExceeded        Code = 0x54
+	UncertainSubNormal         Code = 0x58
+
+	Good              Code = 0xC0
+	GoodLocalOverride Code = 0xD8
+)
+
+var substatusNames = map[Code]string{
+	Bad:                   "non_specific",
+	BadConfigError:        "config_error",
+	BadNotConnected:       "not_connected",
+	BadDeviceFailure:      "device_failure",
+	BadSensorFailure:      "sensor_failure",
+	BadLastKnownValue:     "last_known_value",
+	BadCommFailure:        "comm_failure",
+	BadOutOfService:       "out_of_service",
+	BadWaitingInitialData: "waiting_for_initial_data",
+
+	Uncertain:                  "non_specific",
+	UncertainLastUsableValue:   "last_usable_value",
+	UncertainSensorNotAccurate: "sensor_not_accurate",
+	UncertainEUExceeded:        "eu_units_exceeded",
+	UncertainSubNormal:         "sub_normal",
+
+	Good:              "non_specific",
+	GoodLocalOverride: "local_override",
+}
+
+// New собирает код из стандартного кода с подстатусом (например, BadCommFailure) и признака ограничения
+func New(substatus Code, limit Limit) Code {
+	return substatus&(ClassMask|SubstatusMask) | Code(limit)&LimitMask
+}
+
+// Class возвращает класс качества. Зарезервированное значение QQ=10 считается bad.
+func (c Code) Class() Class {
+	switch Class(c & ClassMask) {
+	case ClassGood:
+		return ClassGood
+	case ClassUncertain:
+		return ClassUncertain
+	}
+	return ClassBad
+}
+
+// IsGood сообщает, относится ли код к классу good
+func (c Code) IsGood() bool {
+	return c.Class() == ClassGood
+}
+
+// Limit возвращает признак ограничения значения
+func (c Code) Limit() Limit {
+	return Limit(c & LimitMask)
+}
+
+// Vendor возвращает старший байт, зарезервированный за поставщиком
+func (c Code) Vendor() uint8 {
+	return uint8(c >> 8)
+}
+
+// Substatus возвращает название подстатуса или "unknown" для нестандартного значения
+func (c Code) Substatus() string {
+	if name, ok := substatusNames[c&(ClassMask|SubstatusMask)]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// String возвращает код в виде "bad/comm_failure" или "good/non_specific/high"
+func (c Code) String() string {
+	s := c.Class().String() + "/" + c.Substatus()
+	if l := c.Limit(); l != LimitNone {
+		s += "/" + l.String()
+	}
+	return s
+}
+
+// Parse разбирает код из записи String: "bad/comm_failure", "good/non_specific/high".
+// Подстатус и признак ограничения можно опустить: "uncertain" — Uncertain без подстатуса.
+func Parse(s string) (Code, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(s)), "/")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid quality %q: use class[/substatus[/limit]]", s)
+	}
+
+	class, err := ParseClass(parts[0])
+	if err != nil {
+		return 0, err
+	}
+	code := Code(class)
+
+	if len(parts) > 1 && parts[1] != "non_specific" {
+		found := false
+		for c, name := range substatusNames {
+			if c.Class() == class && name == parts[1] {
+				code, found = c, true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown %s quality substatus %q", class, parts[1])
+		}
+	}
+
+	limit := LimitNone
+	if len(parts) > 2 {
+		switch parts[2] {
+		case "none":
+		case "low":
+			limit = LimitLow
+		case "high":
+			limit = LimitHigh
+		case "constant":
+			limit = LimitConstant
+		default:
+			return 0, fmt.Errorf("unknown quality limit %q: use none, low, high or constant", parts[2])
+		}
+	}
+	return New(code, limit), nil
+}
+
+// Info — разобранный код качества для ответов API
+type Info struct {
+	Class     string `json:"class"`
+	Substatus string `json:"substatus"`
+	Limit     string `json:"limit,omitempty"`
+}
+
+// Decode разбирает код на составляющие
+func (c Code) Decode() Info {
+	info := Info{Class: c.Class().String(), Substatus: c.Substatus()}
+	if l := c.Limit(); l != LimitNone {
+		info.Limit = l.String()
+	}
+	return info
+}
+
+// String возвращает название класса
+func (c Class) String() string {
+	switch c {
+	case ClassGood:
+		return "good"
+	case ClassUncertain:
+		return "uncertain"
+	}
+	return "bad"
+}
+
+// ParseClass разбирает название класса (good, uncertain, bad)
+func ParseClass(s string) (Class, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "good":
+		return ClassGood, nil
+	case "uncertain":
+		return ClassUncertain, nil
+	case "bad":
+		return ClassBad, nil
+	}
+	return 0, fmt.Errorf("unknown quality class %q: use good, uncertain or bad", s)
+}
+
+// String возвращает название признака ограничения
+func (l Limit) String() string {
+	switch l {
+	case LimitLow:
+		return "low"
+	case LimitHigh:
+		return "high"
+	case LimitConstant:
+		return "constant"
+	}
+	return "none"
+}
+
+// Серьезность и биты ограничения StatusCode OPC UA
+const (
+	uaSeverityMask      = 0xC0000000
+	uaSeverityGood      = 0x00000000
+	uaSeverityUncertain = 0x40000000
+	uaLimitShift        = 8
+)
+
+// Соответствие распространенных кодов OPC UA подстатусам OPC DA
+var uaSubstatus = map[uint32]Code{
+	0x80890000: BadConfigError,             // BadConfigurationError
+	0x808A0000: BadNotConnected,            // BadNotConnected
+	0x808B0000: BadDeviceFailure,           // BadDeviceFailure
+	0x808C0000: BadSensorFailure,           // BadSensorFailure
+	0x80050000: BadCommFailure,             // BadCommunicationError
+	0x808D0000: BadOutOfService,            // BadOutOfService
+	0x80320000: BadWaitingInitialData,      // BadWaitingForInitialData
+	0x40900000: UncertainLastUsableValue,   // UncertainLastUsableValue
+	0x40930000: UncertainSensorNotAccurate, // UncertainSensorNotAccurate
+	0x40940000: UncertainEUExceeded,        // UncertainEngineeringUnitsExceeded
+	0x40950000: UncertainSubNormal,         // UncertainSubNormal
+	0x00960000: GoodLocalOverride,          // GoodLocalOverride
+}
+
+// FromUAStatus переводит StatusCode OPC UA в код OPC DA с сохранением класса,
+// известных подстатусов и битов ограничения
+func FromUAStatus(status uint32) Code {
+	limit := Limit((status >> uaLimitShift) & 0x3)
+
+	if sub, ok := uaSubstatus[status&0xFFFF0000]; ok {
+		return New(sub, limit)
+	}
+
+	switch status & uaSeverityMask {
+	case uaSeverityGood:
+		return New(Good, limit)
+	case uaSeverityUncertain:
+		return New(Uncertain, limit)
+	}
+	return New(Bad, limit)
+}
@@ -0,0 +1,287 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/pkg/parser"
+	"petrochemical-data-platform/internal/pkg/quality"
+)
+
+// Состояния оборудования
+const (
+	StateStopped  = "stopped"
+	StateStarting = "starting" // Выпуск растет до уставки
+	StateRunning  = "running"
+	StateStopping = "stopping" // Выпуск снижается до нуля
+)
+
+// Команды оборудования (ControlCommand.Command)
+const (
+	CommandStart       = "start"
+	CommandStop        = "stop"
+	CommandSetSetpoint = "set_setpoint" // Уставка выпуска в параметре value
+)
+
+// EquipmentModel описывает установку, управляемую командами: выпуск продукта стремится
+// к уставке в работе и к нулю при останове со скоростью ramp_rate
+type EquipmentModel struct {
+	EquipmentID string            `mapstructure:"equipment_id"`
+	CompanyID   string            `mapstructure:"company_id"`
+	ProductName string            `mapstructure:"product_name"`
+	Unit        string            `mapstructure:"unit"`
+	State       string            `mapstructure:"state"`    // Начальное состояние: running (по умолчанию) или stopped
+	Setpoint    float64           `mapstructure:"setpoint"` // Начальная уставка выпуска
+	MinSetpoint *float64          `mapstructure:"min_setpoint"`
+	MaxSetpoint *float64          `mapstructure:"max_setpoint"`
+	RampRate    float64           `mapstructure:"ramp_rate"` // Изменение выпуска в единицах за минуту; 0 — мгновенно
+	Noise       float64           `mapstructure:"noise"`
+	AckDelay    time.Duration     `mapstructure:"ack_delay"`    // Задержка подтверждения команды, по умолчанию 500ms
+	AckJitter   time.Duration     `mapstructure:"ack_jitter"`   // Случайная добавка к задержке
+	FailureRate float64           `mapstructure:"failure_rate"` // Доля команд, завершающихся отказом устройства
+	Tags        []string          `mapstructure:"tags"`
+	Labels      map[string]string `mapstructure:"labels"`
+}
+
+func (m *EquipmentModel) validate() error {
+	if m.EquipmentID == "" {
+		return errors.New("equipment_id is required")
+	}
+	if m.CompanyID == "" || m.ProductName == "" {
+		return errors.New("company_id and product_name are required")
+	}
+	switch m.State {
+	case "":
+		m.State = StateRunning
+	case StateRunning, StateStopped:
+	default:
+		return fmt.Errorf("invalid state %q: use running or stopped", m.State)
+	}
+	if m.MinSetpoint != nil && m.MaxSetpoint != nil && *m.MinSetpoint > *m.MaxSetpoint {
+		return errors.New("min_setpoint is greater than max_setpoint")
+	}
+	if !m.inRange(m.Setpoint) {
+		return fmt.Errorf("setpoint %g is outside the allowed range", m.Setpoint)
+	}
+	if m.RampRate < 0 {
+		return errors.New("ramp_rate must not be negative")
+	}
+	if m.FailureRate < 0 || m.FailureRate > 1 {
+		return errors.New("failure_rate must be between 0 and 1")
+	}
+	if m.AckDelay <= 0 {
+		m.AckDelay = 500 * time.Millisecond
+	}
+	return nil
+}
+
+func (m *EquipmentModel) inRange(v float64) bool {
+	return (m.MinSetpoint == nil || v >= *m.MinSetpoint) && (m.MaxSetpoint == nil || v <= *m.MaxSetpoint)
+}
+
+// Equipment — конечный автомат установки. Состояние продвигается по модельному времени
+// запросами точек и командами; методы безопасны для одновременного вызова.
+type Equipment struct {
+	model    *EquipmentModel
+	scenario string
+	key      uint64
+
+	mu       sync.Mutex
+	state    string
+	setpoint float64
+	output   float64   // Выпуск без шума
+	updated  time.Time // Момент, к которому рассчитано состояние
+}
+
+func newEquipment(m *EquipmentModel, sc *Scenario) *Equipment {
+	e := &Equipment{
+		model:    m,
+		scenario: sc.Name,
+		key:      seriesKey(sc.Seed, m.CompanyID, m.EquipmentID),
+		state:    m.State,
+		setpoint: m.Setpoint,
+	}
+	if e.state == StateRunning {
+		e.output = m.Setpoint
+	}
+	return e
+}
+
+// ID возвращает идентификатор оборудования
+func (e *Equipment) ID() string {
+	return e.model.EquipmentID
+}
+
+// State возвращает текущее состояние и уставку
+func (e *Equipment) State() (string, float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state, e.setpoint
+}
+
+// AckDelay возвращает задержку подтверждения команды: ack_delay плюс доля ack_jitter,
+// определяемая идентификатором команды
+func (e *Equipment) AckDelay(commandID string) time.Duration {
+	return e.model.AckDelay + time.Duration(e.uniform(commandID, 1)*float64(e.model.AckJitter))
+}
+
+// Execute выполняет команду в момент at и возвращает подтверждение. Отказ устройства
+// (failure_rate) определяется идентификатором команды, поэтому повтор дает тот же исход.
+func (e *Equipment) Execute(cmd domain.ControlCommand, at time.Time) domain.ControlAck {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.advance(at)
+
+	ack := domain.ControlAck{
+		CommandID:   cmd.ID,
+		EquipmentID: e.model.EquipmentID,
+		Status:      domain.AckExecuted,
+		ExecutedAt:  at.UTC(),
+	}
+	reject := func(reason, format string, args ...interface{}) domain.ControlAck {
+		ack.Status, ack.Reason, ack.Error = domain.AckRejected, reason, fmt.Sprintf(format, args...)
+		ack.State = e.state
+		return ack
+	}
+
+	switch cmd.Command {
+	case CommandStart, CommandStop, CommandSetSetpoint:
+	default:
+		return reject(domain.AckReasonUnsupportedCommand, "command %q is not supported", cmd.Command)
+	}
+	if e.model.FailureRate > 0 && e.uniform(cmd.ID, 0) < e.model.FailureRate {
+		ack.Status, ack.Reason, ack.Error = domain.AckFailed, domain.AckReasonDeviceFailure, "device did not confirm the command"
+		ack.State = e.state
+		return ack
+	}
+
+	switch cmd.Command {
+	case CommandStart:
+		if e.state == StateRunning || e.state == StateStarting {
+			return reject(domain.AckReasonInvalidState, "equipment is already %s", e.state)
+		}
+		e.state = StateStarting
+	case CommandStop:
+		if e.state == StateStopped || e.state == StateStopping {
+			return reject(domain.AckReasonInvalidState, "equipment is already %s", e.state)
+		}
+		e.state = StateStopping
+	case CommandSetSetpoint:
+		v, ok := numberParam(cmd.Parameters, "value")
+		if !ok {
+			return reject(domain.AckReasonInvalidParameters, "numeric parameter value is required")
+		}
+		if !e.model.inRange(v) || v < 0 {
+			return reject(domain.AckReasonInvalidParameters, "setpoint %g is outside the allowed range", v)
+		}
+		e.setpoint = v
+	}
+	e.settle()
+
+	ack.State = e.state
+	return ack
+}
+
+// Point возвращает точку выпуска в момент t. Метка state показывает состояние установки.
+func (e *Equipment) Point(t time.Time) parser.DataPoint {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.advance(t)
+
+	value := e.output
+	if value > 0 && e.model.Noise > 0 {
+		value = math.Max(0, value*(1+e.model.Noise*normal(e.key, t.UnixNano())))
+	}
+	value = math.Round(value*1000) / 1000
+
+	labels := make(map[string]string, len(e.model.Labels)+4)
+	for k, v := range e.model.Labels {
+		labels[k] = v
+	}
+	labels["source"] = "simulator"
+	labels["scenario"] = e.scenario
+	labels["equipment_id"] = e.model.EquipmentID
+	labels["state"] = e.state
+
+	return parser.DataPoint{
+		CompanyID:   e.model.CompanyID,
+		ProductName: e.model.ProductName,
+		Value:       value,
+		Unit:        e.model.Unit,
+		Timestamp:   t,
+		Quality:     uint16(quality.Good),
+		Tags:        e.model.Tags,
+		Labels:      labels,
+	}
+}
+
+// advance продвигает выпуск к цели до момента t. Более ранние моменты (догоняющая
+// генерация после команды) состояние не меняют.
+func (e *Equipment) advance(t time.Time) {
+	if e.updated.IsZero() {
+		e.updated = t
+		return
+	}
+	if !t.After(e.updated) {
+		return
+	}
+
+	target := e.setpoint
+	if e.state == StateStopping || e.state == StateStopped {
+		target = 0
+	}
+	if e.model.RampRate == 0 {
+		e.output = target
+	} else {
+		step := e.model.RampRate * t.Sub(e.updated).Minutes()
+		if e.output < target {
+			e.output = math.Min(target, e.output+step)
+		} else {
+			e.output = math.Max(target, e.output-step)
+		}
+	}
+	e.updated = t
+	e.settle()
+}
+
+// settle завершает пуск и останов, когда выпуск достиг цели
+func (e *Equipment) settle() {
+	if e.model.RampRate == 0 {
+		switch e.state {
+		case StateStarting, StateRunning:
+			e.output = e.setpoint
+		case StateStopping, StateStopped:
+			e.output = 0
+		}
+	}
+	switch {
+	case e.state == StateStarting && e.output == e.setpoint:
+		e.state = StateRunning
+	case e.state == StateStopping && e.output == 0:
+		e.state = StateStopped
+	}
+}
+
+// uniform возвращает число в [0, 1), однозначно определяемое оборудованием, командой и потоком
+func (e *Equipment) uniform(commandID string, stream uint64) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(commandID))
+	x := splitmix(e.key ^ h.Sum64() ^ splitmix(stream))
+	return float64(x>>11) / (1 << 53)
+}
+
+// numberParam извлекает числовой параметр команды (JSON: число)
+func numberParam(params map[string]interface{}, name string) (float64, bool) {
+	switch v := params[name].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
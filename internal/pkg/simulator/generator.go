@@ -0,0 +1,199 @@
+package simulator
+
+import (
+	"hash/fnv"
+	"math"
+	"time"
+
+	"petrochemical-data-platform/internal/pkg/parser"
+	"petrochemical-data-platform/internal/pkg/quality"
+)
+
+// hoursPerYear — длина года для тренда
+const hoursPerYear = 365.25 * 24
+
+// Generator вычисляет точки рядов сценария в заданные моменты времени
+type Generator struct {
+	sc        *Scenario
+	start     time.Time
+	series    []*series
+	equipment map[string]*Equipment
+	order     []*Equipment // Установки в порядке сценария
+}
+
+// series — ряд сценария: модель, продукт и относящиеся к нему неисправности
+type series struct {
+	model   *Series
+	product string
+	key     uint64 // Зерно шума ряда: от зерна сценария, компании и продукта
+	faults  []*Fault
+}
+
+// NewGenerator проверяет сценарий и готовит ряды. Начало сценария — start из сценария,
+// а в реальном режиме — текущее время, округленное до шага.
+func NewGenerator(sc *Scenario) (*Generator, error) {
+	if err := sc.Validate(); err != nil {
+		return nil, err
+	}
+
+	g := &Generator{sc: sc, start: sc.Start}
+	if g.start.IsZero() {
+		g.start = time.Now().UTC().Truncate(sc.Interval)
+	}
+
+	for i := range sc.Series {
+		m := &sc.Series[i]
+		for _, product := range m.Products {
+			s := &series{model: m, product: product, key: seriesKey(sc.Seed, m.CompanyID, product)}
+			for j := range sc.Faults {
+				if f := &sc.Faults[j]; f.matches(m.CompanyID, product) {
+					s.faults = append(s.faults, f)
+				}
+			}
+			g.series = append(g.series, s)
+		}
+	}
+
+	g.equipment = make(map[string]*Equipment, len(sc.Equipment))
+	for i := range sc.Equipment {
+		e := newEquipment(&sc.Equipment[i], sc)
+		g.equipment[e.ID()] = e
+		g.order = append(g.order, e)
+	}
+	return g, nil
+}
+
+// Scenario возвращает сценарий генератора
+func (g *Generator) Scenario() *Scenario {
+	return g.sc
+}
+
+// Start возвращает начало сценария (отсчет неисправностей, заданных смещением)
+func (g *Generator) Start() time.Time {
+	return g.start
+}
+
+// SeriesCount возвращает число рядов, включая установки
+func (g *Generator) SeriesCount() int {
+	return len(g.series) + len(g.order)
+}
+
+// Equipment возвращает установку сценария по идентификатору
+func (g *Generator) Equipment(id string) (*Equipment, bool) {
+	e, ok := g.equipment[id]
+	return e, ok
+}
+
+// EquipmentCount возвращает число установок, управляемых командами
+func (g *Generator) EquipmentCount() int {
+	return len(g.order)
+}
+
+// Points возвращает точки всех рядов и установок в момент t с учетом неисправностей
+// (неисправности относятся к рядам). Ряды в окне пропадания данных точек не дают.
+func (g *Generator) Points(t time.Time) []parser.DataPoint {
+	t = t.UTC()
+	points := make([]parser.DataPoint, 0, g.SeriesCount())
+
+	for _, s := range g.series {
+		value, limit := s.value(g.sc, t)
+		code := quality.New(quality.Good, limit)
+		skip := false
+
+		for _, f := range s.faults {
+			begin, ok := f.window(t, g.start)
+			if !ok {
+				continue
+			}
+			switch f.Type {
+			case FaultOutage:
+				skip = true
+			case FaultStuck:
+				value, limit = s.value(g.sc, begin)
+				code = quality.New(quality.Good, limit)
+			case FaultSpike:
+				value *= f.Magnitude
+			case FaultBadQuality:
+				code = f.code
+			}
+		}
+		if skip {
+			continue
+		}
+
+		labels := make(map[string]string, len(s.model.Labels)+2)
+		for k, v := range s.model.Labels {
+			labels[k] = v
+		}
+		labels["source"] = "simulator"
+		labels["scenario"] = g.sc.Name
+
+		points = append(points, parser.DataPoint{
+			CompanyID:   s.model.CompanyID,
+			ProductName: s.product,
+			Value:       value,
+			Unit:        s.model.Unit,
+			Timestamp:   t,
+			Quality:     uint16(code),
+			Tags:        s.model.Tags,
+			Labels:      labels,
+		})
+	}
+
+	for _, e := range g.order {
+		points = append(points, e.Point(t))
+	}
+	return points
+}
+
+// value вычисляет значение модели без неисправностей и признак ограничения диапазоном
+func (s *series) value(sc *Scenario, t time.Time) (float64, quality.Limit) {
+	m := s.model
+	years := t.Sub(sc.Epoch).Hours() / hoursPerYear
+
+	seasonal := 0.0
+	for _, h := range m.Seasonality {
+		cycles := float64(t.UnixNano()%int64(h.Period))/float64(h.Period) - h.Phase
+		seasonal += h.Amplitude * math.Sin(2*math.Pi*cycles)
+	}
+
+	value := m.Baseline * (1 + m.Trend*years) * (1 + seasonal)
+	if m.Noise > 0 {
+		value *= 1 + m.Noise*normal(s.key, t.UnixNano())
+	}
+	value = math.Round(value*1000) / 1000
+
+	switch {
+	case m.Min != nil && value < *m.Min:
+		return *m.Min, quality.LimitLow
+	case m.Max != nil && value > *m.Max:
+		return *m.Max, quality.LimitHigh
+	}
+	return value, quality.LimitNone
+}
+
+func seriesKey(seed int64, companyID, product string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(companyID))
+	h.Write([]byte{0})
+	h.Write([]byte(product))
+	return h.Sum64() ^ splitmix(uint64(seed))
+}
+
+// normal возвращает стандартное нормальное число, однозначно определяемое ключом ряда
+// и моментом времени (преобразование Бокса — Мюллера над двумя хешами)
+func normal(key uint64, t int64) float64 {
+	a := splitmix(key ^ uint64(t))
+	b := splitmix(a)
+	u1 := (float64(a>>11) + 0.5) / (1 << 53)
+	u2 := float64(b>>11) / (1 << 53)
+	return math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
+}
+
+// splitmix — шаг генератора SplitMix64, используемый как хеш-функция
+func splitmix(x uint64) uint64 {
+	x += 0x9E3779B97F4A7C15
+	x = (x ^ x>>30) * 0xBF58476D1CE4E5B9
+	x = (x ^ x>>27) * 0x94D049BB133111EB
+	return x ^ x>>31
+}
@@ -0,0 +1,283 @@
+// Package simulator генерирует телеметрию по сценариям: базовый уровень, тренд роста
+// или снижения, сезонность и шум для каждого ряда, а также плановые неисправности
+// (пропадание данных, выбросы, зависшие значения, плохое качество). Значения зависят
+// только от сценария, зерна и момента времени, поэтому повторный запуск дает те же данные.
+package simulator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"petrochemical-data-platform/internal/pkg/quality"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// defaultEpoch — начало отсчета тренда, если в сценарии не задано
+var defaultEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Seasonality — гармоника с периодом period и относительной амплитудой amplitude.
+// Phase — сдвиг в долях периода (0.25 — максимум на четверти периода).
+type Seasonality struct {
+	Period    time.Duration `mapstructure:"period"`
+	Amplitude float64       `mapstructure:"amplitude"`
+	Phase     float64       `mapstructure:"phase"`
+}
+
+// Series описывает модель одного или нескольких рядов компании:
+// значение = baseline * (1 + trend * годы от epoch) * (1 + сумма гармоник) * (1 + noise * N(0,1)),
+// ограниченное диапазоном [min, max]
+type Series struct {
+	CompanyID   string            `mapstructure:"company_id"`
+	ProductName string            `mapstructure:"product_name"`
+	Products    []string          `mapstructure:"products"` // Несколько продуктов с одной моделью
+	Unit        string            `mapstructure:"unit"`
+	Baseline    float64           `mapstructure:"baseline"`
+	Trend       float64           `mapstructure:"trend"` // Относительное изменение за год: 0.05 — рост 5%, -0.03 — снижение
+	Seasonality []Seasonality     `mapstructure:"seasonality"`
+	Noise       float64           `mapstructure:"noise"` // Стандартное отклонение шума в долях значения
+	Min         *float64          `mapstructure:"min"`
+	Max         *float64          `mapstructure:"max"`
+	Tags        []string          `mapstructure:"tags"`
+	Labels      map[string]string `mapstructure:"labels"`
+}
+
+// Типы неисправностей
+const (
+	FaultOutage     = "outage"      // Точки не передаются
+	FaultSpike      = "spike"       // Значение умножается на magnitude
+	FaultStuck      = "stuck"       // Значение замирает на начале окна
+	FaultBadQuality = "bad_quality" // Значение передается с кодом качества quality
+)
+
+// Fault — плановая неисправность рядов. Окно начинается в момент at (время RFC3339 или
+// смещение от начала сценария), длится duration и повторяется каждые every (count раз;
+// 0 — без ограничения). Пустые company_id и product_name относят неисправность ко всем рядам.
+type Fault struct {
+	Name        string        `mapstructure:"name"`
+	Type        string        `mapstructure:"type"`
+	CompanyID   string        `mapstructure:"company_id"`
+	ProductName string        `mapstructure:"product_name"`
+	At          string        `mapstructure:"at"`
+	Duration    time.Duration `mapstructure:"duration"`
+	Every       time.Duration `mapstructure:"every"`
+	Count       int           `mapstructure:"count"`
+	Magnitude   float64       `mapstructure:"magnitude"` // Множитель выброса, по умолчанию 3
+	Quality     string        `mapstructure:"quality"`   // Код качества, по умолчанию bad/sensor_failure
+
+	start   time.Time
+	offset  time.Duration
+	code    quality.Code
+	matches func(companyID, productName string) bool
+}
+
+// Scenario — сценарий генерации. Без start сценарий идет в реальном времени от момента
+// запуска; со start и end генерируется история за период.
+type Scenario struct {
+	Name      string           `mapstructure:"name"`
+	Seed      int64            `mapstructure:"seed"`
+	Interval  time.Duration    `mapstructure:"interval"` // Шаг между точками ряда
+	Epoch     time.Time        `mapstructure:"epoch"`    // Начало отсчета тренда
+	Start     time.Time        `mapstructure:"start"`
+	End       time.Time        `mapstructure:"end"`
+	Speed     float64          `mapstructure:"speed"` // Ускорение модельного времени в реальном режиме (больше 1 — метки уходят в будущее)
+	Series    []Series         `mapstructure:"series"`
+	Faults    []Fault          `mapstructure:"faults"`
+	Equipment []EquipmentModel `mapstructure:"equipment"` // Установки, управляемые командами
+}
+
+// LoadScenario читает сценарий из YAML-файла
+func LoadScenario(path string) (*Scenario, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+	sc, err := unmarshalScenario(v)
+	if err != nil {
+		return nil, err
+	}
+	if sc.Name == "" {
+		sc.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return sc, nil
+}
+
+// ParseScenario разбирает сценарий из YAML
+func ParseScenario(data []byte) (*Scenario, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+	return unmarshalScenario(v)
+}
+
+func unmarshalScenario(v *viper.Viper) (*Scenario, error) {
+	var sc Scenario
+	hook := viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToTimeHookFunc(time.RFC3339),
+		mapstructure.StringToSliceHookFunc(","),
+	))
+	if err := v.Unmarshal(&sc, hook); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario: %w", err)
+	}
+	return &sc, nil
+}
+
+// Validate проверяет сценарий и подставляет значения по умолчанию. Вызывается после
+// изменения полей (например, переопределения периода флагами).
+func (sc *Scenario) Validate() error {
+	if sc.Interval <= 0 {
+		sc.Interval = time.Minute
+	}
+	if sc.Epoch.IsZero() {
+		sc.Epoch = defaultEpoch
+	}
+	if sc.Speed <= 0 {
+		sc.Speed = 1
+	}
+	if !sc.End.IsZero() && (sc.Start.IsZero() || !sc.End.After(sc.Start)) {
+		return errors.New("scenario end requires a start before it")
+	}
+	if len(sc.Series) == 0 && len(sc.Equipment) == 0 {
+		return errors.New("scenario has no series or equipment")
+	}
+
+	seen := make(map[string]bool)
+	for i := range sc.Series {
+		s := &sc.Series[i]
+		if s.CompanyID == "" {
+			return fmt.Errorf("series %d: company_id is required", i+1)
+		}
+		if s.ProductName != "" {
+			s.Products = append([]string{s.ProductName}, s.Products...)
+			s.ProductName = ""
+		}
+		if len(s.Products) == 0 {
+			return fmt.Errorf("series %d: product_name or products is required", i+1)
+		}
+		if s.Baseline == 0 {
+			return fmt.Errorf("series %s: baseline is required", s.CompanyID)
+		}
+		for _, h := range s.Seasonality {
+			if h.Period <= 0 {
+				return fmt.Errorf("series %s: seasonality period must be positive", s.CompanyID)
+			}
+		}
+		if s.Min != nil && s.Max != nil && *s.Min > *s.Max {
+			return fmt.Errorf("series %s: min is greater than max", s.CompanyID)
+		}
+		for _, p := range s.Products {
+			key := s.CompanyID + "\x00" + p
+			if seen[key] {
+				return fmt.Errorf("series %s/%s is defined twice", s.CompanyID, p)
+			}
+			seen[key] = true
+		}
+	}
+
+	equipment := make(map[string]bool, len(sc.Equipment))
+	for i := range sc.Equipment {
+		m := &sc.Equipment[i]
+		if err := m.validate(); err != nil {
+			return fmt.Errorf("equipment %d: %w", i+1, err)
+		}
+		if equipment[m.EquipmentID] {
+			return fmt.Errorf("equipment %s is defined twice", m.EquipmentID)
+		}
+		equipment[m.EquipmentID] = true
+
+		key := m.CompanyID + "\x00" + m.ProductName
+		if seen[key] {
+			return fmt.Errorf("equipment %s: series %s/%s is already defined", m.EquipmentID, m.CompanyID, m.ProductName)
+		}
+		seen[key] = true
+	}
+
+	for i := range sc.Faults {
+		if err := sc.Faults[i].validate(); err != nil {
+			name := sc.Faults[i].Name
+			if name == "" {
+				name = fmt.Sprintf("%d", i+1)
+			}
+			return fmt.Errorf("fault %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (f *Fault) validate() error {
+	switch f.Type {
+	case FaultOutage, FaultStuck:
+	case FaultSpike:
+		if f.Magnitude == 0 {
+			f.Magnitude = 3
+		}
+	case FaultBadQuality:
+		f.code = quality.BadSensorFailure
+		if f.Quality != "" {
+			code, err := quality.Parse(f.Quality)
+			if err != nil {
+				return err
+			}
+			f.code = code
+		}
+	default:
+		return fmt.Errorf("unknown fault type %q: use outage, spike, stuck or bad_quality", f.Type)
+	}
+
+	if f.Duration <= 0 {
+		return errors.New("duration is required")
+	}
+	if f.Every > 0 && f.Every < f.Duration {
+		return errors.New("every must not be shorter than duration")
+	}
+	if f.At == "" {
+		return errors.New("at is required")
+	}
+	if t, err := time.Parse(time.RFC3339, f.At); err == nil {
+		f.start = t
+	} else if d, err := time.ParseDuration(f.At); err == nil {
+		f.offset = d
+	} else {
+		return fmt.Errorf("invalid at %q: use RFC3339 time or offset from the scenario start", f.At)
+	}
+
+	companyID, productName := f.CompanyID, f.ProductName
+	f.matches = func(c, p string) bool {
+		return (companyID == "" || companyID == c) && (productName == "" || productName == p)
+	}
+	return nil
+}
+
+// window возвращает начало окна неисправности, в которое попадает t, если оно есть.
+// Начало сценария start нужно для неисправностей, заданных смещением.
+func (f *Fault) window(t, start time.Time) (time.Time, bool) {
+	first := f.start
+	if first.IsZero() {
+		first = start.Add(f.offset)
+	}
+	if t.Before(first) {
+		return time.Time{}, false
+	}
+
+	n := int64(0)
+	if f.Every > 0 {
+		n = int64(t.Sub(first) / f.Every)
+		if f.Count > 0 && n >= int64(f.Count) {
+			n = int64(f.Count) - 1
+		}
+	}
+	begin := first.Add(time.Duration(n) * f.Every)
+	if t.Before(begin.Add(f.Duration)) {
+		return begin, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,71 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// MinPairs — минимальное число совпадающих наблюдений для расчета корреляции
+const MinPairs = 3
+
+// Pearson вычисляет коэффициент корреляции Пирсона. Возвращает false, если наблюдений
+// меньше MinPairs или один из рядов постоянен.
+func Pearson(x, y []float64) (float64, bool) {
+	n := len(x)
+	if n != len(y) || n < MinPairs {
+		return 0, false
+	}
+
+	var mx, my float64
+	for i := range x {
+		mx += x[i]
+		my += y[i]
+	}
+	mx /= float64(n)
+	my /= float64(n)
+
+	var sxy, sxx, syy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+
+	if sxx == 0 || syy == 0 {
+		return 0, false
+	}
+	return sxy / math.Sqrt(sxx*syy), true
+}
+
+// Spearman вычисляет ранговую корреляцию Спирмена (связанным значениям присваивается средний ранг)
+func Spearman(x, y []float64) (float64, bool) {
+	if len(x) != len(y) {
+		return 0, false
+	}
+	return Pearson(Ranks(x), Ranks(y))
+}
+
+// Ranks возвращает ранги значений, начиная с 1, со средним рангом для связанных значений
+func Ranks(values []float64) []float64 {
+	idx := make([]int, len(values))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return values[idx[a]] < values[idx[b]] })
+
+	ranks := make([]float64, len(values))
+	for i := 0; i < len(idx); {
+		j := i
+		for j+1 < len(idx) && values[idx[j+1]] == values[idx[i]] {
+			j++
+		}
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			ranks[idx[k]] = rank
+		}
+		i = j + 1
+	}
+
+	return ranks
+}
@@ -0,0 +1,226 @@
+package transform
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"petrochemical-data-platform/internal/domain"
+)
+
+// Kind определяет тип производного ряда
+type Kind string
+
+const (
+	KindYoY    Kind = "yoy"     // Изменение к тому же месяцу прошлого года
+	KindYoYPct Kind = "yoy_pct" // То же, в процентах
+	KindMoM    Kind = "mom"     // Изменение к предыдущему месяцу
+	KindMoMPct Kind = "mom_pct" // То же, в процентах
+	KindSMA    Kind = "sma"     // Простая скользящая средняя, sma:N
+	KindEMA    Kind = "ema"     // Экспоненциальная скользящая средняя, ema:N
+	KindCumSum Kind = "cumsum"  // Накопленная сумма
+)
+
+// MaxWindow ограничивает размер окна скользящих средних
+const MaxWindow = 1000
+
+// Spec описывает один запрошенный производный ряд
+type Spec struct {
+	Kind   Kind
+	Window int // Размер окна для sma/ema
+}
+
+// String возвращает спецификацию в формате параметра запроса (например, "ema:12")
+func (s Spec) String() string {
+	if s.Window > 0 {
+		return fmt.Sprintf("%s:%d", s.Kind, s.Window)
+	}
+	return string(s.Kind)
+}
+
+// Monthly сообщает, строится ли ряд по помесячным средним
+func (s Spec) Monthly() bool {
+	switch s.Kind {
+	case KindYoY, KindYoYPct, KindMoM, KindMoMPct:
+		return true
+	}
+	return false
+}
+
+// Percent сообщает, выражен ли ряд в процентах
+func (s Spec) Percent() bool {
+	return s.Kind == KindYoYPct || s.Kind == KindMoMPct
+}
+
+// LagMonths возвращает сдвиг в месяцах для YoY/MoM
+func (s Spec) LagMonths() int {
+	switch s.Kind {
+	case KindYoY, KindYoYPct:
+		return 12
+	case KindMoM, KindMoMPct:
+		return 1
+	}
+	return 0
+}
+
+// Parse разбирает список преобразований вида "yoy,ema:12,cumsum"
+func Parse(raw string) ([]Spec, error) {
+	var specs []Spec
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(strings.ToLower(part))
+		if part == "" {
+			continue
+		}
+
+		name, arg, hasArg := strings.Cut(part, ":")
+		spec := Spec{Kind: Kind(name)}
+
+		switch spec.Kind {
+		case KindSMA, KindEMA:
+			if !hasArg {
+				return nil, fmt.Errorf("transform %q requires a window, e.g. %s:12", name, name)
+			}
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 || n > MaxWindow {
+				return nil, fmt.Errorf("invalid window for %q: must be between 1 and %d", name, MaxWindow)
+			}
+			spec.Window = n
+		case KindYoY, KindYoYPct, KindMoM, KindMoMPct, KindCumSum:
+			if hasArg {
+				return nil, fmt.Errorf("transform %q does not take arguments", name)
+			}
+		default:
+			return nil, fmt.Errorf("unknown transform %q", name)
+		}
+
+		if !seen[spec.String()] {
+			seen[spec.String()] = true
+			specs = append(specs, spec)
+		}
+	}
+
+	return specs, nil
+}
+
+// Apply вычисляет производный ряд в Go. Используется для преобразований,
+// которые нельзя выразить оконными функциями ClickHouse, и как запасной путь.
+// Точки должны относиться к одному продукту; порядок не важен.
+func Apply(spec Spec, points []domain.SeriesPoint) []domain.SeriesPoint {
+	sorted := make([]domain.SeriesPoint, len(points))
+	copy(sorted, points)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Timestamp.Before(sorted[j].Timestamp) })
+
+	switch spec.Kind {
+	case KindYoY, KindYoYPct, KindMoM, KindMoMPct:
+		return monthlyDelta(MonthlyMeans(sorted), spec.LagMonths(), spec.Percent())
+	case KindSMA:
+		return sma(sorted, spec.Window)
+	case KindEMA:
+		return ema(sorted, spec.Window)
+	case KindCumSum:
+		return cumsum(sorted)
+	}
+	return nil
+}
+
+// MonthlyMeans агрегирует отсортированные точки в средние по календарным месяцам (UTC)
+func MonthlyMeans(sorted []domain.SeriesPoint) []domain.SeriesPoint {
+	var result []domain.SeriesPoint
+	var sum float64
+	var count int
+
+	for i, p := range sorted {
+		sum += p.Value
+		count++
+
+		month := StartOfMonth(p.Timestamp)
+		if i == len(sorted)-1 || !StartOfMonth(sorted[i+1].Timestamp).Equal(month) {
+			result = append(result, domain.SeriesPoint{Timestamp: month, Value: sum / float64(count)})
+			sum, count = 0, 0
+		}
+	}
+
+	return result
+}
+
+// StartOfMonth возвращает начало календарного месяца в UTC
+func StartOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func monthlyDelta(months []domain.SeriesPoint, lag int, percent bool) []domain.SeriesPoint {
+	byMonth := make(map[time.Time]float64, len(months))
+	for _, m := range months {
+		byMonth[m.Timestamp] = m.Value
+	}
+
+	var result []domain.SeriesPoint
+	for _, m := range months {
+		prev, ok := byMonth[m.Timestamp.AddDate(0, -lag, 0)]
+		if !ok {
+			continue
+		}
+
+		if percent {
+			if prev == 0 {
+				continue
+			}
+			result = append(result, domain.SeriesPoint{Timestamp: m.Timestamp, Value: (m.Value - prev) / prev * 100})
+		} else {
+			result = append(result, domain.SeriesPoint{Timestamp: m.Timestamp, Value: m.Value - prev})
+		}
+	}
+
+	return result
+}
+
+func sma(sorted []domain.SeriesPoint, window int) []domain.SeriesPoint {
+	var result []domain.SeriesPoint
+	var sum float64
+
+	for i, p := range sorted {
+		sum += p.Value
+		if i >= window {
+			sum -= sorted[i-window].Value
+		}
+		if i >= window-1 {
+			result = append(result, domain.SeriesPoint{Timestamp: p.Timestamp, Value: sum / float64(window)})
+		}
+	}
+
+	return result
+}
+
+func ema(sorted []domain.SeriesPoint, window int) []domain.SeriesPoint {
+	alpha := 2 / float64(window+1)
+	result := make([]domain.SeriesPoint, 0, len(sorted))
+
+	var current float64
+	for i, p := range sorted {
+		if i == 0 {
+			current = p.Value
+		} else {
+			current = alpha*p.Value + (1-alpha)*current
+		}
+		result = append(result, domain.SeriesPoint{Timestamp: p.Timestamp, Value: current})
+	}
+
+	return result
+}
+
+func cumsum(sorted []domain.SeriesPoint) []domain.SeriesPoint {
+	result := make([]domain.SeriesPoint, 0, len(sorted))
+
+	var total float64
+	for _, p := range sorted {
+		total += p.Value
+		result = append(result, domain.SeriesPoint{Timestamp: p.Timestamp, Value: total})
+	}
+
+	return result
+}
@@ -0,0 +1,422 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"petrochemical-data-platform/internal/domain"
+
+	"go.uber.org/zap"
+)
+
+// Interval определяет шаг агрегации временного ряда
+type Interval string
+
+const (
+	IntervalHour  Interval = "hour"
+	IntervalDay   Interval = "day"
+	IntervalWeek  Interval = "week"
+	IntervalMonth Interval = "month"
+)
+
+// ParseInterval проверяет название интервала
+func ParseInterval(s string) (Interval, error) {
+	switch i := Interval(s); i {
+	case IntervalHour, IntervalDay, IntervalWeek, IntervalMonth:
+		return i, nil
+	}
+	return "", fmt.Errorf("unknown interval %q: use hour, day, week or month", s)
+}
+
+// Add сдвигает момент времени на n интервалов
+func (i Interval) Add(t time.Time, n int) time.Time {
+	switch i {
+	case IntervalHour:
+		return t.Add(time.Duration(n) * time.Hour)
+	case IntervalDay:
+		return t.AddDate(0, 0, n)
+	case IntervalWeek:
+		return t.AddDate(0, 0, 7*n)
+	default:
+		return t.AddDate(0, n, 0)
+	}
+}
+
+// Truncate возвращает начало интервала, содержащего момент времени (UTC; недели с понедельника)
+func (i Interval) Truncate(t time.Time) time.Time {
+	t = t.UTC()
+	switch i {
+	case IntervalHour:
+		return t.Truncate(time.Hour)
+	case IntervalDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case IntervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// Count возвращает число интервалов, пересекающихся с периодом [start, end], не строя сетку
+func (i Interval) Count(start, end time.Time) int {
+	first := i.Truncate(start)
+	end = end.UTC()
+	if end.Before(first) {
+		return 0
+	}
+	switch i {
+	case IntervalHour:
+		return int(end.Sub(first)/time.Hour) + 1
+	case IntervalDay, IntervalWeek:
+		last := IntervalDay.Truncate(end)
+		days := int(last.Sub(first) / (24 * time.Hour))
+		if i == IntervalWeek {
+			return days/7 + 1
+		}
+		return days + 1
+	default:
+		return (end.Year()-first.Year())*12 + int(end.Month()-first.Month()) + 1
+	}
+}
+
+// Grid возвращает начала всех интервалов, пересекающихся с периодом [start, end]
+func (i Interval) Grid(start, end time.Time) []time.Time {
+	var grid []time.Time
+	for t := i.Truncate(start); !t.After(end); t = i.Add(t, 1) {
+		grid = append(grid, t)
+	}
+	return grid
+}
+
+// rollup описывает таблицу предагрегированных данных
+type rollup struct {
+	table  string
+	bucket string                    // Выражение бакета, совпадающее с материализованным представлением
+	floor  func(time.Time) time.Time // Начало бакета таблицы, содержащего момент времени
+	next   func(time.Time) time.Time // Начало следующего бакета
+}
+
+var (
+	rollupHourly = rollup{
+		table:  "petrochemical.telemetry_hourly",
+		bucket: "toStartOfHour(toDateTime(timestamp, 'UTC'))",
+		floor:  func(t time.Time) time.Time { return t.UTC().Truncate(time.Hour) },
+		next:   func(t time.Time) time.Time { return t.Add(time.Hour) },
+	}
+	rollupDaily = rollup{
+		table:  "petrochemical.telemetry_daily",
+		bucket: "toDateTime(toStartOfDay(timestamp), 'UTC')",
+		floor: func(t time.Time) time.Time {
+			t = t.UTC()
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		},
+		next: func(t time.Time) time.Time { return t.AddDate(0, 0, 1) },
+	}
+	rollupMonthly = rollup{
+		table:  "petrochemical.telemetry_monthly",
+		bucket: "toDateTime(toStartOfMonth(timestamp), 'UTC')",
+		floor: func(t time.Time) time.Time {
+			t = t.UTC()
+			return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		},
+		next: func(t time.Time) time.Time { return t.AddDate(0, 1, 0) },
+	}
+
+	allRollups = []rollup{rollupHourly, rollupDaily, rollupMonthly}
+)
+
+// rollup возвращает самую грубую таблицу, бакеты которой целиком укладываются в интервал
+func (i Interval) rollup() rollup {
+	switch i {
+	case IntervalHour:
+		return rollupHourly
+	case IntervalDay, IntervalWeek:
+		return rollupDaily
+	default:
+		return rollupMonthly
+	}
+}
+
+// bucketExpr возвращает SQL-выражение начала интервала для столбца column
+func (i Interval) bucketExpr(column string) string {
+	switch i {
+	case IntervalHour:
+		return fmt.Sprintf("toStartOfHour(%s)", column)
+	case IntervalDay:
+		return fmt.Sprintf("toDateTime(toStartOfDay(%s), 'UTC')", column)
+	case IntervalWeek:
+		return fmt.Sprintf("toDateTime(toMonday(%s), 'UTC')", column)
+	default:
+		return fmt.Sprintf("toDateTime(toStartOfMonth(%s), 'UTC')", column)
+	}
+}
+
+// GetAggregatedSeries возвращает средние значения продукта по интервалам из подходящей таблицы агрегатов.
+// Границы периода расширяются до границ бакетов таблицы.
+func (r *ClickHouseRepository) GetAggregatedSeries(ctx context.Context, companyID, productName string, start, end time.Time, interval Interval) ([]domain.SeriesPoint, error) {
+	ru := interval.rollup()
+	query := fmt.Sprintf(`
+		SELECT %s AS b, avgMerge(value_avg) AS value
+		FROM %s
+		WHERE company_id = ? AND product_name = ? AND bucket >= ? AND bucket <= ?
+		GROUP BY b
+		ORDER BY b`, interval.bucketExpr("bucket"), ru.table)
+
+	rows, err := r.conn.Query(ctx, query, companyID, productName, ru.floor(start), end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aggregated series: %w", err)
+	}
+	defer rows.Close()
+
+	var points []domain.SeriesPoint
+	for rows.Next() {
+		var point domain.SeriesPoint
+		if err := rows.Scan(&point.Timestamp, &point.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan aggregated series: %w", err)
+		}
+		points = append(points, point)
+	}
+
+	return points, rows.Err()
+}
+
+// GetAggregatedTelemetry возвращает средние значения продуктов по интервалам.
+// Обычно данные берутся из таблиц агрегатов; фильтры по качеству, тегам и меткам
+// (TelemetryFilter.RawOnly) требуют агрегации сырых данных, что заметно дороже.
+func (r *ClickHouseRepository) GetAggregatedTelemetry(ctx context.Context, filter TelemetryFilter, start, end time.Time, interval Interval) ([]domain.TelemetryData, error) {
+	cond, condArgs := filter.conditions()
+
+	var query string
+	var args []interface{}
+	if filter.RawOnly() {
+		query = fmt.Sprintf(`
+			SELECT company_id, product_name, anyLast(toString(unit)) AS unit, %s AS b, avg(value) AS value
+			FROM petrochemical.telemetry FINAL
+			WHERE timestamp >= ? AND timestamp <= ?%s
+			GROUP BY company_id, product_name, b
+			ORDER BY company_id, product_name, b`, interval.bucketExpr("toDateTime(timestamp, 'UTC')"), cond)
+		args = append([]interface{}{interval.rollup().floor(start), end}, condArgs...)
+	} else {
+		ru := interval.rollup()
+		query = fmt.Sprintf(`
+			SELECT company_id, product_name, anyLast(unit) AS unit, %s AS b, avgMerge(value_avg) AS value
+			FROM %s
+			WHERE bucket >= ? AND bucket <= ?%s
+			GROUP BY company_id, product_name, b
+			ORDER BY company_id, product_name, b`, interval.bucketExpr("bucket"), ru.table, cond)
+		args = append([]interface{}{ru.floor(start), end}, condArgs...)
+	}
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aggregated telemetry: %w", err)
+	}
+	defer rows.Close()
+
+	var results []domain.TelemetryData
+	for rows.Next() {
+		var data domain.TelemetryData
+		if err := rows.Scan(&data.CompanyID, &data.ProductName, &data.Unit, &data.Timestamp, &data.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan aggregated telemetry: %w", err)
+		}
+		results = append(results, data)
+	}
+
+	return results, rows.Err()
+}
+
+// BackfillRollups пересчитывает таблицы агрегатов за период из сырых данных для рядов,
+// отобранных фильтром (пустой фильтр — все ряды). Существующие строки периода удаляются,
+// поэтому пересчет идет под LockTelemetry фильтра: запись в эти ряды из любого процесса
+// ждет его окончания (запускается после массовых загрузок истории).
+// Бакеты, сырые данные которых уже удалены по TTL, не трогаются: пересчет из неполных
+// данных безвозвратно исказил бы агрегаты.
+func (r *ClickHouseRepository) BackfillRollups(ctx context.Context, filter TelemetryFilter, start, end time.Time) error {
+	cond, condArgs := filter.conditions()
+
+	for _, ru := range allRollups {
+		from, to := ru.floor(start), ru.next(ru.floor(end))
+		if err := r.rebuildRollup(ctx, ru, cond, condArgs, from, to, nil); err != nil {
+			return err
+		}
+		r.logger.Info("Backfilled rollup", zap.String("table", ru.table), zap.Time("from", from), zap.Time("to", to))
+	}
+
+	return nil
+}
+
+// RollupBucket — момент времени ряда, бакеты агрегатов которого нужно пересчитать
+type RollupBucket struct {
+	CompanyID   string
+	ProductName string
+	Timestamp   time.Time
+}
+
+// RebuildRollupBuckets пересчитывает во всех таблицах агрегатов бакеты, содержащие
+// указанные моменты рядов. Каждая таблица пересчитывается одной мутацией независимо от числа бакетов.
+// Вызывается под LockSeries этих рядов, иначе параллельная запись в бакет учтется дважды.
+func (r *ClickHouseRepository) RebuildRollupBuckets(ctx context.Context, buckets []RollupBucket) error {
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	var filter TelemetryFilter
+	for _, b := range buckets {
+		if !slices.Contains(filter.CompanyIDs, b.CompanyID) {
+			filter.CompanyIDs = append(filter.CompanyIDs, b.CompanyID)
+		}
+		if !slices.Contains(filter.Products, b.ProductName) {
+			filter.Products = append(filter.Products, b.ProductName)
+		}
+	}
+	cond, condArgs := filter.conditions()
+
+	for _, ru := range allRollups {
+		// Ключ бакета — company_id, product_name и начало бакета (Unix-время) через "/"
+		keys := make([]string, 0, len(buckets))
+		seen := make(map[string]struct{}, len(buckets))
+		var from, last time.Time
+		for _, b := range buckets {
+			start := ru.floor(b.Timestamp)
+			key := fmt.Sprintf("%s/%s/%d", b.CompanyID, b.ProductName, start.Unix())
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+			if from.IsZero() || start.Before(from) {
+				from = start
+			}
+			if start.After(last) {
+				last = start
+			}
+		}
+
+		if err := r.rebuildRollup(ctx, ru, cond, condArgs, from, ru.next(last), keys); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rebuildRollup заменяет бакеты [from, to) таблицы агрегатов пересчетом из сырых данных.
+// Непустой buckets ограничивает пересчет перечисленными бакетами рядов (ключи RebuildRollupBuckets).
+func (r *ClickHouseRepository) rebuildRollup(ctx context.Context, ru rollup, cond string, condArgs []interface{}, from, to time.Time, buckets []string) error {
+	keys, bounds, err := r.rollupCoverage(ctx, ru, cond, condArgs)
+	if err != nil {
+		return err
+	}
+
+	// Для рядов с частично удаленными сырыми данными бакеты раньше границы покрытия сохраняются
+	var coverage, rawCoverage string
+	var coverageArgs []interface{}
+	if len(keys) > 0 {
+		coverage = " AND toUnixTimestamp(bucket) >= transform(concat(company_id, '/', product_name), ?, CAST(? AS Array(Int64)), toInt64(0))"
+		rawCoverage = fmt.Sprintf(" AND toUnixTimestamp(%s) >= transform(concat(company_id, '/', product_name), ?, CAST(? AS Array(Int64)), toInt64(0))", ru.bucket)
+		coverageArgs = []interface{}{keys, bounds}
+		r.logger.Warn("Keeping rollup buckets whose raw data has expired",
+			zap.String("table", ru.table), zap.Int("series", len(keys)))
+	}
+
+	var only, rawOnly string
+	var onlyArgs []interface{}
+	if len(buckets) > 0 {
+		only = " AND has(?, concat(company_id, '/', product_name, '/', toString(toUnixTimestamp(bucket))))"
+		rawOnly = fmt.Sprintf(" AND has(?, concat(company_id, '/', product_name, '/', toString(toUnixTimestamp(%s))))", ru.bucket)
+		onlyArgs = []interface{}{buckets}
+	}
+
+	args := append(append(append([]interface{}{from, to}, condArgs...), coverageArgs...), onlyArgs...)
+
+	// mutations_sync = 2 ждет завершения удаления на всех репликах
+	deleteQuery := fmt.Sprintf(`
+		ALTER TABLE %s DELETE WHERE bucket >= ? AND bucket < ?%s%s%s
+		SETTINGS mutations_sync = 2`, ru.table, cond, coverage, only)
+	if err := r.conn.Exec(ctx, deleteQuery, args...); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", ru.table, err)
+	}
+
+	// Та же агрегация, что и в материализованном представлении таблицы
+	insertQuery := fmt.Sprintf(`
+		INSERT INTO %s (company_id, product_name, bucket, unit, value_avg, value_min, value_max, value_sum, samples)
+		SELECT company_id, product_name, %s AS bucket,
+			anyLast(toString(unit)), avgState(value), min(value), max(value), sum(value), count()
+		FROM petrochemical.telemetry FINAL
+		WHERE timestamp >= ? AND timestamp < ?%s%s%s
+		GROUP BY company_id, product_name, bucket`, ru.table, ru.bucket, cond, rawCoverage, rawOnly)
+	if err := r.conn.Exec(ctx, insertQuery, args...); err != nil {
+		return fmt.Errorf("failed to backfill %s: %w", ru.table, err)
+	}
+
+	return nil
+}
+
+// rollupCoverage находит ряды, сырые данные которых начинаются позже агрегатов (удалены по TTL),
+// и для каждого — начало первого бакета, целиком покрытого сырыми данными (Unix-время).
+// Ключ ряда — company_id и product_name через "/" (в идентификаторах компаний его нет).
+func (r *ClickHouseRepository) rollupCoverage(ctx context.Context, ru rollup, cond string, condArgs []interface{}) ([]string, []int64, error) {
+	rawStart, err := r.seriesMinimum(ctx, "min(timestamp)", "petrochemical.telemetry", cond, condArgs)
+	if err != nil {
+		return nil, nil, err
+	}
+	rollupStart, err := r.seriesMinimum(ctx, "min(bucket)", ru.table, cond, condArgs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var keys []string
+	var bounds []int64
+	for key, first := range rollupStart {
+		raw, ok := rawStart[key]
+		if !ok {
+			// Сырых данных ряда не осталось: агрегаты сохраняются целиком
+			keys = append(keys, key)
+			bounds = append(bounds, math.MaxUint32)
+			continue
+		}
+
+		floor := ru.floor(raw)
+		if !first.Before(floor) {
+			continue
+		}
+		// Первый бакет с сырыми данными покрыт целиком, только если они начинаются с его начала
+		bound := floor
+		if raw.After(floor) {
+			bound = ru.next(floor)
+		}
+		keys = append(keys, key)
+		bounds = append(bounds, bound.Unix())
+	}
+	return keys, bounds, nil
+}
+
+// seriesMinimum возвращает минимум выражения expr по каждому ряду таблицы
+func (r *ClickHouseRepository) seriesMinimum(ctx context.Context, expr, table, cond string, condArgs []interface{}) (map[string]time.Time, error) {
+	query := fmt.Sprintf(`
+		SELECT company_id, product_name, %s
+		FROM %s
+		WHERE 1 = 1%s
+		GROUP BY company_id, product_name`, expr, table, cond)
+
+	rows, err := r.conn.Query(ctx, query, condArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query coverage of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]time.Time)
+	for rows.Next() {
+		var companyID, productName string
+		var t time.Time
+		if err := rows.Scan(&companyID, &productName, &t); err != nil {
+			return nil, fmt.Errorf("failed to scan coverage of %s: %w", table, err)
+		}
+		result[companyID+"/"+productName] = t
+	}
+	return result, rows.Err()
+}
@@ -2,10 +2,12 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/pkg/transform"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"go.uber.org/zap"
@@ -19,20 +21,25 @@ type ClickHouseRepository struct {
 
 // TelemetryData представляет данные производства/продаж для ClickHouse
 type TelemetryData struct {
-	CompanyID   string    `ch:"company_id"`
-	ProductName string    `ch:"product_name"`
-	Value       float64   `ch:"value"`
-	Unit        string    `ch:"unit"`
-	Timestamp   time.Time `ch:"timestamp"`
-	Quality     uint16    `ch:"quality"`
+	CompanyID    string            `ch:"company_id"`
+	ProductName  string            `ch:"product_name"`
+	Value        float64           `ch:"value"`
+	Unit         string            `ch:"unit"`
+	Timestamp    time.Time         `ch:"timestamp"`
+	Quality      uint16            `ch:"quality"`
+	Tags         []string          `ch:"tags"`
+	Labels       map[string]string `ch:"labels"`
+	AnomalyScore float64           `ch:"anomaly_score"`
+	Version      uint64            `ch:"version"` // Побеждает строка с наибольшей версией; 0 — текущее время
 }
 
-// NewClickHouseRepository создает новый репозиторий ClickHouse
-func NewClickHouseRepository(addr, database, username, password string, logger *zap.Logger) (*ClickHouseRepository, error) {
+// NewClickHouseRepository создает новый репозиторий ClickHouse.
+// Запросы обращаются к таблицам по полному имени, поэтому соединение открывается
+// в базе по умолчанию: база платформы может еще не существовать до миграций.
+func NewClickHouseRepository(addr, username, password string, logger *zap.Logger) (*ClickHouseRepository, error) {
 	conn, err := clickhouse.Open(&clickhouse.Options{
 		Addr: []string{addr},
 		Auth: clickhouse.Auth{
-			Database: database,
 			Username: username,
 			Password: password,
 		},
@@ -56,14 +63,20 @@ func (r *ClickHouseRepository) Close() error {
 	return r.conn.Close()
 }
 
+// Conn возвращает соединение с ClickHouse (используется миграциями)
+func (r *ClickHouseRepository) Conn() clickhouse.Conn {
+	return r.conn
+}
+
 // SaveTelemetryData сохраняет данные производства/продаж в ClickHouse
 func (r *ClickHouseRepository) SaveTelemetryData(ctx context.Context, data TelemetryData) error {
 	query := `
 		INSERT INTO petrochemical.telemetry
-		(company_id, product_name, value, unit, timestamp, quality)
-		VALUES (?, ?, ?, ?, ?, ?)`
+		(company_id, product_name, value, unit, timestamp, quality, tags, labels, anomaly_score, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	err := r.conn.Exec(ctx, query, data.CompanyID, data.ProductName, data.Value, data.Unit, data.Timestamp, data.Quality)
+	err := r.conn.Exec(ctx, query, data.CompanyID, data.ProductName, data.Value, data.Unit, data.Timestamp, data.Quality,
+		data.Tags, data.Labels, data.AnomalyScore, versionOf(data))
 	if err != nil {
 		return fmt.Errorf("failed to save telemetry data: %w", err)
 	}
@@ -77,46 +90,293 @@ func (r *ClickHouseRepository) SaveTelemetryData(ctx context.Context, data Telem
 	return nil
 }
 
-// GetTelemetryData получает данные производства/продаж для компании в заданном временном диапазоне
-func (r *ClickHouseRepository) GetTelemetryData(ctx context.Context, companyID string, start, end time.Time) ([]domain.TelemetryData, error) {
+// SaveTelemetryBatch сохраняет пачку точек одним INSERT
+func (r *ClickHouseRepository) SaveTelemetryBatch(ctx context.Context, data []TelemetryData) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	batch, err := r.conn.PrepareBatch(ctx, `
+		INSERT INTO petrochemical.telemetry
+		(company_id, product_name, value, unit, timestamp, quality, tags, labels, anomaly_score, version)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare telemetry batch: %w", err)
+	}
+
+	for _, d := range data {
+		if err := batch.Append(d.CompanyID, d.ProductName, d.Value, d.Unit, d.Timestamp, d.Quality,
+			d.Tags, d.Labels, d.AnomalyScore, versionOf(d)); err != nil {
+			return fmt.Errorf("failed to append telemetry batch: %w", err)
+		}
+	}
+
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to send telemetry batch: %w", err)
+	}
+
+	r.logger.Debug("Saved telemetry batch", zap.Int("rows", len(data)))
+
+	return nil
+}
+
+// versionOf возвращает версию строки; по умолчанию — время записи в наносекундах
+func versionOf(data TelemetryData) uint64 {
+	if data.Version != 0 {
+		return data.Version
+	}
+	return uint64(time.Now().UnixNano())
+}
+
+// dateTime64 форматирует момент времени для сравнения со столбцами DateTime64(3):
+// позиционные параметры драйвера передают время с точностью до секунды.
+// В запросе используется как toDateTime64(?, 3, 'UTC').
+func dateTime64(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05.000")
+}
+
+// TelemetryQuery описывает запрос страницы сырых данных
+type TelemetryQuery struct {
+	Filter     TelemetryFilter
+	Start, End time.Time
+	Limit      int
+	After      *TelemetryCursor // Продолжение с позиции курсора; nil — первая страница
+}
+
+// GetTelemetryData получает страницу данных производства/продаж в порядке убывания
+// (timestamp, company_id, product_name). Возвращает курсор следующей страницы или nil, если данных больше нет.
+func (r *ClickHouseRepository) GetTelemetryData(ctx context.Context, q TelemetryQuery) ([]domain.TelemetryData, *TelemetryCursor, error) {
+	cond, condArgs := q.Filter.conditions()
 	query := `
-		SELECT company_id, product_name, value, unit, timestamp, quality
-		FROM petrochemical.telemetry
-		WHERE company_id = ? AND timestamp >= ? AND timestamp <= ?
-		ORDER BY timestamp DESC
-		LIMIT 1000`
+		SELECT company_id, product_name, value, unit, timestamp, quality, tags, labels, anomaly_score
+		FROM petrochemical.telemetry FINAL
+		WHERE timestamp >= ? AND timestamp <= ?` + cond
+	args := append([]interface{}{q.Start, q.End}, condArgs...)
+
+	if q.After != nil {
+		query += ` AND (timestamp, company_id, product_name) < (toDateTime64(?, 3, 'UTC'), ?, ?)`
+		args = append(args, dateTime64(q.After.Timestamp), q.After.CompanyID, q.After.ProductName)
+	}
+
+	// Одна лишняя строка показывает, есть ли следующая страница
+	query += ` ORDER BY timestamp DESC, company_id DESC, product_name DESC LIMIT ?`
+	args = append(args, q.Limit+1)
 
-	rows, err := r.conn.Query(ctx, query, companyID, start, end)
+	rows, err := r.conn.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query telemetry data: %w", err)
+		return nil, nil, fmt.Errorf("failed to query telemetry data: %w", err)
 	}
 	defer rows.Close()
 
-	var results []domain.TelemetryData
+	results := []domain.TelemetryData{}
 	for rows.Next() {
 		var data domain.TelemetryData
-		err := rows.Scan(&data.CompanyID, &data.ProductName, &data.Value, &data.Unit, &data.Timestamp, &data.Quality)
+		err := rows.Scan(&data.CompanyID, &data.ProductName, &data.Value, &data.Unit, &data.Timestamp, &data.Quality, &data.Tags, &data.Labels, &data.AnomalyScore)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan telemetry data: %w", err)
+			return nil, nil, fmt.Errorf("failed to scan telemetry data: %w", err)
 		}
 		results = append(results, data)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read telemetry data: %w", err)
+	}
+
+	var next *TelemetryCursor
+	if len(results) > q.Limit {
+		results = results[:q.Limit]
+		last := results[len(results)-1]
+		next = &TelemetryCursor{Timestamp: last.Timestamp, CompanyID: last.CompanyID, ProductName: last.ProductName}
+	}
+
+	return results, next, nil
+}
+
+// CountTelemetryData возвращает число точек за период после схлопывания повторов ключа,
+// то есть столько же строк, сколько отдают страницы GetTelemetryData
+func (r *ClickHouseRepository) CountTelemetryData(ctx context.Context, filter TelemetryFilter, start, end time.Time) (uint64, error) {
+	cond, condArgs := filter.conditions()
+	query := `
+		SELECT count()
+		FROM petrochemical.telemetry FINAL
+		WHERE timestamp >= ? AND timestamp <= ?` + cond
+
+	var count uint64
+	args := append([]interface{}{start, end}, condArgs...)
+	if err := r.conn.QueryRow(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count telemetry data: %w", err)
+	}
+	return count, nil
+}
+
+// StreamTelemetryData передает в fn все строки за период в порядке возрастания времени,
+// не загружая их в память целиком. Ошибка fn прерывает чтение.
+func (r *ClickHouseRepository) StreamTelemetryData(ctx context.Context, filter TelemetryFilter, start, end time.Time, fn func(domain.TelemetryData) error) error {
+	cond, condArgs := filter.conditions()
+	return r.streamTelemetry(ctx, `timestamp >= ? AND timestamp <= ?`+cond, append([]interface{}{start, end}, condArgs...), fn)
+}
+
+// StreamTelemetryRange передает в fn строки полуинтервала [from, to) в порядке возрастания
+// времени. Границы сравниваются с точностью до миллисекунды, поэтому соседние отрезки
+// не теряют и не повторяют точки с долями секунды.
+func (r *ClickHouseRepository) StreamTelemetryRange(ctx context.Context, filter TelemetryFilter, from, to time.Time, fn func(domain.TelemetryData) error) error {
+	cond, condArgs := filter.conditions()
+	where := `timestamp >= toDateTime64(?, 3, 'UTC') AND timestamp < toDateTime64(?, 3, 'UTC')` + cond
+	return r.streamTelemetry(ctx, where, append([]interface{}{dateTime64(from), dateTime64(to)}, condArgs...), fn)
+}
+
+func (r *ClickHouseRepository) streamTelemetry(ctx context.Context, where string, args []interface{}, fn func(domain.TelemetryData) error) error {
+	query := `
+		SELECT company_id, product_name, value, unit, timestamp, quality, tags, labels, anomaly_score
+		FROM petrochemical.telemetry FINAL
+		WHERE ` + where + `
+		ORDER BY timestamp, company_id, product_name`
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query telemetry stream: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data domain.TelemetryData
+		if err := rows.Scan(&data.CompanyID, &data.ProductName, &data.Value, &data.Unit, &data.Timestamp, &data.Quality, &data.Tags, &data.Labels, &data.AnomalyScore); err != nil {
+			return fmt.Errorf("failed to scan telemetry stream: %w", err)
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ErrTransformNotSupported возвращается, если преобразование нельзя выполнить оконными функциями ClickHouse
+var ErrTransformNotSupported = errors.New("transform is not supported in ClickHouse")
+
+// GetTelemetrySeries получает все точки компании за период, сгруппированные по продуктам в порядке возрастания времени.
+// Пустой products означает все продукты компании.
+func (r *ClickHouseRepository) GetTelemetrySeries(ctx context.Context, companyID string, products []string, start, end time.Time) (map[string][]domain.SeriesPoint, error) {
+	cond, condArgs := TelemetryFilter{Products: products}.conditions()
+	query := `
+		SELECT product_name, timestamp, value
+		FROM petrochemical.telemetry FINAL
+		WHERE company_id = ? AND timestamp >= ? AND timestamp <= ?` + cond + `
+		ORDER BY product_name, timestamp`
+
+	rows, err := r.conn.Query(ctx, query, append([]interface{}{companyID, start, end}, condArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query telemetry series: %w", err)
+	}
+	defer rows.Close()
+
+	series := make(map[string][]domain.SeriesPoint)
+	for rows.Next() {
+		var product string
+		var point domain.SeriesPoint
+		if err := rows.Scan(&product, &point.Timestamp, &point.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan telemetry series: %w", err)
+		}
+		series[product] = append(series[product], point)
+	}
+
+	return series, rows.Err()
+}
+
+// GetDerivedSeries вычисляет производный ряд оконными функциями ClickHouse.
+// Пустой products означает все продукты компании.
+// Для преобразований без SQL-реализации возвращает ErrTransformNotSupported.
+func (r *ClickHouseRepository) GetDerivedSeries(ctx context.Context, companyID string, products []string, start, end time.Time, spec transform.Spec) ([]domain.DerivedSeries, error) {
+	var query string
+	var args []interface{}
+
+	cond, condArgs := TelemetryFilter{Products: products}.conditions()
+	where := func(from time.Time) []interface{} {
+		return append([]interface{}{companyID, from, end}, condArgs...)
+	}
+
+	switch spec.Kind {
+	case transform.KindYoY, transform.KindYoYPct, transform.KindMoM, transform.KindMoMPct:
+		// Нулевой базовый месяц исключается только для процентов, как в transform.Apply
+		derived, filter := "value - prev", ""
+		if spec.Percent() {
+			derived, filter = "(value - prev) / prev * 100", " AND prev != 0"
+		}
+		lag := spec.LagMonths()
+
+		// Окно RANGE по номеру месяца выбирает ровно тот месяц, который отстоит на lag назад,
+		// поэтому пропуски в данных не смещают сравнение
+		query = fmt.Sprintf(`
+			SELECT product_name, month, %s AS derived
+			FROM (
+				SELECT product_name, month, value,
+					anyOrNull(value) OVER (
+						PARTITION BY product_name ORDER BY month_num
+						RANGE BETWEEN %d PRECEDING AND %d PRECEDING
+					) AS prev
+				FROM (
+					SELECT product_name, toStartOfMonth(timestamp) AS month,
+						toRelativeMonthNum(month) AS month_num, avg(value) AS value
+					FROM petrochemical.telemetry FINAL
+					WHERE company_id = ? AND timestamp >= ? AND timestamp <= ?%s
+					GROUP BY product_name, month
+				)
+			)
+			WHERE month >= ? AND prev IS NOT NULL%s
+			ORDER BY product_name, month`, derived, lag, lag, cond, filter)
+		lookback := transform.StartOfMonth(start).AddDate(0, -lag, 0)
+		args = append(where(lookback), transform.StartOfMonth(start))
+
+	case transform.KindSMA:
+		query = fmt.Sprintf(`
+			SELECT product_name, timestamp, derived
+			FROM (
+				SELECT product_name, timestamp,
+					avg(value) OVER w AS derived,
+					count() OVER w AS n
+				FROM petrochemical.telemetry FINAL
+				WHERE company_id = ? AND timestamp >= ? AND timestamp <= ?%s
+				WINDOW w AS (PARTITION BY product_name ORDER BY timestamp ROWS BETWEEN %d PRECEDING AND CURRENT ROW)
+			)
+			WHERE n >= %d
+			ORDER BY product_name, timestamp`, cond, spec.Window-1, spec.Window)
+		args = where(start)
+
+	case transform.KindCumSum:
+		query = `
+			SELECT product_name, timestamp,
+				sum(value) OVER (PARTITION BY product_name ORDER BY timestamp ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) AS derived
+			FROM petrochemical.telemetry FINAL
+			WHERE company_id = ? AND timestamp >= ? AND timestamp <= ?` + cond + `
+			ORDER BY product_name, timestamp`
+		args = where(start)
+
+	default:
+		return nil, ErrTransformNotSupported
+	}
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query derived series %s: %w", spec, err)
+	}
+	defer rows.Close()
+
+	var results []domain.DerivedSeries
+	for rows.Next() {
+		var product string
+		var point domain.SeriesPoint
+		if err := rows.Scan(&product, &point.Timestamp, &point.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan derived series: %w", err)
+		}
 
-	if len(results) == 0 {
-		// Return mock data if no real data found (for development)
-		r.logger.Info("No telemetry data found, returning mock data",
-			zap.String("company_id", companyID))
-		results = []domain.TelemetryData{
-			{
+		if len(results) == 0 || results[len(results)-1].ProductName != product {
+			results = append(results, domain.DerivedSeries{
 				CompanyID:   companyID,
-				ProductName: "Полипропилен",
-				Value:       125.5,
-				Unit:        "т/час",
-				Timestamp:   time.Now(),
-				Quality:     1,
-			},
+				ProductName: product,
+				Transform:   spec.String(),
+			})
 		}
+		last := &results[len(results)-1]
+		last.Points = append(last.Points, point)
 	}
 
-	return results, nil
+	return results, rows.Err()
 }
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SeriesID идентифицирует ряд компании и продукта
+type SeriesID struct {
+	CompanyID   string
+	ProductName string
+}
+
+// SeriesSpan описывает ряд и период, за который по нему есть данные
+type SeriesSpan struct {
+	SeriesID
+	FirstSeen time.Time // Начало первого дня с данными
+	LastSeen  time.Time // Начало последнего дня с данными
+}
+
+// ListSeries возвращает все ряды, подходящие под фильтр, с первым и последним днем данных.
+// Используются дневные агрегаты, поэтому фильтр не должен требовать сырых данных.
+func (r *ClickHouseRepository) ListSeries(ctx context.Context, filter TelemetryFilter) ([]SeriesSpan, error) {
+	cond, condArgs := filter.conditions()
+	query := fmt.Sprintf(`
+		SELECT company_id, product_name, min(bucket), max(bucket)
+		FROM %s
+		WHERE 1 = 1%s
+		GROUP BY company_id, product_name
+		ORDER BY company_id, product_name`, rollupDaily.table, cond)
+
+	rows, err := r.conn.Query(ctx, query, condArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list telemetry series: %w", err)
+	}
+	defer rows.Close()
+
+	var series []SeriesSpan
+	for rows.Next() {
+		var s SeriesSpan
+		if err := rows.Scan(&s.CompanyID, &s.ProductName, &s.FirstSeen, &s.LastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan telemetry series: %w", err)
+		}
+		series = append(series, s)
+	}
+
+	return series, rows.Err()
+}
+
+// GetSeriesBuckets возвращает для каждого ряда отсортированные начала интервалов, в которых есть данные
+func (r *ClickHouseRepository) GetSeriesBuckets(ctx context.Context, filter TelemetryFilter, start, end time.Time, interval Interval) (map[SeriesID][]time.Time, error) {
+	ru := interval.rollup()
+	cond, condArgs := filter.conditions()
+	query := fmt.Sprintf(`
+		SELECT company_id, product_name, arraySort(groupUniqArray(%s)) AS buckets
+		FROM %s
+		WHERE bucket >= ? AND bucket <= ?%s
+		GROUP BY company_id, product_name`, interval.bucketExpr("bucket"), ru.table, cond)
+
+	rows, err := r.conn.Query(ctx, query, append([]interface{}{ru.floor(start), end}, condArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query series buckets: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[SeriesID][]time.Time)
+	for rows.Next() {
+		var id SeriesID
+		var buckets []time.Time
+		if err := rows.Scan(&id.CompanyID, &id.ProductName, &buckets); err != nil {
+			return nil, fmt.Errorf("failed to scan series buckets: %w", err)
+		}
+		result[id] = buckets
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrInvalidCursor возвращается для поврежденного или чужого токена курсора
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TelemetryCursor — позиция в выборке, упорядоченной по (timestamp, company_id, product_name)
+type TelemetryCursor struct {
+	Timestamp   time.Time
+	CompanyID   string
+	ProductName string
+}
+
+type cursorToken struct {
+	T int64  `json:"t"` // Unix-время в наносекундах
+	C string `json:"c"`
+	P string `json:"p"`
+}
+
+// Encode возвращает непрозрачный токен курсора для передачи клиенту
+func (c TelemetryCursor) Encode() string {
+	data, _ := json.Marshal(cursorToken{T: c.Timestamp.UnixNano(), C: c.CompanyID, P: c.ProductName})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeTelemetryCursor разбирает токен, полученный от Encode
+func DecodeTelemetryCursor(token string) (*TelemetryCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var t cursorToken
+	if err := json.Unmarshal(data, &t); err != nil || t.T == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &TelemetryCursor{Timestamp: time.Unix(0, t.T).UTC(), CompanyID: t.C, ProductName: t.P}, nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"petrochemical-data-platform/internal/pkg/quality"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// QualityFilter определяет отбор точек по признаку качества
+type QualityFilter string
+
+const (
+	QualityAll       QualityFilter = ""          // Все точки независимо от качества
+	QualityGood      QualityFilter = "good"      // Только класс good
+	QualityUncertain QualityFilter = "uncertain" // Только класс uncertain
+	QualityBad       QualityFilter = "bad"       // Только класс bad
+)
+
+// ParseQualityFilter проверяет значение параметра quality
+func ParseQualityFilter(s string) (QualityFilter, error) {
+	switch q := QualityFilter(strings.ToLower(s)); q {
+	case QualityGood, QualityUncertain, QualityBad:
+		return q, nil
+	case "", "all":
+		return QualityAll, nil
+	}
+	return "", fmt.Errorf("unknown quality filter %q: use good, uncertain, bad or all", s)
+}
+
+// TelemetryFilter описывает отбор строк телеметрии. Пустые поля не ограничивают выборку.
+type TelemetryFilter struct {
+	CompanyIDs []string
+	Products   []string
+	Quality    QualityFilter
+	Tags       []string          // Строка должна содержать все перечисленные теги
+	Labels     map[string]string // Строка должна содержать все перечисленные метки с указанными значениями
+}
+
+// RawOnly сообщает, требует ли фильтр сырых данных: в таблицах агрегатов нет качества, тегов и меток,
+// поэтому такие агрегаты считаются по таблице telemetry
+func (f TelemetryFilter) RawOnly() bool {
+	return f.Quality != QualityAll || len(f.Tags) > 0 || len(f.Labels) > 0
+}
+
+// conditions возвращает условия WHERE (через AND, с ведущим AND) и их параметры.
+// Значения всегда передаются параметрами, в текст запроса попадают только имена столбцов.
+func (f TelemetryFilter) conditions() (string, []interface{}) {
+	var sb strings.Builder
+	var args []interface{}
+
+	if len(f.CompanyIDs) > 0 {
+		sb.WriteString(" AND company_id IN ?")
+		args = append(args, groupSet(f.CompanyIDs))
+	}
+	if len(f.Products) > 0 {
+		sb.WriteString(" AND product_name IN ?")
+		args = append(args, groupSet(f.Products))
+	}
+
+	// Класс качества — старшие два бита младшего байта (quality.ClassMask)
+	switch f.Quality {
+	case QualityGood:
+		fmt.Fprintf(&sb, " AND bitAnd(quality, %d) = %d", quality.ClassMask, quality.ClassGood)
+	case QualityUncertain:
+		fmt.Fprintf(&sb, " AND bitAnd(quality, %d) = %d", quality.ClassMask, quality.ClassUncertain)
+	case QualityBad:
+		fmt.Fprintf(&sb, " AND bitAnd(quality, %d) NOT IN (%d, %d)", quality.ClassMask, quality.ClassGood, quality.ClassUncertain)
+	}
+
+	if len(f.Tags) > 0 {
+		sb.WriteString(" AND hasAll(tags, ?)")
+		args = append(args, f.Tags)
+	}
+
+	// Ключи сортируются, чтобы текст запроса не зависел от порядка обхода карты
+	keys := make([]string, 0, len(f.Labels))
+	for k := range f.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteString(" AND labels[?] = ?")
+		args = append(args, k, f.Labels[k])
+	}
+
+	return sb.String(), args
+}
+
+// groupSet оборачивает значения для подстановки в IN (...)
+func groupSet(values []string) clickhouse.GroupSet {
+	set := clickhouse.GroupSet{Value: make([]any, len(values))}
+	for i, v := range values {
+		set.Value[i] = v
+	}
+	return set
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// assetColumns — столбцы актива в порядке scanAsset
+const assetColumns = `id, name, type, COALESCE(location, ''), created_at, updated_at,
+	COALESCE(parent_id, ''), level, COALESCE(telemetry_company_id, '')`
+
+func scanAsset(row pgx.Row, asset *Asset, extra ...interface{}) error {
+	dest := append([]interface{}{
+		&asset.ID, &asset.Name, &asset.Type, &asset.Location, &asset.CreatedAt, &asset.UpdatedAt,
+		&asset.ParentID, &asset.Level, &asset.TelemetryCompanyID,
+	}, extra...)
+	return row.Scan(dest...)
+}
+
+// GetAsset получает актив по идентификатору; nil, если актива нет
+func (r *PostgresRepository) GetAsset(ctx context.Context, id string) (*Asset, error) {
+	var asset Asset
+	err := scanAsset(r.pool.QueryRow(ctx, `SELECT `+assetColumns+` FROM assets WHERE id = $1`, id), &asset)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query asset: %w", err)
+	}
+	return &asset, nil
+}
+
+// GetAssetAncestors возвращает предков актива от корня (компании) к непосредственному
+// родителю. Depth — число шагов вверх от актива.
+func (r *PostgresRepository) GetAssetAncestors(ctx context.Context, id string) ([]Asset, error) {
+	// path защищает от зацикливания, если ссылки на родителей были изменены в обход сервиса
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT a.*, 0 AS depth, ARRAY[a.id] AS path
+			FROM assets a
+			WHERE a.id = $1
+			UNION ALL
+			SELECT p.*, an.depth + 1, an.path || p.id
+			FROM assets p
+			JOIN ancestors an ON p.id = an.parent_id
+			WHERE NOT p.id = ANY(an.path)
+		)
+		SELECT ` + assetColumns + `, depth
+		FROM ancestors
+		WHERE depth > 0
+		ORDER BY depth DESC`
+
+	return r.queryAssets(ctx, query, id)
+}
+
+// GetAssetSubtree возвращает актив и всех его потомков в порядке обхода в ширину.
+// Пустой id — все деревья от компаний. Depth — число шагов вниз от корня выборки.
+func (r *PostgresRepository) GetAssetSubtree(ctx context.Context, id string) ([]Asset, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT a.*, 0 AS depth, ARRAY[a.id] AS path
+			FROM assets a
+			WHERE ($1::text = '' AND a.parent_id IS NULL) OR a.id = $1::text
+			UNION ALL
+			SELECT c.*, s.depth + 1, s.path || c.id
+			FROM assets c
+			JOIN subtree s ON c.parent_id = s.id
+			WHERE NOT c.id = ANY(s.path)
+		)
+		SELECT ` + assetColumns + `, depth
+		FROM subtree
+		ORDER BY depth, name, id`
+
+	return r.queryAssets(ctx, query, id)
+}
+
+// queryAssets выполняет запрос, возвращающий столбцы assetColumns и depth
+func (r *PostgresRepository) queryAssets(ctx context.Context, query string, args ...interface{}) ([]Asset, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query asset hierarchy: %w", err)
+	}
+	defer rows.Close()
+
+	var assets []Asset
+	for rows.Next() {
+		var asset Asset
+		if err := scanAsset(rows, &asset, &asset.Depth); err != nil {
+			return nil, fmt.Errorf("failed to scan asset: %w", err)
+		}
+		assets = append(assets, asset)
+	}
+	return assets, rows.Err()
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"petrochemical-data-platform/internal/domain"
+)
+
+// SavePriceIndex сохраняет рассчитанные значения индекса. Повторный расчет замещает
+// прежние значения тех же периодов (ReplacingMergeTree по computed_at).
+func (r *ClickHouseRepository) SavePriceIndex(ctx context.Context, indexID string, points []domain.SeriesPoint, computedAt time.Time) error {
+	batch, err := r.conn.PrepareBatch(ctx, `INSERT INTO petrochemical.price_indices (index_id, timestamp, value, computed_at)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare price index batch: %w", err)
+	}
+
+	for _, p := range points {
+		if err := batch.Append(indexID, p.Timestamp, p.Value, computedAt); err != nil {
+			return fmt.Errorf("failed to append price index batch: %w", err)
+		}
+	}
+
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to save price index %s: %w", indexID, err)
+	}
+
+	return nil
+}
+
+// GetPriceIndex получает значения индекса за период
+func (r *ClickHouseRepository) GetPriceIndex(ctx context.Context, indexID string, start, end time.Time) ([]domain.SeriesPoint, error) {
+	query := `
+		SELECT timestamp, value
+		FROM petrochemical.price_indices FINAL
+		WHERE index_id = ? AND timestamp >= ? AND timestamp <= ?
+		ORDER BY timestamp`
+
+	rows, err := r.conn.Query(ctx, query, indexID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price index: %w", err)
+	}
+	defer rows.Close()
+
+	var points []domain.SeriesPoint
+	for rows.Next() {
+		var p domain.SeriesPoint
+		if err := rows.Scan(&p.Timestamp, &p.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan price index: %w", err)
+		}
+		points = append(points, p)
+	}
+
+	return points, rows.Err()
+}
+
+// GetPriceIndexValueAt получает первое значение индекса не раньше at; false, если таких нет
+func (r *ClickHouseRepository) GetPriceIndexValueAt(ctx context.Context, indexID string, at time.Time) (domain.SeriesPoint, bool, error) {
+	query := `
+		SELECT timestamp, value
+		FROM petrochemical.price_indices FINAL
+		WHERE index_id = ? AND timestamp >= ?
+		ORDER BY timestamp
+		LIMIT 1`
+
+	rows, err := r.conn.Query(ctx, query, indexID, at)
+	if err != nil {
+		return domain.SeriesPoint{}, false, fmt.Errorf("failed to query price index: %w", err)
+	}
+	defer rows.Close()
+
+	var p domain.SeriesPoint
+	if !rows.Next() {
+		return p, false, rows.Err()
+	}
+	if err := rows.Scan(&p.Timestamp, &p.Value); err != nil {
+		return p, false, fmt.Errorf("failed to scan price index: %w", err)
+	}
+	return p, true, nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Блокировки рядов телеметрии согласуют запись в ClickHouse и пересчет агрегатов между
+// процессами (экземпляры API, cmd/parser, cmd/simulator). Пересчет удаляет бакеты и
+// вставляет их заново из сырых данных: точка, записанная между удалением и вставкой,
+// учитывается в агрегатах дважды. Поэтому запись и пересчет ряда идут под одной
+// advisory-блокировкой PostgreSQL, а пересчет без списка рядов блокирует все ряды.
+
+// allSeriesLockKey — ключ блокировки всех рядов. Запись и пересчет отдельных рядов берут
+// его в разделяемом режиме, пересчет всех рядов — в эксклюзивном.
+var allSeriesLockKey = lockKey("telemetry")
+
+// lockKey переводит имя блокировки в ключ pg_advisory_lock
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// seriesLockKey — ключ блокировки ряда. Нулевой байт не встречается в идентификаторах,
+// поэтому ключ ряда не совпадает с ключом всех рядов.
+func seriesLockKey(s SeriesID) int64 {
+	return lockKey(s.CompanyID + "\x00" + s.ProductName)
+}
+
+// SeriesOf возвращает ряды точек без повторов
+func SeriesOf(data []TelemetryData) []SeriesID {
+	var series []SeriesID
+	seen := make(map[SeriesID]struct{})
+	for _, d := range data {
+		id := SeriesID{CompanyID: d.CompanyID, ProductName: d.ProductName}
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			series = append(series, id)
+		}
+	}
+	return series
+}
+
+// LockSeries берет эксклюзивные блокировки рядов и ждет, пока их отпустят другие процессы.
+// Блокировки держатся до вызова unlock. Ключи берутся по возрастанию, поэтому встречные
+// вызовы с пересекающимися рядами не взаимоблокируются.
+func (r *PostgresRepository) LockSeries(ctx context.Context, series []SeriesID) (unlock func(), err error) {
+	keys := make([]int64, 0, len(series))
+	for _, s := range series {
+		keys = append(keys, seriesLockKey(s))
+	}
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	batch := &pgx.Batch{}
+	batch.Queue("SELECT pg_advisory_lock_shared($1)", allSeriesLockKey)
+	for _, key := range keys {
+		batch.Queue("SELECT pg_advisory_lock($1)", key)
+	}
+	return r.advisoryLock(ctx, batch)
+}
+
+// LockTelemetry блокирует ряды, отобранные фильтром: перечисленные компании и продукты
+// блокируются как ряды, иначе (пустой список или фильтр по качеству, тегам и меткам) —
+// все ряды сразу
+func (r *PostgresRepository) LockTelemetry(ctx context.Context, filter TelemetryFilter) (unlock func(), err error) {
+	if len(filter.CompanyIDs) > 0 && len(filter.Products) > 0 && !filter.RawOnly() {
+		series := make([]SeriesID, 0, len(filter.CompanyIDs)*len(filter.Products))
+		for _, companyID := range filter.CompanyIDs {
+			for _, product := range filter.Products {
+				series = append(series, SeriesID{CompanyID: companyID, ProductName: product})
+			}
+		}
+		return r.LockSeries(ctx, series)
+	}
+
+	batch := &pgx.Batch{}
+	batch.Queue("SELECT pg_advisory_lock($1)", allSeriesLockKey)
+	return r.advisoryLock(ctx, batch)
+}
+
+// advisoryLock выполняет запросы блокировок на выделенном соединении: сессионные
+// блокировки принадлежат соединению, поэтому оно возвращается в пул только после unlock
+func (r *PostgresRepository) advisoryLock(ctx context.Context, batch *pgx.Batch) (func(), error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection for series locks: %w", err)
+	}
+
+	release := func() {
+		// Контекст вызова может быть уже отменен, а блокировки нужно снять в любом случае
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock_all()"); err != nil {
+			r.logger.Error("Failed to release series locks, closing connection", zap.Error(err))
+			conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}
+
+	if err := conn.SendBatch(ctx, batch).Close(); err != nil {
+		release()
+		return nil, fmt.Errorf("failed to lock series: %w", err)
+	}
+	return release, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"petrochemical-data-platform/internal/domain"
+)
+
+// GetTagVocabulary возвращает теги и метки, встречающиеся в сырых данных за период,
+// с числом строк. Для каждого ключа метки возвращается не более maxValues самых частых значений.
+func (r *ClickHouseRepository) GetTagVocabulary(ctx context.Context, companyIDs []string, start, end time.Time, maxValues int) (*domain.TagVocabulary, error) {
+	cond, condArgs := TelemetryFilter{CompanyIDs: companyIDs}.conditions()
+	args := append([]interface{}{start, end}, condArgs...)
+
+	vocabulary := &domain.TagVocabulary{Tags: []domain.TagCount{}, Labels: []domain.LabelVocabulary{}}
+
+	tagQuery := `
+		SELECT tag, count() AS n
+		FROM petrochemical.telemetry
+		ARRAY JOIN tags AS tag
+		WHERE timestamp >= ? AND timestamp <= ?` + cond + `
+		GROUP BY tag
+		ORDER BY n DESC, tag`
+
+	rows, err := r.conn.Query(ctx, tagQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag vocabulary: %w", err)
+	}
+	for rows.Next() {
+		var tag domain.TagCount
+		if err := rows.Scan(&tag.Value, &tag.Count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan tag vocabulary: %w", err)
+		}
+		vocabulary.Tags = append(vocabulary.Tags, tag)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tag vocabulary: %w", err)
+	}
+
+	labelQuery := `
+		SELECT key, sum(n) OVER (PARTITION BY key) AS key_total, value, n
+		FROM (
+			SELECT key, value, count() AS n
+			FROM petrochemical.telemetry
+			ARRAY JOIN mapKeys(labels) AS key, mapValues(labels) AS value
+			WHERE timestamp >= ? AND timestamp <= ?` + cond + `
+			GROUP BY key, value
+		)
+		ORDER BY key, n DESC, value
+		LIMIT ? BY key`
+
+	rows, err = r.conn.Query(ctx, labelQuery, append(args, maxValues)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query label vocabulary: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var total uint64
+		var value domain.TagCount
+		if err := rows.Scan(&key, &total, &value.Value, &value.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan label vocabulary: %w", err)
+		}
+
+		if n := len(vocabulary.Labels); n == 0 || vocabulary.Labels[n-1].Key != key {
+			vocabulary.Labels = append(vocabulary.Labels, domain.LabelVocabulary{Key: key, Count: total})
+		}
+		last := &vocabulary.Labels[len(vocabulary.Labels)-1]
+		last.Values = append(last.Values, value)
+	}
+
+	return vocabulary, rows.Err()
+}
@@ -2,9 +2,13 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"petrochemical-data-platform/internal/domain"
+
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
@@ -37,19 +41,28 @@ func (r *PostgresRepository) Close() {
 	r.pool.Close()
 }
 
+// Pool возвращает пул соединений (используется миграциями)
+func (r *PostgresRepository) Pool() *pgxpool.Pool {
+	return r.pool
+}
+
 // SaveAsset сохраняет метаданные актива
 func (r *PostgresRepository) SaveAsset(ctx context.Context, asset Asset) error {
 	query := `
-		INSERT INTO assets (id, name, type, location, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO assets (id, name, type, location, parent_id, level, telemetry_company_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, NULLIF($7, ''), $8, $9)
 		ON CONFLICT (id) DO UPDATE SET
 			name = EXCLUDED.name,
 			type = EXCLUDED.type,
 			location = EXCLUDED.location,
+			parent_id = EXCLUDED.parent_id,
+			level = EXCLUDED.level,
+			telemetry_company_id = EXCLUDED.telemetry_company_id,
 			updated_at = EXCLUDED.updated_at`
 
 	_, err := r.pool.Exec(ctx, query,
 		asset.ID, asset.Name, asset.Type, asset.Location,
+		asset.ParentID, asset.Level, asset.TelemetryCompanyID,
 		asset.CreatedAt, asset.UpdatedAt)
 
 	if err != nil {
@@ -62,7 +75,7 @@ func (r *PostgresRepository) SaveAsset(ctx context.Context, asset Asset) error {
 
 // GetAssets получает все активы
 func (r *PostgresRepository) GetAssets(ctx context.Context) ([]Asset, error) {
-	query := `SELECT id, name, type, location, created_at, updated_at FROM assets ORDER BY created_at DESC`
+	query := `SELECT ` + assetColumns + ` FROM assets ORDER BY created_at DESC`
 
 	rows, err := r.pool.Query(ctx, query)
 	if err != nil {
@@ -73,8 +86,7 @@ func (r *PostgresRepository) GetAssets(ctx context.Context) ([]Asset, error) {
 	var assets []Asset
 	for rows.Next() {
 		var asset Asset
-		err := rows.Scan(&asset.ID, &asset.Name, &asset.Type, &asset.Location, &asset.CreatedAt, &asset.UpdatedAt)
-		if err != nil {
+		if err := scanAsset(rows, &asset); err != nil {
 			return nil, err
 		}
 		assets = append(assets, asset)
@@ -83,6 +95,138 @@ func (r *PostgresRepository) GetAssets(ctx context.Context) ([]Asset, error) {
 	return assets, rows.Err()
 }
 
+// SaveAlert сохраняет оповещение
+func (r *PostgresRepository) SaveAlert(ctx context.Context, alert Alert) error {
+	query := `
+		INSERT INTO alerts (id, type, severity, message, company_id, asset_id, timestamp, acked)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO NOTHING`
+
+	_, err := r.pool.Exec(ctx, query,
+		alert.ID, alert.Type, alert.Severity, alert.Message,
+		alert.CompanyID, alert.AssetID, alert.Timestamp, alert.Acked)
+
+	if err != nil {
+		r.logger.Error("Failed to save alert", zap.Error(err), zap.String("alert_id", alert.ID))
+		return err
+	}
+
+	return nil
+}
+
+// GetRetentionPolicies получает все политики хранения
+func (r *PostgresRepository) GetRetentionPolicies(ctx context.Context) ([]RetentionPolicy, error) {
+	query := `SELECT data_class, company_id, retain_days, updated_at FROM retention_policies ORDER BY data_class, company_id`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []RetentionPolicy
+	for rows.Next() {
+		var p RetentionPolicy
+		if err := rows.Scan(&p.DataClass, &p.CompanyID, &p.RetainDays, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+
+	return policies, rows.Err()
+}
+
+// SaveRetentionPolicy создает или обновляет политику хранения
+func (r *PostgresRepository) SaveRetentionPolicy(ctx context.Context, policy RetentionPolicy) error {
+	query := `
+		INSERT INTO retention_policies (data_class, company_id, retain_days, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (data_class, company_id) DO UPDATE SET
+			retain_days = EXCLUDED.retain_days,
+			updated_at = EXCLUDED.updated_at`
+
+	_, err := r.pool.Exec(ctx, query, policy.DataClass, policy.CompanyID, policy.RetainDays, policy.UpdatedAt)
+	if err != nil {
+		r.logger.Error("Failed to save retention policy", zap.Error(err),
+			zap.String("data_class", policy.DataClass), zap.String("company_id", policy.CompanyID))
+		return err
+	}
+
+	return nil
+}
+
+// DeleteRetentionPolicy удаляет политику хранения. Возвращает false, если политики не было.
+func (r *PostgresRepository) DeleteRetentionPolicy(ctx context.Context, dataClass, companyID string) (bool, error) {
+	tag, err := r.pool.Exec(ctx,
+		`DELETE FROM retention_policies WHERE data_class = $1 AND company_id = $2`, dataClass, companyID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetProducts получает каталог продуктов
+func (r *PostgresRepository) GetProducts(ctx context.Context) ([]domain.Product, error) {
+	query := `SELECT id::text, company_id, name, type, unit, status, created_at, updated_at FROM products ORDER BY company_id, name`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	var products []domain.Product
+	for rows.Next() {
+		var p domain.Product
+		if err := rows.Scan(&p.ID, &p.CompanyID, &p.Name, &p.Type, &p.Unit, &p.Status, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		products = append(products, p)
+	}
+
+	return products, rows.Err()
+}
+
+// GetFeedReport получает сведения об обработанном отчете источника за дату. Возвращает nil, если отчета не было.
+func (r *PostgresRepository) GetFeedReport(ctx context.Context, source string, date time.Time) (*FeedReport, error) {
+	query := `
+		SELECT source, report_date, file_name, checksum, quotes, points, processed_at
+		FROM feed_reports WHERE source = $1 AND report_date = $2`
+
+	var report FeedReport
+	err := r.pool.QueryRow(ctx, query, source, date).Scan(&report.Source, &report.ReportDate,
+		&report.FileName, &report.Checksum, &report.Quotes, &report.Points, &report.ProcessedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feed report: %w", err)
+	}
+	return &report, nil
+}
+
+// SaveFeedReport сохраняет сведения об обработанном отчете, заменяя прежние за ту же дату
+func (r *PostgresRepository) SaveFeedReport(ctx context.Context, report FeedReport) error {
+	query := `
+		INSERT INTO feed_reports (source, report_date, file_name, checksum, quotes, points, processed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (source, report_date) DO UPDATE SET
+			file_name = EXCLUDED.file_name,
+			checksum = EXCLUDED.checksum,
+			quotes = EXCLUDED.quotes,
+			points = EXCLUDED.points,
+			processed_at = EXCLUDED.processed_at`
+
+	_, err := r.pool.Exec(ctx, query, report.Source, report.ReportDate, report.FileName,
+		report.Checksum, report.Quotes, report.Points, report.ProcessedAt)
+	if err != nil {
+		r.logger.Error("Failed to save feed report", zap.Error(err), zap.String("source", report.Source))
+		return err
+	}
+
+	return nil
+}
+
 // Asset represents equipment metadata
 type Asset struct {
 	ID        string    `json:"id" db:"id"`
@@ -91,4 +235,41 @@ type Asset struct {
 	Location  string    `json:"location" db:"location"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	ParentID           string `json:"parent_id" db:"parent_id"`
+	Level              string `json:"level" db:"level"`
+	TelemetryCompanyID string `json:"telemetry_company_id" db:"telemetry_company_id"`
+	Depth              int    `json:"depth" db:"depth"` // Distance from the queried asset in hierarchy queries
+}
+
+// Alert represents a stored system alert
+type Alert struct {
+	ID        string     `json:"id" db:"id"`
+	Type      string     `json:"type" db:"type"`
+	Severity  string     `json:"severity" db:"severity"`
+	Message   string     `json:"message" db:"message"`
+	CompanyID string     `json:"company_id" db:"company_id"`
+	AssetID   string     `json:"asset_id" db:"asset_id"`
+	Timestamp time.Time  `json:"timestamp" db:"timestamp"`
+	Acked     bool       `json:"acked" db:"acked"`
+	AckedAt   *time.Time `json:"acked_at" db:"acked_at"`
+}
+
+// RetentionPolicy represents a stored retention policy
+type RetentionPolicy struct {
+	DataClass  string    `json:"data_class" db:"data_class"`
+	CompanyID  string    `json:"company_id" db:"company_id"`
+	RetainDays int       `json:"retain_days" db:"retain_days"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// FeedReport represents a processed price feed report
+type FeedReport struct {
+	Source      string    `json:"source" db:"source"`
+	ReportDate  time.Time `json:"report_date" db:"report_date"`
+	FileName    string    `json:"file_name" db:"file_name"`
+	Checksum    string    `json:"checksum" db:"checksum"`
+	Quotes      int       `json:"quotes" db:"quotes"`
+	Points      int       `json:"points" db:"points"`
+	ProcessedAt time.Time `json:"processed_at" db:"processed_at"`
 }
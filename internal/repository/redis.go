@@ -127,3 +127,66 @@ func (r *RedisRepository) PublishData(ctx context.Context, channel string, data
 
 	return nil
 }
+
+// IdempotencyRecord — состояние запроса с ключом идемпотентности
+type IdempotencyRecord struct {
+	Fingerprint string          `json:"fingerprint"`        // Хэш тела запроса
+	Response    json.RawMessage `json:"response,omitempty"` // Пусто, пока запрос обрабатывается
+}
+
+func idempotencyKey(scope, key string) string {
+	return fmt.Sprintf("idempotency:%s:%s", scope, key)
+}
+
+// ReserveIdempotencyKey занимает ключ на время обработки запроса.
+// Возвращает false, если ключ уже занят или использован.
+func (r *RedisRepository) ReserveIdempotencyKey(ctx context.Context, scope, key, fingerprint string, ttl time.Duration) (bool, error) {
+	recordJSON, err := json.Marshal(IdempotencyRecord{Fingerprint: fingerprint})
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+
+	ok, err := r.client.SetNX(ctx, idempotencyKey(scope, key), recordJSON, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	return ok, nil
+}
+
+// GetIdempotencyRecord возвращает состояние ключа или nil, если ключ не использовался
+func (r *RedisRepository) GetIdempotencyRecord(ctx context.Context, scope, key string) (*IdempotencyRecord, error) {
+	recordJSON, err := r.client.Get(ctx, idempotencyKey(scope, key)).Result()
+	if err == redis.Nil {
+		return nil, nil // Not found
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+
+	var record IdempotencyRecord
+	if err := json.Unmarshal([]byte(recordJSON), &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal idempotency record: %w", err)
+	}
+
+	return &record, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос для повторов с тем же ключом
+func (r *RedisRepository) CompleteIdempotencyKey(ctx context.Context, scope, key, fingerprint string, response []byte, ttl time.Duration) error {
+	recordJSON, err := json.Marshal(IdempotencyRecord{Fingerprint: fingerprint, Response: response})
+	if err != nil {
+		return fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+
+	if err := r.client.Set(ctx, idempotencyKey(scope, key), recordJSON, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save idempotency record: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey освобождает ключ после неудачной обработки, чтобы запрос можно было повторить
+func (r *RedisRepository) ReleaseIdempotencyKey(ctx context.Context, scope, key string) error {
+	if err := r.client.Del(ctx, idempotencyKey(scope, key)).Err(); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
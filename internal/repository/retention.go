@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CountOlderThan считает строки таблицы со временем раньше cutoff. Если companyID задан,
+// учитываются только строки компании; иначе — строки всех компаний, кроме exclude.
+func (r *ClickHouseRepository) CountOlderThan(ctx context.Context, table, timeColumn string, cutoff time.Time, companyID string, exclude []string) (uint64, error) {
+	conditions := []string{fmt.Sprintf("%s < ?", timeColumn)}
+	args := []interface{}{cutoff}
+
+	if companyID != "" {
+		conditions = append(conditions, "company_id = ?")
+		args = append(args, companyID)
+	} else if len(exclude) > 0 {
+		conditions = append(conditions, "NOT has(?, company_id)")
+		args = append(args, exclude)
+	}
+
+	query := fmt.Sprintf("SELECT count() FROM %s WHERE %s", table, strings.Join(conditions, " AND "))
+
+	var rows uint64
+	if err := r.conn.QueryRow(ctx, query, args...).Scan(&rows); err != nil {
+		return 0, fmt.Errorf("failed to count rows in %s: %w", table, err)
+	}
+	return rows, nil
+}
+
+// AvgRowBytes возвращает средний сжатый размер строки таблицы по активным кускам
+func (r *ClickHouseRepository) AvgRowBytes(ctx context.Context, table string) (float64, error) {
+	database, name, _ := strings.Cut(table, ".")
+
+	query := `
+		SELECT if(sum(rows) = 0, 0, sum(data_compressed_bytes) / sum(rows))
+		FROM system.parts
+		WHERE database = ? AND table = ? AND active`
+
+	var avg float64
+	if err := r.conn.QueryRow(ctx, query, database, name).Scan(&avg); err != nil {
+		return 0, fmt.Errorf("failed to estimate row size of %s: %w", table, err)
+	}
+	return avg, nil
+}
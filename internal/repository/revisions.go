@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"petrochemical-data-platform/internal/domain"
+)
+
+// TelemetryKey — ключ идемпотентной записи: одна точка на компанию, продукт и момент времени
+type TelemetryKey struct {
+	CompanyID   string
+	ProductName string
+	Timestamp   int64 // Unix-время в миллисекундах (точность столбца timestamp)
+}
+
+// KeyOf возвращает ключ строки телеметрии
+func KeyOf(data TelemetryData) TelemetryKey {
+	return TelemetryKey{CompanyID: data.CompanyID, ProductName: data.ProductName, Timestamp: data.Timestamp.UnixMilli()}
+}
+
+// StoredValue — текущее значение точки в хранилище
+type StoredValue struct {
+	Value   float64
+	Quality uint16
+	Version uint64
+}
+
+// Revision — замена ранее записанного значения точки
+type Revision struct {
+	Key       TelemetryKey
+	Old       StoredValue
+	New       StoredValue
+	RevisedAt time.Time
+	Source    string
+}
+
+// GetCurrentValues возвращает текущие значения точек по ключам; отсутствующие ключи в результат не попадают
+func (r *ClickHouseRepository) GetCurrentValues(ctx context.Context, keys []TelemetryKey) (map[TelemetryKey]StoredValue, error) {
+	result := make(map[TelemetryKey]StoredValue, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	// Запрос по диапазону времени и набору рядов; лишние строки отсекаются по ключу
+	wanted := make(map[TelemetryKey]bool, len(keys))
+	companies := make(map[string]bool)
+	products := make(map[string]bool)
+	minTs, maxTs := keys[0].Timestamp, keys[0].Timestamp
+	for _, k := range keys {
+		wanted[k] = true
+		companies[k.CompanyID] = true
+		products[k.ProductName] = true
+		minTs, maxTs = min(minTs, k.Timestamp), max(maxTs, k.Timestamp)
+	}
+
+	filter := TelemetryFilter{CompanyIDs: setKeys(companies), Products: setKeys(products)}
+	cond, condArgs := filter.conditions()
+	query := `
+		SELECT company_id, product_name, toUnixTimestamp64Milli(timestamp), value, quality, version
+		FROM petrochemical.telemetry FINAL
+		WHERE timestamp >= toDateTime64(?, 3, 'UTC') AND timestamp <= toDateTime64(?, 3, 'UTC')` + cond
+
+	args := append([]interface{}{dateTime64(time.UnixMilli(minTs)), dateTime64(time.UnixMilli(maxTs))}, condArgs...)
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query current telemetry values: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var k TelemetryKey
+		var v StoredValue
+		if err := rows.Scan(&k.CompanyID, &k.ProductName, &k.Timestamp, &v.Value, &v.Quality, &v.Version); err != nil {
+			return nil, fmt.Errorf("failed to scan current telemetry values: %w", err)
+		}
+		if wanted[k] {
+			result[k] = v
+		}
+	}
+
+	return result, rows.Err()
+}
+
+// SaveRevisions записывает историю замен значений
+func (r *ClickHouseRepository) SaveRevisions(ctx context.Context, revisions []Revision) error {
+	if len(revisions) == 0 {
+		return nil
+	}
+
+	batch, err := r.conn.PrepareBatch(ctx, `
+		INSERT INTO petrochemical.telemetry_revisions
+		(company_id, product_name, timestamp, old_value, new_value, old_quality, new_quality, old_version, new_version, revised_at, source)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare revisions batch: %w", err)
+	}
+
+	for _, rev := range revisions {
+		err := batch.Append(rev.Key.CompanyID, rev.Key.ProductName, time.UnixMilli(rev.Key.Timestamp).UTC(),
+			rev.Old.Value, rev.New.Value, rev.Old.Quality, rev.New.Quality, rev.Old.Version, rev.New.Version,
+			rev.RevisedAt, rev.Source)
+		if err != nil {
+			return fmt.Errorf("failed to append revisions batch: %w", err)
+		}
+	}
+
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to save revisions: %w", err)
+	}
+
+	return nil
+}
+
+// GetRevisions возвращает историю замен значений точек за период (по времени точки), новые правки первыми
+func (r *ClickHouseRepository) GetRevisions(ctx context.Context, filter TelemetryFilter, start, end time.Time) ([]domain.TelemetryRevision, error) {
+	cond, condArgs := TelemetryFilter{CompanyIDs: filter.CompanyIDs, Products: filter.Products}.conditions()
+	query := `
+		SELECT company_id, product_name, timestamp, old_value, new_value, old_quality, new_quality, revised_at, source
+		FROM petrochemical.telemetry_revisions
+		WHERE timestamp >= ? AND timestamp <= ?` + cond + `
+		ORDER BY revised_at DESC, company_id, product_name, timestamp`
+
+	rows, err := r.conn.Query(ctx, query, append([]interface{}{start, end}, condArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query telemetry revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []domain.TelemetryRevision
+	for rows.Next() {
+		var rev domain.TelemetryRevision
+		err := rows.Scan(&rev.CompanyID, &rev.ProductName, &rev.Timestamp, &rev.OldValue, &rev.NewValue,
+			&rev.OldQuality, &rev.NewQuality, &rev.RevisedAt, &rev.Source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan telemetry revisions: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+
+	return revisions, rows.Err()
+}
+
+func setKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/pkg/stats"
+	"petrochemical-data-platform/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// SeriesKey идентифицирует ряд продукта компании
+type SeriesKey struct {
+	CompanyID   string
+	ProductName string
+}
+
+// String возвращает ключ в формате company_id:product_name
+func (k SeriesKey) String() string {
+	return k.CompanyID + ":" + k.ProductName
+}
+
+// CorrelationRequest описывает параметры корреляционного анализа
+type CorrelationRequest struct {
+	Series     []SeriesKey
+	Start, End time.Time
+	Interval   repository.Interval
+	MaxLag     int
+}
+
+// AnalyticsService выполняет кросс-продуктовую аналитику по рядам ClickHouse
+type AnalyticsService struct {
+	repo   *repository.ClickHouseRepository
+	logger *zap.Logger
+}
+
+// NewAnalyticsService создает новый сервис аналитики
+func NewAnalyticsService(repo *repository.ClickHouseRepository, logger *zap.Logger) *AnalyticsService {
+	return &AnalyticsService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Correlate выравнивает ряды по общему интервалу и считает корреляции Пирсона/Спирмена,
+// матрицы корреляций и профиль кросс-корреляции со сдвигами от -MaxLag до MaxLag
+func (s *AnalyticsService) Correlate(ctx context.Context, req CorrelationRequest) (*domain.CorrelationReport, error) {
+	if len(req.Series) < 2 {
+		return nil, fmt.Errorf("at least two series are required")
+	}
+
+	aligned := make([]map[time.Time]float64, len(req.Series))
+	for i, key := range req.Series {
+		points, err := s.repo.GetAggregatedSeries(ctx, key.CompanyID, key.ProductName, req.Start, req.End, req.Interval)
+		if err != nil {
+			return nil, err
+		}
+
+		aligned[i] = make(map[time.Time]float64, len(points))
+		for _, p := range points {
+			aligned[i][p.Timestamp.UTC()] = p.Value
+		}
+	}
+
+	n := len(req.Series)
+	report := &domain.CorrelationReport{
+		Interval: string(req.Interval),
+		Series:   make([]string, n),
+		Pearson:  make([][]*float64, n),
+		Spearman: make([][]*float64, n),
+	}
+	for i, key := range req.Series {
+		report.Series[i] = key.String()
+		report.Pearson[i] = make([]*float64, n)
+		report.Spearman[i] = make([]*float64, n)
+		one := 1.0
+		report.Pearson[i][i], report.Spearman[i][i] = &one, &one
+	}
+
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			x, y := pairAt(aligned[i], aligned[j], req.Interval, 0)
+
+			pair := domain.CorrelationPair{
+				A:        report.Series[i],
+				B:        report.Series[j],
+				Pearson:  optional(stats.Pearson(x, y)),
+				Spearman: optional(stats.Spearman(x, y)),
+				N:        len(x),
+			}
+
+			var best float64
+			for lag := -req.MaxLag; lag <= req.MaxLag && req.MaxLag > 0; lag++ {
+				lx, ly := pairAt(aligned[i], aligned[j], req.Interval, lag)
+				corr := optional(stats.Pearson(lx, ly))
+				pair.Lags = append(pair.Lags, domain.LagCorrelation{Lag: lag, Correlation: corr, N: len(lx)})
+
+				if corr != nil && (pair.BestLag == nil || math.Abs(*corr) > best) {
+					best = math.Abs(*corr)
+					l := lag
+					pair.BestLag = &l
+				}
+			}
+
+			report.Pearson[i][j], report.Pearson[j][i] = pair.Pearson, pair.Pearson
+			report.Spearman[i][j], report.Spearman[j][i] = pair.Spearman, pair.Spearman
+			report.Pairs = append(report.Pairs, pair)
+		}
+	}
+
+	return report, nil
+}
+
+// pairAt сопоставляет значение a в момент t со значением b в момент t + lag интервалов.
+// Положительный lag означает, что b отстает от a.
+func pairAt(a, b map[time.Time]float64, interval repository.Interval, lag int) ([]float64, []float64) {
+	var x, y []float64
+	for _, t := range sortedTimes(a) {
+		if v, ok := b[interval.Add(t, lag)]; ok {
+			x = append(x, a[t])
+			y = append(y, v)
+		}
+	}
+	return x, y
+}
+
+func sortedTimes(m map[time.Time]float64) []time.Time {
+	times := make([]time.Time, 0, len(m))
+	for t := range m {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	return times
+}
+
+func optional(v float64, ok bool) *float64 {
+	if !ok {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,359 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/pkg/parser"
+	"petrochemical-data-platform/internal/repository"
+
+	"github.com/klauspost/compress/snappy"
+	"go.uber.org/zap"
+)
+
+// Ошибки приема пачки по HTTP
+var (
+	ErrPayloadTooLarge      = errors.New("payload too large")
+	ErrInvalidPayload       = errors.New("invalid payload")
+	ErrIngestInProgress     = errors.New("a request with this idempotency key is in progress")
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used with a different payload")
+)
+
+const (
+	// idempotencyScope — пространство ключей идемпотентности приема телеметрии
+	idempotencyScope = "ingest"
+	// ingestReservationTTL ограничивает блокировку ключа, если обработка прервалась
+	ingestReservationTTL = 10 * time.Minute
+	// catalogTTL — период обновления каталога продуктов
+	catalogTTL = time.Minute
+)
+
+// Payload — тело запроса приема телеметрии
+type Payload struct {
+	Body           io.Reader
+	Format         parser.Format
+	Encoding       string        // Сжатие тела: пусто, gzip или snappy
+	Precision      time.Duration // Точность меток времени протокола InfluxDB
+	IdempotencyKey string        // Пусто — без защиты от повторной отправки
+}
+
+// IngestPayload разбирает пачку, проверяет записи по каталогу продуктов и записывает принятые.
+// Ошибки отдельных записей возвращаются в IngestResult.Rejected с номером записи в пачке.
+// Повтор запроса с тем же ключом идемпотентности возвращает сохраненный результат без записи.
+func (s *IngestionService) IngestPayload(ctx context.Context, payload Payload, opts IngestOptions) (*IngestResult, error) {
+	raw, err := readLimited(payload.Body, s.cfg.MaxBodyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Отпечаток учитывает режим записи: тот же ключ и тело с другим ?mode= — другой запрос
+	mode := opts.Mode
+	if mode == "" {
+		mode = s.mode
+	}
+	hash := sha256.New()
+	hash.Write([]byte(payload.Format))
+	hash.Write([]byte{0})
+	hash.Write([]byte(mode))
+	hash.Write([]byte{0})
+	hash.Write(raw)
+	fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+	if payload.IdempotencyKey == "" {
+		return s.ingestPayload(ctx, raw, payload, opts)
+	}
+
+	record, err := s.cache.GetIdempotencyRecord(ctx, idempotencyScope, payload.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if record != nil {
+		return replay(record, fingerprint)
+	}
+
+	reserved, err := s.cache.ReserveIdempotencyKey(ctx, idempotencyScope, payload.IdempotencyKey, fingerprint, ingestReservationTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !reserved {
+		return nil, ErrIngestInProgress
+	}
+
+	result, err := s.ingestPayload(ctx, raw, payload, opts)
+	if err != nil {
+		// Ключ освобождается, чтобы клиент мог повторить запрос
+		if relErr := s.cache.ReleaseIdempotencyKey(context.WithoutCancel(ctx), idempotencyScope, payload.IdempotencyKey); relErr != nil {
+			s.logger.Warn("Failed to release idempotency key", zap.Error(relErr))
+		}
+		return nil, err
+	}
+
+	response, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ingest result: %w", err)
+	}
+	if err := s.cache.CompleteIdempotencyKey(ctx, idempotencyScope, payload.IdempotencyKey, fingerprint, response, s.cfg.IdempotencyTTL); err != nil {
+		s.logger.Warn("Failed to save idempotent ingest result", zap.Error(err))
+	}
+
+	return result, nil
+}
+
+func (s *IngestionService) ingestPayload(ctx context.Context, raw []byte, payload Payload, opts IngestOptions) (*IngestResult, error) {
+	body, err := s.decompress(raw, payload.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	records, skipped, err := s.decode(body, payload)
+	if errors.Is(err, parser.ErrTooManyRecords) {
+		return nil, fmt.Errorf("%w: more than %d records", ErrPayloadTooLarge, s.cfg.MaxRecords)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	catalog, err := s.catalog.get(ctx, s.postgres)
+	if err != nil {
+		return nil, err
+	}
+
+	// Принятые точки и их номера в пачке
+	now := time.Now()
+	var rejected []RejectedPoint
+	points := make([]parser.DataPoint, 0, len(records))
+	indices := make([]int, 0, len(records))
+	for _, rec := range records {
+		err := rec.Err
+		if err == nil {
+			err = s.validate(&rec.Point, catalog, now)
+		}
+		if err != nil {
+			rejected = append(rejected, RejectedPoint{Index: rec.Index, Reason: err.Error()})
+			continue
+		}
+		points = append(points, rec.Point)
+		indices = append(indices, rec.Index)
+	}
+
+	result, err := s.Ingest(ctx, points, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range result.Rejected {
+		rejected = append(rejected, RejectedPoint{Index: indices[r.Index], Reason: r.Reason})
+	}
+	sort.Slice(rejected, func(i, j int) bool { return rejected[i].Index < rejected[j].Index })
+
+	result.Received = len(records) + skipped
+	result.Skipped = skipped
+	result.Rejected = append([]RejectedPoint{}, rejected...)
+
+	s.logger.Info("Telemetry batch ingested",
+		zap.String("format", string(payload.Format)),
+		zap.Int("received", result.Received),
+		zap.Int("written", result.Written),
+		zap.Int("revised", result.Revised),
+		zap.Int("unchanged", result.Unchanged),
+		zap.Int("rejected", len(result.Rejected)))
+
+	return result, nil
+}
+
+// decompress распаковывает тело, соблюдая предел размера
+func (s *IngestionService) decompress(raw []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", "identity":
+		return raw, nil
+
+	case "gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+		}
+		defer gz.Close()
+
+		body, err := readLimited(gz, s.cfg.MaxBodyBytes)
+		if err != nil && !errors.Is(err, ErrPayloadTooLarge) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+		}
+		return body, err
+
+	case "snappy":
+		// remote_write использует блочный формат snappy без кадров
+		n, err := snappy.DecodedLen(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+		}
+		if int64(n) > s.cfg.MaxBodyBytes {
+			return nil, fmt.Errorf("%w: limit is %d bytes", ErrPayloadTooLarge, s.cfg.MaxBodyBytes)
+		}
+		body, err := snappy.Decode(nil, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+		}
+		return body, nil
+	}
+
+	return nil, fmt.Errorf("%w: unsupported encoding %q", ErrInvalidPayload, encoding)
+}
+
+// decode разбирает тело в записи. Для протоколов InfluxDB и Prometheus образцы сопоставляются
+// с продуктами; skipped — образцы, не подходящие ни под одно правило.
+func (s *IngestionService) decode(body []byte, payload Payload) ([]parser.Record, int, error) {
+	var samples []parser.SampleRecord
+	var mapper *parser.Mapper
+	var err error
+
+	switch payload.Format {
+	case parser.FormatInflux:
+		precision := payload.Precision
+		if precision <= 0 {
+			precision = time.Nanosecond
+		}
+		samples, err = parser.ParseLineProtocol(bytes.NewReader(body), precision, time.Now(), s.cfg.MaxRecords)
+		mapper = s.influx
+	case parser.FormatRemoteWrite:
+		samples, err = parser.ParseRemoteWrite(body, s.cfg.MaxRecords)
+		mapper = s.remote
+	default:
+		records, err := parser.Decode(bytes.NewReader(body), payload.Format, s.cfg.MaxRecords)
+		return records, 0, err
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records := make([]parser.Record, 0, len(samples))
+	skipped := 0
+	for _, sample := range samples {
+		rec := parser.Record{Index: sample.Index, Err: sample.Err}
+		if rec.Err == nil {
+			var ok bool
+			if rec.Point, ok, rec.Err = mapper.Map(sample.Sample); !ok {
+				skipped++
+				continue
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, skipped, nil
+}
+
+// validate проверяет точку по каталогу продуктов; пустая единица измерения берется из каталога
+func (s *IngestionService) validate(p *parser.DataPoint, catalog map[repository.SeriesID]domain.Product, now time.Time) error {
+	switch {
+	case p.CompanyID == "":
+		return errors.New("company_id is required")
+	case p.ProductName == "":
+		return errors.New("product_name is required")
+	case p.Timestamp.IsZero():
+		return errors.New("timestamp is required")
+	case p.Timestamp.After(now.Add(s.cfg.MaxFutureSkew)):
+		return fmt.Errorf("timestamp %s is in the future", p.Timestamp.Format(time.RFC3339))
+	case math.IsNaN(p.Value) || math.IsInf(p.Value, 0):
+		return errors.New("value must be a finite number")
+	}
+
+	product, ok := catalog[repository.SeriesID{CompanyID: p.CompanyID, ProductName: p.ProductName}]
+	if !ok {
+		return fmt.Errorf("product %q is not in the catalog of company %q", p.ProductName, p.CompanyID)
+	}
+	if product.Status != "active" {
+		return fmt.Errorf("product %q of company %q is %s", p.ProductName, p.CompanyID, product.Status)
+	}
+
+	if p.Unit == "" {
+		p.Unit = product.Unit
+	} else if p.Unit != product.Unit {
+		return fmt.Errorf("unit %q does not match catalog unit %q", p.Unit, product.Unit)
+	}
+
+	return nil
+}
+
+// checkProduct проверяет ряд, настроенный для коннектора оборудования: продукт есть
+// в каталоге и активен, а заданная единица измерения совпадает с единицей каталога
+func checkProduct(catalog map[repository.SeriesID]domain.Product, companyID, productName, unit string) error {
+	product, ok := catalog[repository.SeriesID{CompanyID: companyID, ProductName: productName}]
+	switch {
+	case !ok:
+		return fmt.Errorf("product %q is not in the catalog of company %q", productName, companyID)
+	case product.Status != "active":
+		return fmt.Errorf("product %q of company %q is %s", productName, companyID, product.Status)
+	case unit != "" && unit != product.Unit:
+		return fmt.Errorf("unit %q does not match catalog unit %q", unit, product.Unit)
+	}
+	return nil
+}
+
+// replay возвращает сохраненный результат запроса с тем же ключом идемпотентности
+func replay(record *repository.IdempotencyRecord, fingerprint string) (*IngestResult, error) {
+	if record.Fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if len(record.Response) == 0 {
+		return nil, ErrIngestInProgress
+	}
+
+	var result IngestResult
+	if err := json.Unmarshal(record.Response, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal idempotent ingest result: %w", err)
+	}
+	result.Replayed = true
+	return &result, nil
+}
+
+// readLimited читает r целиком, но не больше limit байт
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payload: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrPayloadTooLarge, limit)
+	}
+	return data, nil
+}
+
+// productCatalog кэширует каталог продуктов, обновляя его не чаще catalogTTL
+type productCatalog struct {
+	mu       sync.Mutex
+	products map[repository.SeriesID]domain.Product
+	loadedAt time.Time
+}
+
+func (c *productCatalog) get(ctx context.Context, repo *repository.PostgresRepository) (map[repository.SeriesID]domain.Product, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.products != nil && time.Since(c.loadedAt) < catalogTTL {
+		return c.products, nil
+	}
+
+	products, err := repo.GetProducts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.products = make(map[repository.SeriesID]domain.Product, len(products))
+	for _, p := range products {
+		c.products[repository.SeriesID{CompanyID: p.CompanyID, ProductName: p.Name}] = p
+	}
+	c.loadedAt = time.Now()
+
+	return c.products, nil
+}
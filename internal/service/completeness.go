@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"petrochemical-data-platform/internal/config"
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// completenessRule — разобранное правило частоты поступления данных
+type completenessRule struct {
+	companyID string
+	product   string
+	frequency repository.Interval
+	grace     time.Duration
+}
+
+// matches сообщает, подходит ли правило ряду, и насколько оно конкретно
+func (r completenessRule) matches(id repository.SeriesID) (bool, int) {
+	if (r.companyID != "" && r.companyID != id.CompanyID) || (r.product != "" && r.product != id.ProductName) {
+		return false, 0
+	}
+	specificity := 0
+	if r.companyID != "" {
+		specificity += 2
+	}
+	if r.product != "" {
+		specificity++
+	}
+	return true, specificity
+}
+
+// CompletenessService проверяет полноту данных относительно ожидаемой частоты
+// и оповещает об отсутствии новых данных
+type CompletenessService struct {
+	repo          *repository.ClickHouseRepository
+	alerts        *repository.PostgresRepository
+	defaults      completenessRule
+	rules         []completenessRule
+	lookback      time.Duration
+	checkInterval time.Duration
+	logger        *zap.Logger
+
+	mu      sync.Mutex
+	alerted map[repository.SeriesID]time.Time // Начало пропуска, о котором уже отправлено оповещение
+}
+
+// NewCompletenessService создает сервис полноты данных из конфигурации
+func NewCompletenessService(repo *repository.ClickHouseRepository, alerts *repository.PostgresRepository, cfg config.CompletenessConfig, logger *zap.Logger) (*CompletenessService, error) {
+	s := &CompletenessService{
+		repo:          repo,
+		alerts:        alerts,
+		defaults:      completenessRule{frequency: repository.IntervalMonth, grace: cfg.Grace},
+		lookback:      cfg.Lookback,
+		checkInterval: cfg.CheckInterval,
+		logger:        logger,
+		alerted:       make(map[repository.SeriesID]time.Time),
+	}
+
+	if cfg.DefaultFrequency != "" {
+		frequency, err := repository.ParseInterval(cfg.DefaultFrequency)
+		if err != nil {
+			return nil, fmt.Errorf("completeness: %w", err)
+		}
+		s.defaults.frequency = frequency
+	}
+	if s.lookback <= 0 {
+		s.lookback = 365 * 24 * time.Hour
+	}
+
+	for _, r := range cfg.Rules {
+		frequency, err := repository.ParseInterval(r.Frequency)
+		if err != nil {
+			return nil, fmt.Errorf("completeness rule %s/%s: %w", r.CompanyID, r.Product, err)
+		}
+		rule := completenessRule{companyID: r.CompanyID, product: r.Product, frequency: frequency, grace: r.Grace}
+		if rule.grace == 0 {
+			rule.grace = cfg.Grace
+		}
+		s.rules = append(s.rules, rule)
+	}
+
+	return s, nil
+}
+
+// Report строит отчет о полноте данных за период. Учитываются ряды, по которым когда-либо
+// были данные, и ряды, явно заданные правилами. Интервалы до первых данных ряда
+// и интервалы, срок поступления которых еще не наступил, не считаются пропусками.
+func (s *CompletenessService) Report(ctx context.Context, filter repository.TelemetryFilter, start, end time.Time) (*domain.CompletenessReport, error) {
+	report, _, err := s.report(ctx, filter, start, end, time.Now())
+	return report, err
+}
+
+// report строит отчет и для каждого ряда сообщает, пропущен ли последний ожидаемый интервал
+func (s *CompletenessService) report(ctx context.Context, filter repository.TelemetryFilter, start, end, now time.Time) (*domain.CompletenessReport, []bool, error) {
+	spans, err := s.series(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Интервалы с данными загружаются одним запросом на каждую используемую частоту
+	buckets := make(map[repository.Interval]map[repository.SeriesID][]time.Time)
+	for _, span := range spans {
+		frequency := s.rule(span.SeriesID).frequency
+		if _, ok := buckets[frequency]; ok {
+			continue
+		}
+		if buckets[frequency], err = s.repo.GetSeriesBuckets(ctx, filter, start, end, frequency); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	report := &domain.CompletenessReport{Start: start, End: end, Series: []domain.SeriesCompleteness{}}
+	open := make([]bool, 0, len(spans))
+	for _, span := range spans {
+		rule := s.rule(span.SeriesID)
+		series, trailing := completeness(span, rule, buckets[rule.frequency][span.SeriesID], start, end, now)
+
+		report.Expected += series.Expected
+		report.Present += series.Present
+		report.Series = append(report.Series, series)
+		open = append(open, trailing)
+	}
+	report.Coverage = coverage(report.Present, report.Expected)
+
+	return report, open, nil
+}
+
+// Run периодически проверяет отсутствие новых данных, пока не отменен ctx
+func (s *CompletenessService) Run(ctx context.Context) {
+	if s.checkInterval <= 0 || s.alerts == nil {
+		return
+	}
+
+	ticker := time.NewTicker(s.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.CheckNoData(ctx); err != nil {
+			s.logger.Error("Failed to check data completeness", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckNoData оповещает о рядах, по которым не поступили данные за последний ожидаемый интервал.
+// Об одном пропуске оповещение отправляется один раз; после поступления данных состояние сбрасывается.
+func (s *CompletenessService) CheckNoData(ctx context.Context) error {
+	now := time.Now()
+	report, open, err := s.report(ctx, repository.TelemetryFilter{}, now.Add(-s.lookback), now, now)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, series := range report.Series {
+		id := repository.SeriesID{CompanyID: series.CompanyID, ProductName: series.ProductName}
+		if !open[i] {
+			delete(s.alerted, id)
+			continue
+		}
+
+		// Ряды, замолчавшие раньше периода проверки, не оповещаются, если не заданы правилом явно
+		if (series.LastSeen == nil || series.LastSeen.Before(report.Start)) && !s.explicit(id) {
+			continue
+		}
+
+		gap := series.Gaps[len(series.Gaps)-1]
+		if alertedAt, ok := s.alerted[id]; ok && alertedAt.Equal(gap.Start) {
+			continue
+		}
+
+		if err := s.raiseNoDataAlert(ctx, series, gap); err != nil {
+			s.logger.Error("Failed to save no-data alert", zap.Error(err),
+				zap.String("company_id", series.CompanyID), zap.String("product", series.ProductName))
+			continue
+		}
+		s.alerted[id] = gap.Start
+	}
+
+	return nil
+}
+
+func (s *CompletenessService) raiseNoDataAlert(ctx context.Context, series domain.SeriesCompleteness, gap domain.DataGap) error {
+	severity := "warning"
+	if gap.Missing >= 3 {
+		severity = "critical"
+	}
+
+	alert := repository.Alert{
+		ID:       strconv.FormatInt(time.Now().UnixNano(), 10),
+		Type:     "no_data",
+		Severity: severity,
+		Message: fmt.Sprintf("%s: нет данных с %s (ожидаемая частота %s, пропущено интервалов: %d)",
+			series.ProductName, gap.Start.Format(time.DateOnly), series.Frequency, gap.Missing),
+		CompanyID: series.CompanyID,
+		Timestamp: time.Now(),
+	}
+
+	s.logger.Warn("No data for series",
+		zap.String("company_id", series.CompanyID),
+		zap.String("product", series.ProductName),
+		zap.Time("since", gap.Start),
+		zap.Int("missing", gap.Missing))
+
+	return s.alerts.SaveAlert(ctx, alert)
+}
+
+// series возвращает ряды с данными и ряды, явно заданные правилами
+func (s *CompletenessService) series(ctx context.Context, filter repository.TelemetryFilter) ([]repository.SeriesSpan, error) {
+	spans, err := s.repo.ListSeries(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[repository.SeriesID]bool, len(spans))
+	for _, span := range spans {
+		known[span.SeriesID] = true
+	}
+
+	for _, r := range s.rules {
+		id := repository.SeriesID{CompanyID: r.companyID, ProductName: r.product}
+		if id.CompanyID == "" || id.ProductName == "" || known[id] || !filterMatches(filter, id) {
+			continue
+		}
+		known[id] = true
+		spans = append(spans, repository.SeriesSpan{SeriesID: id})
+	}
+
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].CompanyID != spans[j].CompanyID {
+			return spans[i].CompanyID < spans[j].CompanyID
+		}
+		return spans[i].ProductName < spans[j].ProductName
+	})
+
+	return spans, nil
+}
+
+// rule возвращает самое конкретное подходящее правило или правило по умолчанию
+func (s *CompletenessService) rule(id repository.SeriesID) completenessRule {
+	best, bestSpecificity := s.defaults, -1
+	for _, r := range s.rules {
+		if ok, specificity := r.matches(id); ok && specificity > bestSpecificity {
+			best, bestSpecificity = r, specificity
+		}
+	}
+	return best
+}
+
+// explicit сообщает, задан ли ряд правилом явно (компания и продукт)
+func (s *CompletenessService) explicit(id repository.SeriesID) bool {
+	for _, r := range s.rules {
+		if r.companyID == id.CompanyID && r.product == id.ProductName {
+			return true
+		}
+	}
+	return false
+}
+
+// completeness сравнивает интервалы с данными с ожидаемыми интервалами ряда.
+// Второе значение сообщает, пропущен ли последний ожидаемый интервал.
+func completeness(span repository.SeriesSpan, rule completenessRule, present []time.Time, start, end, now time.Time) (domain.SeriesCompleteness, bool) {
+	result := domain.SeriesCompleteness{
+		CompanyID:   span.CompanyID,
+		ProductName: span.ProductName,
+		Frequency:   string(rule.frequency),
+		Gaps:        []domain.DataGap{},
+	}
+	if !span.LastSeen.IsZero() {
+		lastSeen := span.LastSeen
+		result.LastSeen = &lastSeen
+	}
+
+	from := rule.frequency.Truncate(start)
+	if !span.FirstSeen.IsZero() && span.FirstSeen.After(from) {
+		from = rule.frequency.Truncate(span.FirstSeen)
+	}
+
+	have := make(map[time.Time]bool, len(present))
+	for _, b := range present {
+		have[b.UTC()] = true
+	}
+
+	trailing := false
+	for b := from; !b.After(end); b = rule.frequency.Add(b, 1) {
+		next := rule.frequency.Add(b, 1)
+		if next.Add(rule.grace).After(now) {
+			break // Срок поступления данных за интервал еще не наступил
+		}
+
+		result.Expected++
+		trailing = !have[b]
+		if have[b] {
+			result.Present++
+			continue
+		}
+
+		if n := len(result.Gaps); n > 0 && result.Gaps[n-1].End.Equal(b) {
+			result.Gaps[n-1].End = next
+			result.Gaps[n-1].Missing++
+		} else {
+			result.Gaps = append(result.Gaps, domain.DataGap{Start: b, End: next, Missing: 1})
+		}
+	}
+
+	result.Coverage = coverage(result.Present, result.Expected)
+	return result, trailing
+}
+
+// filterMatches сообщает, проходит ли ряд фильтр по компаниям и продуктам
+func filterMatches(filter repository.TelemetryFilter, id repository.SeriesID) bool {
+	return (len(filter.CompanyIDs) == 0 || slices.Contains(filter.CompanyIDs, id.CompanyID)) &&
+		(len(filter.Products) == 0 || slices.Contains(filter.Products, id.ProductName))
+}
+
+func coverage(present, expected int) float64 {
+	if expected == 0 {
+		return 100
+	}
+	return float64(present) / float64(expected) * 100
+}
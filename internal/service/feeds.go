@@ -0,0 +1,363 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"petrochemical-data-platform/internal/config"
+	"petrochemical-data-platform/internal/pkg/parser"
+	"petrochemical-data-platform/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// Подкаталоги каталога входящих файлов для обработанных и ошибочных отчетов
+const (
+	feedsProcessedDir = "processed"
+	feedsFailedDir    = "failed"
+)
+
+// errInvalidFeed отмечает ошибки содержимого файла: такой файл переносится в failed,
+// а при ошибках хранилищ остается в каталоге до следующего просмотра
+var errInvalidFeed = errors.New("invalid feed file")
+
+// FeedResult — итог обработки файла отчета
+type FeedResult struct {
+	Source    string
+	File      string
+	Date      time.Time
+	Quotes    int
+	Points    int
+	Unmapped  []string      // Инструменты без правил сопоставления
+	Duplicate bool          // Отчет за эту дату с тем же содержимым уже загружен
+	Ingest    *IngestResult // nil для дубликатов
+}
+
+// FeedService принимает файлы отчетов бирж и ценовых агентств из каталога: разбор адаптером
+// источника, сопоставление инструментов с каталогом продуктов и запись через прием телеметрии.
+// Отчет за дату загружается один раз; файл с другим содержимым за ту же дату считается
+// исправленным отчетом и записывается согласно режиму записи.
+type FeedService struct {
+	ingestion *IngestionService
+	postgres  *repository.PostgresRepository
+	sources   []*parser.FeedSource
+	cfg       config.FeedsConfig
+	mode      WriteMode
+	unmatched map[string]bool // Файлы без источника, о которых уже сообщено
+	logger    *zap.Logger
+}
+
+// NewFeedService создает сервис приема отчетов из конфигурации
+func NewFeedService(ingestion *IngestionService, postgres *repository.PostgresRepository, cfg config.FeedsConfig, logger *zap.Logger) (*FeedService, error) {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Minute
+	}
+	if cfg.SettleTime <= 0 {
+		cfg.SettleTime = 10 * time.Second
+	}
+
+	var mode WriteMode
+	if cfg.WriteMode != "" {
+		var err error
+		if mode, err = ParseWriteMode(cfg.WriteMode); err != nil {
+			return nil, fmt.Errorf("feeds: %w", err)
+		}
+	}
+
+	sources, err := NewFeedSources(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FeedService{
+		ingestion: ingestion,
+		postgres:  postgres,
+		sources:   sources,
+		cfg:       cfg,
+		mode:      mode,
+		unmatched: make(map[string]bool),
+		logger:    logger,
+	}, nil
+}
+
+// NewFeedSources создает адаптеры и правила сопоставления источников из конфигурации
+func NewFeedSources(cfg config.FeedsConfig) ([]*parser.FeedSource, error) {
+	sources := make([]*parser.FeedSource, 0, len(cfg.Sources))
+	for _, sc := range cfg.Sources {
+		if sc.Name == "" || sc.Pattern == "" {
+			return nil, errors.New("feed source: name and pattern are required")
+		}
+		if _, err := filepath.Match(sc.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("feed source %s: invalid pattern: %w", sc.Name, err)
+		}
+
+		adapter, err := parser.NewFeedAdapter(sc.Format, parser.FeedOptions{
+			Encoding:    sc.Encoding,
+			Delimiter:   sc.Delimiter,
+			Decimal:     sc.Decimal,
+			Record:      sc.Record,
+			Table:       sc.Table,
+			Instrument:  sc.Instrument,
+			Name:        sc.InstrumentName,
+			Fields:      sc.Fields,
+			Date:        sc.Date,
+			DatePattern: sc.DatePattern,
+			DateFormat:  sc.DateFormat,
+			Timezone:    sc.Timezone,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("feed source %s: %w", sc.Name, err)
+		}
+
+		rules := make([]parser.InstrumentRule, len(sc.Instruments))
+		for i, m := range sc.Instruments {
+			rules[i] = parser.InstrumentRule{
+				Code:        m.Code,
+				Prefix:      m.Prefix,
+				Field:       m.Field,
+				CompanyID:   m.CompanyID,
+				ProductName: m.ProductName,
+				SeriesName:  m.SeriesName,
+				Unit:        m.Unit,
+				Aggregate:   m.Aggregate,
+			}
+		}
+		mapper, err := parser.NewInstrumentMapper(rules)
+		if err != nil {
+			return nil, fmt.Errorf("feed source %s: %w", sc.Name, err)
+		}
+
+		sources = append(sources, &parser.FeedSource{Name: sc.Name, Pattern: sc.Pattern, Adapter: adapter, Mapper: mapper})
+	}
+	return sources, nil
+}
+
+// Run просматривает каталог входящих файлов с периодом из конфигурации, пока не отменен ctx
+func (s *FeedService) Run(ctx context.Context) {
+	if s.cfg.Directory == "" || len(s.sources) == 0 {
+		return
+	}
+	for _, dir := range []string{feedsProcessedDir, feedsFailedDir} {
+		if err := os.MkdirAll(filepath.Join(s.cfg.Directory, dir), 0o755); err != nil {
+			s.logger.Error("Failed to create feed directory", zap.String("dir", dir), zap.Error(err))
+			return
+		}
+	}
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.ScanDirectory(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ScanDirectory обрабатывает файлы каталога в порядке имен. Обработанные файлы
+// переносятся в processed/<источник>, ошибочные — в failed с описанием ошибки рядом.
+func (s *FeedService) ScanDirectory(ctx context.Context) {
+	entries, err := os.ReadDir(s.cfg.Directory)
+	if err != nil {
+		s.logger.Error("Failed to read feed directory", zap.String("dir", s.cfg.Directory), zap.Error(err))
+		return
+	}
+
+	settled := time.Now().Add(-s.cfg.SettleTime)
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return
+		}
+		name := entry.Name()
+		if !entry.Type().IsRegular() || incompleteFile(name) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(settled) {
+			continue // Файл еще дописывается
+		}
+
+		src := s.source(name)
+		if src == nil {
+			if !s.unmatched[name] {
+				s.unmatched[name] = true
+				s.logger.Warn("Feed file matches no source", zap.String("file", name))
+			}
+			continue
+		}
+
+		path := filepath.Join(s.cfg.Directory, name)
+		result, err := s.ProcessFile(ctx, src, path)
+		if err != nil {
+			if ctx.Err() != nil {
+				return // Остановка сервиса: файл будет обработан при следующем запуске
+			}
+			s.logger.Error("Failed to process feed file", zap.String("source", src.Name), zap.String("file", name), zap.Error(err))
+			if errors.Is(err, errInvalidFeed) {
+				s.moveFile(path, feedsFailedDir, err)
+			}
+			continue
+		}
+
+		fields := []zap.Field{
+			zap.String("source", result.Source),
+			zap.String("file", name),
+			zap.String("date", result.Date.Format(time.DateOnly)),
+			zap.Int("quotes", result.Quotes),
+			zap.Int("points", result.Points),
+			zap.Bool("duplicate", result.Duplicate),
+		}
+		if result.Ingest != nil {
+			fields = append(fields,
+				zap.Int("written", result.Ingest.Written),
+				zap.Int("revised", result.Ingest.Revised),
+				zap.Int("rejected", len(result.Ingest.Rejected)))
+		}
+		if len(result.Unmapped) > 0 {
+			fields = append(fields, zap.Strings("unmapped", result.Unmapped))
+		}
+		s.logger.Info("Processed feed file", fields...)
+		s.moveFile(path, filepath.Join(feedsProcessedDir, src.Name), nil)
+	}
+}
+
+// ProcessFile разбирает и загружает файл отчета источника
+func (s *FeedService) ProcessFile(ctx context.Context, src *parser.FeedSource, path string) (*FeedResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	report, err := src.Adapter.Parse(bytes.NewReader(data), path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidFeed, err)
+	}
+	if report.Date.After(time.Now().Add(s.ingestion.cfg.MaxFutureSkew)) {
+		return nil, fmt.Errorf("%w: report date %s is in the future", errInvalidFeed, report.Date.Format(time.DateOnly))
+	}
+
+	result := &FeedResult{Source: src.Name, File: filepath.Base(path), Date: report.Date, Quotes: len(report.Quotes)}
+
+	prev, err := s.postgres.GetFeedReport(ctx, src.Name, report.Date)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil && prev.Checksum == checksum {
+		result.Duplicate = true
+		return result, nil
+	}
+
+	if err := s.checkCatalog(ctx, src); err != nil {
+		return nil, err
+	}
+
+	points, unmapped := src.Mapper.Map(report, src.Name)
+	result.Points = len(points)
+	result.Unmapped = unmapped
+
+	if len(points) > 0 {
+		if result.Ingest, err = s.ingestion.Ingest(ctx, points, IngestOptions{Mode: s.mode, Source: "feed:" + src.Name}); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.postgres.SaveFeedReport(ctx, repository.FeedReport{
+		Source:      src.Name,
+		ReportDate:  report.Date,
+		FileName:    result.File,
+		Checksum:    checksum,
+		Quotes:      result.Quotes,
+		Points:      result.Points,
+		ProcessedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// checkCatalog проверяет правила источника: продукт отчета есть в каталоге и активен,
+// а записываемый ряд (series_name) — ряд каталога с той же единицей измерения. Цены
+// и объемы торгов пишутся в свои ряды, а не в ряд выпуска продукта.
+func (s *FeedService) checkCatalog(ctx context.Context, src *parser.FeedSource) error {
+	catalog, err := s.ingestion.catalog.get(ctx, s.postgres)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range src.Mapper.Rules() {
+		product, ok := catalog[repository.SeriesID{CompanyID: r.CompanyID, ProductName: r.ProductName}]
+		if !ok {
+			return fmt.Errorf("%w: instrument mapping: product %q is not in the catalog of company %q", errInvalidFeed, r.ProductName, r.CompanyID)
+		}
+		if product.Status != "active" {
+			return fmt.Errorf("%w: instrument mapping: product %q of company %q is %s", errInvalidFeed, r.ProductName, r.CompanyID, product.Status)
+		}
+		if err := checkProduct(catalog, r.CompanyID, r.SeriesName, r.Unit); err != nil {
+			return fmt.Errorf("%w: instrument mapping of %s %s: %v", errInvalidFeed, r.Field, r.ProductName, err)
+		}
+	}
+	return nil
+}
+
+func (s *FeedService) source(name string) *parser.FeedSource {
+	for _, src := range s.sources {
+		if src.Match(name) {
+			return src
+		}
+	}
+	return nil
+}
+
+// moveFile переносит файл в подкаталог; для ошибочных файлов рядом записывается текст ошибки
+func (s *FeedService) moveFile(path, dir string, cause error) {
+	dir = filepath.Join(s.cfg.Directory, dir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		s.logger.Error("Failed to create feed directory", zap.String("dir", dir), zap.Error(err))
+		return
+	}
+
+	name := filepath.Base(path)
+	target := filepath.Join(dir, name)
+	if _, err := os.Stat(target); err == nil {
+		ext := filepath.Ext(name)
+		target = filepath.Join(dir, fmt.Sprintf("%s.%s%s", strings.TrimSuffix(name, ext), time.Now().UTC().Format("20060102T150405"), ext))
+	}
+
+	if err := os.Rename(path, target); err != nil {
+		s.logger.Error("Failed to move feed file", zap.String("file", path), zap.Error(err))
+		return
+	}
+	if cause != nil {
+		if err := os.WriteFile(target+".error", []byte(cause.Error()+"\n"), 0o644); err != nil {
+			s.logger.Error("Failed to write feed error file", zap.String("file", target), zap.Error(err))
+		}
+	}
+}
+
+// incompleteFile отсеивает скрытые и временные файлы незавершенной загрузки
+func incompleteFile(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+	switch filepath.Ext(name) {
+	case ".tmp", ".part", ".crdownload", ".error":
+		return true
+	}
+	return false
+}
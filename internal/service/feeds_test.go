@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/pkg/parser"
+	"petrochemical-data-platform/internal/repository"
+)
+
+func TestFeedCheckCatalog(t *testing.T) {
+	svc := &FeedService{ingestion: &IngestionService{catalog: productCatalog{loadedAt: time.Now(), products: map[repository.SeriesID]domain.Product{
+		{CompanyID: "SIBUR_TOBOLSK", ProductName: "Полипропилен"}:        {Name: "Полипропилен", Unit: "т/час", Status: "active"},
+		{CompanyID: "SIBUR_TOBOLSK", ProductName: "Полипропилен (цена)"}: {Name: "Полипропилен (цена)", Unit: "руб/т", Status: "active"},
+		{CompanyID: "SIBUR_TOBOLSK", ProductName: "Полиэтилен"}:          {Name: "Полиэтилен", Unit: "т/час", Status: "inactive"},
+	}}}}
+
+	tests := []struct {
+		name  string
+		rule  parser.InstrumentRule
+		valid bool
+	}{
+		{"price series", parser.InstrumentRule{Code: "PP", CompanyID: "SIBUR_TOBOLSK", ProductName: "Полипропилен", SeriesName: "Полипропилен (цена)", Unit: "руб/т"}, true},
+		{"price into the production series", parser.InstrumentRule{Code: "PP", CompanyID: "SIBUR_TOBOLSK", ProductName: "Полипропилен", Unit: "руб/т"}, false},
+		{"unit of another series", parser.InstrumentRule{Code: "PP", CompanyID: "SIBUR_TOBOLSK", ProductName: "Полипропилен", SeriesName: "Полипропилен (цена)", Unit: "долл/т"}, false},
+		{"series not in the catalog", parser.InstrumentRule{Code: "PP", Field: "volume", CompanyID: "SIBUR_TOBOLSK", ProductName: "Полипропилен", SeriesName: "Полипропилен (объём)", Unit: "т"}, false},
+		{"unknown product", parser.InstrumentRule{Code: "PP", CompanyID: "KAZANORG", ProductName: "Полипропилен", SeriesName: "Полипропилен (цена)", Unit: "руб/т"}, false},
+		{"inactive product", parser.InstrumentRule{Code: "PE", CompanyID: "SIBUR_TOBOLSK", ProductName: "Полиэтилен", SeriesName: "Полипропилен (цена)", Unit: "руб/т"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapper, err := parser.NewInstrumentMapper([]parser.InstrumentRule{tt.rule})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = svc.checkCatalog(context.Background(), &parser.FeedSource{Name: "test", Mapper: mapper})
+			if tt.valid && err != nil {
+				t.Fatalf("checkCatalog: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, errInvalidFeed) {
+				t.Fatalf("checkCatalog = %v, want errInvalidFeed", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/pkg/forecast"
+	"petrochemical-data-platform/internal/pkg/transform"
+	"petrochemical-data-platform/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// ForecastRequest описывает параметры запроса прогноза
+type ForecastRequest struct {
+	CompanyID   string
+	ProductName string
+	Start, End  time.Time
+	Method      forecast.Method
+	Period      int
+	Horizon     int
+	Confidence  float64
+	Holdout     int // 0 — без бэктеста
+}
+
+// ForecastService строит прогнозы по помесячным рядам из ClickHouse
+type ForecastService struct {
+	repo   *repository.ClickHouseRepository
+	logger *zap.Logger
+}
+
+// NewForecastService создает новый сервис прогнозирования
+func NewForecastService(repo *repository.ClickHouseRepository, logger *zap.Logger) *ForecastService {
+	return &ForecastService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Forecast строит прогноз по помесячным средним продукта и, если задано, метрики бэктеста
+func (s *ForecastService) Forecast(ctx context.Context, req ForecastRequest) (*domain.Forecast, error) {
+	series, err := s.repo.GetTelemetrySeries(ctx, req.CompanyID, []string{req.ProductName}, req.Start, req.End)
+	if err != nil {
+		return nil, err
+	}
+
+	history := fillMonthlyGaps(transform.MonthlyMeans(series[req.ProductName]))
+	if len(history) == 0 {
+		return nil, fmt.Errorf("%w: no data for %s/%s", forecast.ErrNotEnoughData, req.CompanyID, req.ProductName)
+	}
+
+	values := make([]float64, len(history))
+	for i, p := range history {
+		values[i] = p.Value
+	}
+
+	opts := forecast.Options{
+		Method:     req.Method,
+		Period:     req.Period,
+		Horizon:    req.Horizon,
+		Confidence: req.Confidence,
+	}
+
+	result, err := forecast.Forecast(values, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &domain.Forecast{
+		CompanyID:   req.CompanyID,
+		ProductName: req.ProductName,
+		Method:      string(req.Method),
+		Period:      req.Period,
+		Confidence:  req.Confidence,
+		History:     history,
+		Points:      make([]domain.ForecastPoint, len(result.Values)),
+	}
+
+	last := history[len(history)-1].Timestamp
+	for h := range result.Values {
+		out.Points[h] = domain.ForecastPoint{
+			Timestamp: last.AddDate(0, h+1, 0),
+			Value:     result.Values[h],
+			Lower:     result.Lower[h],
+			Upper:     result.Upper[h],
+		}
+	}
+
+	if req.Holdout > 0 {
+		metrics, err := forecast.Backtest(values, opts, req.Holdout)
+		if err != nil {
+			// Бэктест необязателен: короткий ряд не должен ломать сам прогноз
+			s.logger.Warn("Backtest skipped", zap.Error(err),
+				zap.String("company_id", req.CompanyID), zap.String("product", req.ProductName))
+		} else {
+			out.Backtest = &domain.BacktestMetrics{
+				MAPE:    metrics.MAPE,
+				RMSE:    metrics.RMSE,
+				Holdout: metrics.Holdout,
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// fillMonthlyGaps достраивает пропущенные месяцы линейной интерполяцией,
+// так как модели прогнозирования требуют равномерной сетки
+func fillMonthlyGaps(months []domain.SeriesPoint) []domain.SeriesPoint {
+	if len(months) < 2 {
+		return months
+	}
+
+	result := []domain.SeriesPoint{months[0]}
+	for _, next := range months[1:] {
+		prev := result[len(result)-1]
+		steps := monthsBetween(prev.Timestamp, next.Timestamp)
+		for k := 1; k < steps; k++ {
+			result = append(result, domain.SeriesPoint{
+				Timestamp: prev.Timestamp.AddDate(0, k, 0),
+				Value:     prev.Value + (next.Value-prev.Value)*float64(k)/float64(steps),
+			})
+		}
+		result = append(result, next)
+	}
+
+	return result
+}
+
+func monthsBetween(a, b time.Time) int {
+	return (b.Year()-a.Year())*12 + int(b.Month()) - int(a.Month())
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/repository"
+)
+
+var (
+	// ErrAssetNotFound возвращается для неизвестного идентификатора актива
+	ErrAssetNotFound = errors.New("asset not found")
+	// ErrInvalidAsset возвращается, если уровень или родитель актива нарушают иерархию
+	ErrInvalidAsset = errors.New("invalid asset")
+)
+
+// levelRank — порядок уровней иерархии: родитель всегда на меньшем ранге, чем потомок
+var levelRank = map[string]int{
+	domain.AssetLevelCompany:   0,
+	domain.AssetLevelSite:      1,
+	domain.AssetLevelPlant:     2,
+	domain.AssetLevelUnit:      3,
+	domain.AssetLevelEquipment: 4,
+}
+
+// RollupOptions задает состав сводки телеметрии по иерархии
+type RollupOptions struct {
+	ProductType string   // Тип продукта по каталогу (polymer, fuel...); пусто — все
+	Products    []string // Названия продуктов; пусто — все
+	ByChild     bool     // Отдельный ряд для каждого непосредственного потомка
+}
+
+func toDomainAsset(a repository.Asset) domain.Asset {
+	return domain.Asset{
+		ID:                 a.ID,
+		Name:               a.Name,
+		Type:               a.Type,
+		Level:              a.Level,
+		ParentID:           a.ParentID,
+		TelemetryCompanyID: a.TelemetryCompanyID,
+		Location:           a.Location,
+		CreatedAt:          a.CreatedAt,
+		UpdatedAt:          a.UpdatedAt,
+	}
+}
+
+// validateParent проверяет место актива в иерархии: компании — корни, остальные активы
+// подчинены активу более высокого уровня. Поскольку уровень строго растет вниз по дереву,
+// циклов не бывает; при изменении существующего актива проверяются и его потомки.
+func (s *AssetService) validateParent(ctx context.Context, asset domain.Asset) error {
+	if err := checkPlacement(asset, nil, nil); err != nil {
+		return err
+	}
+
+	var parent *repository.Asset
+	if asset.ParentID != "" {
+		var err error
+		if parent, err = s.repo.GetAsset(ctx, asset.ParentID); err != nil {
+			return fmt.Errorf("failed to get parent asset: %w", err)
+		}
+		if parent == nil {
+			return fmt.Errorf("%w: parent %s not found", ErrInvalidAsset, asset.ParentID)
+		}
+	}
+
+	subtree, err := s.repo.GetAssetSubtree(ctx, asset.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get asset subtree: %w", err)
+	}
+	var children []repository.Asset
+	for _, a := range subtree {
+		if a.Depth == 1 {
+			children = append(children, a)
+		}
+	}
+	return checkPlacement(asset, parent, children)
+}
+
+// checkPlacement проверяет уровень актива относительно родителя и непосредственных потомков.
+// С parent == nil и без потомков проверяются только идентификатор, уровень и наличие родителя.
+func checkPlacement(asset domain.Asset, parent *repository.Asset, children []repository.Asset) error {
+	if asset.ID == "" {
+		return fmt.Errorf("%w: id is required", ErrInvalidAsset)
+	}
+	rank, ok := levelRank[asset.Level]
+	if !ok {
+		return fmt.Errorf("%w: unknown level %q", ErrInvalidAsset, asset.Level)
+	}
+
+	if asset.Level == domain.AssetLevelCompany {
+		if asset.ParentID != "" {
+			return fmt.Errorf("%w: a company cannot have a parent", ErrInvalidAsset)
+		}
+	} else if asset.ParentID == "" {
+		return fmt.Errorf("%w: %s asset requires a parent", ErrInvalidAsset, asset.Level)
+	}
+	if parent != nil && levelRank[parent.Level] >= rank {
+		return fmt.Errorf("%w: %s asset cannot be placed under %s %s", ErrInvalidAsset, asset.Level, parent.Level, parent.ID)
+	}
+
+	for _, child := range children {
+		if levelRank[child.Level] <= rank {
+			return fmt.Errorf("%w: child %s (%s) must be below %s", ErrInvalidAsset, child.ID, child.Level, asset.Level)
+		}
+	}
+	return nil
+}
+
+// GetAsset получает актив по идентификатору
+func (s *AssetService) GetAsset(ctx context.Context, id string) (domain.Asset, error) {
+	asset, err := s.repo.GetAsset(ctx, id)
+	if err != nil {
+		return domain.Asset{}, err
+	}
+	if asset == nil {
+		return domain.Asset{}, ErrAssetNotFound
+	}
+	return toDomainAsset(*asset), nil
+}
+
+// GetAncestors возвращает предков актива от компании к непосредственному родителю
+func (s *AssetService) GetAncestors(ctx context.Context, id string) ([]domain.Asset, error) {
+	if _, err := s.GetAsset(ctx, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.repo.GetAssetAncestors(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	assets := make([]domain.Asset, 0, len(rows))
+	for _, a := range rows {
+		assets = append(assets, toDomainAsset(a))
+	}
+	return assets, nil
+}
+
+// GetDescendants возвращает всех потомков актива по уровням, без самого актива
+func (s *AssetService) GetDescendants(ctx context.Context, id string) ([]domain.Asset, error) {
+	rows, err := s.repo.GetAssetSubtree(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrAssetNotFound
+	}
+
+	assets := make([]domain.Asset, 0, len(rows)-1)
+	for _, a := range rows[1:] {
+		assets = append(assets, toDomainAsset(a))
+	}
+	return assets, nil
+}
+
+// GetTree возвращает поддерево актива; пустой rootID — деревья всех компаний
+func (s *AssetService) GetTree(ctx context.Context, rootID string) ([]*domain.AssetNode, error) {
+	rows, err := s.repo.GetAssetSubtree(ctx, rootID)
+	if err != nil {
+		return nil, err
+	}
+	if rootID != "" && len(rows) == 0 {
+		return nil, ErrAssetNotFound
+	}
+	return buildTree(rows), nil
+}
+
+// buildTree собирает деревья из выборки в порядке обхода в ширину (родитель раньше потомков)
+func buildTree(rows []repository.Asset) []*domain.AssetNode {
+	roots := []*domain.AssetNode{}
+	nodes := make(map[string]*domain.AssetNode, len(rows))
+	for _, a := range rows {
+		node := &domain.AssetNode{Asset: toDomainAsset(a), Children: []*domain.AssetNode{}}
+		nodes[a.ID] = node
+
+		if parent, ok := nodes[a.ParentID]; ok && a.Depth > 0 {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
+
+// RollupTelemetry суммирует средний выпуск по интервалам по всем рядам телеметрии поддерева
+// актива, например суммарный выпуск полимеров всех площадок компании. Ряд company_id
+// учитывается один раз, даже если на него ссылаются несколько активов; единицы измерения
+// не смешиваются — для каждой строится свой ряд.
+func (s *AssetService) RollupTelemetry(ctx context.Context, id string, start, end time.Time, interval repository.Interval, opts RollupOptions) (*domain.AssetRollup, error) {
+	subtree, err := s.repo.GetAssetSubtree(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(subtree) == 0 {
+		return nil, ErrAssetNotFound
+	}
+
+	rollup := &domain.AssetRollup{
+		AssetID:     id,
+		Interval:    string(interval),
+		ProductType: opts.ProductType,
+		Products:    opts.Products,
+		Companies:   []string{},
+		Series:      []domain.RollupSeries{},
+	}
+
+	// Группа ряда — сам актив или, при разбивке, его непосредственный потомок, в поддереве
+	// которого находится ряд
+	group := make(map[string]string, len(subtree))
+	companyGroup := make(map[string]string)
+	for _, a := range subtree {
+		switch {
+		case !opts.ByChild || a.Depth == 0:
+			group[a.ID] = id
+		case a.Depth == 1:
+			group[a.ID] = a.ID
+		default:
+			group[a.ID] = group[a.ParentID]
+		}
+
+		if c := a.TelemetryCompanyID; c != "" {
+			if _, ok := companyGroup[c]; !ok {
+				companyGroup[c] = group[a.ID]
+				rollup.Companies = append(rollup.Companies, c)
+			}
+		}
+	}
+	if len(rollup.Companies) == 0 {
+		return rollup, nil
+	}
+
+	filter := repository.TelemetryFilter{CompanyIDs: rollup.Companies, Products: opts.Products}
+
+	// Тип продукта определяется каталогом компании: фильтр по названиям сужается до
+	// продуктов нужного типа, а пары компания/продукт проверяются по строкам результата
+	var allowed map[[2]string]bool
+	if opts.ProductType != "" {
+		products, err := s.repo.GetProducts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		allowed = make(map[[2]string]bool)
+		var names []string
+		for _, p := range products {
+			if p.Type != opts.ProductType {
+				continue
+			}
+			if _, ok := companyGroup[p.CompanyID]; !ok {
+				continue
+			}
+			if len(opts.Products) > 0 && !slices.Contains(opts.Products, p.Name) {
+				continue
+			}
+			allowed[[2]string{p.CompanyID, p.Name}] = true
+			if !slices.Contains(names, p.Name) {
+				names = append(names, p.Name)
+			}
+		}
+		if len(names) == 0 {
+			return rollup, nil
+		}
+		filter.Products = names
+	}
+
+	rows, err := s.telemetry.GetAggregatedTelemetry(ctx, filter, start, end, interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aggregated telemetry: %w", err)
+	}
+
+	type seriesKey struct{ group, unit string }
+	sums := make(map[seriesKey]map[time.Time]float64)
+	for _, r := range rows {
+		if allowed != nil && !allowed[[2]string{r.CompanyID, r.ProductName}] {
+			continue
+		}
+		key := seriesKey{companyGroup[r.CompanyID], r.Unit}
+		if sums[key] == nil {
+			sums[key] = make(map[time.Time]float64)
+		}
+		sums[key][r.Timestamp] += r.Value
+	}
+
+	// Ряды в порядке обхода поддерева, внутри актива — по единице измерения
+	order := make(map[string]int, len(subtree))
+	for i, a := range subtree {
+		order[a.ID] = i
+	}
+	keys := make([]seriesKey, 0, len(sums))
+	for key := range sums {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].group != keys[j].group {
+			return order[keys[i].group] < order[keys[j].group]
+		}
+		return keys[i].unit < keys[j].unit
+	})
+
+	for _, key := range keys {
+		points := make([]domain.SeriesPoint, 0, len(sums[key]))
+		for t, v := range sums[key] {
+			points = append(points, domain.SeriesPoint{Timestamp: t, Value: v})
+		}
+		sort.Slice(points, func(i, j int) bool { return points[i].Timestamp.Before(points[j].Timestamp) })
+		rollup.Series = append(rollup.Series, domain.RollupSeries{AssetID: key.group, Unit: key.unit, Points: points})
+	}
+	return rollup, nil
+}
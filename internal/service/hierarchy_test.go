@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/repository"
+)
+
+func TestCheckPlacement(t *testing.T) {
+	company := &repository.Asset{ID: "SIBUR", Level: domain.AssetLevelCompany}
+	site := &repository.Asset{ID: "SIBUR_TOBOLSK", Level: domain.AssetLevelSite}
+	unit := &repository.Asset{ID: "SIBUR_TOBOLSK_PP", Level: domain.AssetLevelUnit}
+	line := repository.Asset{ID: "SIBUR_TOBOLSK_PP_LINE1", Level: domain.AssetLevelEquipment, Depth: 1}
+	plant := repository.Asset{ID: "SIBUR_TOBOLSK_POLYMER", Level: domain.AssetLevelPlant, Depth: 1}
+
+	asset := func(id, level, parent string) domain.Asset {
+		return domain.Asset{ID: id, Level: level, ParentID: parent}
+	}
+
+	tests := []struct {
+		name     string
+		asset    domain.Asset
+		parent   *repository.Asset
+		children []repository.Asset
+		valid    bool
+	}{
+		{"company as a root", asset("SIBUR", domain.AssetLevelCompany, ""), nil, []repository.Asset{{ID: "SIBUR_TOBOLSK", Level: domain.AssetLevelSite, Depth: 1}}, true},
+		{"site under a company", asset("SIBUR_TOBOLSK", domain.AssetLevelSite, "SIBUR"), company, []repository.Asset{plant}, true},
+		{"equipment under a unit", asset("SIBUR_TOBOLSK_PP_LINE2", domain.AssetLevelEquipment, "SIBUR_TOBOLSK_PP"), unit, nil, true},
+		{"level may skip a step", asset("SIBUR_TOBOLSK_PP", domain.AssetLevelUnit, "SIBUR_TOBOLSK"), site, []repository.Asset{line}, true},
+		{"missing id", asset("", domain.AssetLevelSite, "SIBUR"), company, nil, false},
+		{"unknown level", asset("SIBUR_TOBOLSK", "region", "SIBUR"), company, nil, false},
+		{"company with a parent", asset("SIBUR", domain.AssetLevelCompany, "GAZPROM"), company, nil, false},
+		{"site without a parent", asset("SIBUR_TOBOLSK", domain.AssetLevelSite, ""), nil, nil, false},
+		{"site under a site", asset("SIBUR_OMSK", domain.AssetLevelSite, "SIBUR_TOBOLSK"), site, nil, false},
+		{"unit under equipment", asset("SIBUR_TOBOLSK_PP2", domain.AssetLevelUnit, "SIBUR_TOBOLSK_PP_LINE1"), &line, nil, false},
+		// Понижение уровня актива, потомки которого окажутся не ниже него
+		{"plant demoted below its child", asset("SIBUR_TOBOLSK_POLYMER", domain.AssetLevelEquipment, "SIBUR_TOBOLSK"), site, []repository.Asset{{ID: "SIBUR_TOBOLSK_PP", Level: domain.AssetLevelUnit, Depth: 1}}, false},
+		{"child on the same level", asset("SIBUR_TOBOLSK_PP", domain.AssetLevelUnit, "SIBUR_TOBOLSK_POLYMER"), &plant, []repository.Asset{{ID: "SIBUR_TOBOLSK_PP_X", Level: domain.AssetLevelUnit, Depth: 1}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPlacement(tt.asset, tt.parent, tt.children)
+			if tt.valid && err != nil {
+				t.Fatalf("checkPlacement: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidAsset) {
+				t.Fatalf("error = %v, want ErrInvalidAsset", err)
+			}
+		})
+	}
+}
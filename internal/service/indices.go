@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"petrochemical-data-platform/internal/config"
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/pkg/index"
+	"petrochemical-data-platform/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// ErrIndexNotFound возвращается для неизвестного идентификатора индекса
+var ErrIndexNotFound = errors.New("price index not found")
+
+// IndexService рассчитывает ценовые индексы по расписанию и отдает их как временные ряды
+type IndexService struct {
+	repo        *repository.ClickHouseRepository
+	definitions []index.Definition
+	starts      map[string]time.Time
+	interval    repository.Interval
+	schedule    time.Duration
+	logger      *zap.Logger
+}
+
+// NewIndexService создает сервис индексов из конфигурации
+func NewIndexService(repo *repository.ClickHouseRepository, cfg config.IndicesConfig, logger *zap.Logger) (*IndexService, error) {
+	interval := repository.IntervalMonth
+	if cfg.Interval != "" {
+		var err error
+		if interval, err = repository.ParseInterval(cfg.Interval); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &IndexService{
+		repo:     repo,
+		starts:   make(map[string]time.Time),
+		interval: interval,
+		schedule: cfg.Schedule,
+		logger:   logger,
+	}
+
+	seen := make(map[string]bool, len(cfg.Definitions))
+	for _, d := range cfg.Definitions {
+		if seen[d.ID] {
+			return nil, fmt.Errorf("duplicate index id %q", d.ID)
+		}
+		seen[d.ID] = true
+
+		def := index.Definition{
+			ID:        d.ID,
+			Name:      d.Name,
+			Sector:    d.Sector,
+			Method:    index.Method(d.Method),
+			BaseValue: d.BaseValue,
+		}
+
+		baseDate, err := time.Parse(time.DateOnly, d.BaseDate)
+		if err != nil {
+			return nil, fmt.Errorf("index %s: invalid base_date: %w", d.ID, err)
+		}
+		def.BaseDate = baseDate
+
+		start := baseDate
+		if d.Start != "" {
+			if start, err = time.Parse(time.DateOnly, d.Start); err != nil {
+				return nil, fmt.Errorf("index %s: invalid start: %w", d.ID, err)
+			}
+		}
+		s.starts[d.ID] = start
+
+		for _, c := range d.Components {
+			def.Components = append(def.Components, index.Component{
+				CompanyID:   c.CompanyID,
+				ProductName: c.Product,
+				Weight:      c.Weight,
+			})
+		}
+
+		if err := def.Validate(); err != nil {
+			return nil, err
+		}
+		s.definitions = append(s.definitions, def)
+	}
+
+	return s, nil
+}
+
+// Indices возвращает определения всех индексов
+func (s *IndexService) Indices() []domain.PriceIndex {
+	result := make([]domain.PriceIndex, 0, len(s.definitions))
+	for _, def := range s.definitions {
+		result = append(result, toPriceIndex(def))
+	}
+	return result
+}
+
+// Index возвращает определение индекса по идентификатору
+func (s *IndexService) Index(id string) (domain.PriceIndex, error) {
+	def, ok := s.definition(id)
+	if !ok {
+		return domain.PriceIndex{}, ErrIndexNotFound
+	}
+	return toPriceIndex(def), nil
+}
+
+// Run пересчитывает все индексы сразу и затем с периодом из конфигурации, пока не отменен ctx
+func (s *IndexService) Run(ctx context.Context) {
+	if s.schedule <= 0 || len(s.definitions) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.schedule)
+	defer ticker.Stop()
+
+	for {
+		if err := s.ComputeAll(ctx); err != nil {
+			s.logger.Error("Failed to compute price indices", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ComputeAll пересчитывает все индексы. Ошибка одного индекса не прерывает расчет остальных.
+func (s *IndexService) ComputeAll(ctx context.Context) error {
+	var errs []error
+	for _, def := range s.definitions {
+		if err := s.compute(ctx, def); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// GetSeries возвращает значения индекса за период. Если rebaseAt не нулевой,
+// ряд перебазируется так, чтобы в этом периоде индекс равнялся rebaseValue.
+func (s *IndexService) GetSeries(ctx context.Context, id string, start, end, rebaseAt time.Time, rebaseValue float64) ([]domain.SeriesPoint, error) {
+	if _, ok := s.definition(id); !ok {
+		return nil, ErrIndexNotFound
+	}
+
+	points, err := s.repo.GetPriceIndex(ctx, id, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if rebaseAt.IsZero() {
+		return points, nil
+	}
+
+	// Период перебазирования может лежать вне запрошенного окна
+	base, ok, err := s.repo.GetPriceIndexValueAt(ctx, id, rebaseAt)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: no index value on or after %s", index.ErrNoRebaseValue, rebaseAt.Format(time.DateOnly))
+	}
+	return index.Rebase(points, base, rebaseValue)
+}
+
+func (s *IndexService) compute(ctx context.Context, def index.Definition) error {
+	end := time.Now()
+	prices := make(index.Prices, len(def.Components))
+	for i, c := range def.Components {
+		series, err := s.repo.GetAggregatedSeries(ctx, c.CompanyID, c.ProductName, s.starts[def.ID], end, s.interval)
+		if err != nil {
+			return fmt.Errorf("index %s: %w", def.ID, err)
+		}
+
+		prices[i] = make(map[time.Time]float64, len(series))
+		for _, p := range series {
+			prices[i][p.Timestamp.UTC()] = p.Value
+		}
+	}
+
+	points, err := index.Compute(def, prices)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.SavePriceIndex(ctx, def.ID, points, end); err != nil {
+		return err
+	}
+
+	s.logger.Info("Computed price index", zap.String("index_id", def.ID), zap.Int("points", len(points)))
+	return nil
+}
+
+func (s *IndexService) definition(id string) (index.Definition, bool) {
+	for _, def := range s.definitions {
+		if def.ID == id {
+			return def, true
+		}
+	}
+	return index.Definition{}, false
+}
+
+func toPriceIndex(def index.Definition) domain.PriceIndex {
+	pi := domain.PriceIndex{
+		ID:        def.ID,
+		Name:      def.Name,
+		Sector:    def.Sector,
+		Method:    string(def.Method),
+		BaseDate:  def.BaseDate,
+		BaseValue: def.BaseValue,
+	}
+	for _, c := range def.Components {
+		pi.Components = append(pi.Components, domain.IndexComponent{
+			CompanyID:   c.CompanyID,
+			ProductName: c.ProductName,
+			Weight:      c.Weight,
+		})
+	}
+	return pi
+}
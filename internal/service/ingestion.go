@@ -0,0 +1,400 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"petrochemical-data-platform/internal/config"
+	"petrochemical-data-platform/internal/pkg/anomaly"
+	"petrochemical-data-platform/internal/pkg/parser"
+	"petrochemical-data-platform/internal/pkg/quality"
+	"petrochemical-data-platform/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// latestPointTTL — время жизни последнего значения продукта в кэше
+const latestPointTTL = 24 * time.Hour
+
+// WriteMode определяет обработку точки, для ключа которой уже записано другое значение
+type WriteMode string
+
+const (
+	WriteReject    WriteMode = "reject"    // Точка отклоняется
+	WriteOverwrite WriteMode = "overwrite" // Новое значение заменяет прежнее без сохранения истории
+	WriteRevision  WriteMode = "revision"  // Новое значение заменяет прежнее, прежнее сохраняется в истории правок
+)
+
+// ParseWriteMode проверяет название режима записи
+func ParseWriteMode(s string) (WriteMode, error) {
+	switch m := WriteMode(s); m {
+	case WriteReject, WriteOverwrite, WriteRevision:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown write mode %q: use reject, overwrite or revision", s)
+}
+
+// IngestOptions задает параметры приема пачки
+type IngestOptions struct {
+	Mode   WriteMode // Пусто — режим из конфигурации
+	Source string    // Источник данных для истории правок (http, mqtt, parser, ...)
+}
+
+// RejectedPoint описывает отклоненную точку пачки
+type RejectedPoint struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+// IngestResult представляет итог приема пачки
+type IngestResult struct {
+	Received  int             `json:"received"`          // Записей в пачке
+	Written   int             `json:"written"`           // Новые точки
+	Revised   int             `json:"revised"`           // Замененные значения
+	Unchanged int             `json:"unchanged"`         // Повторы уже записанных значений
+	Skipped   int             `json:"skipped,omitempty"` // Метрики, не подходящие ни под одно правило сопоставления
+	Rejected  []RejectedPoint `json:"rejected"`
+	Replayed  bool            `json:"-"` // Результат повторен по ключу идемпотентности
+}
+
+// IngestionService принимает точки от источников данных, оценивает их детектором
+// аномалий и записывает в ClickHouse
+type IngestionService struct {
+	telemetry *repository.ClickHouseRepository
+	postgres  *repository.PostgresRepository // Оповещения и каталог продуктов
+	cache     *repository.RedisRepository
+	detector  *anomaly.Detector // nil, если детектор отключен
+	mode      WriteMode         // Режим записи по умолчанию
+	cfg       config.IngestConfig
+	catalog   productCatalog
+	influx    *parser.Mapper // Сопоставление протокола InfluxDB
+	remote    *parser.Mapper // Сопоставление Prometheus remote_write
+	logger    *zap.Logger
+
+	// Пересчет агрегатов после замены значений: ряды и часы с правками копятся в очереди
+	// и пересчитываются пачкой в Run под блокировками только этих рядов
+	rebuildMu sync.Mutex
+	rebuild   map[repository.RollupBucket]struct{}
+	rebuildCh chan struct{}
+}
+
+// NewIngestionService создает новый сервис приема данных
+func NewIngestionService(telemetry *repository.ClickHouseRepository, postgres *repository.PostgresRepository, cache *repository.RedisRepository, detector *anomaly.Detector, mode WriteMode, cfg config.IngestConfig, logger *zap.Logger) (*IngestionService, error) {
+	if mode == "" {
+		mode = WriteRevision
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 32 << 20
+	}
+	if cfg.MaxFutureSkew <= 0 {
+		cfg.MaxFutureSkew = time.Hour
+	}
+	if cfg.IdempotencyTTL <= 0 {
+		cfg.IdempotencyTTL = 24 * time.Hour
+	}
+
+	influx, err := parser.NewMapper(toMappingRules(cfg.Influx))
+	if err != nil {
+		return nil, fmt.Errorf("influx %w", err)
+	}
+	remote, err := parser.NewMapper(toMappingRules(cfg.Prometheus))
+	if err != nil {
+		return nil, fmt.Errorf("prometheus %w", err)
+	}
+
+	return &IngestionService{
+		telemetry: telemetry,
+		postgres:  postgres,
+		cache:     cache,
+		detector:  detector,
+		mode:      mode,
+		cfg:       cfg,
+		influx:    influx,
+		remote:    remote,
+		logger:    logger,
+		rebuild:   make(map[repository.RollupBucket]struct{}),
+		rebuildCh: make(chan struct{}, 1),
+	}, nil
+}
+
+func toMappingRules(mappings []config.MetricMapping) []parser.MappingRule {
+	rules := make([]parser.MappingRule, len(mappings))
+	for i, m := range mappings {
+		rules[i] = parser.MappingRule{
+			Match:       m.Match,
+			Field:       m.Field,
+			CompanyID:   m.CompanyID,
+			ProductName: m.ProductName,
+			Unit:        m.Unit,
+			Tags:        m.Tags,
+			Labels:      m.Labels,
+		}
+	}
+	return rules
+}
+
+// NewAnomalyDetector создает детектор аномалий из конфигурации. Возвращает nil, если детектор отключен.
+func NewAnomalyDetector(cfg config.AnomalyConfig) (*anomaly.Detector, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	overrides := make(map[string]anomaly.Override, len(cfg.Products))
+	for product, s := range cfg.Products {
+		o := toOverride(s)
+		if err := o.Validate(); err != nil {
+			return nil, fmt.Errorf("anomaly settings for %s: %w", product, err)
+		}
+		overrides[product] = o
+	}
+
+	o := toOverride(cfg.Default)
+	if err := o.Validate(); err != nil {
+		return nil, fmt.Errorf("default anomaly settings: %w", err)
+	}
+	return anomaly.NewDetector(anomaly.DefaultSensitivity().Merge(o), overrides), nil
+}
+
+// Ingest обрабатывает пачку точек: оценка аномальности, запись в ClickHouse, кэширование, оповещения.
+// Запись идемпотентна по ключу (company_id, product_name, timestamp): повтор того же значения
+// ничего не меняет, а новое значение для существующего ключа обрабатывается согласно режиму записи.
+func (s *IngestionService) Ingest(ctx context.Context, points []parser.DataPoint, opts IngestOptions) (*IngestResult, error) {
+	mode := opts.Mode
+	if mode == "" {
+		mode = s.mode
+	}
+
+	candidates := make([]repository.TelemetryData, len(points))
+	keys := make([]repository.TelemetryKey, len(points))
+	for i, p := range points {
+		candidates[i] = repository.TelemetryData{
+			CompanyID:   p.CompanyID,
+			ProductName: p.ProductName,
+			Value:       p.Value,
+			Unit:        p.Unit,
+			Timestamp:   p.Timestamp,
+			Quality:     p.Quality,
+			Tags:        p.Tags,
+			Labels:      p.Labels,
+		}
+		keys[i] = repository.KeyOf(candidates[i])
+	}
+
+	// Проверка текущих значений и запись идут под блокировкой рядов пачки: иначе две
+	// пачки с одним ключом, в том числе из разных экземпляров API, обе сочтут точку новой,
+	// и материализованные представления учтут ее дважды
+	unlock, err := s.postgres.LockSeries(ctx, repository.SeriesOf(candidates))
+	if err != nil {
+		return nil, err
+	}
+	result, written, anomalies, err := s.write(ctx, points, candidates, keys, mode, opts.Source)
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range written {
+		if err := s.cache.CacheDataPoint(ctx, p, latestPointTTL); err != nil {
+			s.logger.Warn("Failed to cache data point", zap.Error(err))
+		}
+	}
+
+	for _, hit := range anomalies {
+		s.logger.Info("Anomaly detected",
+			zap.String("company_id", hit.point.CompanyID),
+			zap.String("product", hit.point.ProductName),
+			zap.Float64("value", hit.point.Value),
+			zap.Float64("score", hit.result.Score),
+			zap.String("method", string(hit.result.Method)))
+
+		if s.detector.Sensitivity(hit.point.ProductName).RaiseAlerts {
+			if err := s.raiseAnomalyAlert(ctx, hit); err != nil {
+				s.logger.Warn("Failed to raise anomaly alert", zap.Error(err))
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// write сравнивает точки с записанными значениями и записывает новые и замененные.
+// Вызывается под блокировкой рядов пачки.
+func (s *IngestionService) write(ctx context.Context, points []parser.DataPoint, candidates []repository.TelemetryData, keys []repository.TelemetryKey, mode WriteMode, source string) (*IngestResult, []parser.DataPoint, []anomalyHit, error) {
+	current, err := s.telemetry.GetCurrentValues(ctx, keys)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	result := &IngestResult{Received: len(points), Rejected: []RejectedPoint{}}
+	rows := make([]repository.TelemetryData, 0, len(points))
+	written := make([]parser.DataPoint, 0, len(points))
+	var replaced []repository.TelemetryData
+	var revisions []repository.Revision
+	var anomalies []anomalyHit
+	now := time.Now()
+	baseVersion := uint64(now.UnixNano())
+
+	// Детектор обновляет базовую линию только после успешной записи пачки
+	var scores *anomaly.Batch
+	if s.detector != nil {
+		scores = s.detector.Batch()
+	}
+	score := func(i int, row *repository.TelemetryData, learn bool) {
+		// Недостоверные точки не оцениваются и не попадают в базовую линию детектора
+		if scores == nil || !quality.Code(row.Quality).IsGood() {
+			return
+		}
+		res := scores.Score(row.CompanyID, row.ProductName, row.Timestamp, row.Value, learn)
+		row.AnomalyScore = res.Score
+		if res.Anomaly {
+			anomalies = append(anomalies, anomalyHit{point: points[i], result: res})
+		}
+	}
+
+	for i, row := range candidates {
+		// Версии внутри пачки растут, чтобы при повторе ключа побеждала последняя точка
+		row.Version = baseVersion + uint64(i)
+		key := keys[i]
+		stored := repository.StoredValue{Value: row.Value, Quality: row.Quality, Version: row.Version}
+
+		old, exists := current[key]
+		switch {
+		case !exists:
+			result.Written++
+			score(i, &row, true)
+		case old.Value == row.Value && old.Quality == row.Quality:
+			result.Unchanged++
+			continue
+		case mode == WriteReject:
+			result.Rejected = append(result.Rejected, RejectedPoint{Index: i, Reason: "a value for this company, product and timestamp already exists"})
+			continue
+		default:
+			// Замененное значение оценивается (и может поднять оповещение), но в базовую линию
+			// не добавляется: прежнее значение ключа в ней уже учтено
+			result.Revised++
+			score(i, &row, false)
+			replaced = append(replaced, row)
+			if mode == WriteRevision {
+				revisions = append(revisions, repository.Revision{Key: key, Old: old, New: stored, RevisedAt: now, Source: source})
+			}
+		}
+
+		current[key] = stored
+		rows = append(rows, row)
+		written = append(written, points[i])
+	}
+
+	if err := s.telemetry.SaveTelemetryBatch(ctx, rows); err != nil {
+		return nil, nil, nil, err
+	}
+	if scores != nil {
+		scores.Commit()
+	}
+	if err := s.telemetry.SaveRevisions(ctx, revisions); err != nil {
+		return nil, nil, nil, err
+	}
+	s.queueRollupRebuild(replaced)
+
+	return result, written, anomalies, nil
+}
+
+// queueRollupRebuild ставит в очередь часы рядов с замененными значениями: материализованные
+// представления учли и прежнее, и новое значение. Повторы объединяются.
+func (s *IngestionService) queueRollupRebuild(rows []repository.TelemetryData) {
+	if len(rows) == 0 {
+		return
+	}
+
+	s.rebuildMu.Lock()
+	for _, row := range rows {
+		key := repository.RollupBucket{CompanyID: row.CompanyID, ProductName: row.ProductName, Timestamp: row.Timestamp.UTC().Truncate(time.Hour)}
+		s.rebuild[key] = struct{}{}
+	}
+	s.rebuildMu.Unlock()
+
+	select {
+	case s.rebuildCh <- struct{}{}:
+	default:
+	}
+}
+
+// Run пересчитывает накопленные бакеты до отмены контекста: все бакеты из очереди — одной
+// мутацией на таблицу агрегатов, под блокировкой только затронутых рядов. Правки, пришедшие
+// во время пересчета, накапливаются для следующего прохода. Ошибка пересчета не отменяет
+// записи и только логируется.
+func (s *IngestionService) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.rebuildCh:
+		}
+
+		s.rebuildMu.Lock()
+		pending := make([]repository.RollupBucket, 0, len(s.rebuild))
+		for key := range s.rebuild {
+			pending = append(pending, key)
+		}
+		s.rebuild = make(map[repository.RollupBucket]struct{})
+		s.rebuildMu.Unlock()
+
+		if err := s.rebuildRollups(ctx, pending); err != nil && ctx.Err() == nil {
+			s.logger.Error("Failed to rebuild rollups after revisions", zap.Int("buckets", len(pending)), zap.Error(err))
+		}
+	}
+}
+
+func (s *IngestionService) rebuildRollups(ctx context.Context, buckets []repository.RollupBucket) error {
+	series := make([]repository.SeriesID, 0, len(buckets))
+	for _, b := range buckets {
+		series = append(series, repository.SeriesID{CompanyID: b.CompanyID, ProductName: b.ProductName})
+	}
+
+	unlock, err := s.postgres.LockSeries(ctx, series)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.telemetry.RebuildRollupBuckets(ctx, buckets)
+}
+
+type anomalyHit struct {
+	point  parser.DataPoint
+	result anomaly.Result
+}
+
+func (s *IngestionService) raiseAnomalyAlert(ctx context.Context, hit anomalyHit) error {
+	severity := "warning"
+	if hit.result.Score >= 2*s.detector.Sensitivity(hit.point.ProductName).Threshold {
+		severity = "critical"
+	}
+
+	alert := repository.Alert{
+		ID:       strconv.FormatInt(time.Now().UnixNano(), 10),
+		Type:     "anomaly",
+		Severity: severity,
+		Message: fmt.Sprintf("%s: значение %.2f %s отклоняется от ожидаемого %.2f (оценка %.1f, метод %s)",
+			hit.point.ProductName, hit.point.Value, hit.point.Unit, hit.result.Baseline, hit.result.Score, hit.result.Method),
+		CompanyID: hit.point.CompanyID,
+		Timestamp: hit.point.Timestamp,
+	}
+
+	return s.postgres.SaveAlert(ctx, alert)
+}
+
+func toOverride(c config.AnomalySensitivity) anomaly.Override {
+	s := anomaly.Override{
+		Threshold:   c.Threshold,
+		Window:      c.Window,
+		MinPoints:   c.MinPoints,
+		Season:      anomaly.Season(c.Season),
+		RaiseAlerts: c.RaiseAlerts,
+	}
+	for _, m := range c.Methods {
+		s.Methods = append(s.Methods, anomaly.Method(m))
+	}
+	return s
+}
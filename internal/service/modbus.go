@@ -0,0 +1,522 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"petrochemical-data-platform/internal/config"
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/pkg/modbus"
+	"petrochemical-data-platform/internal/pkg/parser"
+	"petrochemical-data-platform/internal/pkg/quality"
+
+	"go.uber.org/zap"
+)
+
+// maxModbusGap — наибольший разрыв адресов, который читается одним запросом
+// вместо двух: лишние регистры дешевле отдельного обмена с устройством
+const maxModbusGap = 16
+
+// errInvalidRegisterMap отмечает карту регистров, не соответствующую каталогу продуктов
+var errInvalidRegisterMap = errors.New("invalid register map")
+
+// ModbusService опрашивает оборудование по Modbus TCP согласно картам регистров
+// и передает показания в прием телеметрии. Он же служит транспортом команд управления:
+// команда оборудования записывается в регистр, указанный в конфигурации устройства.
+type ModbusService struct {
+	ingestion *IngestionService
+	devices   map[string]*modbusDevice
+	logger    *zap.Logger
+}
+
+// modbusDevice — устройство с подготовленной картой регистров
+type modbusDevice struct {
+	cfg       config.ModbusDeviceConfig
+	client    *modbus.Client
+	registers map[string]*modbusRegister
+	blocks    []modbusBlock
+	commands  map[string]config.ModbusCommandConfig
+
+	mu      sync.Mutex
+	last    map[string]float64 // Последние достоверные значения по именам регистров
+	failing bool               // Опрос завершается ошибкой; сообщается один раз
+}
+
+// modbusRegister — значение карты регистров
+type modbusRegister struct {
+	cfg      config.ModbusRegisterConfig
+	typ      modbus.RegisterType
+	dataType modbus.DataType
+	order    modbus.ByteOrder
+	scale    float64
+}
+
+// modbusBlock — непрерывный диапазон адресов, читаемый одним запросом
+type modbusBlock struct {
+	typ       modbus.RegisterType
+	address   uint16
+	qty       uint16
+	registers []*modbusRegister
+}
+
+// NewModbusService создает сервис опроса устройств из конфигурации
+func NewModbusService(ingestion *IngestionService, cfg config.ModbusConfig, logger *zap.Logger) (*ModbusService, error) {
+	s := &ModbusService{
+		ingestion: ingestion,
+		devices:   make(map[string]*modbusDevice, len(cfg.Devices)),
+		logger:    logger,
+	}
+
+	for _, dc := range cfg.Devices {
+		if dc.EquipmentID == "" || dc.Address == "" {
+			return nil, errors.New("modbus device: equipment_id and address are required")
+		}
+		if _, ok := s.devices[dc.EquipmentID]; ok {
+			return nil, fmt.Errorf("modbus device %s: duplicate equipment_id", dc.EquipmentID)
+		}
+		dev, err := newModbusDevice(dc)
+		if err != nil {
+			return nil, fmt.Errorf("modbus device %s: %w", dc.EquipmentID, err)
+		}
+		s.devices[dc.EquipmentID] = dev
+	}
+
+	return s, nil
+}
+
+func newModbusDevice(cfg config.ModbusDeviceConfig) (*modbusDevice, error) {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 10 * time.Second
+	}
+	if _, err := modbus.ParseByteOrder(cfg.ByteOrder); err != nil {
+		return nil, err
+	}
+
+	dev := &modbusDevice{
+		cfg:       cfg,
+		client:    modbus.NewClient(cfg.Address, cfg.UnitID, cfg.Timeout),
+		registers: make(map[string]*modbusRegister, len(cfg.Registers)),
+		commands:  make(map[string]config.ModbusCommandConfig, len(cfg.Commands)),
+		last:      make(map[string]float64),
+	}
+
+	var polled []*modbusRegister
+	for _, rc := range cfg.Registers {
+		r, err := newModbusRegister(rc, cfg.ByteOrder)
+		if err != nil {
+			return nil, fmt.Errorf("register %s: %w", rc.Name, err)
+		}
+		if _, ok := dev.registers[rc.Name]; ok {
+			return nil, fmt.Errorf("register %s: duplicate name", rc.Name)
+		}
+		dev.registers[rc.Name] = r
+
+		if rc.ProductName != "" {
+			if cfg.CompanyID == "" && rc.CompanyID == "" {
+				return nil, fmt.Errorf("register %s: company_id is required for polled registers", rc.Name)
+			}
+			polled = append(polled, r)
+		}
+	}
+	dev.blocks = modbusBlocks(polled)
+
+	for _, cc := range cfg.Commands {
+		r, ok := dev.registers[cc.Register]
+		if !ok {
+			return nil, fmt.Errorf("command %s: unknown register %q", cc.Command, cc.Register)
+		}
+		if !r.typ.Writable() {
+			return nil, fmt.Errorf("command %s: register %s of type %s is read-only", cc.Command, cc.Register, r.typ)
+		}
+		if _, ok := dev.commands[cc.Command]; ok {
+			return nil, fmt.Errorf("command %s: duplicate command", cc.Command)
+		}
+		if cc.Parameter == "" {
+			cc.Parameter = "value"
+		}
+		dev.commands[cc.Command] = cc
+	}
+
+	return dev, nil
+}
+
+func newModbusRegister(cfg config.ModbusRegisterConfig, deviceOrder string) (*modbusRegister, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	typ, err := modbus.ParseRegisterType(cfg.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &modbusRegister{cfg: cfg, typ: typ, scale: cfg.Scale}
+	if r.scale == 0 {
+		r.scale = 1
+	}
+
+	if typ.Bit() {
+		if cfg.DataType != "" && cfg.DataType != string(modbus.Bool) {
+			return nil, fmt.Errorf("%s registers hold bits, data_type must be bool", typ)
+		}
+		r.dataType = modbus.Bool
+		return r, nil
+	}
+
+	if r.dataType, err = modbus.ParseDataType(cfg.DataType); err != nil {
+		return nil, err
+	}
+	if r.dataType == modbus.Bool {
+		return nil, fmt.Errorf("data_type bool requires a coil or discrete register")
+	}
+	order := cfg.ByteOrder
+	if order == "" {
+		order = deviceOrder
+	}
+	if r.order, err = modbus.ParseByteOrder(order); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// size возвращает число регистров (битов) значения
+func (r *modbusRegister) size() int {
+	if r.typ.Bit() {
+		return 1
+	}
+	return r.dataType.Registers()
+}
+
+// modbusBlocks группирует опрашиваемые регистры в запросы: по областям, по возрастанию
+// адресов, с разрывами не больше maxModbusGap и в пределах ограничений протокола
+func modbusBlocks(registers []*modbusRegister) []modbusBlock {
+	sorted := slices.Clone(registers)
+	slices.SortStableFunc(sorted, func(a, b *modbusRegister) int {
+		if a.typ != b.typ {
+			if a.typ < b.typ {
+				return -1
+			}
+			return 1
+		}
+		return int(a.cfg.Address) - int(b.cfg.Address)
+	})
+
+	var blocks []modbusBlock
+	for _, r := range sorted {
+		limit := modbus.MaxReadRegisters
+		if r.typ.Bit() {
+			limit = modbus.MaxReadBits
+		}
+		end := int(r.cfg.Address) + r.size()
+
+		if n := len(blocks); n > 0 {
+			b := &blocks[n-1]
+			blockEnd := int(b.address) + int(b.qty)
+			if b.typ == r.typ && int(r.cfg.Address) <= blockEnd+maxModbusGap && end-int(b.address) <= limit {
+				b.qty = uint16(max(blockEnd, end) - int(b.address))
+				b.registers = append(b.registers, r)
+				continue
+			}
+		}
+		blocks = append(blocks, modbusBlock{typ: r.typ, address: r.cfg.Address, qty: uint16(r.size()), registers: []*modbusRegister{r}})
+	}
+	return blocks
+}
+
+// Run опрашивает устройства с периодами из конфигурации, пока не отменен ctx
+func (s *ModbusService) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, dev := range s.devices {
+		if len(dev.blocks) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.runDevice(ctx, dev)
+		}()
+	}
+	wg.Wait()
+
+	for _, dev := range s.devices {
+		dev.client.Close()
+	}
+}
+
+func (s *ModbusService) runDevice(ctx context.Context, dev *modbusDevice) {
+	ticker := time.NewTicker(dev.cfg.PollInterval)
+	defer ticker.Stop()
+
+	// Карта регистров сверяется с каталогом до первого опроса; если каталог недоступен,
+	// проверка повторяется на следующем периоде
+	checked := false
+	for {
+		if !checked {
+			err := s.checkCatalog(ctx, dev)
+			switch {
+			case errors.Is(err, errInvalidRegisterMap):
+				s.logger.Error("Modbus device is not polled", zap.String("equipment_id", dev.cfg.EquipmentID), zap.Error(err))
+				return
+			case err != nil:
+				s.logger.Warn("Failed to check modbus register map", zap.String("equipment_id", dev.cfg.EquipmentID), zap.Error(err))
+			default:
+				checked = true
+			}
+		}
+		if checked {
+			s.pollDevice(ctx, dev)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollDevice выполняет один опрос устройства и записывает показания
+func (s *ModbusService) pollDevice(ctx context.Context, dev *modbusDevice) {
+	points, err := s.Poll(ctx, dev.cfg.EquipmentID)
+	if ctx.Err() != nil {
+		return
+	}
+	s.reportState(dev, err)
+	if len(points) == 0 {
+		return
+	}
+
+	result, err := s.ingestion.Ingest(ctx, points, IngestOptions{Source: "modbus"})
+	if err != nil {
+		s.logger.Error("Failed to ingest modbus readings", zap.String("equipment_id", dev.cfg.EquipmentID), zap.Error(err))
+		return
+	}
+	if len(result.Rejected) > 0 {
+		s.logger.Warn("Modbus readings rejected",
+			zap.String("equipment_id", dev.cfg.EquipmentID),
+			zap.Int("rejected", len(result.Rejected)))
+	}
+}
+
+// checkCatalog проверяет, что опрашиваемые регистры ссылаются на действующие продукты
+// каталога с той же единицей измерения
+func (s *ModbusService) checkCatalog(ctx context.Context, dev *modbusDevice) error {
+	catalog, err := s.ingestion.catalog.get(ctx, s.ingestion.postgres)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range dev.blocks {
+		for _, r := range b.registers {
+			if err := checkProduct(catalog, dev.companyID(r), r.cfg.ProductName, r.cfg.Unit); err != nil {
+				return fmt.Errorf("%w: register %s: %w", errInvalidRegisterMap, r.cfg.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// reportState сообщает о пропадании и восстановлении связи с устройством без повторов на каждом опросе
+func (s *ModbusService) reportState(dev *modbusDevice, err error) {
+	dev.mu.Lock()
+	changed := dev.failing != (err != nil)
+	dev.failing = err != nil
+	dev.mu.Unlock()
+
+	switch {
+	case changed && err != nil:
+		s.logger.Warn("Modbus device poll failed", zap.String("equipment_id", dev.cfg.EquipmentID), zap.String("address", dev.cfg.Address), zap.Error(err))
+	case changed:
+		s.logger.Info("Modbus device poll recovered", zap.String("equipment_id", dev.cfg.EquipmentID))
+	}
+}
+
+// Poll читает опрашиваемые регистры устройства и возвращает точки телеметрии.
+// Если блок не прочитан, для его регистров возвращаются последние достоверные значения
+// с кодом качества ошибки; регистры без прежних значений пропускаются.
+// Ошибка — первая ошибка чтения; точки при этом тоже возвращаются.
+func (s *ModbusService) Poll(ctx context.Context, equipmentID string) ([]parser.DataPoint, error) {
+	dev, ok := s.devices[equipmentID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEquipment, equipmentID)
+	}
+
+	timestamp := time.Now().UTC().Truncate(time.Millisecond)
+	points := make([]parser.DataPoint, 0, len(dev.registers))
+	var firstErr error
+
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+
+	for _, b := range dev.blocks {
+		values, err := dev.readBlock(ctx, b)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			code := quality.BadLastKnownValue
+			if modbus.IsException(err, modbus.ExceptionIllegalAddress) {
+				code = quality.BadConfigError // Карта регистров не соответствует устройству
+			}
+			for _, r := range b.registers {
+				if last, ok := dev.last[r.cfg.Name]; ok {
+					points = append(points, dev.point(r, last, code, timestamp))
+				}
+			}
+			continue
+		}
+
+		for i, r := range b.registers {
+			value := values[i]
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				// Датчик передал NaN/Inf (обычно признак неисправности в float-регистрах)
+				if last, ok := dev.last[r.cfg.Name]; ok {
+					points = append(points, dev.point(r, last, quality.BadSensorFailure, timestamp))
+				}
+				continue
+			}
+			dev.last[r.cfg.Name] = value
+			points = append(points, dev.point(r, value, quality.Good, timestamp))
+		}
+	}
+
+	return points, firstErr
+}
+
+// readBlock читает блок и возвращает масштабированные значения его регистров
+func (d *modbusDevice) readBlock(ctx context.Context, b modbusBlock) ([]float64, error) {
+	values := make([]float64, len(b.registers))
+
+	if b.typ.Bit() {
+		bits, err := d.client.ReadBits(ctx, b.typ, b.address, b.qty)
+		if err != nil {
+			return nil, err
+		}
+		for i, r := range b.registers {
+			if bits[r.cfg.Address-b.address] {
+				values[i] = r.scale + r.cfg.Offset
+			} else {
+				values[i] = r.cfg.Offset
+			}
+		}
+		return values, nil
+	}
+
+	regs, err := d.client.ReadRegisters(ctx, b.typ, b.address, b.qty)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range b.registers {
+		start := int(r.cfg.Address - b.address)
+		raw, err := modbus.Decode(regs[start:start+r.size()], r.dataType, r.order)
+		if err != nil {
+			return nil, fmt.Errorf("register %s: %w", r.cfg.Name, err)
+		}
+		values[i] = raw*r.scale + r.cfg.Offset
+	}
+	return values, nil
+}
+
+func (d *modbusDevice) companyID(r *modbusRegister) string {
+	if r.cfg.CompanyID != "" {
+		return r.cfg.CompanyID
+	}
+	return d.cfg.CompanyID
+}
+
+func (d *modbusDevice) point(r *modbusRegister, value float64, code quality.Code, timestamp time.Time) parser.DataPoint {
+
+	labels := make(map[string]string, len(r.cfg.Labels)+2)
+	for k, v := range r.cfg.Labels {
+		labels[k] = v
+	}
+	labels["equipment_id"] = d.cfg.EquipmentID
+	labels["register"] = r.cfg.Name
+
+	return parser.DataPoint{
+		CompanyID:   d.companyID(r),
+		ProductName: r.cfg.ProductName,
+		Value:       value,
+		Unit:        r.cfg.Unit,
+		Timestamp:   timestamp,
+		Quality:     uint16(code),
+		Tags:        r.cfg.Tags,
+		Labels:      labels,
+	}
+}
+
+// Handles сообщает, управляется ли оборудование через Modbus
+func (s *ModbusService) Handles(equipmentID string) bool {
+	_, ok := s.devices[equipmentID]
+	return ok
+}
+
+// Execute записывает команду в регистр устройства. Значение берется из параметра команды
+// или из постоянного значения в конфигурации, проверяется по допустимому диапазону
+// и переводится в сырое значение обратным масштабированием.
+func (s *ModbusService) Execute(ctx context.Context, cmd domain.ControlCommand) error {
+	dev, ok := s.devices[cmd.EquipmentID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownEquipment, cmd.EquipmentID)
+	}
+	cc, ok := dev.commands[cmd.Command]
+	if !ok {
+		return fmt.Errorf("%w: %q for equipment %s", ErrUnsupportedCommand, cmd.Command, cmd.EquipmentID)
+	}
+	r := dev.registers[cc.Register]
+
+	var value float64
+	if cc.Value != nil {
+		value = *cc.Value
+	} else {
+		v, err := commandValue(cmd.Parameters, cc.Parameter)
+		if err != nil {
+			return err
+		}
+		value = v
+	}
+	if cc.Min != nil && value < *cc.Min || cc.Max != nil && value > *cc.Max {
+		return fmt.Errorf("%w: %s = %g is outside the allowed range", ErrInvalidCommand, cc.Parameter, value)
+	}
+
+	raw := (value - r.cfg.Offset) / r.scale
+	if r.typ.Bit() {
+		if err := dev.client.WriteCoil(ctx, r.cfg.Address, raw != 0); err != nil {
+			return fmt.Errorf("failed to write coil %s: %w", r.cfg.Name, err)
+		}
+		return nil
+	}
+
+	regs, err := modbus.Encode(raw, r.dataType, r.order)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidCommand, cc.Parameter, err)
+	}
+	if err := dev.client.WriteRegisters(ctx, r.cfg.Address, regs); err != nil {
+		return fmt.Errorf("failed to write register %s: %w", r.cfg.Name, err)
+	}
+	return nil
+}
+
+// commandValue извлекает числовое значение параметра команды (JSON: число или bool)
+func commandValue(params map[string]interface{}, name string) (float64, error) {
+	v, ok := params[name]
+	if !ok {
+		return 0, fmt.Errorf("%w: parameter %q is required", ErrInvalidCommand, name)
+	}
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("%w: parameter %q must be a number", ErrInvalidCommand, name)
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"petrochemical-data-platform/internal/config"
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/pkg/modbus"
+	"petrochemical-data-platform/internal/pkg/quality"
+
+	"go.uber.org/zap"
+)
+
+func float(v float64) *float64 { return &v }
+
+// startModbusDevice запускает симулятор и сервис с картой регистров установки полипропилена
+func startModbusDevice(t *testing.T) (*modbus.Server, *ModbusService) {
+	t.Helper()
+	srv := modbus.NewServer(64)
+	if err := srv.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	svc, err := NewModbusService(nil, config.ModbusConfig{Devices: []config.ModbusDeviceConfig{{
+		EquipmentID: "TOB-PP-LINE1",
+		Address:     srv.Addr(),
+		Timeout:     time.Second,
+		CompanyID:   "SIBUR_TOBOLSK",
+		ByteOrder:   "CDAB",
+		Registers: []config.ModbusRegisterConfig{
+			{Name: "throughput", Type: "input", Address: 0, DataType: "float32", ProductName: "Полипропилен", Unit: "т/час"},
+			{Name: "temperature", Type: "input", Address: 4, DataType: "int16", Scale: 0.1, Offset: -50, ProductName: "Температура реактора", Unit: "°C"},
+			{Name: "level", Type: "holding", Address: 10, DataType: "uint32", ByteOrder: "ABCD", Scale: 0.01, ProductName: "Уровень", Unit: "%"},
+			{Name: "setpoint", Type: "holding", Address: 20, DataType: "uint16", Scale: 0.5},
+			{Name: "running", Type: "coil", Address: 3},
+		},
+		Commands: []config.ModbusCommandConfig{
+			{Command: "set_throughput", Register: "setpoint", Min: float(0), Max: float(60)},
+			{Command: "start", Register: "running", Value: float(1)},
+			{Command: "stop", Register: "running", Value: float(0)},
+		},
+	}}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewModbusService: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, dev := range svc.devices {
+			dev.client.Close()
+		}
+	})
+	return srv, svc
+}
+
+func TestModbusPollScaling(t *testing.T) {
+	srv, svc := startModbusDevice(t)
+	ctx := context.Background()
+
+	srv.SetValue(modbus.InputRegister, 0, 42.5, modbus.Float32, modbus.WordSwap)
+	srv.SetRegisters(modbus.InputRegister, 4, 1234)                                 // 1234 * 0.1 - 50
+	srv.SetValue(modbus.HoldingRegister, 10, 8750, modbus.Uint32, modbus.BigEndian) // 8750 * 0.01
+
+	points, err := svc.Poll(ctx, "TOB-PP-LINE1")
+	if err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+
+	want := map[string]float64{"throughput": 42.5, "temperature": 73.4, "level": 87.5}
+	if len(points) != len(want) {
+		t.Fatalf("got %d points, want %d: %+v", len(points), len(want), points)
+	}
+	for _, p := range points {
+		name := p.Labels["register"]
+		if v, ok := want[name]; !ok || p.Value < v-1e-9 || p.Value > v+1e-9 {
+			t.Errorf("register %s = %v, want %v", name, p.Value, want[name])
+		}
+		if p.CompanyID != "SIBUR_TOBOLSK" || p.Labels["equipment_id"] != "TOB-PP-LINE1" {
+			t.Errorf("register %s: company %q, labels %v", name, p.CompanyID, p.Labels)
+		}
+		if quality.Code(p.Quality) != quality.Good {
+			t.Errorf("register %s: quality %s, want good", name, quality.Code(p.Quality))
+		}
+	}
+}
+
+func TestModbusPollKeepsLastValueOnFailure(t *testing.T) {
+	srv, svc := startModbusDevice(t)
+	ctx := context.Background()
+
+	srv.SetValue(modbus.InputRegister, 0, 42.5, modbus.Float32, modbus.WordSwap)
+	if _, err := svc.Poll(ctx, "TOB-PP-LINE1"); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+
+	srv.Close()
+	points, err := svc.Poll(ctx, "TOB-PP-LINE1")
+	if err == nil {
+		t.Fatal("Poll succeeded with the device stopped")
+	}
+	if len(points) != 3 {
+		t.Fatalf("got %d points, want the last values of 3 registers", len(points))
+	}
+	for _, p := range points {
+		if quality.Code(p.Quality) != quality.BadLastKnownValue {
+			t.Errorf("register %s: quality %s, want bad/last_known_value", p.Labels["register"], quality.Code(p.Quality))
+		}
+		if p.Labels["register"] == "throughput" && p.Value != 42.5 {
+			t.Errorf("throughput = %v, want the last value 42.5", p.Value)
+		}
+	}
+}
+
+func TestModbusControlTransport(t *testing.T) {
+	srv, svc := startModbusDevice(t)
+	control := NewControlService(zap.NewNop(), svc)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		cmd      domain.ControlCommand
+		err      error
+		executed bool
+	}{
+		{"setpoint with inverse scaling", domain.ControlCommand{EquipmentID: "TOB-PP-LINE1", Command: "set_throughput", Parameters: map[string]interface{}{"value": 42.5}}, nil, true},
+		{"constant value", domain.ControlCommand{EquipmentID: "TOB-PP-LINE1", Command: "start"}, nil, true},
+		{"out of range", domain.ControlCommand{EquipmentID: "TOB-PP-LINE1", Command: "set_throughput", Parameters: map[string]interface{}{"value": 75.0}}, ErrInvalidCommand, false},
+		{"missing parameter", domain.ControlCommand{EquipmentID: "TOB-PP-LINE1", Command: "set_throughput"}, ErrInvalidCommand, false},
+		{"unknown command", domain.ControlCommand{EquipmentID: "TOB-PP-LINE1", Command: "purge"}, ErrUnsupportedCommand, false},
+		{"equipment without transport", domain.ControlCommand{EquipmentID: "TOB-PP-LINE2", Command: "start"}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executed, err := control.SendControlCommand(ctx, tt.cmd)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if executed != tt.executed {
+				t.Errorf("executed = %v, want %v", executed, tt.executed)
+			}
+		})
+	}
+
+	// 42.5 / 0.5 = 85 в регистре уставки
+	if regs, _ := srv.Registers(modbus.HoldingRegister, 20, 1); regs[0] != 85 {
+		t.Errorf("setpoint register = %d, want 85", regs[0])
+	}
+	if bits, _ := srv.Bits(modbus.Coil, 3, 1); !bits[0] {
+		t.Error("running coil is not set")
+	}
+
+	if _, err := control.SendControlCommand(ctx, domain.ControlCommand{EquipmentID: "TOB-PP-LINE1", Command: "stop"}); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if bits, _ := srv.Bits(modbus.Coil, 3, 1); bits[0] {
+		t.Error("running coil is still set after stop")
+	}
+
+	// Недоступное устройство — ошибка транспорта, а не отказ в команде
+	srv.Close()
+	_, err := control.SendControlCommand(ctx, domain.ControlCommand{EquipmentID: "TOB-PP-LINE1", Command: "start"})
+	if err == nil || errors.Is(err, ErrInvalidCommand) || errors.Is(err, ErrUnsupportedCommand) {
+		t.Fatalf("error = %v, want a transport error", err)
+	}
+}
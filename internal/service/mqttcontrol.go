@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"petrochemical-data-platform/internal/config"
+	"petrochemical-data-platform/internal/domain"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.uber.org/zap"
+)
+
+// Топики команд и подтверждений по умолчанию
+const (
+	defaultCommandTopic = "petrochem/control/{equipment_id}/command"
+	defaultAckTopic     = "petrochem/control/{equipment_id}/ack"
+)
+
+// ErrControlNotConnected возвращается, пока нет связи с брокером MQTT
+var ErrControlNotConnected = errors.New("MQTT control transport is not connected")
+
+// MQTTControlService доставляет команды управления оборудованию, подключенному через брокер
+// MQTT (шлюзы, симулятор оборудования): команда публикуется в топик оборудования,
+// результат — подтверждение ControlAck с тем же идентификатором команды.
+type MQTTControlService struct {
+	broker config.MQTTConfig
+	cfg    config.ControlMQTTConfig
+	logger *zap.Logger
+
+	mu      sync.Mutex
+	client  mqtt.Client
+	pending map[string]chan domain.ControlAck // Ожидающие подтверждения по ID команды
+}
+
+// NewMQTTControlService создает транспорт команд через MQTT из конфигурации
+func NewMQTTControlService(broker config.MQTTConfig, cfg config.ControlMQTTConfig, logger *zap.Logger) (*MQTTControlService, error) {
+	if cfg.CommandTopic == "" {
+		cfg.CommandTopic = defaultCommandTopic
+	}
+	if cfg.AckTopic == "" {
+		cfg.AckTopic = defaultAckTopic
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	for _, topic := range []string{cfg.CommandTopic, cfg.AckTopic} {
+		if !strings.Contains(topic, "{equipment_id}") {
+			return nil, fmt.Errorf("control topic %q must contain {equipment_id}", topic)
+		}
+	}
+	for _, pattern := range cfg.Equipment {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid equipment pattern %q: %w", pattern, err)
+		}
+	}
+	if len(cfg.Equipment) > 0 && broker.Broker == "" {
+		return nil, errors.New("control over MQTT requires mqtt.broker")
+	}
+
+	return &MQTTControlService{
+		broker:  broker,
+		cfg:     cfg,
+		logger:  logger,
+		pending: make(map[string]chan domain.ControlAck),
+	}, nil
+}
+
+// Run подключается к брокеру и слушает подтверждения до отмены контекста.
+// Без настроенного оборудования транспорт не используется.
+func (s *MQTTControlService) Run(ctx context.Context) {
+	if len(s.cfg.Equipment) == 0 {
+		return
+	}
+
+	ackTopic := strings.ReplaceAll(s.cfg.AckTopic, "{equipment_id}", "+")
+	opts := mqtt.NewClientOptions().
+		AddBroker(s.broker.Broker).
+		SetClientID(s.broker.ClientID + "_control").
+		SetUsername(s.broker.Username).
+		SetPassword(s.broker.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOnConnectHandler(func(c mqtt.Client) {
+			// Подписка восстанавливается после каждого переподключения
+			if token := c.Subscribe(ackTopic, 1, s.onAck); token.Wait() && token.Error() != nil {
+				s.logger.Error("Failed to subscribe to control acks", zap.String("topic", ackTopic), zap.Error(token.Error()))
+				return
+			}
+			s.logger.Info("Control transport connected", zap.String("broker", s.broker.Broker), zap.String("ack_topic", ackTopic))
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			s.logger.Warn("Control transport connection lost", zap.Error(err))
+		})
+
+	client := mqtt.NewClient(opts)
+	client.Connect()
+
+	s.mu.Lock()
+	s.client = client
+	s.mu.Unlock()
+
+	<-ctx.Done()
+	client.Disconnect(250)
+}
+
+// Handles сообщает, управляется ли оборудование через MQTT
+func (s *MQTTControlService) Handles(equipmentID string) bool {
+	for _, pattern := range s.cfg.Equipment {
+		if ok, _ := path.Match(pattern, equipmentID); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute публикует команду и ждет подтверждения оборудования не дольше timeout.
+// Отклонение команды оборудованием переводится в ErrUnsupportedCommand или ErrInvalidCommand.
+func (s *MQTTControlService) Execute(ctx context.Context, cmd domain.ControlCommand) error {
+	s.mu.Lock()
+	client := s.client
+	if client == nil || !client.IsConnectionOpen() {
+		s.mu.Unlock()
+		return ErrControlNotConnected
+	}
+	ch := make(chan domain.ControlAck, 1)
+	s.pending[cmd.ID] = ch
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, cmd.ID)
+		s.mu.Unlock()
+	}()
+
+	payload, err := json.Marshal(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to encode control command: %w", err)
+	}
+	topic := strings.ReplaceAll(s.cfg.CommandTopic, "{equipment_id}", cmd.EquipmentID)
+
+	timer := time.NewTimer(s.cfg.Timeout)
+	defer timer.Stop()
+
+	token := client.Publish(topic, 1, false, payload)
+	select {
+	case <-token.Done():
+		if err := token.Error(); err != nil {
+			return fmt.Errorf("failed to publish control command: %w", err)
+		}
+	case <-timer.C:
+		return fmt.Errorf("failed to publish control command to %s: timeout", topic)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case ack := <-ch:
+		return ackError(ack)
+	case <-timer.C:
+		return fmt.Errorf("equipment %s did not acknowledge command %s within %s", cmd.EquipmentID, cmd.ID, s.cfg.Timeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *MQTTControlService) onAck(_ mqtt.Client, msg mqtt.Message) {
+	var ack domain.ControlAck
+	if err := json.Unmarshal(msg.Payload(), &ack); err != nil {
+		s.logger.Warn("Invalid control ack", zap.String("topic", msg.Topic()), zap.Error(err))
+		return
+	}
+
+	s.mu.Lock()
+	ch, ok := s.pending[ack.CommandID]
+	s.mu.Unlock()
+	if !ok {
+		// Подтверждение после истечения ожидания или команды другого экземпляра API
+		s.logger.Debug("Unexpected control ack", zap.String("command_id", ack.CommandID))
+		return
+	}
+	select {
+	case ch <- ack:
+	default:
+	}
+}
+
+// ackError переводит подтверждение оборудования в ошибку выполнения команды
+func ackError(ack domain.ControlAck) error {
+	switch ack.Status {
+	case domain.AckExecuted:
+		return nil
+	case domain.AckRejected:
+		switch ack.Reason {
+		case domain.AckReasonUnsupportedCommand:
+			return fmt.Errorf("%w: %s", ErrUnsupportedCommand, ack.Error)
+		case domain.AckReasonInvalidParameters, domain.AckReasonInvalidState:
+			return fmt.Errorf("%w: %s", ErrInvalidCommand, ack.Error)
+		}
+		return fmt.Errorf("equipment %s rejected command: %s", ack.EquipmentID, ack.Error)
+	}
+	return fmt.Errorf("equipment %s failed to execute command (%s): %s", ack.EquipmentID, ack.Reason, ack.Error)
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"petrochemical-data-platform/internal/config"
+	"petrochemical-data-platform/internal/pkg/parser"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.uber.org/zap"
+)
+
+// MQTTIngestService принимает телеметрию из брокера MQTT (шлюзы, cmd/simulator -output mqtt):
+// каждое сообщение — JSON-массив точек, который проходит ту же проверку по каталогу, что
+// и POST /api/v1/telemetry/ingest. Компания берется из точек, а не из топика.
+type MQTTIngestService struct {
+	ingestion *IngestionService
+	broker    config.MQTTConfig
+	topics    []string
+	logger    *zap.Logger
+}
+
+// NewMQTTIngestService создает прием телеметрии из брокера по конфигурации ingest.mqtt
+func NewMQTTIngestService(ingestion *IngestionService, broker config.MQTTConfig, cfg config.IngestMQTTConfig, logger *zap.Logger) (*MQTTIngestService, error) {
+	var topics []string
+	for _, topic := range cfg.Topics {
+		topic = strings.TrimSpace(topic)
+		if topic == "" {
+			return nil, errors.New("ingest mqtt: empty topic filter")
+		}
+		topics = append(topics, topic)
+	}
+	if len(topics) > 0 && broker.Broker == "" {
+		return nil, errors.New("ingest over MQTT requires mqtt.broker")
+	}
+
+	return &MQTTIngestService{
+		ingestion: ingestion,
+		broker:    broker,
+		topics:    topics,
+		logger:    logger,
+	}, nil
+}
+
+// Run подключается к брокеру и записывает полученные пачки до отмены контекста.
+// Без настроенных топиков прием не запускается.
+func (s *MQTTIngestService) Run(ctx context.Context) {
+	if len(s.topics) == 0 {
+		return
+	}
+
+	filters := make(map[string]byte, len(s.topics))
+	for _, topic := range s.topics {
+		filters[topic] = 1
+	}
+	handler := func(_ mqtt.Client, msg mqtt.Message) { s.onMessage(ctx, msg) }
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(s.broker.Broker).
+		SetClientID(s.broker.ClientID + "_ingest").
+		SetUsername(s.broker.Username).
+		SetPassword(s.broker.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOnConnectHandler(func(c mqtt.Client) {
+			// Подписка восстанавливается после каждого переподключения
+			if token := c.SubscribeMultiple(filters, handler); token.Wait() && token.Error() != nil {
+				s.logger.Error("Failed to subscribe to telemetry topics", zap.Strings("topics", s.topics), zap.Error(token.Error()))
+				return
+			}
+			s.logger.Info("MQTT telemetry ingest connected", zap.String("broker", s.broker.Broker), zap.Strings("topics", s.topics))
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			s.logger.Warn("MQTT telemetry ingest connection lost", zap.Error(err))
+		})
+
+	client := mqtt.NewClient(opts)
+	client.Connect()
+
+	<-ctx.Done()
+	client.Disconnect(250)
+}
+
+// onMessage записывает пачку из сообщения. Отклоненные точки и нечитаемые сообщения только
+// логируются: отправителю по MQTT некуда вернуть результат.
+func (s *MQTTIngestService) onMessage(ctx context.Context, msg mqtt.Message) {
+	if ctx.Err() != nil {
+		return
+	}
+	log := s.logger.With(zap.String("topic", msg.Topic()))
+
+	result, err := s.ingestion.IngestPayload(ctx, Payload{
+		Body:   bytes.NewReader(msg.Payload()),
+		Format: parser.FormatJSON,
+	}, IngestOptions{Source: "mqtt"})
+	switch {
+	case errors.Is(err, ErrInvalidPayload), errors.Is(err, ErrPayloadTooLarge):
+		log.Warn("Invalid MQTT telemetry message", zap.Error(err))
+		return
+	case err != nil:
+		log.Error("Failed to ingest MQTT telemetry", zap.Error(err))
+		return
+	}
+
+	if len(result.Rejected) > 0 {
+		first := result.Rejected[0]
+		log.Warn("MQTT telemetry points rejected",
+			zap.Int("rejected", len(result.Rejected)),
+			zap.String("first", fmt.Sprintf("#%d: %s", first.Index, first.Reason)))
+	}
+}
@@ -0,0 +1,418 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"petrochemical-data-platform/internal/config"
+	"petrochemical-data-platform/internal/pkg/opcua"
+	"petrochemical-data-platform/internal/pkg/parser"
+	"petrochemical-data-platform/internal/pkg/quality"
+
+	"go.uber.org/zap"
+)
+
+// opcuaBatchSize — число уведомлений, после которого пакет записывается, не дожидаясь
+// периода публикации
+const opcuaBatchSize = 1000
+
+// Ошибки просмотра адресного пространства
+var (
+	ErrOPCUAConnectionNotFound = errors.New("OPC UA connection not found")
+	ErrOPCUANotConnected       = errors.New("OPC UA connection has no active session")
+	ErrInvalidNodeID           = errors.New("invalid node id")
+)
+
+// errInvalidNodeMap отмечает узлы, не соответствующие каталогу продуктов
+var errInvalidNodeMap = errors.New("invalid node map")
+
+// OPCUAService подписывается на узлы серверов OPC UA и передает изменения в прием
+// телеметрии. StatusCode значений переводится в коды качества, метки времени источника
+// сохраняются. При потере связи или сессии последние значения записываются с качеством
+// last_known_value, а сессия и подписка создаются заново с нарастающей паузой.
+type OPCUAService struct {
+	ingestion   *IngestionService
+	write       ingestFunc // Запись точек; по умолчанию ingestion.Ingest
+	connections map[string]*opcuaConnection
+	logger      *zap.Logger
+}
+
+// ingestFunc записывает точки телеметрии, как IngestionService.Ingest
+type ingestFunc func(ctx context.Context, points []parser.DataPoint, opts IngestOptions) (*IngestResult, error)
+
+// opcuaConnection — подключение к серверу с картой узлов
+type opcuaConnection struct {
+	cfg   config.OPCUAConnectionConfig
+	nodes map[opcua.NodeID]*opcuaNode
+	order []opcua.NodeID // Узлы в порядке конфигурации
+
+	disconnected bool // Потеря связи уже отмечена в рядах
+
+	mu      sync.Mutex
+	session opcua.Session // nil, пока нет связи
+}
+
+// opcuaNode — узел карты с последним достоверным значением
+type opcuaNode struct {
+	cfg       config.OPCUANodeConfig
+	companyID string
+	scale     float64
+
+	hasLast   bool
+	last      float64
+	invalid   bool // Узел отсутствует на сервере или не является переменной
+	typeError bool // О нечисловом значении уже сообщено
+}
+
+// NewOPCUAService создает коннектор из конфигурации
+func NewOPCUAService(ingestion *IngestionService, cfg config.OPCUAConfig, logger *zap.Logger) (*OPCUAService, error) {
+	s := &OPCUAService{
+		ingestion:   ingestion,
+		write:       ingestion.Ingest,
+		connections: make(map[string]*opcuaConnection, len(cfg.Connections)),
+		logger:      logger,
+	}
+
+	for _, cc := range cfg.Connections {
+		if cc.Name == "" || cc.Endpoint == "" {
+			return nil, errors.New("opcua connection: name and endpoint are required")
+		}
+		if _, ok := s.connections[cc.Name]; ok {
+			return nil, fmt.Errorf("opcua connection %s: duplicate name", cc.Name)
+		}
+		conn, err := newOPCUAConnection(cc)
+		if err != nil {
+			return nil, fmt.Errorf("opcua connection %s: %w", cc.Name, err)
+		}
+		s.connections[cc.Name] = conn
+	}
+
+	return s, nil
+}
+
+func newOPCUAConnection(cfg config.OPCUAConnectionConfig) (*opcuaConnection, error) {
+	if err := opcua.CheckEndpoint(cfg.Endpoint); err != nil {
+		return nil, err
+	}
+	if cfg.PublishInterval <= 0 {
+		cfg.PublishInterval = time.Second
+	}
+	if cfg.ReconnectInterval <= 0 {
+		cfg.ReconnectInterval = 5 * time.Second
+	}
+	if cfg.MaxReconnectInterval < cfg.ReconnectInterval {
+		cfg.MaxReconnectInterval = max(2*time.Minute, cfg.ReconnectInterval)
+	}
+
+	conn := &opcuaConnection{cfg: cfg, nodes: make(map[opcua.NodeID]*opcuaNode, len(cfg.Nodes))}
+	for _, nc := range cfg.Nodes {
+		id, err := opcua.ParseNodeID(nc.NodeID)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := conn.nodes[id]; ok {
+			return nil, fmt.Errorf("node %s: duplicate node id", id)
+		}
+		if nc.ProductName == "" {
+			return nil, fmt.Errorf("node %s: product_name is required", id)
+		}
+
+		n := &opcuaNode{cfg: nc, companyID: nc.CompanyID, scale: nc.Scale}
+		if n.companyID == "" {
+			n.companyID = cfg.CompanyID
+		}
+		if n.companyID == "" {
+			return nil, fmt.Errorf("node %s: company_id is required", id)
+		}
+		if n.scale == 0 {
+			n.scale = 1
+		}
+		conn.nodes[id] = n
+		conn.order = append(conn.order, id)
+	}
+
+	return conn, nil
+}
+
+// Run поддерживает сессии со всеми серверами, пока не отменен ctx
+func (s *OPCUAService) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, conn := range s.connections {
+		if len(conn.order) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.runConnection(ctx, conn)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *OPCUAService) runConnection(ctx context.Context, conn *opcuaConnection) {
+	log := s.logger.With(zap.String("connection", conn.cfg.Name), zap.String("endpoint", conn.cfg.Endpoint))
+	backoff := conn.cfg.ReconnectInterval
+	checked := false
+
+	for {
+		// Карта узлов сверяется с каталогом до первой сессии; если каталог недоступен,
+		// проверка повторяется после паузы
+		if !checked {
+			err := s.checkCatalog(ctx, conn)
+			switch {
+			case errors.Is(err, errInvalidNodeMap):
+				log.Error("OPC UA connection is not started", zap.Error(err))
+				return
+			case err != nil:
+				log.Warn("Failed to check OPC UA node map", zap.Error(err))
+			default:
+				checked = true
+			}
+		}
+
+		if checked {
+			started := time.Now()
+			err := s.runSession(ctx, conn, log)
+			if ctx.Err() != nil {
+				return
+			}
+			// Сессия, проработавшая дольше предельной паузы, считается восстановленной
+			if time.Since(started) > conn.cfg.MaxReconnectInterval {
+				backoff = conn.cfg.ReconnectInterval
+			}
+			log.Warn("OPC UA session lost", zap.Error(err), zap.Duration("retry_in", backoff))
+			s.markDisconnected(ctx, conn, log)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, conn.cfg.MaxReconnectInterval)
+	}
+}
+
+// runSession открывает сессию, проверяет узлы, подписывается на них и записывает
+// уведомления до потери сессии. Возвращает причину завершения.
+func (s *OPCUAService) runSession(ctx context.Context, conn *opcuaConnection, log *zap.Logger) error {
+	session, err := opcua.Dial(ctx, conn.cfg.Endpoint, opcua.Options{
+		Username: conn.cfg.Username,
+		Password: conn.cfg.Password,
+		Timeout:  conn.cfg.Timeout,
+	})
+	if err != nil {
+		return err
+	}
+	conn.setSession(session)
+	defer func() {
+		conn.setSession(nil)
+		session.Close()
+	}()
+
+	values, err := session.Read(ctx, conn.order)
+	if err != nil {
+		return fmt.Errorf("failed to read nodes: %w", err)
+	}
+	nodes := make([]opcua.NodeID, 0, len(conn.order))
+	for i, id := range conn.order {
+		n := conn.nodes[id]
+		status := values[i].Status
+		n.invalid = status == opcua.StatusBadNodeIDUnknown || status == opcua.StatusBadNodeIDInvalid
+		if n.invalid {
+			log.Error("OPC UA node is not subscribed", zap.String("node_id", string(id)), zap.Error(status))
+			continue
+		}
+		nodes = append(nodes, id)
+	}
+	if len(nodes) == 0 {
+		return errors.New("no valid nodes to subscribe")
+	}
+
+	sub, err := session.Subscribe(ctx, conn.cfg.PublishInterval, nodes)
+	if err != nil {
+		return fmt.Errorf("failed to create subscription: %w", err)
+	}
+	defer sub.Close()
+	conn.disconnected = false
+	log.Info("OPC UA session established", zap.Int("nodes", len(nodes)))
+
+	ticker := time.NewTicker(conn.cfg.PublishInterval)
+	defer ticker.Stop()
+
+	var batch []parser.DataPoint
+	flush := func() {
+		if len(batch) > 0 && ctx.Err() == nil {
+			s.ingest(ctx, conn, batch, log)
+			batch = nil
+		}
+	}
+	defer flush()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			flush()
+		case n, ok := <-sub.Notifications():
+			if !ok {
+				if err := sub.Err(); err != nil {
+					return err
+				}
+				return opcua.StatusBadSubscriptionIDInvalid
+			}
+			if p, ok := s.point(conn, n, log); ok {
+				batch = append(batch, p)
+			}
+			if len(batch) >= opcuaBatchSize {
+				flush()
+			}
+		}
+	}
+}
+
+// point переводит уведомление в точку телеметрии. Плохие значения без числа заменяются
+// последним достоверным значением; если его нет, уведомление пропускается.
+func (s *OPCUAService) point(conn *opcuaConnection, notification opcua.Notification, log *zap.Logger) (parser.DataPoint, bool) {
+	n, ok := conn.nodes[notification.NodeID]
+	if !ok {
+		return parser.DataPoint{}, false
+	}
+	dv := notification.Value
+
+	raw, numeric := dv.Float64()
+	var value float64
+	switch {
+	case numeric:
+		value = raw*n.scale + n.cfg.Offset
+	case dv.Value != nil && !n.typeError:
+		n.typeError = true
+		log.Error("OPC UA node value is not numeric", zap.String("node_id", string(notification.NodeID)), zap.String("type", fmt.Sprintf("%T", dv.Value)))
+		fallthrough
+	default:
+		if !n.hasLast {
+			return parser.DataPoint{}, false
+		}
+		value = n.last
+	}
+	if numeric && !dv.Status.IsBad() {
+		n.last, n.hasLast = value, true
+	}
+
+	timestamp := dv.Timestamp()
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	return conn.point(notification.NodeID, n, value, quality.FromUAStatus(uint32(dv.Status)), timestamp), true
+}
+
+// markDisconnected записывает последние значения узлов с качеством last_known_value,
+// чтобы потеря связи была видна в рядах. Повторные неудачные подключения не отмечаются.
+func (s *OPCUAService) markDisconnected(ctx context.Context, conn *opcuaConnection, log *zap.Logger) {
+	if conn.disconnected {
+		return
+	}
+	conn.disconnected = true
+
+	now := time.Now()
+	var points []parser.DataPoint
+	for _, id := range conn.order {
+		n := conn.nodes[id]
+		if n.hasLast && !n.invalid {
+			points = append(points, conn.point(id, n, n.last, quality.BadLastKnownValue, now))
+		}
+	}
+	if len(points) > 0 {
+		s.ingest(ctx, conn, points, log)
+	}
+}
+
+func (s *OPCUAService) ingest(ctx context.Context, conn *opcuaConnection, points []parser.DataPoint, log *zap.Logger) {
+	result, err := s.write(ctx, points, IngestOptions{Source: "opcua:" + conn.cfg.Name})
+	if err != nil {
+		log.Error("Failed to ingest OPC UA values", zap.Error(err))
+		return
+	}
+	if len(result.Rejected) > 0 {
+		log.Warn("OPC UA values rejected", zap.Int("rejected", len(result.Rejected)))
+	}
+}
+
+// checkCatalog проверяет, что узлы ссылаются на действующие продукты каталога
+func (s *OPCUAService) checkCatalog(ctx context.Context, conn *opcuaConnection) error {
+	catalog, err := s.ingestion.catalog.get(ctx, s.ingestion.postgres)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range conn.order {
+		n := conn.nodes[id]
+		if err := checkProduct(catalog, n.companyID, n.cfg.ProductName, n.cfg.Unit); err != nil {
+			return fmt.Errorf("%w: node %s: %w", errInvalidNodeMap, id, err)
+		}
+	}
+	return nil
+}
+
+// Browse возвращает дочерние узлы node в текущей сессии подключения; пустой node — папка Objects
+func (s *OPCUAService) Browse(ctx context.Context, connection, node string) ([]opcua.Reference, error) {
+	conn, ok := s.connections[connection]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrOPCUAConnectionNotFound, connection)
+	}
+
+	id := opcua.ObjectsFolder
+	if node != "" {
+		var err error
+		if id, err = opcua.ParseNodeID(node); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidNodeID, err)
+		}
+	}
+
+	session := conn.getSession()
+	if session == nil {
+		return nil, fmt.Errorf("%w: %s", ErrOPCUANotConnected, connection)
+	}
+	refs, err := session.Browse(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to browse node %s: %w", id, err)
+	}
+	return refs, nil
+}
+
+func (c *opcuaConnection) setSession(session opcua.Session) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.session = session
+}
+
+func (c *opcuaConnection) getSession() opcua.Session {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.session
+}
+
+func (c *opcuaConnection) point(id opcua.NodeID, n *opcuaNode, value float64, code quality.Code, timestamp time.Time) parser.DataPoint {
+	labels := make(map[string]string, len(n.cfg.Labels)+2)
+	for k, v := range n.cfg.Labels {
+		labels[k] = v
+	}
+	labels["connection"] = c.cfg.Name
+	labels["node_id"] = string(id)
+
+	return parser.DataPoint{
+		CompanyID:   n.companyID,
+		ProductName: n.cfg.ProductName,
+		Value:       value,
+		Unit:        n.cfg.Unit,
+		Timestamp:   timestamp.UTC(),
+		Quality:     uint16(code),
+		Tags:        n.cfg.Tags,
+		Labels:      labels,
+	}
+}
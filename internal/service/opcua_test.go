@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"petrochemical-data-platform/internal/config"
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/pkg/opcua"
+	"petrochemical-data-platform/internal/pkg/parser"
+	"petrochemical-data-platform/internal/pkg/quality"
+	"petrochemical-data-platform/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+const (
+	opcuaThroughput opcua.NodeID = "ns=2;s=TOB.PP.Line1.Throughput"
+	opcuaLevel      opcua.NodeID = "ns=2;s=TOB.PP.Line1.Level"
+)
+
+// startOPCUAServer запускает стенд линии полипропилена с двумя переменными
+func startOPCUAServer(t *testing.T, name string) *opcua.Server {
+	t.Helper()
+	srv, err := opcua.NewServer(name)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	if err := srv.AddVariable(opcua.ObjectsFolder, opcuaThroughput, "Throughput", 42.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.AddVariable(opcua.ObjectsFolder, opcuaLevel, "Level", int32(8750)); err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+// listenOPCUAServer возвращает адрес стенда для транспорта scheme: sim:// или opc.tcp://
+// на адресе addr
+func listenOPCUAServer(t *testing.T, srv *opcua.Server, scheme, addr string) string {
+	t.Helper()
+	if scheme == "sim" {
+		return srv.Endpoint()
+	}
+	endpoint, err := srv.ListenTCP(addr)
+	if err != nil {
+		t.Fatalf("ListenTCP: %v", err)
+	}
+	return endpoint
+}
+
+// newOPCUATestService создает коннектор со стендом, каталогом в памяти и записью точек в канал
+func newOPCUATestService(t *testing.T, endpoint string) (*OPCUAService, <-chan []parser.DataPoint) {
+	t.Helper()
+	svc, err := NewOPCUAService(&IngestionService{}, config.OPCUAConfig{Connections: []config.OPCUAConnectionConfig{{
+		Name:                 "tobolsk",
+		Endpoint:             endpoint,
+		PublishInterval:      10 * time.Millisecond,
+		ReconnectInterval:    10 * time.Millisecond,
+		MaxReconnectInterval: 50 * time.Millisecond,
+		CompanyID:            "SIBUR_TOBOLSK",
+		Nodes: []config.OPCUANodeConfig{
+			{NodeID: string(opcuaThroughput), ProductName: "Полипропилен", Unit: "т/час"},
+			{NodeID: string(opcuaLevel), ProductName: "Уровень", Unit: "%", Scale: 0.01, Offset: -5},
+		},
+	}}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewOPCUAService: %v", err)
+	}
+
+	svc.ingestion.catalog = productCatalog{loadedAt: time.Now(), products: map[repository.SeriesID]domain.Product{
+		{CompanyID: "SIBUR_TOBOLSK", ProductName: "Полипропилен"}: {CompanyID: "SIBUR_TOBOLSK", Name: "Полипропилен", Unit: "т/час", Status: "active"},
+		{CompanyID: "SIBUR_TOBOLSK", ProductName: "Уровень"}:      {CompanyID: "SIBUR_TOBOLSK", Name: "Уровень", Unit: "%", Status: "active"},
+	}}
+
+	written := make(chan []parser.DataPoint, 64)
+	svc.write = func(ctx context.Context, points []parser.DataPoint, opts IngestOptions) (*IngestResult, error) {
+		if opts.Source != "opcua:tobolsk" {
+			t.Errorf("source = %q, want opcua:tobolsk", opts.Source)
+		}
+		written <- points
+		return &IngestResult{Received: len(points), Written: len(points), Rejected: []RejectedPoint{}}, nil
+	}
+	return svc, written
+}
+
+// waitPoint ждет записанную точку узла, для которой match возвращает true
+func waitPoint(t *testing.T, written <-chan []parser.DataPoint, node opcua.NodeID, match func(parser.DataPoint) bool) parser.DataPoint {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case points := <-written:
+			for _, p := range points {
+				if p.Labels["node_id"] == string(node) && match(p) {
+					return p
+				}
+			}
+		case <-deadline:
+			t.Fatalf("no matching point of %s within 5s", node)
+		}
+	}
+}
+
+func TestOPCUAPointQuality(t *testing.T) {
+	svc, _ := newOPCUATestService(t, "sim://unused")
+	conn := svc.connections["tobolsk"]
+	log := zap.NewNop()
+	source := time.Date(2025, 3, 14, 11, 30, 0, 0, time.FixedZone("YEKT", 5*3600))
+
+	tests := []struct {
+		name    string
+		node    opcua.NodeID
+		value   interface{}
+		status  opcua.StatusCode
+		want    float64
+		quality quality.Code
+		skipped bool
+	}{
+		{"bad without a previous value", opcuaThroughput, nil, opcua.StatusBadWaitingForInitialData, 0, 0, true},
+		{"good", opcuaThroughput, 42.5, opcua.StatusGood, 42.5, quality.Good, false},
+		{"scale and offset", opcuaLevel, int32(8750), opcua.StatusGood, 82.5, quality.Good, false},
+		{"good with high limit", opcuaThroughput, float32(44), opcua.StatusGood | 0x200, 44, quality.New(quality.Good, quality.LimitHigh), false},
+		{"uncertain last usable value", opcuaThroughput, 45.0, opcua.StatusUncertainLastUsableValue, 45, quality.UncertainLastUsableValue, false},
+		{"bad sensor failure keeps the last value", opcuaThroughput, nil, opcua.StatusBadSensorFailure, 45, quality.BadSensorFailure, false},
+		{"bad with a number", opcuaThroughput, 0.0, opcua.StatusBadCommunicationError, 0, quality.BadCommFailure, false},
+		{"non-numeric value", opcuaThroughput, "n/a", opcua.StatusGood, 45, quality.Good, false},
+		{"unknown node", "ns=2;s=TOB.PP.Line2.Throughput", 1.0, opcua.StatusGood, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := svc.point(conn, opcua.Notification{NodeID: tt.node, Value: opcua.DataValue{
+				Value:           tt.value,
+				Status:          tt.status,
+				SourceTimestamp: source,
+				ServerTimestamp: source.Add(time.Second),
+			}}, log)
+			if ok == tt.skipped {
+				t.Fatalf("point returned ok = %v, want %v", ok, !tt.skipped)
+			}
+			if tt.skipped {
+				return
+			}
+			if p.Value < tt.want-1e-9 || p.Value > tt.want+1e-9 {
+				t.Errorf("value = %v, want %v", p.Value, tt.want)
+			}
+			if quality.Code(p.Quality) != tt.quality {
+				t.Errorf("quality = %s, want %s", quality.Code(p.Quality), tt.quality)
+			}
+			// Метка источника сохраняется и приводится к UTC
+			if !p.Timestamp.Equal(source) || p.Timestamp.Location() != time.UTC {
+				t.Errorf("timestamp = %v, want %v in UTC", p.Timestamp, source)
+			}
+			if p.CompanyID != "SIBUR_TOBOLSK" || p.Labels["connection"] != "tobolsk" || p.Labels["node_id"] != string(tt.node) {
+				t.Errorf("point = %+v, want the tobolsk series of %s", p, tt.node)
+			}
+		})
+	}
+}
+
+func TestOPCUASubscriptionDelivery(t *testing.T) {
+	for _, scheme := range []string{"sim", "opc.tcp"} {
+		t.Run(scheme, func(t *testing.T) {
+			srv := startOPCUAServer(t, "opcua-delivery-"+scheme)
+			svc, written := newOPCUATestService(t, listenOPCUAServer(t, srv, scheme, "127.0.0.1:0"))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				svc.Run(ctx)
+				close(done)
+			}()
+			defer func() {
+				cancel()
+				<-done
+			}()
+
+			// Начальные значения приходят сразу после подписки
+			initial := make(map[string]float64)
+			for len(initial) < 2 {
+				select {
+				case points := <-written:
+					for _, p := range points {
+						initial[p.Labels["node_id"]] = p.Value
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("initial values = %v, want both nodes", initial)
+				}
+			}
+			if v := initial[string(opcuaThroughput)]; v != 42.5 {
+				t.Errorf("initial throughput = %v, want 42.5", v)
+			}
+			if v := initial[string(opcuaLevel)]; v < 82.49 || v > 82.51 {
+				t.Errorf("initial level = %v, want 82.5", v)
+			}
+
+			source := time.Date(2025, 3, 14, 6, 0, 0, 0, time.UTC)
+			srv.SetValue(opcuaThroughput, 47.0, opcua.StatusUncertainLastUsableValue, source)
+			p := waitPoint(t, written, opcuaThroughput, func(p parser.DataPoint) bool { return p.Value == 47 })
+			if !p.Timestamp.Equal(source) || quality.Code(p.Quality) != quality.UncertainLastUsableValue {
+				t.Errorf("point = %v %s, want %v uncertain/last_usable_value", p.Timestamp, quality.Code(p.Quality), source)
+			}
+
+			refs, err := svc.Browse(ctx, "tobolsk", "")
+			if err != nil || len(refs) != 2 {
+				t.Fatalf("Browse = %+v, %v; want 2 variables", refs, err)
+			}
+			if _, err := svc.Browse(ctx, "omsk", ""); !errors.Is(err, ErrOPCUAConnectionNotFound) {
+				t.Errorf("Browse(omsk): %v, want ErrOPCUAConnectionNotFound", err)
+			}
+		})
+	}
+}
+
+func TestOPCUASessionRecovery(t *testing.T) {
+	for _, scheme := range []string{"sim", "opc.tcp"} {
+		t.Run(scheme, func(t *testing.T) {
+			srv := startOPCUAServer(t, "opcua-recovery-"+scheme)
+			endpoint := listenOPCUAServer(t, srv, scheme, "127.0.0.1:0")
+			svc, written := newOPCUATestService(t, endpoint)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				svc.Run(ctx)
+				close(done)
+			}()
+			defer func() {
+				cancel()
+				<-done
+			}()
+
+			waitPoint(t, written, opcuaThroughput, func(p parser.DataPoint) bool { return p.Value == 42.5 })
+
+			// Перезапуск сервера: последние значения записываются с качеством last_known_value
+			srv.Close()
+			p := waitPoint(t, written, opcuaThroughput, func(p parser.DataPoint) bool {
+				return quality.Code(p.Quality) == quality.BadLastKnownValue
+			})
+			if p.Value != 42.5 {
+				t.Errorf("last known value = %v, want 42.5", p.Value)
+			}
+			if _, err := svc.Browse(ctx, "tobolsk", ""); !errors.Is(err, ErrOPCUANotConnected) {
+				t.Errorf("Browse without a session: %v, want ErrOPCUANotConnected", err)
+			}
+
+			// После перезапуска коннектор открывает новую сессию и подписку сам
+			restarted := startOPCUAServer(t, "opcua-recovery-"+scheme)
+			listenOPCUAServer(t, restarted, scheme, strings.TrimPrefix(endpoint, "opc.tcp://"))
+			restarted.SetValue(opcuaThroughput, 51.0, opcua.StatusGood, time.Time{})
+			waitPoint(t, written, opcuaThroughput, func(p parser.DataPoint) bool {
+				return p.Value == 51 && quality.Code(p.Quality) == quality.Good
+			})
+
+			// Сброс сессий сервером тоже восстанавливается
+			restarted.DropSessions(opcua.StatusBadSessionIDInvalid)
+			waitPoint(t, written, opcuaThroughput, func(p parser.DataPoint) bool {
+				return p.Value == 51 && quality.Code(p.Quality) == quality.BadLastKnownValue
+			})
+			restarted.SetValue(opcuaThroughput, 52.0, opcua.StatusGood, time.Time{})
+			waitPoint(t, written, opcuaThroughput, func(p parser.DataPoint) bool { return p.Value == 52 })
+			if n := restarted.Sessions(); n != 1 {
+				t.Errorf("Sessions = %d, want 1", n)
+			}
+		})
+	}
+}
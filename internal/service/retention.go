@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/migrate"
+	"petrochemical-data-platform/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrUnknownDataClass возвращается для неизвестного класса данных
+	ErrUnknownDataClass = errors.New("unknown data class")
+	// ErrPolicyNotFound возвращается при удалении несуществующей политики
+	ErrPolicyNotFound = errors.New("retention policy not found")
+	// ErrInvalidPolicy возвращается для недопустимых параметров политики
+	ErrInvalidPolicy = errors.New("invalid retention policy")
+)
+
+// dataClass связывает класс данных с таблицей ClickHouse
+type dataClass struct {
+	table      string // Имя таблицы в базе petrochemical
+	timeColumn string
+}
+
+var dataClasses = map[string]dataClass{
+	"raw":     {table: "telemetry", timeColumn: "timestamp"},
+	"hourly":  {table: "telemetry_hourly", timeColumn: "bucket"},
+	"daily":   {table: "telemetry_daily", timeColumn: "bucket"},
+	"monthly": {table: "telemetry_monthly", timeColumn: "bucket"},
+}
+
+// RetentionService управляет политиками хранения и соответствующими TTL таблиц ClickHouse
+type RetentionService struct {
+	policies  *repository.PostgresRepository
+	telemetry *repository.ClickHouseRepository
+	ttl       *migrate.ClickHouseDriver
+	logger    *zap.Logger
+}
+
+// NewRetentionService создает новый сервис политик хранения
+func NewRetentionService(policies *repository.PostgresRepository, telemetry *repository.ClickHouseRepository, ttl *migrate.ClickHouseDriver, logger *zap.Logger) *RetentionService {
+	return &RetentionService{
+		policies:  policies,
+		telemetry: telemetry,
+		ttl:       ttl,
+		logger:    logger,
+	}
+}
+
+// GetPolicies возвращает все политики хранения
+func (s *RetentionService) GetPolicies(ctx context.Context) ([]domain.RetentionPolicy, error) {
+	stored, err := s.policies.GetRetentionPolicies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get retention policies: %w", err)
+	}
+
+	policies := make([]domain.RetentionPolicy, 0, len(stored))
+	for _, p := range stored {
+		policies = append(policies, domain.RetentionPolicy{
+			DataClass:  p.DataClass,
+			CompanyID:  p.CompanyID,
+			RetainDays: p.RetainDays,
+			UpdatedAt:  p.UpdatedAt,
+		})
+	}
+	return policies, nil
+}
+
+// SetPolicy сохраняет политику и перестраивает TTL таблицы класса.
+// При dryRun политика не сохраняется, а возвращается отчет о данных, которые будут удалены.
+func (s *RetentionService) SetPolicy(ctx context.Context, policy domain.RetentionPolicy, dryRun bool) ([]domain.RetentionReport, error) {
+	if _, ok := dataClasses[policy.DataClass]; !ok {
+		return nil, ErrUnknownDataClass
+	}
+	if policy.RetainDays < 0 {
+		return nil, fmt.Errorf("%w: retain_days must not be negative", ErrInvalidPolicy)
+	}
+
+	policies, err := s.GetPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policies = upsertPolicy(policies, policy)
+
+	if dryRun {
+		return s.report(ctx, policies, policy.DataClass)
+	}
+
+	policy.UpdatedAt = time.Now()
+	if err := s.policies.SaveRetentionPolicy(ctx, repository.RetentionPolicy{
+		DataClass:  policy.DataClass,
+		CompanyID:  policy.CompanyID,
+		RetainDays: policy.RetainDays,
+		UpdatedAt:  policy.UpdatedAt,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := s.applyTTL(ctx, policies, policy.DataClass); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// DeletePolicy удаляет переопределение политики для компании и перестраивает TTL.
+// Политику по умолчанию удалить нельзя: для бессрочного хранения задается retain_days = 0.
+func (s *RetentionService) DeletePolicy(ctx context.Context, dataClass, companyID string) error {
+	if _, ok := dataClasses[dataClass]; !ok {
+		return ErrUnknownDataClass
+	}
+	if companyID == "" {
+		return fmt.Errorf("%w: default policy cannot be deleted, set retain_days to 0 instead", ErrInvalidPolicy)
+	}
+
+	deleted, err := s.policies.DeleteRetentionPolicy(ctx, dataClass, companyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete retention policy: %w", err)
+	}
+	if !deleted {
+		return ErrPolicyNotFound
+	}
+
+	policies, err := s.GetPolicies(ctx)
+	if err != nil {
+		return err
+	}
+	return s.applyTTL(ctx, policies, dataClass)
+}
+
+// Report возвращает отчет о данных, которые удаляют текущие политики (всех классов, если dataClass пуст)
+func (s *RetentionService) Report(ctx context.Context, dataClass string) ([]domain.RetentionReport, error) {
+	if _, ok := dataClasses[dataClass]; dataClass != "" && !ok {
+		return nil, ErrUnknownDataClass
+	}
+
+	policies, err := s.GetPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.report(ctx, policies, dataClass)
+}
+
+// SyncTTL применяет сохраненные политики ко всем таблицам (вызывается после миграций)
+func (s *RetentionService) SyncTTL(ctx context.Context) error {
+	policies, err := s.GetPolicies(ctx)
+	if err != nil {
+		return err
+	}
+
+	for class := range dataClasses {
+		if err := s.applyTTL(ctx, policies, class); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RetentionService) applyTTL(ctx context.Context, policies []domain.RetentionPolicy, class string) error {
+	dc := dataClasses[class]
+	var rules []migrate.TTLRule
+	var overridden []string
+	defaultDays := 0
+
+	for _, p := range policies {
+		if p.DataClass != class {
+			continue
+		}
+		if p.CompanyID == "" {
+			defaultDays = p.RetainDays
+			continue
+		}
+
+		overridden = append(overridden, migrate.QuoteString(p.CompanyID))
+		if p.RetainDays > 0 {
+			rules = append(rules, migrate.TTLRule{
+				Days:  p.RetainDays,
+				Where: "company_id = " + migrate.QuoteString(p.CompanyID),
+			})
+		}
+	}
+
+	if defaultDays > 0 {
+		rule := migrate.TTLRule{Days: defaultDays}
+		if len(overridden) > 0 {
+			rule.Where = fmt.Sprintf("company_id NOT IN (%s)", strings.Join(overridden, ", "))
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := s.ttl.ApplyTTL(ctx, dc.table, dc.timeColumn, rules); err != nil {
+		return err
+	}
+
+	s.logger.Info("Applied retention TTL", zap.String("data_class", class), zap.Int("rules", len(rules)))
+	return nil
+}
+
+func (s *RetentionService) report(ctx context.Context, policies []domain.RetentionPolicy, class string) ([]domain.RetentionReport, error) {
+	overrides := make(map[string][]string)
+	for _, p := range policies {
+		if p.CompanyID != "" {
+			overrides[p.DataClass] = append(overrides[p.DataClass], p.CompanyID)
+		}
+	}
+
+	var reports []domain.RetentionReport
+	for _, p := range policies {
+		if class != "" && p.DataClass != class {
+			continue
+		}
+
+		report := domain.RetentionReport{
+			DataClass:  p.DataClass,
+			CompanyID:  p.CompanyID,
+			RetainDays: p.RetainDays,
+		}
+		if p.RetainDays == 0 {
+			reports = append(reports, report)
+			continue
+		}
+
+		dc := dataClasses[p.DataClass]
+		table := "petrochemical." + dc.table
+		cutoff := time.Now().AddDate(0, 0, -p.RetainDays)
+		report.Cutoff = &cutoff
+
+		var exclude []string
+		if p.CompanyID == "" {
+			exclude = overrides[p.DataClass]
+		}
+
+		rows, err := s.telemetry.CountOlderThan(ctx, table, dc.timeColumn, cutoff, p.CompanyID, exclude)
+		if err != nil {
+			return nil, err
+		}
+		rowBytes, err := s.telemetry.AvgRowBytes(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Rows = rows
+		report.EstimatedBytes = uint64(math.Round(float64(rows) * rowBytes))
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func upsertPolicy(policies []domain.RetentionPolicy, policy domain.RetentionPolicy) []domain.RetentionPolicy {
+	for i, p := range policies {
+		if p.DataClass == policy.DataClass && p.CompanyID == policy.CompanyID {
+			policies[i].RetainDays = policy.RetainDays
+			return policies
+		}
+	}
+	return append(policies, policy)
+}
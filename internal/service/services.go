@@ -2,10 +2,16 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
+	"petrochemical-data-platform/internal/config"
 	"petrochemical-data-platform/internal/domain"
+	"petrochemical-data-platform/internal/pkg/gapfill"
+	"petrochemical-data-platform/internal/pkg/quality"
+	"petrochemical-data-platform/internal/pkg/transform"
 	"petrochemical-data-platform/internal/repository"
 
 	"go.uber.org/zap"
@@ -13,17 +19,19 @@ import (
 
 // AssetService обрабатывает бизнес-логику, связанную с активами
 type AssetService struct {
-	repo   *repository.PostgresRepository
-	cache  *repository.RedisRepository
-	logger *zap.Logger
+	repo      *repository.PostgresRepository
+	telemetry *repository.ClickHouseRepository // Телеметрия для сводок по иерархии
+	cache     *repository.RedisRepository
+	logger    *zap.Logger
 }
 
 // NewAssetService создает новый сервис активов
-func NewAssetService(repo *repository.PostgresRepository, cache *repository.RedisRepository, logger *zap.Logger) *AssetService {
+func NewAssetService(repo *repository.PostgresRepository, telemetry *repository.ClickHouseRepository, cache *repository.RedisRepository, logger *zap.Logger) *AssetService {
 	return &AssetService{
-		repo:   repo,
-		cache:  cache,
-		logger: logger,
+		repo:      repo,
+		telemetry: telemetry,
+		cache:     cache,
+		logger:    logger,
 	}
 }
 
@@ -42,30 +50,35 @@ func (s *AssetService) GetAssets(ctx context.Context) ([]domain.Asset, error) {
 
 		// Convert repository assets to domain assets
 		for _, dbAsset := range dbAssets {
-			asset := domain.Asset{
-				ID:        dbAsset.ID,
-				Name:      dbAsset.Name,
-				Type:      dbAsset.Type,
-				Location:  dbAsset.Location,
-				CreatedAt: dbAsset.CreatedAt,
-				UpdatedAt: dbAsset.UpdatedAt,
-			}
-			assets = append(assets, asset)
+			assets = append(assets, toDomainAsset(dbAsset))
 		}
 	}
 
 	return assets, nil
 }
 
-// CreateAsset создает новый актив
+// CreateAsset создает актив или обновляет существующий с тем же идентификатором
 func (s *AssetService) CreateAsset(ctx context.Context, asset domain.Asset) error {
+	if err := s.validateParent(ctx, asset); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if asset.CreatedAt.IsZero() {
+		asset.CreatedAt = now
+	}
+	asset.UpdatedAt = now
+
 	repoAsset := repository.Asset{
-		ID:        asset.ID,
-		Name:      asset.Name,
-		Type:      asset.Type,
-		Location:  asset.Location,
-		CreatedAt: asset.CreatedAt,
-		UpdatedAt: asset.UpdatedAt,
+		ID:                 asset.ID,
+		Name:               asset.Name,
+		Type:               asset.Type,
+		Location:           asset.Location,
+		ParentID:           asset.ParentID,
+		Level:              asset.Level,
+		TelemetryCompanyID: asset.TelemetryCompanyID,
+		CreatedAt:          asset.CreatedAt,
+		UpdatedAt:          asset.UpdatedAt,
 	}
 
 	if err := s.repo.SaveAsset(ctx, repoAsset); err != nil {
@@ -83,44 +96,324 @@ func (s *AssetService) CreateAsset(ctx context.Context, asset domain.Asset) erro
 // TelemetryService обрабатывает операции с данными телеметрии
 type TelemetryService struct {
 	repo   *repository.ClickHouseRepository
+	locks  *repository.PostgresRepository // Блокировки рядов на время пересчета агрегатов
+	cfg    config.TelemetryConfig
 	logger *zap.Logger
 }
 
 // NewTelemetryService создает новый сервис телеметрии
-func NewTelemetryService(repo *repository.ClickHouseRepository, logger *zap.Logger) *TelemetryService {
+func NewTelemetryService(repo *repository.ClickHouseRepository, locks *repository.PostgresRepository, cfg config.TelemetryConfig, logger *zap.Logger) *TelemetryService {
+	if cfg.DefaultPageSize <= 0 {
+		cfg.DefaultPageSize = 1000
+	}
+	if cfg.MaxPageSize < cfg.DefaultPageSize {
+		cfg.MaxPageSize = cfg.DefaultPageSize
+	}
+
 	return &TelemetryService{
 		repo:   repo,
+		locks:  locks,
+		cfg:    cfg,
 		logger: logger,
 	}
 }
 
-// GetTelemetry получает данные производства/продаж для компании
-func (s *TelemetryService) GetTelemetry(ctx context.Context, companyID string, start, end time.Time) ([]domain.TelemetryData, error) {
-	return s.repo.GetTelemetryData(ctx, companyID, start, end)
+// GetTelemetry получает страницу данных производства/продаж, отобранных фильтром.
+// cursor — токен из предыдущей страницы (пусто для первой), limit — размер страницы
+// (0 — по умолчанию; значения больше максимального ограничиваются).
+func (s *TelemetryService) GetTelemetry(ctx context.Context, filter repository.TelemetryFilter, start, end time.Time, cursor string, limit int) (*domain.TelemetryPage, error) {
+	q := repository.TelemetryQuery{
+		Filter: filter,
+		Start:  start,
+		End:    end,
+		Limit:  s.pageSize(limit),
+	}
+
+	if cursor != "" {
+		after, err := repository.DecodeTelemetryCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		q.After = after
+	}
+
+	data, next, err := s.repo.GetTelemetryData(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.repo.CountTelemetryData(ctx, filter, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range data {
+		decodeQuality(&data[i])
+	}
+
+	page := &domain.TelemetryPage{Data: data, TotalEstimate: total}
+	if next != nil {
+		page.NextCursor = next.Encode()
+	}
+	return page, nil
+}
+
+// StreamTelemetry передает в fn все отобранные фильтром строки за период без ограничения размера
+func (s *TelemetryService) StreamTelemetry(ctx context.Context, filter repository.TelemetryFilter, start, end time.Time, fn func(domain.TelemetryData) error) error {
+	return s.repo.StreamTelemetryData(ctx, filter, start, end, func(data domain.TelemetryData) error {
+		decodeQuality(&data)
+		return fn(data)
+	})
+}
+
+// decodeQuality дополняет сырую точку разобранным кодом качества
+func decodeQuality(data *domain.TelemetryData) {
+	info := quality.Code(data.Quality).Decode()
+	data.QualityInfo = &info
+}
+
+// maxLabelValues ограничивает число значений на ключ метки в словаре
+const maxLabelValues = 100
+
+// GetTagVocabulary возвращает словарь тегов и меток за период (пустой companyIDs — по всем компаниям)
+func (s *TelemetryService) GetTagVocabulary(ctx context.Context, companyIDs []string, start, end time.Time) (*domain.TagVocabulary, error) {
+	return s.repo.GetTagVocabulary(ctx, companyIDs, start, end, maxLabelValues)
+}
+
+// GetRevisions возвращает историю правок значений за период (учитываются только компании и продукты фильтра)
+func (s *TelemetryService) GetRevisions(ctx context.Context, filter repository.TelemetryFilter, start, end time.Time) ([]domain.TelemetryRevision, error) {
+	revisions, err := s.repo.GetRevisions(ctx, filter, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if revisions == nil {
+		revisions = []domain.TelemetryRevision{}
+	}
+	return revisions, nil
+}
+
+func (s *TelemetryService) pageSize(limit int) int {
+	if limit <= 0 {
+		return s.cfg.DefaultPageSize
+	}
+	return min(limit, s.cfg.MaxPageSize)
+}
+
+// ErrFillGridTooLarge возвращается, если сетка заполнения пропусков слишком длинная
+var ErrFillGridTooLarge = errors.New("fill grid is too large: use a coarser interval or a shorter period")
+
+// maxFillPoints ограничивает число узлов сетки на один ряд
+const maxFillPoints = 100000
+
+// GetAggregatedTelemetry получает средние значения продуктов по интервалам из таблиц агрегатов.
+// Если задан режим fill, каждый ряд выравнивается по общей сетке интервалов периода,
+// а заполненные точки помечаются как синтетические.
+func (s *TelemetryService) GetAggregatedTelemetry(ctx context.Context, filter repository.TelemetryFilter, start, end time.Time, interval repository.Interval, fill gapfill.Mode) ([]domain.AggregatedPoint, error) {
+	var grid []time.Time
+	if fill != gapfill.ModeNone {
+		// Размер сетки проверяется до ее построения: широкий период не должен занимать память
+		if interval.Count(start, end) > maxFillPoints {
+			return nil, ErrFillGridTooLarge
+		}
+		grid = interval.Grid(start, end)
+	}
+
+	rows, err := s.repo.GetAggregatedTelemetry(ctx, filter, start, end, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.AggregatedPoint, 0, len(rows))
+	// Строки упорядочены по (company_id, product_name, timestamp): ряд — непрерывный отрезок
+	for i := 0; i < len(rows); {
+		j := i
+		for j < len(rows) && rows[j].CompanyID == rows[i].CompanyID && rows[j].ProductName == rows[i].ProductName {
+			j++
+		}
+		result = append(result, alignSeries(rows[i:j], grid, fill)...)
+		i = j
+	}
+
+	return result, nil
+}
+
+// alignSeries переводит строки одного ряда в точки, при необходимости заполняя пропуски сетки
+func alignSeries(rows []domain.TelemetryData, grid []time.Time, fill gapfill.Mode) []domain.AggregatedPoint {
+	first := rows[0]
+	point := func(t time.Time, v *float64, synthetic bool) domain.AggregatedPoint {
+		return domain.AggregatedPoint{
+			CompanyID:   first.CompanyID,
+			ProductName: first.ProductName,
+			Unit:        first.Unit,
+			Timestamp:   t,
+			Value:       v,
+			Synthetic:   synthetic,
+		}
+	}
+
+	result := make([]domain.AggregatedPoint, 0, max(len(rows), len(grid)))
+	if fill == gapfill.ModeNone {
+		for _, r := range rows {
+			v := r.Value
+			result = append(result, point(r.Timestamp, &v, false))
+		}
+		return result
+	}
+
+	values := make(map[time.Time]float64, len(rows))
+	for _, r := range rows {
+		values[r.Timestamp.UTC()] = r.Value
+	}
+	for _, p := range gapfill.Fill(grid, values, fill) {
+		result = append(result, point(p.Timestamp, p.Value, p.Synthetic))
+	}
+	return result
+}
+
+// BackfillRollups пересчитывает таблицы агрегатов за период (после массовой загрузки истории).
+// Запись во все ряды во всех процессах ждет окончания пересчета.
+func (s *TelemetryService) BackfillRollups(ctx context.Context, start, end time.Time) error {
+	var filter repository.TelemetryFilter
+	unlock, err := s.locks.LockTelemetry(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s.logger.Info("Backfilling rollups", zap.Time("start", start), zap.Time("end", end))
+	return s.repo.BackfillRollups(ctx, filter, start, end)
+}
+
+// GetDerivedSeries вычисляет производные ряды (YoY, MoM, скользящие средние, накопленные суммы).
+// Преобразования по возможности выполняются в ClickHouse, остальные — в Go по сырым данным.
+// Ряды строятся отдельно для каждой компании фильтра.
+func (s *TelemetryService) GetDerivedSeries(ctx context.Context, filter repository.TelemetryFilter, start, end time.Time, specs []transform.Spec) ([]domain.DerivedSeries, error) {
+	var results []domain.DerivedSeries
+	for _, companyID := range filter.CompanyIDs {
+		series, err := s.derivedSeries(ctx, companyID, filter.Products, start, end, specs)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, series...)
+	}
+	return results, nil
+}
+
+func (s *TelemetryService) derivedSeries(ctx context.Context, companyID string, products []string, start, end time.Time, specs []transform.Spec) ([]domain.DerivedSeries, error) {
+	var results []domain.DerivedSeries
+	var raw map[string][]domain.SeriesPoint
+
+	for _, spec := range specs {
+		series, err := s.repo.GetDerivedSeries(ctx, companyID, products, start, end, spec)
+		if err == nil {
+			results = append(results, series...)
+			continue
+		}
+		if !errors.Is(err, repository.ErrTransformNotSupported) {
+			s.logger.Warn("ClickHouse transform failed, falling back to Go",
+				zap.Error(err), zap.String("transform", spec.String()))
+		}
+
+		// Сырые данные загружаются один раз с запасом на месячный лаг
+		if raw == nil {
+			lookback := transform.StartOfMonth(start).AddDate(0, -12, 0)
+			raw, err = s.repo.GetTelemetrySeries(ctx, companyID, products, lookback, end)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, product := range sortedKeys(raw) {
+			points := raw[product]
+			if !spec.Monthly() {
+				points = trimBefore(points, start)
+			}
+
+			derived := trimBefore(transform.Apply(spec, points), transform.StartOfMonth(start))
+			if len(derived) == 0 {
+				continue
+			}
+			results = append(results, domain.DerivedSeries{
+				CompanyID:   companyID,
+				ProductName: product,
+				Transform:   spec.String(),
+				Points:      derived,
+			})
+		}
+	}
+
+	return results, nil
+}
+
+// trimBefore отбрасывает точки раньше from (точки отсортированы по времени)
+func trimBefore(points []domain.SeriesPoint, from time.Time) []domain.SeriesPoint {
+	i := sort.Search(len(points), func(i int) bool { return !points[i].Timestamp.Before(from) })
+	return points[i:]
+}
+
+func sortedKeys(m map[string][]domain.SeriesPoint) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Ошибки выполнения команд управления
+var (
+	ErrUnknownEquipment   = errors.New("unknown equipment")
+	ErrUnsupportedCommand = errors.New("unsupported command")
+	ErrInvalidCommand     = errors.New("invalid command parameters")
+)
+
+// ControlTransport доставляет команды управления до оборудования по полевому протоколу
+type ControlTransport interface {
+	// Handles сообщает, обслуживает ли транспорт оборудование
+	Handles(equipmentID string) bool
+	// Execute выполняет команду на оборудовании
+	Execute(ctx context.Context, cmd domain.ControlCommand) error
 }
 
 // ControlService обрабатывает команды управления
 type ControlService struct {
-	logger *zap.Logger
-	// Would include MQTT client for sending commands
+	transports []ControlTransport
+	logger     *zap.Logger
 }
 
-// NewControlService создает новый сервис управления
-func NewControlService(logger *zap.Logger) *ControlService {
+// NewControlService создает новый сервис управления с транспортами оборудования
+func NewControlService(logger *zap.Logger, transports ...ControlTransport) *ControlService {
 	return &ControlService{
-		logger: logger,
+		transports: transports,
+		logger:     logger,
 	}
 }
 
-// SendControlCommand отправляет команду управления
-func (s *ControlService) SendControlCommand(ctx context.Context, cmd domain.ControlCommand) error {
+// SendControlCommand передает команду транспорту, обслуживающему оборудование, и сообщает,
+// выполнена ли она. Команда оборудованию без транспорта принимается без выполнения, как
+// до появления полевых протоколов: executed = false.
+func (s *ControlService) SendControlCommand(ctx context.Context, cmd domain.ControlCommand) (executed bool, err error) {
 	s.logger.Info("Sending control command",
 		zap.String("command_id", cmd.ID),
 		zap.String("equipment_id", cmd.EquipmentID),
 		zap.String("command", cmd.Command))
 
-	// Placeholder - would send via MQTT or other protocol
-	// In real implementation, this would publish to MQTT topic
+	for _, t := range s.transports {
+		if !t.Handles(cmd.EquipmentID) {
+			continue
+		}
+		if err := t.Execute(ctx, cmd); err != nil {
+			s.logger.Error("Control command failed",
+				zap.String("command_id", cmd.ID),
+				zap.String("equipment_id", cmd.EquipmentID),
+				zap.Error(err))
+			return false, err
+		}
+		return true, nil
+	}
 
-	return nil
+	s.logger.Info("No control transport for equipment, command accepted without execution",
+		zap.String("command_id", cmd.ID),
+		zap.String("equipment_id", cmd.EquipmentID))
+	return false, nil
 }